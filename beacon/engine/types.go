@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -91,6 +92,10 @@ type ExecutableData struct {
 	Withdrawals   []*types.Withdrawal `json:"withdrawals"`
 	BlobGasUsed   *uint64             `json:"blobGasUsed"`
 	ExcessBlobGas *uint64             `json:"excessBlobGas"`
+
+	// TxDAG is a field for rollups: the TxDAG sidecar kept out of the block, in
+	// the encoding of types.EncodeTxDAG.
+	TxDAG []byte `json:"txDAG,omitempty" gencodec:"optional"`
 }
 
 // JSON type overrides for executableData.
@@ -105,6 +110,7 @@ type executableDataMarshaling struct {
 	Transactions  []hexutil.Bytes
 	BlobGasUsed   *hexutil.Uint64
 	ExcessBlobGas *hexutil.Uint64
+	TxDAG         hexutil.Bytes
 }
 
 //go:generate go run github.com/fjl/gencodec -type ExecutionPayloadEnvelope -field-override executionPayloadEnvelopeMarshaling -out gen_epe.go
@@ -284,6 +290,16 @@ func ExecutableDataToBlock(params ExecutableData, versionedHashes []common.Hash,
 	if block.Hash() != params.BlockHash {
 		return nil, fmt.Errorf("blockhash mismatch, want %x, got %x", params.BlockHash, block.Hash())
 	}
+	// The TxDAG sidecar is not covered by the block hash, an invalid one is
+	// dropped instead of rejecting the block.
+	if len(params.TxDAG) > 0 {
+		dag, err := types.DecodeTxDAG(params.TxDAG)
+		if err != nil {
+			log.Warn("Invalid TxDAG sidecar in payload", "number", params.Number, "hash", params.BlockHash, "err", err)
+		} else {
+			block = block.WithTxDAG(dag)
+		}
+	}
 	return block, nil
 }
 
@@ -309,6 +325,11 @@ func BlockToExecutableData(block *types.Block, fees *big.Int, sidecars []*types.
 		BlobGasUsed:   block.BlobGasUsed(),
 		ExcessBlobGas: block.ExcessBlobGas(),
 	}
+	if dag := block.TxDAG(); dag != nil {
+		if enc, err := types.EncodeTxDAG(dag); err == nil {
+			data.TxDAG = enc
+		}
+	}
 	bundle := BlobsBundleV1{
 		Commitments: make([]hexutil.Bytes, 0),
 		Blobs:       make([]hexutil.Bytes, 0),
@@ -226,6 +226,7 @@ func runCmd(ctx *cli.Context) error {
 		EVMConfig: vm.Config{
 			Tracer:                    tracer,
 			EnableOpcodeOptimizations: ctx.Bool(VMOpcodeOptimizeFlag.Name),
+			ForceOpcodeOptimizations:  ctx.Bool(VMOpcodeOptimizeFlag.Name),
 		},
 	}
 
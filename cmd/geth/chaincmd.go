@@ -102,6 +102,8 @@ if one is set.  Otherwise it prints the genesis from the datadir.`,
 			utils.TxLookupLimitFlag,
 			utils.TransactionHistoryFlag,
 			utils.StateHistoryFlag,
+			utils.StateHistoryIndexFlag,
+			utils.StateHistoryArchiveFlag,
 		}, utils.DatabaseFlags),
 		Description: `
 The import command imports blocks from an RLP-encoded form. The form can be one file
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"math"
 	"os"
@@ -32,12 +33,15 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/triedb"
 	"github.com/ethereum/go-ethereum/triedb/pathdb"
@@ -87,6 +91,10 @@ Remove blockchain and state databases`,
 			dbPruneHashTrieCmd,
 			dbTrieGetCmd,
 			dbTrieDeleteCmd,
+			dbTxDAGStatsCmd,
+			dbProofsCmd,
+			dbExportStateHistoryCmd,
+			dbImportStateHistoryCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -261,6 +269,81 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "Exports the specified chain data to an RLP encoded stream, optionally gzip-compressed.",
 	}
+	dbTxDAGStatsCmd = &cli.Command{
+		Action:    dbTxDAGStats,
+		Name:      "txdag-stats",
+		Usage:     "Evaluate the parallelism of the TxDAGs carried in the blocks",
+		ArgsUsage: "<start (int)> <end (int)>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command evaluates the TxDAGs carried in the canonical blocks of the given range, both ends included.
+It reports the critical path, the max parallelism, the excluded txs and the estimated speedup, the txs are weighted by their gas used.`,
+	}
+	dbProofsCmd = &cli.Command{
+		Name:  "proofs",
+		Usage: "Export, import and verify the proofs kept by the proof keeper",
+		Subcommands: []*cli.Command{
+			{
+				Action:    dbExportProofs,
+				Name:      "export",
+				Usage:     "Export the kept proofs into a file",
+				ArgsUsage: "<dumpfile>",
+				Flags: flags.Merge([]cli.Flag{
+					utils.SyncModeFlag,
+				}, utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `This command exports the keeper meta records and the proof data records of the proof keeper.
+The file is JSON encoded if its name ends with ".json", RLP encoded otherwise.`,
+			},
+			{
+				Action:    dbImportProofs,
+				Name:      "import",
+				Usage:     "Import the kept proofs from a file",
+				ArgsUsage: "<dumpfile>",
+				Flags: flags.Merge([]cli.Flag{
+					utils.SyncModeFlag,
+				}, utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `This command imports the kept proofs exported by 'geth db proofs export' into an empty proof keeper store,
+e.g. of a freshly synced node. The proofs of the blocks known to the node are verified against their canonical state roots first.`,
+			},
+			{
+				Action:    dbVerifyProofs,
+				Name:      "verify",
+				Usage:     "Verify the kept proofs against the canonical state roots",
+				ArgsUsage: "",
+				Flags: flags.Merge([]cli.Flag{
+					utils.SyncModeFlag,
+				}, utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `This command verifies the account and storage proofs kept by the proof keeper against the state roots of the canonical blocks.`,
+			},
+		},
+	}
+	dbExportStateHistoryCmd = &cli.Command{
+		Action:    dbExportStateHistory,
+		Name:      "export-state-history",
+		Usage:     "Export the path-based state histories into archive files",
+		ArgsUsage: "<dir> [<first> <last>]",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command exports the state histories within [first, last] into checksummed e2store archive
+files in the given directory, each holding at most 1024 histories. All the retained histories are exported by default.`,
+	}
+	dbImportStateHistoryCmd = &cli.Command{
+		Action:    dbImportStateHistory,
+		Name:      "import-state-history",
+		Usage:     "Import the path-based state histories from archive files",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command imports the state histories from the archive files exported by 'geth db export-state-history'
+in the given directory. The histories already retained are skipped. The ones below the retained histories are kept in
+an archive in the key-value store, as the state freezer can't be prepended, and are served for historical state reads
+once they reach the retained ones. The histories pruned since then are deleted, disconnecting the archive, unless
+--history.state.archive moves them into the archive instead. The others must extend the retained
+ones continuously, up to the persisted state, as the histories above the disk layer are truncated on startup.`,
+	}
 	dbMetadataCmd = &cli.Command{
 		Action: showMetaData,
 		Name:   "metadata",
@@ -1118,6 +1201,345 @@ func showMetaData(ctx *cli.Context) error {
 	return nil
 }
 
+func dbTxDAGStats(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	start, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid start block: %v", err)
+	}
+	end, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid end block: %v", err)
+	}
+	if start > end {
+		return fmt.Errorf("invalid block range %d-%d", start, end)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	var (
+		stats  = new(types.TxDAGStats)
+		logged = time.Now()
+	)
+	for number := start; number <= end; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("canonical block #%d not found", number)
+		}
+		block := rawdb.ReadBlock(db, hash, number)
+		if block == nil {
+			return fmt.Errorf("block #%d not found", number)
+		}
+		// The TxDAG in the calldata takes precedence over the sidecar, the same
+		// as the chain reads it.
+		if _, err := types.GetTxDAG(block); err != nil {
+			if dag := rawdb.ReadTxDAG(db, hash, number); dag != nil {
+				block = block.WithTxDAG(dag)
+			}
+		}
+		stats.Merge(types.NewBlockTxDAGStats(block, rawdb.ReadRawReceipts(db, hash, number)))
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Evaluating TxDAGs", "number", number, "blocks", stats.Blocks, "txs", stats.TxCount)
+			logged = time.Now()
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Field", "Value"})
+	table.AppendBulk([][]string{
+		{"Blocks", fmt.Sprintf("%d (#%d-#%d)", stats.Blocks, start, end)},
+		{"Blocks without TxDAG", fmt.Sprintf("%d", stats.NoTxDAGBlocks)},
+		{"Transactions", fmt.Sprintf("%d", stats.TxCount)},
+		{"Excluded transactions", fmt.Sprintf("%d", stats.ExcludedTxs)},
+		{"Critical path (txs)", fmt.Sprintf("%d", stats.CriticalPath)},
+		{"Critical path (gas)", fmt.Sprintf("%d", stats.CriticalPathWeight)},
+		{"Total gas", fmt.Sprintf("%d", stats.TotalWeight)},
+		{"Max parallelism", fmt.Sprintf("%d", stats.MaxParallelism)},
+		{"Estimated speedup", fmt.Sprintf("%.2f", stats.Speedup)},
+	})
+	table.Render()
+	return nil
+}
+
+// openProofFreezer opens the proof freezer of the proof keeper under the
+// ancient directory of the chain database.
+func openProofFreezer(db ethdb.Database, readonly bool) (*rawdb.ResettableFreezer, error) {
+	ancientDir, err := db.AncientDatadir()
+	if err != nil {
+		return nil, err
+	}
+	return rawdb.NewProofFreezer(ancientDir, readonly)
+}
+
+// verifyKeptProofs verifies the kept proofs against the state roots of their
+// canonical blocks, returning the number of verified proofs and the number of
+// proofs whose block is unknown.
+func verifyKeptProofs(db ethdb.Reader, proofs []*core.KeptProof) (int, int, error) {
+	var verified, unknown int
+	for _, proof := range proofs {
+		hash := rawdb.ReadCanonicalHash(db, proof.BlockID)
+		if hash == (common.Hash{}) {
+			unknown++
+			continue
+		}
+		header := rawdb.ReadHeader(db, hash, proof.BlockID)
+		if header == nil {
+			unknown++
+			continue
+		}
+		if err := core.VerifyKeptProof(proof, header.Root); err != nil {
+			return verified, unknown, fmt.Errorf("proof %d of block #%d: %v", proof.ProofID, proof.BlockID, err)
+		}
+		verified++
+	}
+	return verified, unknown, nil
+}
+
+func dbExportProofs(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	freezer, err := openProofFreezer(db, true)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	proofs, err := core.ReadKeptProofs(db, freezer)
+	if err != nil {
+		return err
+	}
+	var blob []byte
+	if strings.HasSuffix(ctx.Args().First(), ".json") {
+		blob, err = json.MarshalIndent(proofs, "", "  ")
+	} else {
+		blob, err = rlp.EncodeToBytes(proofs)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(ctx.Args().First(), blob, 0644); err != nil {
+		return err
+	}
+	log.Info("Exported kept proofs", "file", ctx.Args().First(), "metas", len(proofs.Metas), "proofs", len(proofs.Proofs))
+	return nil
+}
+
+func dbImportProofs(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	blob, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	proofs := new(core.KeptProofs)
+	if strings.HasSuffix(ctx.Args().First(), ".json") {
+		err = json.Unmarshal(blob, proofs)
+	} else {
+		err = rlp.DecodeBytes(blob, proofs)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid kept proofs file: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	verified, unknown, err := verifyKeptProofs(db, proofs.Proofs)
+	if err != nil {
+		return err
+	}
+	if unknown > 0 {
+		log.Warn("Importing proofs of unknown blocks unverified", "proofs", unknown)
+	}
+	freezer, err := openProofFreezer(db, false)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	if err := core.WriteKeptProofs(db, freezer, proofs); err != nil {
+		return err
+	}
+	log.Info("Imported kept proofs", "file", ctx.Args().First(), "metas", len(proofs.Metas), "proofs", len(proofs.Proofs), "verified", verified)
+	return nil
+}
+
+func dbVerifyProofs(ctx *cli.Context) error {
+	if ctx.NArg() != 0 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	freezer, err := openProofFreezer(db, true)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	proofs, err := core.ReadKeptProofs(db, freezer)
+	if err != nil {
+		return err
+	}
+	verified, unknown, err := verifyKeptProofs(db, proofs.Proofs)
+	if err != nil {
+		return err
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Field", "Value"})
+	table.AppendBulk([][]string{
+		{"Keeper metas", fmt.Sprintf("%d", len(proofs.Metas))},
+		{"Proofs", fmt.Sprintf("%d", len(proofs.Proofs))},
+		{"Verified proofs", fmt.Sprintf("%d", verified)},
+		{"Proofs of unknown blocks", fmt.Sprintf("%d", unknown)},
+	})
+	table.Render()
+	return nil
+}
+
+func dbExportStateHistory(ctx *cli.Context) error {
+	if ctx.NArg() != 1 && ctx.NArg() != 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	// The state histories are kept along with the states.
+	statedb := ethdb.Database(db)
+	if db.StateStore() != nil {
+		statedb = db.StateStore()
+	}
+	ancientDir, err := statedb.AncientDatadir()
+	if err != nil {
+		return err
+	}
+	freezer, err := rawdb.NewStateFreezer(ancientDir, true, rawdb.DetectTrieNodesFile(ancientDir))
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	tail, err := freezer.Tail()
+	if err != nil {
+		return err
+	}
+	head, err := freezer.Ancients()
+	if err != nil {
+		return err
+	}
+	first, last := tail+1, head
+	if ctx.NArg() == 3 {
+		if first, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			return fmt.Errorf("invalid first state history id: %v", err)
+		}
+		if last, err = strconv.ParseUint(ctx.Args().Get(2), 10, 64); err != nil {
+			return fmt.Errorf("invalid last state history id: %v", err)
+		}
+	}
+	if first > last {
+		return fmt.Errorf("no state history to export, range: [%d, %d]", first, last)
+	}
+	dir := ctx.Args().First()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	start := time.Now()
+	for i := first; i <= last; i += pathdb.MaxHistoryArchiveSize {
+		end := min(last, i+pathdb.MaxHistoryArchiveSize-1)
+		filename := filepath.Join(dir, pathdb.HistoryArchiveName(i, end))
+		f, err := os.Create(filename)
+		if err != nil {
+			return fmt.Errorf("could not create archive file: %w", err)
+		}
+		err = pathdb.ExportHistoryArchive(freezer, f, i, end)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export state histories [%d, %d]: %w", i, end, err)
+		}
+		log.Info("Exported state histories", "first", i, "last", end, "file", filename, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
+
+func dbImportStateHistory(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	dir := ctx.Args().First()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	// The state histories are kept along with the states.
+	statedb := ethdb.Database(db)
+	if db.StateStore() != nil {
+		statedb = db.StateStore()
+	}
+	ancientDir, err := statedb.AncientDatadir()
+	if err != nil {
+		return err
+	}
+	writeTrieNodes := rawdb.DetectTrieNodesFile(ancientDir)
+	freezer, err := rawdb.NewStateFreezer(ancientDir, false, writeTrieNodes)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	var (
+		start    = time.Now()
+		imported int
+	)
+	// The archive names sort by the history ids.
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".e2hs" {
+			continue
+		}
+		filename := filepath.Join(dir, entry.Name())
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		n, err := pathdb.ImportHistoryArchive(statedb, freezer, f, writeTrieNodes)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", filename, err)
+		}
+		imported += n
+		log.Info("Imported state histories", "file", filename, "count", n, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	log.Info("Imported state history archives", "count", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 func hbss2pbss(ctx *cli.Context) error {
 	if ctx.NArg() > 1 {
 		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
@@ -85,9 +85,17 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrivateLifetimeFlag,
+		utils.TxPoolSenderRateFlag,
+		utils.TxPoolSenderBurstFlag,
+		utils.TxPoolPeerRateFlag,
+		utils.TxPoolPeerBurstFlag,
+		utils.TxPoolReputationFlag,
 		utils.TxPoolReannounceTimeFlag,
 		utils.TxPoolReannounceRemotesFlag,
 		utils.BundlePoolGlobalSlotsFlag,
+		utils.BundlePoolJournalFlag,
+		utils.BundlePoolRejournalFlag,
 		utils.BlobPoolDataDirFlag,
 		utils.BlobPoolDataCapFlag,
 		utils.BlobPoolPriceBumpFlag,
@@ -99,11 +107,15 @@ var (
 		utils.TxLookupLimitFlag, // deprecated
 		utils.TransactionHistoryFlag,
 		utils.StateHistoryFlag,
+		utils.StateHistoryIndexFlag,
+		utils.StateHistoryArchiveFlag,
 		utils.ProposeBlockIntervalFlag,
 		utils.PathDBNodeBufferTypeFlag,
 		utils.EnableProofKeeperFlag,
 		utils.KeepProofBlockSpanFlag,
+		utils.KeepProofTargetsFlag,
 		utils.JournalFileFlag,
+		utils.CheckpointIntervalFlag,
 		utils.LightServeFlag,    // deprecated
 		utils.LightIngressFlag,  // deprecated
 		utils.LightEgressFlag,   // deprecated
@@ -142,7 +154,12 @@ var (
 		utils.MinerNewPayloadTimeout,
 		utils.MevEnabledFlag,
 		utils.MevBundleReceiverUrlFlag,
+		utils.MevBundleReceiverKeyFlag,
 		utils.MevBundleGasPriceFloorFlag,
+		utils.MevBundleMergeStrategyFlag,
+		utils.MevBundleMergeOrderingsFlag,
+		utils.MevBundleMergeBudgetFlag,
+		utils.MevGaslessPolicyFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV4Flag,
@@ -173,10 +190,22 @@ var (
 		utils.RollupSuperchainUpgradesFlag,
 		utils.ParallelTxDAGFlag,
 		utils.ParallelTxDAGSenderPrivFlag,
+		utils.ParallelTxDAGExecFlag,
+		utils.ParallelTxDAGExecWorkersFlag,
+		utils.ParallelTxDAGVerifyFlag,
+		utils.ParallelTxDAGRejectUnsafeFlag,
+		utils.ParallelTxDAGSidecarFlag,
+		utils.ParallelTxDAGOrderingFlag,
+		utils.ParallelTxDAGOrderingToleranceFlag,
 		configFileFlag,
 		utils.LogDebugFlag,
 		utils.LogBacktraceAtFlag,
 		utils.VMOpcodeOptimizeFlag,
+		utils.VMBasicBlockGasFlag,
+		utils.VMOpcodeProfileFlag,
+		utils.VMOpcodeProfileFileFlag,
+		utils.VMOpcodeCheckRateFlag,
+		utils.VMOpcodeCheckDirFlag,
 	}, utils.NetworkFlags, utils.DatabaseFlags)
 
 	rpcFlags = []cli.Flag{
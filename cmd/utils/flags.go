@@ -352,6 +352,17 @@ var (
 		Value:    params.FullImmutabilityThreshold,
 		Category: flags.StateCategory,
 	}
+	KeepProofTargetsFlag = &cli.StringSliceFlag{
+		Name:     "pathdb.keepprooftargets",
+		Usage:    "Comma separated accounts to keep proofs of besides the withdrawal proof, each with optional storage keys (address[:key[:key...]])",
+		Category: flags.StateCategory,
+	}
+	CheckpointIntervalFlag = &cli.DurationFlag{
+		Name:     "pathdb.checkpoint",
+		Usage:    "Interval to checkpoint the node buffer list for the fast crash recovery",
+		Value:    pathdb.DefaultCheckpointInterval,
+		Category: flags.StateCategory,
+	}
 	JournalFileFlag = &cli.BoolFlag{
 		Name:     "journalfile",
 		Usage:    "Enable using journal file to store the TrieJournal instead of KVDB in pbss (default = false)",
@@ -364,6 +375,16 @@ var (
 		Value:    ethconfig.Defaults.StateHistory,
 		Category: flags.StateCategory,
 	}
+	StateHistoryIndexFlag = &cli.BoolFlag{
+		Name:     "history.state.index",
+		Usage:    "Index the retained state history to serve the historical states of the path-based scheme",
+		Category: flags.StateCategory,
+	}
+	StateHistoryArchiveFlag = &cli.BoolFlag{
+		Name:     "history.state.archive",
+		Usage:    "Move the pruned state history into the archive imported below it instead of deleting it",
+		Category: flags.StateCategory,
+	}
 	TransactionHistoryFlag = &cli.Uint64Flag{
 		Name:     "history.transactions",
 		Usage:    "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
@@ -446,6 +467,41 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPrivateLifetimeFlag = &cli.Uint64Flag{
+		Name:     "txpool.privatelifetime",
+		Usage:    "Number of blocks a private transaction is kept in the pool",
+		Value:    ethconfig.Defaults.TxPool.PrivateLifetime,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSenderRateFlag = &cli.Uint64Flag{
+		Name:     "txpool.senderrate",
+		Usage:    "Remote transactions per second admitted from each sender (0 = unlimited)",
+		Value:    ethconfig.Defaults.TxPool.SenderRate,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSenderBurstFlag = &cli.Uint64Flag{
+		Name:     "txpool.senderburst",
+		Usage:    "Remote transactions admitted at once from each sender above its rate",
+		Value:    ethconfig.Defaults.TxPool.SenderBurst,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPeerRateFlag = &cli.Uint64Flag{
+		Name:     "txpool.peerrate",
+		Usage:    "Transactions per second admitted from each network peer (0 = unlimited)",
+		Value:    ethconfig.Defaults.TxPool.PeerRate,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPeerBurstFlag = &cli.Uint64Flag{
+		Name:     "txpool.peerburst",
+		Usage:    "Transactions admitted at once from each network peer above its rate",
+		Value:    ethconfig.Defaults.TxPool.PeerBurst,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolReputationFlag = &cli.BoolFlag{
+		Name:     "txpool.reputation",
+		Usage:    "Reduce the slots of remote senders replacing or submitting invalid transactions, and the admission of peers relaying invalid ones",
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolReannounceTimeFlag = &cli.DurationFlag{
 		Name:     "txpool.reannouncetime",
 		Usage:    "Duration for announcing local pending transactions again (default = 10 years, minimum = 1 minute)",
@@ -465,6 +521,18 @@ var (
 		Value:    ethconfig.Defaults.BundlePool.GlobalSlots,
 		Category: flags.BundlePoolCategory,
 	}
+	BundlePoolJournalFlag = &cli.StringFlag{
+		Name:     "bundlepool.journal",
+		Usage:    "Disk journal for accepted bundles to survive node restarts",
+		Value:    ethconfig.Defaults.BundlePool.Journal,
+		Category: flags.BundlePoolCategory,
+	}
+	BundlePoolRejournalFlag = &cli.DurationFlag{
+		Name:     "bundlepool.rejournal",
+		Usage:    "Time interval to regenerate the bundle journal",
+		Value:    ethconfig.Defaults.BundlePool.Rejournal,
+		Category: flags.BundlePoolCategory,
+	}
 	// Blob transaction pool settings
 	BlobPoolDataDirFlag = &cli.StringFlag{
 		Name:     "blobpool.datadir",
@@ -599,12 +667,40 @@ var (
 		Usage:    "Url of bundle receiver endpoint to use. Multiple urls are supported, separated by commas",
 		Category: flags.MEVCategory,
 	}
+	MevBundleReceiverKeyFlag = &cli.StringFlag{
+		Name:     "mev.bundle.receiver.key",
+		Usage:    "Private key file to sign the requests to the bundle receivers with",
+		Category: flags.MEVCategory,
+	}
 	MevBundleGasPriceFloorFlag = &cli.Int64Flag{
 		Name:     "mev.bundle.gasprice.floor",
 		Usage:    "Minimum bundle gas price for mev",
 		Value:    ethconfig.Defaults.Miner.Mev.MevBundleGasPriceFloor,
 		Category: flags.MEVCategory,
 	}
+	MevGaslessPolicyFlag = &cli.StringFlag{
+		Name:     "mev.gasless.policy",
+		Usage:    "Sponsorship policy file (TOML or JSON) of the gasless transactions, reloaded on changes",
+		Category: flags.MEVCategory,
+	}
+	MevBundleMergeStrategyFlag = &cli.StringFlag{
+		Name:     "mev.bundle.merge.strategy",
+		Usage:    `Strategy to merge the bundles into the block by ("greedy" or "search")`,
+		Value:    ethconfig.Defaults.Miner.Mev.MevBundleMergeStrategy,
+		Category: flags.MEVCategory,
+	}
+	MevBundleMergeOrderingsFlag = &cli.IntFlag{
+		Name:     "mev.bundle.merge.orderings",
+		Usage:    "Maximum number of bundle orderings evaluated by the search merge strategy",
+		Value:    ethconfig.Defaults.Miner.Mev.MevBundleMergeOrderings,
+		Category: flags.MEVCategory,
+	}
+	MevBundleMergeBudgetFlag = &cli.DurationFlag{
+		Name:     "mev.bundle.merge.budget",
+		Usage:    "Time allowance of the search merge strategy",
+		Value:    ethconfig.Defaults.Miner.Mev.MevBundleMergeBudget,
+		Category: flags.MEVCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -1117,12 +1213,86 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		Category: flags.VMCategory,
 	}
 
+	VMBasicBlockGasFlag = &cli.BoolFlag{
+		Name:     "vm.blockgas",
+		Usage:    "Charge the constant gas and check the stack once per basic block in the EVM interpreter",
+		Category: flags.VMCategory,
+	}
+
+	VMOpcodeProfileFlag = &cli.BoolFlag{
+		Name:     "vm.opcode.profile",
+		Usage:    "Profile the opcode n-grams executed in the imported blocks, as the candidates of new fused opcodes",
+		Category: flags.VMCategory,
+	}
+
+	VMOpcodeProfileFileFlag = &cli.StringFlag{
+		Name:     "vm.opcode.profile.file",
+		Usage:    "File the opcode profile is dumped into on shutdown, only works with --vm.opcode.profile",
+		Category: flags.VMCategory,
+	}
+
+	VMOpcodeCheckRateFlag = &cli.Float64Flag{
+		Name:     "vm.opcode.check.rate",
+		Usage:    "Fraction of the imported txs executed twice with and without the opcode optimization to detect divergences (0 = disabled)",
+		Category: flags.VMCategory,
+	}
+
+	VMOpcodeCheckDirFlag = &cli.StringFlag{
+		Name:     "vm.opcode.check.dir",
+		Usage:    "Directory the opcode optimization divergences and their reproducers are recorded into (default = inside the datadir)",
+		Category: flags.VMCategory,
+	}
+
 	ParallelTxDAGSenderPrivFlag = &cli.StringFlag{
 		Name:     "parallel.txdagsenderpriv",
 		Usage:    "private key of the sender who sends the TxDAG transactions",
 		Value:    "",
 		Category: flags.VMCategory,
 	}
+
+	ParallelTxDAGExecFlag = &cli.BoolFlag{
+		Name:     "parallel.txdagexec",
+		Usage:    "Enable the experimental parallel execution of blocks by their TxDAG (default = false)",
+		Category: flags.VMCategory,
+	}
+
+	ParallelTxDAGExecWorkersFlag = &cli.IntFlag{
+		Name:     "parallel.txdagexecworkers",
+		Usage:    "Number of workers to execute the txs in parallel (default = number of CPUs)",
+		Value:    0,
+		Category: flags.VMCategory,
+	}
+
+	ParallelTxDAGVerifyFlag = &cli.BoolFlag{
+		Name:     "parallel.txdagverify",
+		Usage:    "Verify the TxDAG carried in the imported blocks, mark the unsafe ones to be executed in sequence (default = false)",
+		Category: flags.VMCategory,
+	}
+
+	ParallelTxDAGRejectUnsafeFlag = &cli.BoolFlag{
+		Name:     "parallel.txdagrejectunsafe",
+		Usage:    "Reject the blocks whose TxDAG misses any dependency, only works with --parallel.txdagverify (default = false)",
+		Category: flags.VMCategory,
+	}
+
+	ParallelTxDAGSidecarFlag = &cli.BoolFlag{
+		Name:     "parallel.txdagsidecar",
+		Usage:    "Keep the mined TxDAG as a block sidecar instead of a transaction, and exchange the sidecars over the engine API and the txdag p2p protocol (default = false)",
+		Category: flags.VMCategory,
+	}
+
+	ParallelTxDAGOrderingFlag = &cli.BoolFlag{
+		Name:     "parallel.txdagordering",
+		Usage:    "Order the mined txs to shorten the critical path of the generated TxDAG, only works with --parallel.txdag (default = false)",
+		Category: flags.VMCategory,
+	}
+
+	ParallelTxDAGOrderingToleranceFlag = &cli.Uint64Flag{
+		Name:     "parallel.txdagorderingtolerance",
+		Usage:    "Percentage of the best tip the TxDAG ordering may give up when picking a tx",
+		Value:    miner.DefaultConfig.ParallelTxDAGOrderingTolerance,
+		Category: flags.VMCategory,
+	}
 )
 
 var (
@@ -1747,6 +1917,24 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.PrivateLifetime = ctx.Uint64(TxPoolPrivateLifetimeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSenderRateFlag.Name) {
+		cfg.SenderRate = ctx.Uint64(TxPoolSenderRateFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSenderBurstFlag.Name) {
+		cfg.SenderBurst = ctx.Uint64(TxPoolSenderBurstFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPeerRateFlag.Name) {
+		cfg.PeerRate = ctx.Uint64(TxPoolPeerRateFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPeerBurstFlag.Name) {
+		cfg.PeerBurst = ctx.Uint64(TxPoolPeerBurstFlag.Name)
+	}
+	if ctx.IsSet(TxPoolReputationFlag.Name) {
+		cfg.Reputation = ctx.Bool(TxPoolReputationFlag.Name)
+	}
 	if ctx.IsSet(MinerEffectiveGasLimitFlag.Name) {
 		// While technically this is a miner config parameter, we also want the txpool to enforce
 		// it to avoid accepting transactions that can never be included in a block.
@@ -1764,6 +1952,12 @@ func setBundlePool(ctx *cli.Context, cfg *bundlepool.Config) {
 	if ctx.IsSet(BundlePoolGlobalSlotsFlag.Name) {
 		cfg.GlobalSlots = ctx.Uint64(BundlePoolGlobalSlotsFlag.Name)
 	}
+	if ctx.IsSet(BundlePoolJournalFlag.Name) {
+		cfg.Journal = ctx.String(BundlePoolJournalFlag.Name)
+	}
+	if ctx.IsSet(BundlePoolRejournalFlag.Name) {
+		cfg.Rejournal = ctx.Duration(BundlePoolRejournalFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1795,9 +1989,33 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		url := ctx.String(MevBundleReceiverUrlFlag.Name)
 		cfg.Mev.MevReceivers = strings.Split(url, ",")
 	}
+	if ctx.IsSet(MevBundleReceiverKeyFlag.Name) {
+		key, err := crypto.LoadECDSA(ctx.String(MevBundleReceiverKeyFlag.Name))
+		if err != nil {
+			Fatalf("Failed to load bundle receiver key: %v", err)
+		}
+		cfg.Mev.MevReceiverKey = key
+	}
 	if ctx.IsSet(MevBundleGasPriceFloorFlag.Name) {
 		cfg.Mev.MevBundleGasPriceFloor = ctx.Int64(MevBundleGasPriceFloorFlag.Name)
 	}
+	if ctx.IsSet(MevGaslessPolicyFlag.Name) {
+		cfg.Mev.MevGaslessPolicy = ctx.String(MevGaslessPolicyFlag.Name)
+	}
+	if ctx.IsSet(MevBundleMergeStrategyFlag.Name) {
+		switch strategy := ctx.String(MevBundleMergeStrategyFlag.Name); strategy {
+		case miner.BundleMergeGreedy, miner.BundleMergeSearch:
+			cfg.Mev.MevBundleMergeStrategy = strategy
+		default:
+			Fatalf("Invalid bundle merge strategy %q", strategy)
+		}
+	}
+	if ctx.IsSet(MevBundleMergeOrderingsFlag.Name) {
+		cfg.Mev.MevBundleMergeOrderings = ctx.Int(MevBundleMergeOrderingsFlag.Name)
+	}
+	if ctx.IsSet(MevBundleMergeBudgetFlag.Name) {
+		cfg.Mev.MevBundleMergeBudget = ctx.Duration(MevBundleMergeBudgetFlag.Name)
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1938,6 +2156,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(StateHistoryFlag.Name) {
 		cfg.StateHistory = ctx.Uint64(StateHistoryFlag.Name)
 	}
+	if ctx.IsSet(StateHistoryIndexFlag.Name) {
+		cfg.StateHistoryIndex = ctx.Bool(StateHistoryIndexFlag.Name)
+	}
+	if ctx.IsSet(StateHistoryArchiveFlag.Name) {
+		cfg.StateHistoryArchive = ctx.Bool(StateHistoryArchiveFlag.Name)
+	}
 	if ctx.IsSet(StateSchemeFlag.Name) {
 		cfg.StateScheme = ctx.String(StateSchemeFlag.Name)
 	}
@@ -1965,9 +2189,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(KeepProofBlockSpanFlag.Name) {
 		cfg.KeepProofBlockSpan = ctx.Uint64(KeepProofBlockSpanFlag.Name)
 	}
+	if ctx.IsSet(KeepProofTargetsFlag.Name) {
+		for _, target := range ctx.StringSlice(KeepProofTargetsFlag.Name) {
+			t, err := core.ParseProofTarget(target)
+			if err != nil {
+				Fatalf("Invalid proof keeper target: %v", err)
+			}
+			cfg.KeepProofTargets = append(cfg.KeepProofTargets, t)
+		}
+	}
 	if ctx.IsSet(JournalFileFlag.Name) {
 		cfg.JournalFileEnabled = true
 	}
+	if ctx.IsSet(CheckpointIntervalFlag.Name) {
+		cfg.CheckpointInterval = ctx.Duration(CheckpointIntervalFlag.Name)
+	}
 
 	if ctx.String(GCModeFlag.Name) == "archive" && cfg.TransactionHistory != 0 {
 		cfg.TransactionHistory = 0
@@ -2015,6 +2251,35 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		cfg.EnableParallelTxDAG = ctx.Bool(ParallelTxDAGFlag.Name)
 	}
 
+	if ctx.IsSet(ParallelTxDAGExecFlag.Name) {
+		cfg.EnableParallelTxDAGExec = ctx.Bool(ParallelTxDAGExecFlag.Name)
+	}
+
+	if ctx.IsSet(ParallelTxDAGExecWorkersFlag.Name) {
+		cfg.ParallelTxDAGExecWorkers = ctx.Int(ParallelTxDAGExecWorkersFlag.Name)
+	}
+
+	if ctx.IsSet(ParallelTxDAGVerifyFlag.Name) {
+		cfg.EnableParallelTxDAGVerify = ctx.Bool(ParallelTxDAGVerifyFlag.Name)
+	}
+
+	if ctx.IsSet(ParallelTxDAGRejectUnsafeFlag.Name) {
+		cfg.RejectUnsafeTxDAG = ctx.Bool(ParallelTxDAGRejectUnsafeFlag.Name)
+	}
+
+	if ctx.IsSet(ParallelTxDAGSidecarFlag.Name) {
+		cfg.EnableParallelTxDAGSidecar = ctx.Bool(ParallelTxDAGSidecarFlag.Name)
+		cfg.Miner.ParallelTxDAGSidecar = cfg.EnableParallelTxDAGSidecar
+	}
+
+	if ctx.IsSet(ParallelTxDAGOrderingFlag.Name) {
+		cfg.Miner.ParallelTxDAGOrdering = ctx.Bool(ParallelTxDAGOrderingFlag.Name)
+	}
+
+	if ctx.IsSet(ParallelTxDAGOrderingToleranceFlag.Name) {
+		cfg.Miner.ParallelTxDAGOrderingTolerance = ctx.Uint64(ParallelTxDAGOrderingToleranceFlag.Name)
+	}
+
 	if ctx.IsSet(ParallelTxDAGSenderPrivFlag.Name) {
 		priHex := ctx.String(ParallelTxDAGSenderPrivFlag.Name)
 		if cfg.Miner.ParallelTxDAGSenderPriv, err = crypto.HexToECDSA(priHex); err != nil {
@@ -2029,6 +2294,23 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		}
 	}
 
+	if ctx.IsSet(VMBasicBlockGasFlag.Name) {
+		cfg.EnableBasicBlockGas = ctx.Bool(VMBasicBlockGasFlag.Name)
+	}
+
+	if ctx.IsSet(VMOpcodeProfileFlag.Name) {
+		cfg.EnableOpcodeProfiling = ctx.Bool(VMOpcodeProfileFlag.Name)
+	}
+	if ctx.IsSet(VMOpcodeProfileFileFlag.Name) {
+		cfg.OpcodeProfileFile = ctx.String(VMOpcodeProfileFileFlag.Name)
+	}
+	if ctx.IsSet(VMOpcodeCheckRateFlag.Name) {
+		cfg.OpcodeCheckRate = ctx.Float64(VMOpcodeCheckRateFlag.Name)
+	}
+	if ctx.IsSet(VMOpcodeCheckDirFlag.Name) {
+		cfg.OpcodeCheckDir = ctx.String(VMOpcodeCheckDirFlag.Name)
+	}
+
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
 	}
@@ -2526,6 +2808,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
 		StateScheme:         scheme,
 		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
+		StateHistoryIndex:   ctx.Bool(StateHistoryIndexFlag.Name),
+		StateHistoryArchive: ctx.Bool(StateHistoryArchiveFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
@@ -2546,7 +2830,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		cache.TrieDirtyLimit = ctx.Int(CacheFlag.Name) * ctx.Int(CacheGCFlag.Name) / 100
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.Bool(VMEnableDebugFlag.Name),
-		EnableOpcodeOptimizations: ctx.Bool(VMOpcodeOptimizeFlag.Name)}
+		EnableOpcodeOptimizations: ctx.Bool(VMOpcodeOptimizeFlag.Name),
+		EnableBasicBlockGas:       ctx.Bool(VMBasicBlockGasFlag.Name)}
 
 	if vmcfg.EnableOpcodeOptimizations {
 		compiler.EnableOptimization()
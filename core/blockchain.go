@@ -121,6 +121,8 @@ const (
 
 	txLogsCacheLimit      = 512
 	miningStateCacheLimit = 128
+	unsafeTxDAGCacheLimit = 128
+	txDAGSidecarLimit     = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
@@ -160,15 +162,19 @@ type CacheConfig struct {
 	Preimages            bool                  // Whether to store preimage of trie key to the disk
 	NoTries              bool                  // Insecure settings. Do not have any tries in databases if enabled.
 	StateHistory         uint64                // Number of blocks from head whose state histories are reserved.
+	StateHistoryIndex    bool                  // Whether to index state histories for historical state reads
+	StateHistoryArchive  bool                  // Whether to move the pruned state histories into the imported archive
 	StateScheme          string                // Scheme used to store ethereum states and merkle tree nodes on top
 	PathNodeBuffer       pathdb.NodeBufferType // Type of trienodebuffer to cache trie nodes in disklayer
 	ProposeBlockInterval uint64                // Propose block to L1 block interval.
 	EnableProofKeeper    bool                  // Whether to enable proof keeper
 	KeepProofBlockSpan   uint64                // Block span of keep proof
+	KeepProofTargets     []ProofTarget         // Accounts and storage slots to keep proofs of, besides the withdrawal proof
 	SnapshotNoBuild      bool                  // Whether the background generation is allowed
 	SnapshotWait         bool                  // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 	JournalFilePath      string                // The file path to journal pathdb diff layers
 	JournalFile          bool                  // Whether to enable journal file
+	CheckpointInterval   time.Duration         // Interval to checkpoint the pathdb node buffer list
 	UseBase              bool                  // Flag if just use base for nodebufferlist
 
 	TrieCommitInterval uint64 // Define a block height interval, commit trie every TrieCommitInterval block height.
@@ -189,12 +195,15 @@ func (c *CacheConfig) triedbConfig(keepFunc pathdb.NotifyKeepFunc) *triedb.Confi
 		config.PathDB = &pathdb.Config{
 			TrieNodeBufferType:   c.PathNodeBuffer,
 			StateHistory:         c.StateHistory,
+			HistoryIndex:         c.StateHistoryIndex,
+			HistoryArchive:       c.StateHistoryArchive,
 			CleanCacheSize:       c.TrieCleanLimit * 1024 * 1024,
 			DirtyCacheSize:       c.TrieDirtyLimit * 1024 * 1024,
 			ProposeBlockInterval: c.ProposeBlockInterval,
 			NotifyKeep:           keepFunc,
 			JournalFilePath:      c.JournalFilePath,
 			JournalFile:          c.JournalFile,
+			CheckpointInterval:   c.CheckpointInterval,
 		}
 	}
 	return config
@@ -302,7 +311,23 @@ type BlockChain struct {
 	vmConfig   vm.Config
 
 	// parallel EVM related
-	enableTxDAG bool
+	enableTxDAG         bool
+	verifyTxDAG         bool // verify the TxDAG carried in the block against the resolved one
+	rejectUnsafeTxDAG   bool // reject the block if its TxDAG misses any dependency
+	parallelExecWorkers int  // execute the txs in parallel by the TxDAG if positive
+
+	// the missing dependencies of the unsafe TxDAGs in the processed blocks,
+	// they are persisted once the blocks are validated and written
+	unsafeTxDAGs *lru.Cache[common.Hash, []types.TxDAGMissingDep]
+
+	// the TxDAG sidecars fetched from the peers, kept in memory until checked
+	// against the resolved TxDAG of the processed blocks, and the checked ones
+	// persisted once the blocks are validated and written
+	txDAGSidecars     *lru.Cache[common.Hash, types.TxDAG]
+	safeTxDAGSidecars *lru.Cache[common.Hash, types.TxDAG]
+
+	opcodeProfiler *vm.OpcodeProfiler // profiler of the opcode n-grams executed in the imported blocks
+	opcodeChecker  *OpcodeChecker     // checker executing the sampled txs with and without the opcode optimization
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -315,6 +340,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	opts := &proofKeeperOptions{
 		enable:             cacheConfig.EnableProofKeeper,
 		keepProofBlockSpan: cacheConfig.KeepProofBlockSpan,
+		targets:            cacheConfig.KeepProofTargets,
 		watchStartKeepCh:   make(chan *pathdb.KeepRecord),
 		notifyFinishKeepCh: make(chan error),
 	}
@@ -359,6 +385,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		miningTxLogsCache:   lru.NewCache[common.Hash, []*types.Log](txLogsCacheLimit),
 		miningStateCache:    lru.NewCache[common.Hash, *state.StateDB](miningStateCacheLimit),
 		futureBlocks:        lru.NewCache[common.Hash, *types.Block](maxFutureBlocks),
+		unsafeTxDAGs:        lru.NewCache[common.Hash, []types.TxDAGMissingDep](unsafeTxDAGCacheLimit),
+		txDAGSidecars:       lru.NewCache[common.Hash, types.TxDAG](txDAGSidecarLimit),
+		safeTxDAGSidecars:   lru.NewCache[common.Hash, types.TxDAG](txDAGSidecarLimit),
 		engine:              engine,
 		vmConfig:            vmConfig,
 	}
@@ -1884,10 +1913,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 	}()
 
 	defer func() {
-		DebugInnerExecutionDuration = 0
+		DebugInnerExecutionDuration.Store(0)
 	}()
 	for ; block != nil && err == nil || errors.Is(err, ErrKnownBlock); block, err = it.next() {
-		DebugInnerExecutionDuration = 0
+		DebugInnerExecutionDuration.Store(0)
 		// If the chain is terminating, stop processing blocks
 		if bc.insertStopped() {
 			log.Debug("Abort during block processing")
@@ -1988,7 +2017,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 
 			// Process block using the parent state as reference point
 			pstart = time.Now()
-			receipts, logs, usedGas, err = bc.processor.Process(block, statedb, bc.vmConfig)
+			vmConfig := bc.vmConfig
+			vmConfig.OpcodeProfiler = bc.opcodeProfiler
+			receipts, logs, usedGas, err = bc.processor.Process(block, statedb, vmConfig)
 			if err != nil {
 				bc.reportBlock(block, receipts, err)
 				followupInterrupt.Store(true)
@@ -2036,7 +2067,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		blockExecutionTimer.Update(ptime)  // The time spent on block execution
 		blockValidationTimer.Update(vtime) // The time spent on block validation
 
-		innerExecutionTimer.Update(DebugInnerExecutionDuration)
+		innerExecutionTimer.Update(time.Duration(DebugInnerExecutionDuration.Load()))
 
 		log.Debug("New payload execution and validation metrics", "hash", block.Hash(), "execution", common.PrettyDuration(ptime), "validation", common.PrettyDuration(vtime), "accountReads", common.PrettyDuration(statedb.AccountReads), "storageReads", common.PrettyDuration(statedb.StorageReads), "snapshotAccountReads", common.PrettyDuration(statedb.SnapshotAccountReads), "snapshotStorageReads", common.PrettyDuration(statedb.SnapshotStorageReads), "accountUpdates", common.PrettyDuration(statedb.AccountUpdates), "storageUpdates", common.PrettyDuration(statedb.StorageUpdates), "accountHashes", common.PrettyDuration(statedb.AccountHashes), "storageHashes", common.PrettyDuration(statedb.StorageHashes))
 
@@ -2061,6 +2092,15 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 			}
 		}
 		bc.CacheBlock(block.Hash(), block)
+		if missing, ok := bc.unsafeTxDAGs.Get(block.Hash()); ok {
+			rawdb.WriteUnsafeTxDAG(bc.db, block.Hash(), missing)
+			bc.unsafeTxDAGs.Remove(block.Hash())
+		}
+		if dag, ok := bc.safeTxDAGSidecars.Get(block.Hash()); ok {
+			rawdb.WriteTxDAG(bc.db, block.Hash(), block.NumberU64(), dag)
+			bc.safeTxDAGSidecars.Remove(block.Hash())
+			bc.txDAGSidecars.Remove(block.Hash())
+		}
 
 		// Update the metrics touched during block commit
 		accountCommitTimer.Update(statedb.AccountCommits)   // Account commits are complete, we can mark them
@@ -2831,3 +2871,50 @@ func (bc *BlockChain) SetupTxDAGGeneration() {
 	log.Info("node enable TxDAG feature")
 	bc.enableTxDAG = true
 }
+
+// SetupTxDAGVerification enables the verification of the TxDAG carried in the
+// imported blocks, the unsafe blocks are rejected if reject is set, otherwise
+// they are marked as unsafe for the parallel execution.
+func (bc *BlockChain) SetupTxDAGVerification(reject bool) {
+	log.Info("node enable TxDAG verification", "reject", reject)
+	bc.enableTxDAG = true
+	bc.verifyTxDAG = true
+	bc.rejectUnsafeTxDAG = reject
+}
+
+// AddTxDAGSidecar keeps the TxDAG sidecar of the block fetched from the peers
+// in memory for its execution. It's only persisted, and so served to the other
+// peers, once the block is processed and written without any dependency missing
+// in the sidecar, which requires the TxDAG generation.
+func (bc *BlockChain) AddTxDAGSidecar(hash common.Hash, dag types.TxDAG) {
+	bc.txDAGSidecars.Add(hash, dag)
+}
+
+func (bc *BlockChain) SetupTxDAGExecution(workers int) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	log.Info("node enable parallel execution by TxDAG", "workers", workers)
+	bc.parallelExecWorkers = workers
+}
+
+// SetupOpcodeProfiler enables the profiling of the opcode n-grams executed in
+// the imported blocks. The mined blocks and the RPC calls are not profiled.
+func (bc *BlockChain) SetupOpcodeProfiler() {
+	log.Info("node enable opcode profiling")
+	bc.opcodeProfiler = vm.NewOpcodeProfiler()
+}
+
+// OpcodeProfiler returns the profiler of the executed opcode n-grams, nil is
+// returned if the profiling is disabled.
+func (bc *BlockChain) OpcodeProfiler() *vm.OpcodeProfiler {
+	return bc.opcodeProfiler
+}
+
+// SetupOpcodeChecker enables the shadow execution of the given fraction of the
+// txs in the imported blocks, with and without the opcode optimization. The
+// divergences are recorded into dir along with the reproducers.
+func (bc *BlockChain) SetupOpcodeChecker(rate float64, dir string) {
+	log.Info("node enable opcode optimization checking", "rate", rate, "dir", dir)
+	bc.opcodeChecker = NewOpcodeChecker(rate, dir)
+}
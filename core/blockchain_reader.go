@@ -133,6 +133,32 @@ func (bc *BlockChain) GetBodyRLP(hash common.Hash) rlp.RawValue {
 	return body
 }
 
+// GetTxDAGSidecarRLP retrieves the TxDAG sidecar of a block from the database
+// by hash, in the encoding of types.EncodeTxDAG.
+func (bc *BlockChain) GetTxDAGSidecarRLP(hash common.Hash) []byte {
+	number := bc.hc.GetBlockNumber(hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadTxDAGRLP(bc.db, hash, *number)
+}
+
+// GetTxDAG retrieves the TxDAG of a block, either the one carried in the block
+// or the sidecar stored out of band, or fetched and not checked yet.
+func (bc *BlockChain) GetTxDAG(block *types.Block) (types.TxDAG, error) {
+	dag, err := types.GetTxDAG(block)
+	if err == nil {
+		return dag, nil
+	}
+	if dag := rawdb.ReadTxDAG(bc.db, block.Hash(), block.NumberU64()); dag != nil {
+		return dag, nil
+	}
+	if dag, ok := bc.txDAGSidecars.Get(block.Hash()); ok {
+		return dag, nil
+	}
+	return nil, err
+}
+
 // HasBlock checks if a block is fully present in the database or not.
 func (bc *BlockChain) HasBlock(hash common.Hash, number uint64) bool {
 	if bc.blockCache.Contains(hash) {
@@ -380,6 +406,13 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return stateDb, err
 }
 
+// HistoricStateAt returns a new read only state based on a particular point in
+// time, whose trie nodes are gone but the state histories since then are kept
+// and indexed. It's only supported by the path-based state scheme.
+func (bc *BlockChain) HistoricStateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, state.NewHistoricDatabase(bc.stateCache), nil)
+}
+
 // Config retrieves the chain's fork configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 
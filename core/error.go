@@ -33,6 +33,10 @@ var (
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
 	errSideChainReceipts = errors.New("side blocks can't be accepted as ancient chain data")
+
+	// ErrUnsafeTxDAG is returned if the TxDAG carried in the block misses any
+	// dependency between the txs, and the unsafe TxDAG is rejected.
+	ErrUnsafeTxDAG = errors.New("unsafe TxDAG")
 )
 
 // List of evm-call-message pre-checking errors. All state transition messages will
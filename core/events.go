@@ -27,6 +27,9 @@ type NewTxsEvent struct{ Txs []*types.Transaction }
 // ReannoTxsEvent is posted when a batch of local pending transactions exceed a specified duration.
 type ReannoTxsEvent struct{ Txs []*types.Transaction }
 
+// BundleStatusEvent is posted when a bundle transitions into a new state.
+type BundleStatusEvent struct{ Update *types.BundleStatusUpdate }
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
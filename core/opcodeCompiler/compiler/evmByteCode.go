@@ -201,6 +201,8 @@ const (
 	Swap2Pop
 	Dup2LT
 	JumpIfZero // 0xe2
+
+	fusedOpcodeEnd // keep it the last, marks the end of the customized instructions
 )
 
 // 0xf0 range - closures.
@@ -89,6 +89,7 @@ func StoreBitvec(codeHash common.Hash, bitvec []byte) {
 		return
 	}
 	codeCache.AddBitvecCache(codeHash, bitvec)
+	schedulePersist(persistTask{persistBitvec, codeHash, bitvec})
 }
 
 func GenOrLoadOptimizedCode(hash common.Hash, code []byte) {
@@ -126,13 +127,28 @@ func GenOrRewriteOptimizedCode(hash common.Hash, code []byte) ([]byte, error) {
 		return nil, err
 	}
 	codeCache.AddCodeCache(hash, processedCode)
+	schedulePersist(persistTask{persistCode, hash, processedCode})
 	return processedCode, err
 }
 
+// GenerateOptimizedCode returns the optimized code without caching it, whether
+// the optimization is enabled or not.
+func GenerateOptimizedCode(code []byte) ([]byte, error) {
+	return processByteCodes(code)
+}
+
 func TryGenerateOptimizedCode(hash common.Hash, code []byte) ([]byte, error) {
 	processedCode := codeCache.GetCachedCode(hash)
 	var err error = nil
 	if processedCode == nil || len(processedCode) == 0 {
+		// Load the code optimized in the previous runs before regenerating
+		if persisted, bitvec := loadPersistedCode(hash); len(persisted) != 0 {
+			codeCache.AddCodeCache(hash, persisted)
+			if len(bitvec) != 0 {
+				codeCache.AddBitvecCache(hash, bitvec)
+			}
+			return persisted, nil
+		}
 		processedCode, err = GenOrRewriteOptimizedCode(hash, code)
 	}
 	return processedCode, err
@@ -144,6 +160,7 @@ func DeleteCodeCache(hash common.Hash) {
 	}
 	// flush in case there are invalid cached code
 	codeCache.RemoveCachedCode(hash)
+	schedulePersist(persistTask{unpersist, hash, nil})
 }
 
 func processByteCodes(code []byte) ([]byte, error) {
@@ -0,0 +1,180 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const persistChannelSize = 1024
+
+// fusionProbe is a code containing every pattern of the opcode fusion, the
+// fused result of which changes along with the fusion rules.
+var fusionProbe = []byte{
+	byte(AND), byte(SWAP1), byte(POP), byte(SWAP2), byte(SWAP1), byte(JUMPDEST),
+	byte(ISZERO), byte(PUSH2), 0x01, 0x02, byte(JUMPI), byte(JUMPDEST),
+	byte(SWAP2), byte(SWAP1), byte(POP), byte(JUMP), byte(JUMPDEST),
+	byte(SWAP1), byte(POP), byte(SWAP2), byte(SWAP1), byte(JUMPDEST),
+	byte(POP), byte(SWAP2), byte(SWAP1), byte(POP), byte(JUMPDEST),
+	byte(PUSH2), 0x01, 0x02, byte(JUMP), byte(JUMPDEST),
+	byte(PUSH2), 0x01, 0x02, byte(JUMPI), byte(JUMPDEST),
+	byte(PUSH1), 0x01, byte(PUSH1), 0x02, byte(JUMPDEST),
+	byte(PUSH1), 0x01, byte(ADD), byte(JUMPDEST),
+	byte(PUSH1), 0x01, byte(SHL), byte(JUMPDEST),
+	byte(PUSH1), 0x01, byte(DUP1), byte(JUMPDEST),
+	byte(SWAP1), byte(POP), byte(JUMPDEST),
+	byte(POP), byte(JUMP), byte(JUMPDEST),
+	byte(POP), byte(POP), byte(JUMPDEST),
+	byte(SWAP2), byte(SWAP1), byte(JUMPDEST),
+	byte(SWAP2), byte(POP), byte(JUMPDEST),
+	byte(DUP2), byte(LT), byte(JUMPDEST),
+}
+
+// fusionRulesVersion is the version of the fusion rules, which must be bumped
+// along with every change of doCodeFusion or the customized instructions, so
+// that the optimized codes persisted by the outdated rules are never loaded.
+const fusionRulesVersion uint64 = 1
+
+// compilerVersion identifies the fusion rules the optimized codes are generated
+// by. It's derived from the rules version, and the fused result of the probe
+// code as an extra check against a missed version bump.
+var compilerVersion = func() common.Hash {
+	fused, _ := doCodeFusion(fusionProbe)
+	return crypto.Keccak256Hash(binary.BigEndian.AppendUint64(nil, fusionRulesVersion), []byte{byte(fusedOpcodeEnd)}, fused)
+}()
+
+type persistTaskType byte
+
+const (
+	persistCode persistTaskType = iota
+	persistBitvec
+	unpersist
+)
+
+type persistTask struct {
+	taskType persistTaskType
+	hash     common.Hash
+	data     []byte
+}
+
+// persistentCache mirrors the optimized codes and bitvecs into the database, so
+// that they survive the restarts.
+type persistentCache struct {
+	db    ethdb.KeyValueStore
+	tasks chan persistTask
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+var persistent atomic.Pointer[persistentCache]
+
+// EnablePersistence persists the optimized codes into the given database, and
+// warms the code cache up with the ones persisted by the previous runs. The
+// codes generated by the outdated fusion rules are deleted.
+func EnablePersistence(db ethdb.KeyValueStore) error {
+	if persistent.Load() != nil {
+		return nil
+	}
+	stale, err := rawdb.DeleteStaleOptimizedCodes(db, compilerVersion)
+	if err != nil {
+		return err
+	}
+	var loaded int
+	err = rawdb.IterateOptimizedCodes(db, compilerVersion, func(hash common.Hash, code []byte, bitvec []byte) bool {
+		codeCache.AddCodeCache(hash, code)
+		if len(bitvec) != 0 {
+			codeCache.AddBitvecCache(hash, bitvec)
+		}
+		loaded++
+		return loaded < optimizedCodeCacheCap
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("Enabled optimized code persistence", "version", compilerVersion, "loaded", loaded, "stale", stale)
+
+	cache := &persistentCache{
+		db:    db,
+		tasks: make(chan persistTask, persistChannelSize),
+		quit:  make(chan struct{}),
+	}
+	cache.wg.Add(1)
+	go cache.loop()
+	persistent.Store(cache)
+	return nil
+}
+
+// DisablePersistence stops persisting the optimized codes, the pending writes
+// are flushed before it returns. It must be called before closing the database.
+func DisablePersistence() {
+	cache := persistent.Swap(nil)
+	if cache == nil {
+		return
+	}
+	close(cache.quit)
+	cache.wg.Wait()
+}
+
+// loadPersistedCode retrieves the optimized code and its bitvec from the
+// database, nil is returned if the persistence is disabled or not found.
+func loadPersistedCode(hash common.Hash) ([]byte, []byte) {
+	cache := persistent.Load()
+	if cache == nil {
+		return nil, nil
+	}
+	code := rawdb.ReadOptimizedCode(cache.db, compilerVersion, hash)
+	if len(code) == 0 {
+		return nil, nil
+	}
+	return code, rawdb.ReadOptimizedBitvec(cache.db, compilerVersion, hash)
+}
+
+// schedulePersist queues the task for the background writer. The task is dropped
+// if the writer falls behind, which only costs a regeneration after restart.
+func schedulePersist(task persistTask) {
+	cache := persistent.Load()
+	if cache == nil {
+		return
+	}
+	select {
+	case cache.tasks <- task:
+	default:
+	}
+}
+
+func (c *persistentCache) loop() {
+	defer c.wg.Done()
+	for {
+		select {
+		case task := <-c.tasks:
+			c.handle(task)
+		case <-c.quit:
+			for {
+				select {
+				case task := <-c.tasks:
+					c.handle(task)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *persistentCache) handle(task persistTask) {
+	switch task.taskType {
+	case persistCode:
+		rawdb.WriteOptimizedCode(c.db, compilerVersion, task.hash, task.data)
+	case persistBitvec:
+		rawdb.WriteOptimizedBitvec(c.db, compilerVersion, task.hash, task.data)
+	case unpersist:
+		rawdb.DeleteOptimizedCode(c.db, compilerVersion, task.hash)
+		rawdb.DeleteOptimizedBitvec(c.db, compilerVersion, task.hash)
+	}
+}
@@ -0,0 +1,73 @@
+package compiler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPersistentCache(t *testing.T) {
+	EnableOptimization()
+	defer DisableOptimization()
+
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		hash  = common.Hash{0x1}
+		stale = common.Hash{0x2}
+		code  = []byte{byte(PUSH1), 0x01, byte(PUSH1), 0x02, byte(ADD), byte(STOP)}
+	)
+	rawdb.WriteOptimizedCode(db, common.Hash{0xff}, stale, code)
+
+	if err := EnablePersistence(db); err != nil {
+		t.Fatalf("failed to enable persistence: %v", err)
+	}
+	optimized, err := GenOrRewriteOptimizedCode(hash, code)
+	if err != nil {
+		t.Fatalf("failed to optimize code: %v", err)
+	}
+	StoreBitvec(hash, []byte{0xaa})
+	DisablePersistence()
+
+	if blob := rawdb.ReadOptimizedCode(db, compilerVersion, hash); !bytes.Equal(blob, optimized) {
+		t.Fatalf("persisted code mismatch, have %x, want %x", blob, optimized)
+	}
+	if blob := rawdb.ReadOptimizedCode(db, common.Hash{0xff}, stale); len(blob) != 0 {
+		t.Fatalf("stale code not deleted")
+	}
+
+	// Restart with an empty code cache, the persisted code should be warmed up
+	codeCache.RemoveCachedCode(hash)
+	codeCache.bitvecCache.Remove(hash)
+	if err := EnablePersistence(db); err != nil {
+		t.Fatalf("failed to enable persistence: %v", err)
+	}
+	defer DisablePersistence()
+
+	if have := LoadOptimizedCode(hash); !bytes.Equal(have, optimized) {
+		t.Fatalf("warmed code mismatch, have %x, want %x", have, optimized)
+	}
+	if have := LoadBitvec(hash); !bytes.Equal(have, []byte{0xaa}) {
+		t.Fatalf("warmed bitvec mismatch, have %x", have)
+	}
+}
+
+// fusionRulesProbes are the hashes of the customized instructions and the fused
+// probe code by each version of the fusion rules. A change of the rules has to
+// bump fusionRulesVersion and record the new hash here.
+var fusionRulesProbes = map[uint64]common.Hash{
+	1: common.HexToHash("0x8e3aad53d7dffcfbf923ceee39295b3031a4ab34584ebfcc599f52ba3f1c2353"),
+}
+
+func TestFusionRulesVersion(t *testing.T) {
+	fused, err := doCodeFusion(fusionProbe)
+	if err != nil {
+		t.Fatalf("failed to fuse probe code: %v", err)
+	}
+	have := crypto.Keccak256Hash([]byte{byte(fusedOpcodeEnd)}, fused)
+	if want, ok := fusionRulesProbes[fusionRulesVersion]; !ok || have != want {
+		t.Fatalf("fusion rules changed without bumping fusionRulesVersion %d: probe %x", fusionRulesVersion, have)
+	}
+}
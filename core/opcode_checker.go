@@ -0,0 +1,431 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+)
+
+var (
+	opcodeCheckMeter      = metrics.NewRegisteredMeter("chain/opcode/check", nil)
+	opcodeDivergenceMeter = metrics.NewRegisteredMeter("chain/opcode/divergence", nil)
+)
+
+const (
+	// maxShadowSteps is the maximum number of steps recorded per shadow execution,
+	// the divergent pc is not located for the longer executions.
+	maxShadowSteps = 1 << 20
+
+	// maxFusedOpcodes is the number of opcodes fused into the longest
+	// superinstruction, as the most steps the optimized execution skips.
+	maxFusedOpcodes = 5
+)
+
+// shadowStep is an opcode executed in the shadow execution.
+type shadowStep struct {
+	codeHash common.Hash
+	pc       uint64
+	gas      uint64
+	depth    int
+}
+
+// shadowTracer records the executed opcodes and the touched states of a shadow
+// execution, to locate the divergence and to assemble the reproducer.
+type shadowTracer struct {
+	steps     []shadowStep
+	truncated bool
+	accounts  map[common.Address]map[common.Hash]struct{}
+}
+
+func newShadowTracer() *shadowTracer {
+	return &shadowTracer{accounts: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+func (t *shadowTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.accounts[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.accounts[addr] = slots
+	}
+	return slots
+}
+
+func (t *shadowTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *shadowTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *shadowTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.touch(from)
+	t.touch(to)
+}
+
+func (t *shadowTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+func (t *shadowTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.touch(from)
+	t.touch(to)
+}
+
+func (t *shadowTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (t *shadowTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if len(t.steps) < maxShadowSteps {
+		t.steps = append(t.steps, shadowStep{codeHash: scope.Contract.CodeHash, pc: pc, gas: gas, depth: depth})
+	} else {
+		t.truncated = true
+	}
+	stack := scope.Stack.Data()
+	switch op {
+	case vm.SLOAD, vm.SSTORE:
+		if len(stack) > 0 {
+			t.touch(scope.Contract.Address())[common.Hash(stack[len(stack)-1].Bytes32())] = struct{}{}
+		}
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH, vm.SELFDESTRUCT:
+		if len(stack) > 0 {
+			t.touch(common.Address(stack[len(stack)-1].Bytes20()))
+		}
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		if len(stack) > 1 {
+			t.touch(common.Address(stack[len(stack)-2].Bytes20()))
+		}
+	}
+}
+
+func (t *shadowTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// shadowResult is the outcome of a shadow execution.
+type shadowResult struct {
+	err     error
+	gasUsed uint64
+	ret     []byte
+	logs    []*types.Log
+	tracer  *shadowTracer
+
+	accounts map[common.Address]*shadowAccount // the touched accounts after the execution
+}
+
+// account returns the touched account after the execution along with the given
+// slots, the ones untouched by the execution are left as in the prestate.
+func (r *shadowResult) account(prestate *state.StateDB, addr common.Address, slots map[common.Hash]struct{}) *shadowAccount {
+	account, ok := r.accounts[addr]
+	if !ok {
+		return readShadowAccount(prestate, addr, slots)
+	}
+	if account.exist {
+		for slot := range slots {
+			if _, ok := account.storage[slot]; !ok {
+				account.storage[slot] = prestate.GetState(addr, slot)
+			}
+		}
+	}
+	return account
+}
+
+// shadowAccount is the state of an account touched by a shadow execution.
+type shadowAccount struct {
+	exist    bool
+	balance  *uint256.Int
+	nonce    uint64
+	codeHash common.Hash
+	storage  map[common.Hash]common.Hash // the touched slots, empty if destructed
+}
+
+// readShadowAccount reads the account along with the given slots.
+func readShadowAccount(statedb *state.StateDB, addr common.Address, slots map[common.Hash]struct{}) *shadowAccount {
+	account := &shadowAccount{
+		exist:    statedb.Exist(addr),
+		balance:  new(uint256.Int).Set(statedb.GetBalance(addr)),
+		nonce:    statedb.GetNonce(addr),
+		codeHash: statedb.GetCodeHash(addr),
+		storage:  make(map[common.Hash]common.Hash, len(slots)),
+	}
+	if account.exist {
+		for slot := range slots {
+			account.storage[slot] = statedb.GetState(addr, slot)
+		}
+	}
+	return account
+}
+
+// equal reports whether the accounts are the same on the given slots.
+func (a *shadowAccount) equal(b *shadowAccount, slots map[common.Hash]struct{}) bool {
+	if a.exist != b.exist || a.balance.Cmp(b.balance) != 0 || a.nonce != b.nonce || a.codeHash != b.codeHash {
+		return false
+	}
+	for slot := range slots {
+		if a.storage[slot] != b.storage[slot] {
+			return false
+		}
+	}
+	return true
+}
+
+// OpcodeDivergence is a transaction executed differently with the optimized
+// code and the original one.
+type OpcodeDivergence struct {
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	TxIndex     int         `json:"txIndex"`
+	TxHash      common.Hash `json:"txHash"`
+	CodeHash    common.Hash `json:"codeHash"` // the code executing the first divergent opcode
+	PC          uint64      `json:"pc"`       // the pc of the first divergent opcode
+	Located     bool        `json:"located"`  // whether the divergent opcode is located
+	Reasons     []string    `json:"reasons"`
+	Replay      []string    `json:"replay"` // the cmd/evm commands replaying the tx in the reproducer
+}
+
+// OpcodeChecker executes a sampled fraction of the transactions twice against
+// the same state, once with the optimized code and once with the original one,
+// and records the divergences along with the reproducers.
+type OpcodeChecker struct {
+	rate float64 // fraction of the transactions checked
+	dir  string  // directory the divergences are recorded into
+}
+
+// NewOpcodeChecker creates a checker of the opcode optimization, which checks
+// the given fraction of transactions and records the divergences into dir.
+func NewOpcodeChecker(rate float64, dir string) *OpcodeChecker {
+	return &OpcodeChecker{rate: rate, dir: dir}
+}
+
+// sample reports whether the next transaction should be checked.
+func (c *OpcodeChecker) sample() bool {
+	return c.rate >= 1 || rand.Float64() < c.rate
+}
+
+// check executes the transaction in shadow against a copy of the given state,
+// which is left untouched. The copy is reverted after each execution, so it is
+// the prestate of the reproducer as well.
+func (c *OpcodeChecker) check(config *params.ChainConfig, block *types.Block, blockCtx vm.BlockContext, statedb *state.StateDB, msg *Message, tx *types.Transaction, index int) {
+	opcodeCheckMeter.Mark(1)
+
+	prestate := statedb.Copy()
+	raw := c.execute(config, block, blockCtx, prestate, msg, tx, index, vm.Config{})
+	opt := c.execute(config, block, blockCtx, prestate, msg, tx, index, vm.Config{
+		EnableOpcodeOptimizations: true,
+		ForceOpcodeOptimizations:  true,
+	})
+	reasons := compareShadowResults(prestate, raw, opt)
+	if len(reasons) == 0 {
+		return
+	}
+	opcodeDivergenceMeter.Mark(1)
+
+	divergence := &OpcodeDivergence{
+		BlockNumber: block.NumberU64(),
+		BlockHash:   block.Hash(),
+		TxIndex:     index,
+		TxHash:      tx.Hash(),
+		Reasons:     reasons,
+	}
+	divergence.CodeHash, divergence.PC, divergence.Located = locateDivergence(raw.tracer, opt.tracer)
+	log.Error("Opcode optimization diverged", "number", divergence.BlockNumber, "hash", divergence.BlockHash,
+		"tx", divergence.TxHash, "codehash", divergence.CodeHash, "pc", divergence.PC, "located", divergence.Located,
+		"reasons", strings.Join(reasons, "; "))
+
+	if err := c.record(config, block, prestate, msg, divergence, raw.tracer, opt.tracer); err != nil {
+		log.Error("Failed to record opcode divergence", "tx", divergence.TxHash, "err", err)
+	}
+}
+
+// execute applies the message in shadow with the given vm config, and reverts
+// the state afterwards. The state is not finalised, the touched accounts are
+// read from it instead of the root.
+func (c *OpcodeChecker) execute(config *params.ChainConfig, block *types.Block, blockCtx vm.BlockContext, statedb *state.StateDB, msg *Message, tx *types.Transaction, index int, cfg vm.Config) *shadowResult {
+	tracer := newShadowTracer()
+	cfg.Tracer = tracer
+
+	statedb.SetTxContext(tx.Hash(), index)
+	snapshot := statedb.Snapshot()
+	defer statedb.RevertToSnapshot(snapshot)
+
+	evm := vm.NewEVM(blockCtx, NewEVMTxContext(msg), statedb, config, cfg)
+	result, err := ApplyMessage(evm, msg, new(GasPool).AddGas(block.GasLimit()))
+
+	shadow := &shadowResult{
+		err:      err,
+		logs:     statedb.GetLogs(tx.Hash(), block.NumberU64(), block.Hash()),
+		tracer:   tracer,
+		accounts: make(map[common.Address]*shadowAccount, len(tracer.accounts)),
+	}
+	for addr, slots := range tracer.accounts {
+		shadow.accounts[addr] = readShadowAccount(statedb, addr, slots)
+	}
+	if result != nil {
+		shadow.gasUsed = result.UsedGas
+		shadow.ret = result.ReturnData
+		if result.Err != nil {
+			shadow.err = result.Err
+		}
+	}
+	return shadow
+}
+
+// compareShadowResults returns the differences between the shadow executions
+// with the original code and the optimized one from the given prestate.
+func compareShadowResults(prestate *state.StateDB, raw, opt *shadowResult) []string {
+	var reasons []string
+	if fmt.Sprint(raw.err) != fmt.Sprint(opt.err) {
+		reasons = append(reasons, fmt.Sprintf("error: %v != %v", raw.err, opt.err))
+	}
+	if raw.gasUsed != opt.gasUsed {
+		reasons = append(reasons, fmt.Sprintf("gas used: %d != %d", raw.gasUsed, opt.gasUsed))
+	}
+	if !bytes.Equal(raw.ret, opt.ret) {
+		reasons = append(reasons, fmt.Sprintf("return data: %x != %x", raw.ret, opt.ret))
+	}
+	rawLogs, _ := rlp.EncodeToBytes(raw.logs)
+	optLogs, _ := rlp.EncodeToBytes(opt.logs)
+	if !bytes.Equal(rawLogs, optLogs) {
+		reasons = append(reasons, fmt.Sprintf("logs: %d != %d entries", len(raw.logs), len(opt.logs)))
+	}
+	// Compare the accounts touched by either execution
+	touched := make(map[common.Address]map[common.Hash]struct{})
+	for _, tracer := range []*shadowTracer{raw.tracer, opt.tracer} {
+		for addr, slots := range tracer.accounts {
+			if touched[addr] == nil {
+				touched[addr] = make(map[common.Hash]struct{})
+			}
+			for slot := range slots {
+				touched[addr][slot] = struct{}{}
+			}
+		}
+	}
+	for addr, slots := range touched {
+		if !raw.account(prestate, addr, slots).equal(opt.account(prestate, addr, slots), slots) {
+			reasons = append(reasons, fmt.Sprintf("account %s differs", addr.Hex()))
+		}
+	}
+	return reasons
+}
+
+// locateDivergence finds the first opcode executed differently with the
+// optimized code. The optimized execution runs a subsequence of the original
+// steps, as a fused opcode skips the ones fused into it. The divergence is the
+// optimized step after which the executions no longer meet within the length
+// of the longest superinstruction, or meet with different gas left.
+func locateDivergence(raw, opt *shadowTracer) (common.Hash, uint64, bool) {
+	if raw.truncated || opt.truncated || len(opt.steps) == 0 {
+		return common.Hash{}, 0, false
+	}
+	culprit := func(i int) (common.Hash, uint64, bool) {
+		if i > 0 {
+			i--
+		}
+		return opt.steps[i].codeHash, opt.steps[i].pc, true
+	}
+	var j int
+	for i, step := range opt.steps {
+		matched := false
+		for end := j + maxFusedOpcodes; j < len(raw.steps) && j < end; j++ {
+			s := raw.steps[j]
+			if s.depth == step.depth && s.pc == step.pc && s.codeHash == step.codeHash {
+				matched = true
+				break
+			}
+		}
+		if !matched || raw.steps[j].gas != step.gas {
+			return culprit(i)
+		}
+		j++
+	}
+	if len(raw.steps)-j >= maxFusedOpcodes {
+		return culprit(len(opt.steps))
+	}
+	return common.Hash{}, 0, false
+}
+
+// record writes the divergence along with a reproducer into a directory named
+// after the tx. The reproducer is the prestate of the accounts touched by the tx,
+// which can be replayed by `evm run` with and without the optimization.
+func (c *OpcodeChecker) record(config *params.ChainConfig, block *types.Block, prestate *state.StateDB, msg *Message, divergence *OpcodeDivergence, tracers ...*shadowTracer) error {
+	dir := filepath.Join(c.dir, fmt.Sprintf("%d-%d-%s", divergence.BlockNumber, divergence.TxIndex, divergence.TxHash.Hex()))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	alloc := make(types.GenesisAlloc)
+	for _, tracer := range tracers {
+		for addr, slots := range tracer.accounts {
+			if !prestate.Exist(addr) {
+				continue
+			}
+			account, ok := alloc[addr]
+			if !ok {
+				account = types.Account{
+					Balance: prestate.GetBalance(addr).ToBig(),
+					Nonce:   prestate.GetNonce(addr),
+					Code:    prestate.GetCode(addr),
+					Storage: make(map[common.Hash]common.Hash),
+				}
+			}
+			for slot := range slots {
+				account.Storage[slot] = prestate.GetState(addr, slot)
+			}
+			alloc[addr] = account
+		}
+	}
+	genesis := &Genesis{
+		Config:     config,
+		Alloc:      alloc,
+		Number:     block.NumberU64(),
+		Timestamp:  block.Time(),
+		Coinbase:   block.Coinbase(),
+		Difficulty: block.Difficulty(),
+		GasLimit:   msg.GasLimit,
+		BaseFee:    block.BaseFee(),
+	}
+	blob, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "prestate.json"), blob, 0644); err != nil {
+		return err
+	}
+	replay := fmt.Sprintf("evm --prestate prestate.json --sender %s --value %s --gas %d --price %s --input %s",
+		msg.From.Hex(), msg.Value, msg.GasLimit, msg.GasPrice, hexutil.Encode(msg.Data))
+	if msg.To == nil {
+		replay += " --create"
+	} else {
+		replay += " --receiver " + msg.To.Hex()
+	}
+	divergence.Replay = []string{replay + " --json run", replay + " --json --vm.opcode.optimize run"}
+
+	blob, err = json.MarshalIndent(divergence, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "divergence.json"), blob, 0644)
+}
@@ -0,0 +1,313 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/opcodeCompiler/compiler"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var fusedOpcodeAddress = common.HexToAddress("0xf05ed")
+
+// fusedOpcodeCode returns a contract running the opcode patterns fused by the
+// optimization, which stores 2+3+n under the caller.
+func fusedOpcodeCode(n byte) []byte {
+	return []byte{
+		byte(vm.PUSH1), 0x02, byte(vm.PUSH1), 0x03, // Push1Push1
+		byte(vm.ADD),
+		byte(vm.PUSH1), n, byte(vm.ADD), // Push1Add
+		byte(vm.CALLER),
+		byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x02, // Push1Push1
+		byte(vm.DUP2), byte(vm.LT), // Dup2LT
+		byte(vm.POP), byte(vm.POP), // Pop2
+		byte(vm.STOP),
+	}
+}
+
+// generateFusedOpcodeChain generates a block calling the contract running the
+// fused opcode patterns by independent senders, with the TxDAG embedded.
+func generateFusedOpcodeChain(t *testing.T) (*Genesis, []*types.Block) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		keys   = make([]*ecdsa.PrivateKey, 3)
+		alloc  = GenesisAlloc{fusedOpcodeAddress: {Balance: common.Big0, Code: fusedOpcodeCode(0x04)}}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &Genesis{Config: config, Alloc: alloc}
+
+	dag := types.NewPlainTxDAG(3)
+	dag.TxDeps[0] = types.NewTxDep([]uint64{})
+	dag.TxDeps[1] = types.NewTxDep([]uint64{})
+	dag.TxDeps[2] = types.NewTxDep([]uint64{}, types.NonDependentRelFlag)
+	dagData, err := types.EncodeTxDAGCalldata(dag)
+	if err != nil {
+		t.Fatalf("failed to encode TxDAG: %v", err)
+	}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, gen *BlockGen) {
+		gasPrice := new(big.Int).Mul(gen.BaseFee(), common.Big2)
+		for j, key := range keys {
+			to, data := fusedOpcodeAddress, []byte(nil)
+			if j == len(keys)-1 {
+				to, data = common.HexToAddress("0xda90000000000000000000000000000000000000"), dagData
+			}
+			nonce := gen.TxNonce(crypto.PubkeyToAddress(key.PublicKey))
+			tx, err := types.SignTx(types.NewTransaction(nonce, to, common.Big0, 100000, gasPrice, data), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			gen.AddTx(tx)
+		}
+	})
+	return gspec, blocks
+}
+
+func TestOpcodeCheckerNoDivergence(t *testing.T) {
+	gspec, blocks := generateParallelChain(t, 2, [][]uint64{{}, {}, {}, {0}, {1}, {2, 4}})
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	dir := t.TempDir()
+	chain.SetupOpcodeChecker(1, dir)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("unexpected divergences recorded: %d", len(entries))
+	}
+}
+
+func TestOpcodeCheckerDivergence(t *testing.T) {
+	// Cache the optimized code of another contract under the hash of the one
+	// called, which diverges on the stored value.
+	compiler.EnableOptimization()
+	defer compiler.DisableOptimization()
+
+	codeHash := crypto.Keccak256Hash(fusedOpcodeCode(0x04))
+	compiler.DeleteCodeCache(codeHash)
+	if _, err := compiler.GenOrRewriteOptimizedCode(codeHash, fusedOpcodeCode(0x05)); err != nil {
+		t.Fatalf("failed to optimize code: %v", err)
+	}
+	defer compiler.DeleteCodeCache(codeHash)
+
+	for _, workers := range []int{0, 2} {
+		gspec, blocks := generateFusedOpcodeChain(t)
+		chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		if workers > 0 {
+			chain.SetupTxDAGExecution(workers)
+		}
+
+		dir := t.TempDir()
+		chain.SetupOpcodeChecker(1, dir)
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		chain.Stop()
+
+		// Both calls diverge in the stored value, the plain transfer doesn't.
+		matches, _ := filepath.Glob(filepath.Join(dir, "*", "divergence.json"))
+		if len(matches) != 2 {
+			t.Fatalf("workers %d: unexpected divergences recorded: %d", workers, len(matches))
+		}
+		for _, match := range matches {
+			blob, err := os.ReadFile(match)
+			if err != nil {
+				t.Fatalf("failed to read divergence: %v", err)
+			}
+			var divergence OpcodeDivergence
+			if err := json.Unmarshal(blob, &divergence); err != nil {
+				t.Fatalf("failed to decode divergence: %v", err)
+			}
+			if divergence.TxIndex > 1 || !strings.Contains(strings.Join(divergence.Reasons, ";"), fusedOpcodeAddress.Hex()) {
+				t.Fatalf("workers %d: unexpected divergence: %v", workers, divergence)
+			}
+			if len(divergence.Replay) != 2 || !strings.Contains(divergence.Replay[0], "--gas 100000") {
+				t.Fatalf("workers %d: unexpected replay commands: %v", workers, divergence.Replay)
+			}
+		}
+	}
+}
+
+func TestLocateOpcodeDivergence(t *testing.T) {
+	var (
+		code = common.Hash{0x1}
+		raw  = &shadowTracer{steps: []shadowStep{
+			{code, 0, 100, 1}, {code, 2, 97, 1}, {code, 4, 94, 1}, {code, 5, 91, 1}, {code, 6, 89, 1},
+		}}
+	)
+	var tests = []struct {
+		name    string
+		steps   []shadowStep
+		located bool
+		pc      uint64
+	}{
+		{
+			name:  "identical",
+			steps: raw.steps,
+		},
+		{
+			name:  "fused",
+			steps: []shadowStep{{code, 0, 100, 1}, {code, 5, 91, 1}, {code, 6, 89, 1}},
+		},
+		{
+			name:    "gas mismatch",
+			steps:   []shadowStep{{code, 0, 100, 1}, {code, 5, 92, 1}, {code, 6, 90, 1}},
+			located: true,
+			pc:      0,
+		},
+		{
+			name:    "jump mismatch",
+			steps:   []shadowStep{{code, 0, 100, 1}, {code, 2, 97, 1}, {code, 7, 94, 1}},
+			located: true,
+			pc:      2,
+		},
+		{
+			name:    "stop within a fused opcode",
+			steps:   []shadowStep{{code, 0, 100, 1}},
+			located: false,
+		},
+	}
+	for _, tt := range tests {
+		_, pc, located := locateDivergence(raw, &shadowTracer{steps: tt.steps})
+		if located != tt.located || pc != tt.pc {
+			t.Errorf("%s: divergence mismatch, have (%d, %v), want (%d, %v)", tt.name, pc, located, tt.pc, tt.located)
+		}
+	}
+}
+
+func TestOpcodeCheckerExecute(t *testing.T) {
+	gspec, blocks := generateParallelChain(t, 1, [][]uint64{{}, {}, {}, {0}, {1}, {2, 4}})
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		block   = blocks[0]
+		tx      = block.Transactions()[0]
+		checker = NewOpcodeChecker(1, t.TempDir())
+	)
+	statedb, err := chain.StateAt(chain.CurrentBlock().Root)
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	msg, err := TransactionToMessage(tx, types.LatestSigner(chain.Config()), block.BaseFee())
+	if err != nil {
+		t.Fatalf("failed to convert tx: %v", err)
+	}
+	var (
+		root     = statedb.IntermediateRoot(true)
+		nonce    = statedb.GetNonce(msg.From)
+		blockCtx = NewEVMBlockContext(block.Header(), chain, nil, chain.Config(), statedb)
+	)
+	// The executions share the state, which is reverted after each of them.
+	raw := checker.execute(chain.Config(), block, blockCtx, statedb, msg, tx, 0, vm.Config{})
+	opt := checker.execute(chain.Config(), block, blockCtx, statedb, msg, tx, 0, vm.Config{EnableOpcodeOptimizations: true, ForceOpcodeOptimizations: true})
+	if reasons := compareShadowResults(statedb, raw, opt); len(reasons) != 0 {
+		t.Fatalf("unexpected divergence: %v", reasons)
+	}
+	if have := raw.accounts[msg.From].nonce; have != nonce+1 {
+		t.Fatalf("sender nonce mismatch after execution, have %d, want %d", have, nonce+1)
+	}
+	if have := statedb.GetNonce(msg.From); have != nonce {
+		t.Fatalf("sender nonce mismatch after revert, have %d, want %d", have, nonce)
+	}
+	if have := statedb.IntermediateRoot(true); have != root {
+		t.Fatalf("state root mismatch after revert, have %x, want %x", have, root)
+	}
+}
+
+func TestOpcodeCheckerRecord(t *testing.T) {
+	gspec, blocks := generateParallelChain(t, 1, [][]uint64{{}, {}, {}, {0}, {1}, {2, 4}})
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		block   = blocks[0]
+		tx      = block.Transactions()[0]
+		checker = NewOpcodeChecker(1, t.TempDir())
+		tracer  = newShadowTracer()
+	)
+	statedb, err := chain.StateAt(chain.CurrentBlock().Root)
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	msg, err := TransactionToMessage(tx, types.LatestSigner(chain.Config()), block.BaseFee())
+	if err != nil {
+		t.Fatalf("failed to convert tx: %v", err)
+	}
+	tracer.touch(msg.From)
+	tracer.touch(*msg.To)[common.Hash{0x1}] = struct{}{}
+
+	divergence := &OpcodeDivergence{BlockNumber: block.NumberU64(), TxHash: tx.Hash()}
+	if err := checker.record(chain.Config(), block, statedb, msg, divergence, tracer); err != nil {
+		t.Fatalf("failed to record divergence: %v", err)
+	}
+	if len(divergence.Replay) != 2 {
+		t.Fatalf("replay commands missing")
+	}
+	matches, _ := filepath.Glob(filepath.Join(checker.dir, "*", "prestate.json"))
+	if len(matches) != 1 {
+		t.Fatalf("prestate not found")
+	}
+	blob, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("failed to read prestate: %v", err)
+	}
+	var genesis Genesis
+	if err := json.Unmarshal(blob, &genesis); err != nil {
+		t.Fatalf("failed to decode prestate: %v", err)
+	}
+	if len(genesis.Alloc) != 2 || len(genesis.Alloc[*msg.To].Code) == 0 {
+		t.Fatalf("prestate mismatch: %v", genesis.Alloc)
+	}
+}
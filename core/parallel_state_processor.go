@@ -0,0 +1,319 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// parallelTxWindowFactor limits how far ahead of the last merged tx the
+// transactions are scheduled, as a multiple of the worker count.
+const parallelTxWindowFactor = 4
+
+var (
+	parallelTxExecMeter     = metrics.NewRegisteredMeter("parallel/txdag/tx/exec", nil)
+	parallelTxSerialMeter   = metrics.NewRegisteredMeter("parallel/txdag/tx/serial", nil)
+	parallelBlockMeter      = metrics.NewRegisteredMeter("parallel/txdag/block/exec", nil)
+	parallelFallbackMeter   = metrics.NewRegisteredMeter("parallel/txdag/block/fallback", nil)
+	parallelInvalidDAGMeter = metrics.NewRegisteredMeter("parallel/txdag/block/invaliddag", nil)
+	parallelUnsafeDAGMeter  = metrics.NewRegisteredMeter("parallel/txdag/block/unsafedag", nil)
+	parallelExecTimer       = metrics.NewRegisteredTimer("parallel/txdag/exec", nil)
+)
+
+// parallelTxTask is a transaction executed by a worker on its own state copy.
+type parallelTxTask struct {
+	index    int
+	tx       *types.Transaction
+	snapshot int            // the count of txs merged into the state when it was copied
+	state    *state.StateDB // the state copy owned by the task
+	msg      *Message       // the message converted by the worker
+	receipt  *types.Receipt // the receipt generated by the worker
+	err      error          // the execution error, the tx is re-executed in sequence if any
+}
+
+// processParallel executes the transactions of the block according to the
+// TxDAG embedded in the block. The independent transactions are executed
+// concurrently on their own state copies, and merged back into the statedb
+// strictly in order after the read sets are checked against the writes merged
+// since their copies were taken.
+//
+// It returns the receipts of the transactions merged so far, the remaining
+// transactions have to be executed in sequence by the caller. That happens if
+// the block carries no valid TxDAG, or a conflict is detected.
+func (p *StateProcessor) processParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config, vmenv *vm.EVM, feeReceivers []common.Address, gp *GasPool, usedGas *uint64) (types.Receipts, []*types.Log, int, error) {
+	var (
+		txs         = block.Transactions()
+		header      = block.Header()
+		blockHash   = block.Hash()
+		blockNumber = block.Number()
+		signer      = types.MakeSigner(p.config, header.Number, header.Time)
+	)
+	dag, err := p.bc.GetTxDAG(block)
+	if err != nil {
+		log.Debug("No TxDAG found, execute in sequence", "block", block.NumberU64(), "err", err)
+		return nil, nil, 0, nil
+	}
+	if dag.Type() == types.EmptyTxDAGType {
+		return nil, nil, 0, nil
+	}
+	if err := types.ValidateTxDAG(dag, len(txs)); err != nil {
+		log.Warn("Invalid TxDAG, execute in sequence", "block", block.NumberU64(), "err", err)
+		parallelInvalidDAGMeter.Mark(1)
+		return nil, nil, 0, nil
+	}
+	if rawdb.HasUnsafeTxDAG(p.bc.db, block.Hash()) {
+		log.Debug("Unsafe TxDAG found, execute in sequence", "block", block.NumberU64())
+		parallelUnsafeDAGMeter.Mark(1)
+		return nil, nil, 0, nil
+	}
+	parallelBlockMeter.Mark(1)
+	defer func(start time.Time) {
+		parallelExecTimer.UpdateSince(start)
+	}(time.Now())
+
+	// Resolve the latest tx each tx has to wait for. The excluded txs and the
+	// deposits are barriers, they are executed in sequence on the statedb.
+	var (
+		waitFor = make([]int, len(txs))
+		serial  = make([]bool, len(txs))
+		barrier = -1
+	)
+	for i, tx := range txs {
+		if tx.IsDepositTx() || dag.TxDep(i).CheckFlag(types.ExcludedTxFlag) {
+			serial[i], waitFor[i], barrier = true, i-1, i
+			continue
+		}
+		waitFor[i] = barrier
+		for _, dep := range types.TxDependency(dag, i) {
+			if int(dep) > waitFor[i] {
+				waitFor[i] = int(dep)
+			}
+		}
+	}
+
+	var (
+		workers = p.bc.parallelExecWorkers
+		window  = workers * parallelTxWindowFactor
+		tasks   = make(chan *parallelTxTask, window)
+		results = make(chan *parallelTxTask, len(txs))
+		aborted atomic.Bool
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				if !aborted.Load() {
+					p.executeParallelTx(task, header, blockNumber, blockHash, signer, cfg)
+				}
+				results <- task
+			}
+		}()
+	}
+	defer func() {
+		aborted.Store(true)
+		close(tasks)
+		wg.Wait()
+	}()
+
+	var (
+		receipts   = make(types.Receipts, 0, len(txs))
+		allLogs    []*types.Log
+		merged     int
+		dispatched = make([]bool, len(txs))
+		finished   = make([]*parallelTxTask, len(txs))
+		writeSets  = make([]*types.RWSet, len(txs))
+	)
+	for merged < len(txs) {
+		// Execute the barrier directly once all previous txs are merged
+		if serial[merged] {
+			tx := txs[merged]
+			msg, err := TransactionToMessage(tx, signer, header.BaseFee)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", merged, tx.Hash().Hex(), err)
+			}
+			if checker := p.bc.opcodeChecker; checker != nil && checker.sample() {
+				checker.check(p.config, block, vmenv.Context, statedb, msg, tx, merged)
+			}
+			statedb.SetTxContext(tx.Hash(), merged)
+			receipt, err := applyTransaction(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", merged, tx.Hash().Hex(), err)
+			}
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+			parallelTxSerialMeter.Mark(1)
+			merged++
+			continue
+		}
+		// Schedule all the ready txs in the window, stop at the next barrier
+		for i := merged; i < len(txs) && i < merged+window && !serial[i]; i++ {
+			if dispatched[i] || waitFor[i] >= merged {
+				continue
+			}
+			dispatched[i] = true
+			txState := statedb.CopyForParallel(i, feeReceivers)
+			txState.SetTxContext(txs[i].Hash(), i)
+			tasks <- &parallelTxTask{index: i, tx: txs[i], snapshot: merged, state: txState}
+		}
+		for finished[merged] == nil {
+			task := <-results
+			finished[task.index] = task
+		}
+		task := finished[merged]
+		finished[merged] = nil
+		if err := p.checkParallelTx(task, writeSets, gp); err != nil {
+			log.Debug("Parallel tx conflicts, fallback to sequence", "block", block.NumberU64(), "tx", merged, "err", err)
+			parallelFallbackMeter.Mark(1)
+			break
+		}
+		// The statedb is the exact state the tx executes on before merging.
+		if checker := p.bc.opcodeChecker; checker != nil && checker.sample() {
+			checker.check(p.config, block, vmenv.Context, statedb, task.msg, task.tx, merged)
+		}
+		statedb.SetTxContext(task.tx.Hash(), merged)
+		if err := statedb.MergeParallel(task.state, true); err != nil {
+			log.Debug("Failed to merge parallel tx, fallback to sequence", "block", block.NumberU64(), "tx", merged, "err", err)
+			parallelFallbackMeter.Mark(1)
+			break
+		}
+		// Take the gas accounting in order, as the sequential execution does
+		receipt := task.receipt
+		if err := gp.SubGas(receipt.GasUsed); err != nil {
+			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", merged, task.tx.Hash().Hex(), err)
+		}
+		*usedGas += receipt.GasUsed
+		receipt.CumulativeGasUsed = *usedGas
+		receipt.Logs = statedb.GetLogs(task.tx.Hash(), blockNumber.Uint64(), blockHash)
+
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+		writeSets[merged] = task.state.ParallelRWSet()
+		// Feed the TxDAG generation or verification, if it's enabled
+		if mvStates := statedb.MVStates(); mvStates != nil {
+			mvStates.RecordRWSet(writeSets[merged])
+		}
+		parallelTxExecMeter.Mark(1)
+		merged++
+	}
+	return receipts, allLogs, merged, nil
+}
+
+// executeParallelTx executes the task on its own state copy.
+func (p *StateProcessor) executeParallelTx(task *parallelTxTask, header *types.Header, blockNumber *big.Int, blockHash common.Hash, signer types.Signer, cfg vm.Config) {
+	msg, err := TransactionToMessage(task.tx, signer, header.BaseFee)
+	if err != nil {
+		task.err = err
+		return
+	}
+	var (
+		context = NewEVMBlockContext(header, p.bc, nil, p.config, task.state)
+		vmenv   = vm.NewEVM(context, vm.TxContext{}, task.state, p.config, cfg)
+		gp      = new(GasPool).AddGas(header.GasLimit)
+		usedGas = new(uint64)
+	)
+	task.msg = msg
+	task.receipt, task.err = applyTransaction(msg, p.config, gp, task.state, blockNumber, blockHash, task.tx, usedGas, vmenv)
+}
+
+// checkParallelTx checks whether the result of the parallel task equals to the
+// one executed in sequence, it fails if the tx reads any state written by the
+// txs merged after its state copy was taken.
+func (p *StateProcessor) checkParallelTx(task *parallelTxTask, writeSets []*types.RWSet, gp *GasPool) error {
+	if task.err != nil {
+		return task.err
+	}
+	if task.state.ParallelCannotDelayGasFee() {
+		return state.ErrParallelCannotDelayGasFee
+	}
+	if gp.Gas() < task.msg.GasLimit {
+		return ErrGasLimitReached
+	}
+	rwSet := task.state.ParallelRWSet()
+	for i := task.snapshot; i < task.index; i++ {
+		if writeSets[i] == nil {
+			return fmt.Errorf("missing write set of tx %d", i)
+		}
+		if conflictsWithWrites(rwSet, writeSets[i]) {
+			return fmt.Errorf("conflicts with tx %d", i)
+		}
+	}
+	return nil
+}
+
+// conflictsWithWrites reports whether the read or write set of a tx overlaps
+// the given write set of a previous tx. Creating or destructing an account
+// conflicts with any access to the account.
+func conflictsWithWrites(rwSet *types.RWSet, prev *types.RWSet) bool {
+	accReads, slotReads := rwSet.ReadSet()
+	accWrites, slotWrites := rwSet.WriteSet()
+	prevAccWrites, prevSlotWrites := prev.WriteSet()
+
+	for addr, states := range prevAccWrites {
+		_, created := states[types.AccountSelf]
+		_, destructed := states[types.AccountSuicide]
+		if created || destructed {
+			if _, ok := accReads[addr]; ok {
+				return true
+			}
+			if _, ok := accWrites[addr]; ok {
+				return true
+			}
+			if _, ok := slotReads[addr]; ok {
+				return true
+			}
+			if _, ok := slotWrites[addr]; ok {
+				return true
+			}
+			continue
+		}
+		reads := accReads[addr]
+		if len(reads) == 0 {
+			continue
+		}
+		for state := range states {
+			if _, ok := reads[state]; ok {
+				return true
+			}
+		}
+	}
+	for addr, slots := range prevSlotWrites {
+		reads := slotReads[addr]
+		if len(reads) == 0 {
+			continue
+		}
+		for slot := range slots {
+			if _, ok := reads[slot]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
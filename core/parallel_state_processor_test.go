@@ -0,0 +1,269 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// slot[caller]++ and emit an empty log
+	parallelCallerCounterCode = common.FromHex("0x33805460010190556000600060a000")
+	// slot[0]++
+	parallelSharedCounterCode = common.FromHex("0x600054600101600055")
+)
+
+// newParallelTxDAG creates the TxDAG of the blocks of generateParallelChain
+// with the given dependencies.
+func newParallelTxDAG(deps [][]uint64) types.TxDAG {
+	dag := types.NewPlainTxDAG(len(deps) + 1)
+	for i, dep := range deps {
+		dag.TxDeps[i] = types.NewTxDep(dep)
+	}
+	dag.TxDeps[len(deps)] = types.NewTxDep([]uint64{}, types.NonDependentRelFlag)
+	return dag
+}
+
+// generateParallelChain generates a chain whose blocks carry the given TxDAG
+// in the last transaction, or no TxDAG if deps is nil. Each block contains
+//
+//	0: key0 -> caller counter
+//	1: key1 -> caller counter
+//	2: key2 -> caller counter
+//	3: key0 -> transfer to a new account
+//	4: key1 -> shared counter
+//	5: key2 -> shared counter
+//	6: key3 -> TxDAG
+func generateParallelChain(t *testing.T, blocks int, deps [][]uint64) (*Genesis, []*types.Block) {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		keys   = make([]*ecdsa.PrivateKey, 4)
+		alloc  = GenesisAlloc{}
+
+		callerCounter = common.HexToAddress("0xc0de01")
+		sharedCounter = common.HexToAddress("0xc0de02")
+		dagAddress    = common.HexToAddress("0xda90000000000000000000000000000000000000")
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	alloc[callerCounter] = GenesisAccount{Balance: common.Big0, Code: parallelCallerCounterCode}
+	alloc[sharedCounter] = GenesisAccount{Balance: common.Big0, Code: parallelSharedCounterCode}
+	gspec := &Genesis{Config: config, Alloc: alloc}
+
+	var data []byte
+	if deps != nil {
+		var err error
+		if data, err = types.EncodeTxDAGCalldata(newParallelTxDAG(deps)); err != nil {
+			t.Fatalf("failed to encode TxDAG: %v", err)
+		}
+	}
+	_, chain, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), blocks, func(i int, gen *BlockGen) {
+		gasPrice := new(big.Int).Mul(gen.BaseFee(), common.Big2)
+		send := func(key *ecdsa.PrivateKey, to common.Address, value *big.Int, gas uint64, data []byte) {
+			nonce := gen.TxNonce(crypto.PubkeyToAddress(key.PublicKey))
+			tx, err := types.SignTx(types.NewTransaction(nonce, to, value, gas, gasPrice, data), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			gen.AddTx(tx)
+		}
+		send(keys[0], callerCounter, common.Big0, 100000, nil)
+		send(keys[1], callerCounter, common.Big0, 100000, nil)
+		send(keys[2], callerCounter, common.Big0, 100000, nil)
+		send(keys[0], common.BigToAddress(big.NewInt(int64(0x1000+i))), big.NewInt(params.GWei), params.TxGas, nil)
+		send(keys[1], sharedCounter, common.Big0, 100000, nil)
+		send(keys[2], sharedCounter, common.Big0, 100000, nil)
+		send(keys[3], dagAddress, common.Big0, 200000, data)
+	})
+	return gspec, chain
+}
+
+func TestParallelProcessTxDAG(t *testing.T) {
+	var tests = []struct {
+		name     string
+		deps     [][]uint64
+		parallel bool // whether all the txs are expected to be merged in parallel
+	}{
+		{
+			name:     "correct",
+			deps:     [][]uint64{{}, {}, {}, {0}, {1}, {2, 4}},
+			parallel: true,
+		},
+		{
+			name:     "redundant",
+			deps:     [][]uint64{{}, {0}, {1}, {2}, {3}, {4}},
+			parallel: true,
+		},
+		{
+			name: "missing nonce dependency",
+			deps: [][]uint64{{}, {}, {}, {}, {1}, {2, 4}},
+		},
+		{
+			name: "missing storage dependency",
+			deps: [][]uint64{{}, {}, {}, {0}, {1}, {2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gspec, blocks := generateParallelChain(t, 4, tt.deps)
+
+			chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to create chain: %v", err)
+			}
+			defer chain.Stop()
+			chain.SetupTxDAGExecution(4)
+
+			// Check the parallel execution result against the serial one
+			for _, block := range blocks {
+				parent := chain.CurrentBlock()
+				statedb, err := chain.StateAt(parent.Root)
+				if err != nil {
+					t.Fatalf("failed to get state: %v", err)
+				}
+				var (
+					processor = chain.Processor().(*StateProcessor)
+					context   = NewEVMBlockContext(block.Header(), chain, nil, chain.Config(), statedb)
+					vmenv     = vm.NewEVM(context, vm.TxContext{}, statedb, chain.Config(), vm.Config{})
+					gp        = new(GasPool).AddGas(block.GasLimit())
+					usedGas   = new(uint64)
+				)
+				feeReceivers := []common.Address{block.Coinbase(), params.OptimismBaseFeeRecipient, params.OptimismL1FeeRecipient}
+				receipts, _, next, err := processor.processParallel(block, statedb, vm.Config{}, vmenv, feeReceivers, gp, usedGas)
+				if err != nil {
+					t.Fatalf("block %d: failed to process in parallel: %v", block.NumberU64(), err)
+				}
+				if len(receipts) != next {
+					t.Fatalf("block %d: receipt count mismatch, have %d, want %d", block.NumberU64(), len(receipts), next)
+				}
+				if tt.parallel && next != len(block.Transactions()) {
+					t.Fatalf("block %d: parallel execution stopped at tx %d", block.NumberU64(), next)
+				}
+				if !tt.parallel && next == len(block.Transactions()) {
+					t.Fatalf("block %d: conflict not detected", block.NumberU64())
+				}
+				if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+					t.Fatalf("block %d: failed to insert: %v", block.NumberU64(), err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyTxDAG(t *testing.T) {
+	var tests = []struct {
+		name   string
+		deps   [][]uint64
+		reject bool
+		unsafe bool
+	}{
+		{name: "correct", deps: [][]uint64{{}, {}, {}, {0}, {1}, {2, 4}}},
+		{name: "transitive", deps: [][]uint64{{}, {0}, {1}, {2}, {3}, {4}}},
+		{name: "unsafe", deps: [][]uint64{{}, {}, {}, {0}, {1}, {2}}, unsafe: true},
+		{name: "unsafe rejected", deps: [][]uint64{{}, {}, {}, {}, {1}, {2, 4}}, reject: true, unsafe: true},
+	}
+	for _, workers := range []int{0, 2} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/workers=%d", tt.name, workers), func(t *testing.T) {
+				gspec, blocks := generateParallelChain(t, 2, tt.deps)
+
+				db := rawdb.NewMemoryDatabase()
+				chain, err := NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+				if err != nil {
+					t.Fatalf("failed to create chain: %v", err)
+				}
+				defer chain.Stop()
+				chain.SetupTxDAGVerification(tt.reject)
+				if workers > 0 {
+					chain.SetupTxDAGExecution(workers)
+				}
+				_, err = chain.InsertChain(blocks)
+				switch {
+				case tt.unsafe && tt.reject:
+					if !errors.Is(err, ErrUnsafeTxDAG) {
+						t.Fatalf("unsafe block not rejected, err: %v", err)
+					}
+				case err != nil:
+					t.Fatalf("failed to insert: %v", err)
+				}
+				// The rejected block is never written, neither is its mark
+				missing := rawdb.ReadUnsafeTxDAG(db, blocks[0].Hash())
+				if want := tt.unsafe && !tt.reject; want != (len(missing) > 0) {
+					t.Fatalf("unsafe mark mismatch, have %v, want %v", missing, want)
+				}
+			})
+		}
+	}
+}
+
+// Tests that a TxDAG sidecar, not committed to by the block hash, is kept in
+// memory until checked against the processed block, and is only persisted if
+// no dependency is missing. An unsafe one never gets the block rejected.
+func TestVerifyTxDAGSidecar(t *testing.T) {
+	var tests = []struct {
+		name string
+		deps [][]uint64
+		safe bool
+	}{
+		{name: "safe", deps: [][]uint64{{}, {}, {}, {0}, {1}, {2, 4}}, safe: true},
+		{name: "unsafe", deps: [][]uint64{{}, {}, {}, {}, {1}, {2, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gspec, blocks := generateParallelChain(t, 1, nil)
+
+			db := rawdb.NewMemoryDatabase()
+			chain, err := NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to create chain: %v", err)
+			}
+			defer chain.Stop()
+			chain.SetupTxDAGVerification(true)
+			chain.SetupTxDAGExecution(2)
+
+			block := blocks[0]
+			chain.AddTxDAGSidecar(block.Hash(), newParallelTxDAG(tt.deps))
+			if dag := rawdb.ReadTxDAG(db, block.Hash(), block.NumberU64()); dag != nil {
+				t.Fatal("sidecar persisted before the block is processed")
+			}
+			if _, err := chain.InsertChain(blocks); err != nil {
+				t.Fatalf("block with sidecar rejected: %v", err)
+			}
+			if missing := rawdb.ReadUnsafeTxDAG(db, block.Hash()); len(missing) > 0 {
+				t.Fatalf("block marked unsafe by sidecar: %v", missing)
+			}
+			if dag := rawdb.ReadTxDAG(db, block.Hash(), block.NumberU64()); (dag != nil) != tt.safe {
+				t.Fatalf("sidecar persisted mismatch, have %v, want %v", dag != nil, tt.safe)
+			}
+		})
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -45,6 +47,20 @@ type keeperMetaRecord struct {
 	BlockID      uint64 `json:"blockID"`
 	ProofID      uint64 `json:"proofID"`
 	KeepInterval uint64 `json:"keepInterval"`
+	ProofCount   uint64 `json:"proofCount,omitempty"` // Number of proofs kept per block, zero for a single one
+}
+
+// proofsPerBlock returns the number of proof data records kept per block.
+func (m *keeperMetaRecord) proofsPerBlock() uint64 {
+	if m.ProofCount == 0 {
+		return 1
+	}
+	return m.ProofCount
+}
+
+// firstProofID returns the id of the first proof data record kept at the block.
+func (m *keeperMetaRecord) firstProofID(blockID uint64) uint64 {
+	return m.ProofID + (blockID-m.BlockID)/m.KeepInterval*m.proofsPerBlock()
 }
 
 // proofDataRecord is used to store proposed proof data.
@@ -63,15 +79,74 @@ type proofDataRecord struct {
 	StorageProof []common.StorageResult `json:"storageProof"`
 }
 
+// isTarget reports whether the record is the proof of the given account and
+// storage keys.
+func (p *proofDataRecord) isTarget(address common.Address, storageKeys []common.Hash) bool {
+	if p.Address != address || len(p.StorageProof) != len(storageKeys) {
+		return false
+	}
+	for i, key := range storageKeys {
+		if p.StorageProof[i].Key != hexutil.Encode(key[:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProofTarget is an account, and optionally some of its storage slots, whose
+// proof is kept by the proof keeper at every propose block.
+type ProofTarget struct {
+	Address     common.Address
+	StorageKeys []common.Hash
+}
+
+// ParseProofTarget parses a proof target in the address[:key[:key...]] form.
+func ParseProofTarget(target string) (ProofTarget, error) {
+	parts := strings.Split(target, ":")
+	if !common.IsHexAddress(parts[0]) {
+		return ProofTarget{}, fmt.Errorf("invalid proof target address %q", parts[0])
+	}
+	t := ProofTarget{Address: common.HexToAddress(parts[0])}
+	for _, key := range parts[1:] {
+		raw, err := hexutil.Decode(key)
+		if err != nil || len(raw) > common.HashLength {
+			return ProofTarget{}, fmt.Errorf("invalid proof target storage key %q", key)
+		}
+		t.StorageKeys = append(t.StorageKeys, common.BytesToHash(raw))
+	}
+	return t, nil
+}
+
+// is reports whether the target is the given account and storage keys.
+func (t *ProofTarget) is(address common.Address, storageKeys []common.Hash) bool {
+	if t.Address != address || len(t.StorageKeys) != len(storageKeys) {
+		return false
+	}
+	for i, key := range storageKeys {
+		if t.StorageKeys[i] != key {
+			return false
+		}
+	}
+	return true
+}
+
 // proofKeeperOptions defines proof keeper options.
 type proofKeeperOptions struct {
 	enable             bool
 	keepProofBlockSpan uint64
 	gcInterval         uint64
+	targets            []ProofTarget
 	watchStartKeepCh   chan *pathdb.KeepRecord
 	notifyFinishKeepCh chan error
 }
 
+// proofQuery is a query of the kept proof of a target at a block.
+type proofQuery struct {
+	blockID     uint64
+	address     common.Address
+	storageKeys []common.Hash
+}
+
 // ProofKeeper is used to store proposed proof and op-proposer can query.
 type ProofKeeper struct {
 	opts         *proofKeeperOptions
@@ -79,7 +154,7 @@ type ProofKeeper struct {
 	keeperMetaDB ethdb.Database
 	proofDataDB  *rawdb.ResettableFreezer
 
-	queryProofCh     chan uint64
+	queryProofCh     chan *proofQuery
 	waitQueryProofCh chan *proofDataRecord
 	stopCh           chan struct{}
 	waitStopCh       chan error
@@ -94,9 +169,26 @@ func newProofKeeper(opts *proofKeeperOptions) *ProofKeeper {
 	if opts.gcInterval == 0 {
 		opts.gcInterval = gcProofIntervalSecond
 	}
+	// The withdrawal proof of the op-proposer is always kept first, followed
+	// by the distinct configured targets.
+	targets := []ProofTarget{{Address: l2ToL1MessagePasserAddr}}
+	for _, target := range opts.targets {
+		known := false
+		for _, t := range targets {
+			if t.is(target.Address, target.StorageKeys) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			targets = append(targets, target)
+		}
+	}
+	opts.targets = targets
+
 	keeper := &ProofKeeper{
 		opts:             opts,
-		queryProofCh:     make(chan uint64),
+		queryProofCh:     make(chan *proofQuery),
 		waitQueryProofCh: make(chan *proofDataRecord),
 		stopCh:           make(chan struct{}),
 		waitStopCh:       make(chan error),
@@ -179,21 +271,20 @@ func (keeper *ProofKeeper) GetNotifyKeepRecordFunc() pathdb.NotifyKeepFunc {
 	}
 }
 
-// getInnerProof is used to make proof by state db interface.
-func (keeper *ProofKeeper) getInnerProof(kRecord *pathdb.KeepRecord) (*proofDataRecord, error) {
+// getInnerProofs is used to make the proofs of all the targets by state db interface,
+// one proof data record per target.
+func (keeper *ProofKeeper) getInnerProofs(kRecord *pathdb.KeepRecord) ([]*proofDataRecord, error) {
 	var (
-		err          error
-		header       *types.Header
-		stateDB      *state.StateDB
-		worldTrie    *trie2.StateTrie
-		accountProof common.ProofList
-		pRecord      *proofDataRecord
+		err       error
+		header    *types.Header
+		stateDB   *state.StateDB
+		worldTrie *trie2.StateTrie
+		pRecords  []*proofDataRecord
 	)
 
 	startTimestamp := time.Now()
 	defer func() {
 		getInnerProofTimer.UpdateSince(startTimestamp)
-		// log.Info("Succeed to get proof", "proof_record", pRecord, "error", err, "elapsed", common.PrettyDuration(time.Since(startTimestamp)))
 	}()
 
 	if header = keeper.blockChain.GetHeaderByNumber(kRecord.BlockID); header == nil {
@@ -205,26 +296,67 @@ func (keeper *ProofKeeper) getInnerProof(kRecord *pathdb.KeepRecord) (*proofData
 		kRecord.PinnedInnerTrieReader); err != nil {
 		return nil, err
 	}
-	if err = worldTrie.Prove(crypto.Keccak256(l2ToL1MessagePasserAddr.Bytes()), &accountProof); err != nil {
-		return nil, err
-	}
 	if stateDB, err = state.NewStateDBByTrie(worldTrie, keeper.blockChain.stateCache, keeper.blockChain.snaps); err != nil {
 		return nil, err
 	}
-
-	pRecord = &proofDataRecord{
-		BlockID:      kRecord.BlockID,
-		StateRoot:    kRecord.StateRoot,
-		Address:      l2ToL1MessagePasserAddr,
-		AccountProof: accountProof,
-		Balance:      stateDB.GetBalance(l2ToL1MessagePasserAddr).ToBig(),
-		CodeHash:     stateDB.GetCodeHash(l2ToL1MessagePasserAddr),
-		Nonce:        stateDB.GetNonce(l2ToL1MessagePasserAddr),
-		StorageHash:  stateDB.GetStorageRoot(l2ToL1MessagePasserAddr),
-		StorageProof: make([]common.StorageResult, 0),
+	for _, target := range keeper.opts.targets {
+		var accountProof common.ProofList
+		if err = worldTrie.Prove(crypto.Keccak256(target.Address.Bytes()), &accountProof); err != nil {
+			return nil, err
+		}
+		pRecord := &proofDataRecord{
+			BlockID:      kRecord.BlockID,
+			StateRoot:    kRecord.StateRoot,
+			Address:      target.Address,
+			AccountProof: accountProof,
+			Balance:      stateDB.GetBalance(target.Address).ToBig(),
+			CodeHash:     stateDB.GetCodeHash(target.Address),
+			Nonce:        stateDB.GetNonce(target.Address),
+			StorageHash:  stateDB.GetStorageRoot(target.Address),
+			StorageProof: make([]common.StorageResult, 0, len(target.StorageKeys)),
+		}
+		if len(target.StorageKeys) > 0 {
+			if pRecord.StorageProof, err = keeper.getInnerStorageProof(kRecord, header.Root, pRecord, target.StorageKeys); err != nil {
+				return nil, err
+			}
+		}
+		pRecords = append(pRecords, pRecord)
 	}
 	err = stateDB.Error()
-	return pRecord, err
+	return pRecords, err
+}
+
+// getInnerStorageProof is used to make the proofs of the storage keys of an account
+// by the pinned trie reader.
+func (keeper *ProofKeeper) getInnerStorageProof(kRecord *pathdb.KeepRecord, root common.Hash, pRecord *proofDataRecord, keys []common.Hash) ([]common.StorageResult, error) {
+	results := make([]common.StorageResult, len(keys))
+	if pRecord.StorageHash == types.EmptyRootHash || pRecord.StorageHash == (common.Hash{}) {
+		for i, key := range keys {
+			results[i] = common.StorageResult{Key: hexutil.Encode(key[:]), Value: new(big.Int), Proof: []string{}}
+		}
+		return results, nil
+	}
+	storageTrie, err := trie2.NewStateTrieByInnerReader(
+		trie2.StorageTrieID(root, crypto.Keccak256Hash(pRecord.Address.Bytes()), pRecord.StorageHash),
+		keeper.blockChain.stateCache.TrieDB(),
+		kRecord.PinnedInnerTrieReader)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		var (
+			proof common.ProofList
+			value []byte
+		)
+		if err = storageTrie.Prove(crypto.Keccak256(key.Bytes()), &proof); err != nil {
+			return nil, err
+		}
+		if value, err = storageTrie.GetStorage(pRecord.Address, key.Bytes()); err != nil {
+			return nil, err
+		}
+		results[i] = common.StorageResult{Key: hexutil.Encode(key[:]), Value: new(big.Int).SetBytes(value), Proof: proof}
+	}
+	return results, nil
 }
 
 // eventLoop is used to update/query keeper meta and proof data in the event loop, which ensure thread-safe.
@@ -247,10 +379,10 @@ func (keeper *ProofKeeper) eventLoop() {
 			var (
 				hasTruncatedMeta bool
 				curProofID       uint64
-				proofRecord      *proofDataRecord
+				proofRecords     []*proofDataRecord
 			)
 
-			proofRecord, err = keeper.getInnerProof(keepRecord)
+			proofRecords, err = keeper.getInnerProofs(keepRecord)
 			if err == nil {
 				hasTruncatedMeta = keeper.truncateKeeperMetaRecordHeadIfNeeded(keepRecord.BlockID)
 				metaList := keeper.getKeeperMetaRecordList()
@@ -273,19 +405,24 @@ func (keeper *ProofKeeper) eventLoop() {
 						BlockID:      keepRecord.BlockID,
 						ProofID:      curProofID,
 						KeepInterval: keepRecord.KeepInterval,
+						ProofCount:   uint64(len(proofRecords)),
 					})
 				}
-				proofRecord.ProofID = curProofID
-				err = keeper.putProofDataRecord(proofRecord)
+				for i, proofRecord := range proofRecords {
+					proofRecord.ProofID = curProofID + uint64(i)
+					if err = keeper.putProofDataRecord(proofRecord); err != nil {
+						break
+					}
+				}
 				keeper.latestBlockID = keepRecord.BlockID
 			}
 			keeper.opts.notifyFinishKeepCh <- err
 
-		case queryBlockID := <-keeper.queryProofCh:
+		case query := <-keeper.queryProofCh:
 			var resultProofRecord *proofDataRecord
+			queryBlockID := query.blockID
 			metaList := keeper.getKeeperMetaRecordList()
 			if len(metaList) != 0 && (queryBlockID+keeper.opts.keepProofBlockSpan > keeper.latestBlockID) {
-				proofID := uint64(0)
 				index := len(metaList) - 1
 				for index >= 0 {
 					m := metaList[index]
@@ -294,8 +431,16 @@ func (keeper *ProofKeeper) eventLoop() {
 							break
 						}
 
-						proofID = m.ProofID + (queryBlockID-m.BlockID)/m.KeepInterval
-						resultProofRecord = keeper.getProofDataRecord(proofID)
+						// The targets may have been reconfigured since, look the target up
+						// among the proofs kept at the block.
+						firstProofID := m.firstProofID(queryBlockID)
+						for proofID := firstProofID; proofID < firstProofID+m.proofsPerBlock(); proofID++ {
+							record := keeper.getProofDataRecord(proofID)
+							if record != nil && record.BlockID == queryBlockID && record.isTarget(query.address, query.storageKeys) {
+								resultProofRecord = record
+								break
+							}
+						}
 						break
 					}
 					index = index - 1
@@ -325,7 +470,7 @@ func (keeper *ProofKeeper) eventLoop() {
 						m := metaList[index]
 						if gcBeforeBlockID >= m.BlockID {
 							gcBeforeKeepMetaRecord = m
-							proofID = m.ProofID + (gcBeforeBlockID-m.BlockID)/m.KeepInterval
+							proofID = m.firstProofID(gcBeforeBlockID)
 							gcBeforeProofDataRecord = keeper.getProofDataRecord(proofID)
 							break
 						}
@@ -342,13 +487,18 @@ func (keeper *ProofKeeper) eventLoop() {
 
 // getKeeperMetaRecordList returns keeper meta list.
 func (keeper *ProofKeeper) getKeeperMetaRecordList() []*keeperMetaRecord {
+	return readKeeperMetaRecordList(keeper.keeperMetaDB)
+}
+
+// readKeeperMetaRecordList returns the keeper meta list stored in the db.
+func readKeeperMetaRecordList(db ethdb.Iteratee) []*keeperMetaRecord {
 	var (
 		metaList []*keeperMetaRecord
 		err      error
 		iter     ethdb.Iterator
 	)
 
-	iter = rawdb.IterateKeeperMeta(keeper.keeperMetaDB)
+	iter = rawdb.IterateKeeperMeta(db)
 	defer iter.Release()
 	for iter.Next() {
 		keyBlockID := binary.BigEndian.Uint64(iter.Key()[1:])
@@ -401,11 +551,16 @@ func (keeper *ProofKeeper) truncateKeeperMetaRecordHeadIfNeeded(blockID uint64)
 
 // putKeeperMetaRecord puts a new keeper meta record.
 func (keeper *ProofKeeper) putKeeperMetaRecord(m *keeperMetaRecord) {
+	writeKeeperMetaRecord(keeper.keeperMetaDB, m)
+}
+
+// writeKeeperMetaRecord writes a keeper meta record into the db.
+func writeKeeperMetaRecord(db ethdb.KeyValueWriter, m *keeperMetaRecord) {
 	meta, err := json.Marshal(*m)
 	if err != nil {
 		log.Crit("Failed to marshal keeper meta record", "err", err)
 	}
-	rawdb.PutKeeperMeta(keeper.keeperMetaDB, m.BlockID, meta)
+	rawdb.PutKeeperMeta(db, m.BlockID, meta)
 	log.Info("Succeed to put keeper meta", "record", m)
 }
 
@@ -498,7 +653,12 @@ func (keeper *ProofKeeper) getLatestProofDataRecord() *proofDataRecord {
 
 // getProofDataRecord returns proof record by proofid.
 func (keeper *ProofKeeper) getProofDataRecord(proofID uint64) *proofDataRecord {
-	latestProofData := rawdb.GetProofData(keeper.proofDataDB, proofID)
+	return readProofDataRecord(keeper.proofDataDB, proofID)
+}
+
+// readProofDataRecord returns proof record by proofid from the proof freezer.
+func readProofDataRecord(f *rawdb.ResettableFreezer, proofID uint64) *proofDataRecord {
+	latestProofData := rawdb.GetProofData(f, proofID)
 	if latestProofData == nil {
 		log.Info("Skip get proof data record due not found", "proof_id", proofID)
 		return nil
@@ -514,12 +674,17 @@ func (keeper *ProofKeeper) getProofDataRecord(proofID uint64) *proofDataRecord {
 
 // putProofDataRecord puts a new proof data record.
 func (keeper *ProofKeeper) putProofDataRecord(p *proofDataRecord) error {
+	return writeProofDataRecord(keeper.proofDataDB, p)
+}
+
+// writeProofDataRecord appends a proof data record to the proof freezer.
+func writeProofDataRecord(f *rawdb.ResettableFreezer, p *proofDataRecord) error {
 	proof, err := json.Marshal(*p)
 	if err != nil {
 		log.Error("Failed to marshal proof data", "error", err)
 		return err
 	}
-	err = rawdb.PutProofData(keeper.proofDataDB, p.ProofID, proof)
+	err = rawdb.PutProofData(f, p.ProofID, proof)
 	// log.Info("Succeed to put proof data", "record", p, "error", err)
 	return err
 }
@@ -540,24 +705,25 @@ func (keeper *ProofKeeper) gcProofDataRecordIfNeeded(data *proofDataRecord) {
 	log.Info("Succeed to gc proof data", "gc_before_proof_data", data)
 }
 
-// IsProposeProofQuery is used to determine whether it is proposed proof.
-func (keeper *ProofKeeper) IsProposeProofQuery(address common.Address, storageKeys []string, blockID uint64) bool {
+// IsProposeProofQuery is used to determine whether it is the query of a kept proof,
+// namely of the withdrawal proof or of one of the configured targets.
+func (keeper *ProofKeeper) IsProposeProofQuery(address common.Address, storageKeys []common.Hash, blockID uint64) bool {
 	if !keeper.opts.enable {
 		return false
 	}
-	if l2ToL1MessagePasserAddr.Cmp(address) != 0 {
-		return false
-	}
-	if len(storageKeys) != 0 {
-		return false
-	}
 	// blockID%keepInterval == 0 is not checked because keepInterval may have been adjusted before.
 	_ = blockID
-	return true
+	for _, target := range keeper.opts.targets {
+		if target.is(address, storageKeys) {
+			return true
+		}
+	}
+	return false
 }
 
-// QueryProposeProof is used to get proof which is stored in ancient proof.
-func (keeper *ProofKeeper) QueryProposeProof(blockID uint64, stateRoot common.Hash) (*common.AccountResult, error) {
+// QueryProposeProof is used to get the proof of the account and storage keys
+// which is stored in ancient proof.
+func (keeper *ProofKeeper) QueryProposeProof(address common.Address, storageKeys []common.Hash, blockID uint64, stateRoot common.Hash) (*common.AccountResult, error) {
 	var (
 		result         *common.AccountResult
 		err            error
@@ -568,12 +734,13 @@ func (keeper *ProofKeeper) QueryProposeProof(blockID uint64, stateRoot common.Ha
 	defer func() {
 		queryProofTimer.UpdateSince(startTimestamp)
 		log.Info("Query propose proof",
+			"address", address,
 			"block_id", blockID,
 			"state_root", stateRoot.String(),
 			"error", err, "elapsed", common.PrettyDuration(time.Since(startTimestamp)))
 	}()
 
-	keeper.queryProofCh <- blockID
+	keeper.queryProofCh <- &proofQuery{blockID: blockID, address: address, storageKeys: storageKeys}
 	resultProofRecord := <-keeper.waitQueryProofCh
 	if resultProofRecord == nil {
 		// Maybe the keeper was disabled for a certain period of time before.
@@ -0,0 +1,271 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	trie2 "github.com/ethereum/go-ethereum/trie"
+)
+
+// maxListKeptProofs is the maximum number of kept proofs listed at once.
+const maxListKeptProofs = 1024
+
+var errProofKeeperDisabled = errors.New("proof keeper is disabled")
+
+// KeptProof is a proof data record kept by the proof keeper.
+type KeptProof = proofDataRecord
+
+// KeptProofInfo describes a kept proof without its content.
+type KeptProofInfo struct {
+	ProofID     uint64         `json:"proofID"`
+	BlockID     uint64         `json:"blockID"`
+	StateRoot   common.Hash    `json:"stateRoot"`
+	Address     common.Address `json:"address"`
+	StorageKeys []string       `json:"storageKeys"`
+}
+
+// KeptProofs is the content of a proof keeper store, as exported to and
+// imported from files.
+type KeptProofs struct {
+	Metas  []*keeperMetaRecord `json:"metas"`
+	Proofs []*KeptProof        `json:"proofs"`
+}
+
+// ListKeptProofs returns the kept proofs of the blocks within [first, last],
+// at most maxListKeptProofs of them.
+func (keeper *ProofKeeper) ListKeptProofs(first, last uint64) ([]*KeptProofInfo, error) {
+	if !keeper.opts.enable || keeper.proofDataDB == nil {
+		return nil, errProofKeeperDisabled
+	}
+	tail, head, err := proofDataRange(keeper.proofDataDB)
+	if err != nil {
+		return nil, err
+	}
+	// The block ids of the proofs are ascending, look the first one up.
+	start := tail + uint64(sort.Search(int(head-tail), func(i int) bool {
+		record := keeper.getProofDataRecord(tail + uint64(i))
+		return record == nil || record.BlockID >= first
+	}))
+	infos := make([]*KeptProofInfo, 0)
+	for proofID := start; proofID < head && len(infos) < maxListKeptProofs; proofID++ {
+		record := keeper.getProofDataRecord(proofID)
+		if record == nil || record.BlockID > last {
+			break
+		}
+		info := &KeptProofInfo{
+			ProofID:     record.ProofID,
+			BlockID:     record.BlockID,
+			StateRoot:   record.StateRoot,
+			Address:     record.Address,
+			StorageKeys: make([]string, 0, len(record.StorageProof)),
+		}
+		for _, storage := range record.StorageProof {
+			info.StorageKeys = append(info.StorageKeys, storage.Key)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// GetKeptProof returns the kept proof of the given proof id.
+func (keeper *ProofKeeper) GetKeptProof(proofID uint64) (*KeptProof, error) {
+	if !keeper.opts.enable || keeper.proofDataDB == nil {
+		return nil, errProofKeeperDisabled
+	}
+	record := keeper.getProofDataRecord(proofID)
+	if record == nil {
+		return nil, fmt.Errorf("proof is not found, proof_id=%d", proofID)
+	}
+	return record, nil
+}
+
+// proofDataRange returns the range [tail, head) of the proof ids stored in the
+// proof freezer.
+func proofDataRange(f *rawdb.ResettableFreezer) (uint64, uint64, error) {
+	tail, err := f.Tail()
+	if err != nil {
+		return 0, 0, err
+	}
+	head, err := f.Ancients()
+	if err != nil {
+		return 0, 0, err
+	}
+	return tail, head, nil
+}
+
+// ReadKeptProofs reads all the keeper meta records and proof data records of
+// a proof keeper store.
+func ReadKeptProofs(db ethdb.Iteratee, f *rawdb.ResettableFreezer) (*KeptProofs, error) {
+	tail, head, err := proofDataRange(f)
+	if err != nil {
+		return nil, err
+	}
+	proofs := &KeptProofs{Metas: readKeeperMetaRecordList(db)}
+	for proofID := tail; proofID < head; proofID++ {
+		record := readProofDataRecord(f, proofID)
+		if record == nil {
+			return nil, fmt.Errorf("proof is not found, proof_id=%d", proofID)
+		}
+		proofs.Proofs = append(proofs.Proofs, record)
+	}
+	return proofs, nil
+}
+
+// WriteKeptProofs writes kept proofs into an empty proof keeper store. The
+// proofs are renumbered from zero, and the keeper meta records rebased on
+// them, as a fresh proof freezer cannot start past its first item.
+func WriteKeptProofs(db ethdb.KeyValueStore, f *rawdb.ResettableFreezer, proofs *KeptProofs) error {
+	if len(readKeeperMetaRecordList(db)) != 0 {
+		return errors.New("proof keeper store is not empty")
+	}
+	if len(proofs.Proofs) == 0 {
+		return nil
+	}
+	if len(proofs.Metas) == 0 {
+		return errors.New("kept proofs have no keeper meta record")
+	}
+	var (
+		first = proofs.Proofs[0]
+		base  = first.ProofID
+		metas []*keeperMetaRecord
+	)
+	for i, meta := range proofs.Metas {
+		switch {
+		case meta.ProofID >= base:
+			metas = append(metas, &keeperMetaRecord{
+				BlockID:      meta.BlockID,
+				ProofID:      meta.ProofID - base,
+				KeepInterval: meta.KeepInterval,
+				ProofCount:   meta.ProofCount,
+			})
+		case i == len(proofs.Metas)-1 || proofs.Metas[i+1].ProofID > base:
+			// The meta covers the first proof, whose predecessors were
+			// garbage collected, anchor it at the first proof instead.
+			if meta.firstProofID(first.BlockID) != base {
+				return fmt.Errorf("keeper meta is mismatched with the first proof, block_id=%d, proof_id=%d", first.BlockID, base)
+			}
+			metas = append(metas, &keeperMetaRecord{
+				BlockID:      first.BlockID,
+				KeepInterval: meta.KeepInterval,
+				ProofCount:   meta.ProofCount,
+			})
+		}
+	}
+	if err := f.Reset(); err != nil {
+		return err
+	}
+	for i, proof := range proofs.Proofs {
+		if proof.ProofID != base+uint64(i) {
+			return fmt.Errorf("proofs are not continuous, expected_proof_id=%d, actual_proof_id=%d", base+uint64(i), proof.ProofID)
+		}
+		record := *proof
+		record.ProofID = uint64(i)
+		if err := writeProofDataRecord(f, &record); err != nil {
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	batch := db.NewBatch()
+	for _, meta := range metas {
+		writeKeeperMetaRecord(batch, meta)
+	}
+	return batch.Write()
+}
+
+// VerifyKeptProof verifies the account and storage proofs of a kept proof
+// against the given state root.
+func VerifyKeptProof(proof *KeptProof, root common.Hash) error {
+	if proof.StateRoot != root {
+		return fmt.Errorf("state root is mismatched, expected_state_root=%s, actual_state_root=%s", root, proof.StateRoot)
+	}
+	value, err := verifyProofNodes(root, crypto.Keccak256(proof.Address.Bytes()), proof.AccountProof)
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %v", err)
+	}
+	balance := proof.Balance
+	if balance == nil {
+		balance = new(big.Int)
+	}
+	if value == nil {
+		// The proof of absence, the account is empty.
+		if proof.Nonce != 0 || balance.Sign() != 0 {
+			return errors.New("account is absent but not empty")
+		}
+		return verifyStorageProofs(proof, types.EmptyRootHash)
+	}
+	account, err := types.FullAccount(value)
+	if err != nil {
+		return err
+	}
+	switch {
+	case account.Nonce != proof.Nonce:
+		return fmt.Errorf("nonce is mismatched, expected=%d, actual=%d", account.Nonce, proof.Nonce)
+	case account.Balance.ToBig().Cmp(balance) != 0:
+		return fmt.Errorf("balance is mismatched, expected=%s, actual=%s", account.Balance, balance)
+	case account.Root != proof.StorageHash:
+		return fmt.Errorf("storage hash is mismatched, expected=%s, actual=%s", account.Root, proof.StorageHash)
+	case !bytes.Equal(account.CodeHash, proof.CodeHash.Bytes()):
+		return fmt.Errorf("code hash is mismatched, expected=%x, actual=%s", account.CodeHash, proof.CodeHash)
+	}
+	return verifyStorageProofs(proof, account.Root)
+}
+
+// verifyStorageProofs verifies the storage proofs of a kept proof against the
+// given storage root.
+func verifyStorageProofs(proof *KeptProof, root common.Hash) error {
+	for _, storage := range proof.StorageProof {
+		key, err := hexutil.Decode(storage.Key)
+		if err != nil {
+			return fmt.Errorf("invalid storage key %q: %v", storage.Key, err)
+		}
+		expected := new(big.Int)
+		if root != types.EmptyRootHash {
+			value, err := verifyProofNodes(root, crypto.Keccak256(common.BytesToHash(key).Bytes()), storage.Proof)
+			if err != nil {
+				return fmt.Errorf("invalid storage proof of %s: %v", storage.Key, err)
+			}
+			if len(value) > 0 {
+				_, content, _, err := rlp.Split(value)
+				if err != nil {
+					return err
+				}
+				expected.SetBytes(content)
+			}
+		}
+		actual := storage.Value
+		if actual == nil {
+			actual = new(big.Int)
+		}
+		if expected.Cmp(actual) != 0 {
+			return fmt.Errorf("storage value of %s is mismatched, expected=%s, actual=%s", storage.Key, expected, actual)
+		}
+	}
+	return nil
+}
+
+// verifyProofNodes verifies a merkle proof of the key, given as hex encoded
+// trie nodes, and returns the proven value, nil if absent.
+func verifyProofNodes(root common.Hash, key []byte, nodes []string) ([]byte, error) {
+	db := memorydb.New()
+	for _, node := range nodes {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, err
+		}
+		db.Put(crypto.Keccak256(blob), blob)
+	}
+	return trie2.VerifyProof(root, key, db)
+}
@@ -1,14 +1,22 @@
 package core
 
 import (
+	"encoding/json"
+	"math/big"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -163,7 +171,7 @@ func TestProofKeeperQuery(t *testing.T) {
 			ProofID:      i - 1,
 			BlockID:      i,
 			StateRoot:    common.Hash{},
-			Address:      common.Address{},
+			Address:      l2ToL1MessagePasserAddr,
 			AccountProof: nil,
 			Balance:      nil,
 			CodeHash:     common.Hash{},
@@ -175,19 +183,19 @@ func TestProofKeeperQuery(t *testing.T) {
 	}
 
 	keeper.latestBlockID = 100
-	result, err := keeper.QueryProposeProof(45, common.Hash{})
+	result, err := keeper.QueryProposeProof(l2ToL1MessagePasserAddr, nil, 45, common.Hash{})
 	assert.Nil(t, err)
 	assert.NotNil(t, result)
-	result, err = keeper.QueryProposeProof(46, common.Hash{})
+	result, err = keeper.QueryProposeProof(l2ToL1MessagePasserAddr, nil, 46, common.Hash{})
 	assert.Nil(t, err)
 	assert.NotNil(t, result)
-	result, err = keeper.QueryProposeProof(1, common.Hash{}) //  should >= 15
+	result, err = keeper.QueryProposeProof(l2ToL1MessagePasserAddr, nil, 1, common.Hash{}) //  should >= 15
 	assert.NotNil(t, err)
 	assert.Nil(t, result)
-	result, err = keeper.QueryProposeProof(100, common.Hash{})
+	result, err = keeper.QueryProposeProof(l2ToL1MessagePasserAddr, nil, 100, common.Hash{})
 	assert.Nil(t, err)
 	assert.NotNil(t, result)
-	result, err = keeper.QueryProposeProof(101, common.Hash{}) //  should <= 100
+	result, err = keeper.QueryProposeProof(l2ToL1MessagePasserAddr, nil, 101, common.Hash{}) //  should <= 100
 	assert.NotNil(t, err)
 	assert.Nil(t, result)
 
@@ -196,3 +204,183 @@ func TestProofKeeperQuery(t *testing.T) {
 
 	cleanupTestEnv()
 }
+
+func TestProofKeeperQueryTargets(t *testing.T) {
+	setupTestEnv()
+
+	target, err := ParseProofTarget("0x4200000000000000000000000000000000000007:0x01:0x0000000000000000000000000000000000000000000000000000000000000002")
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02")}, target.StorageKeys)
+
+	keeperOpts := &proofKeeperOptions{
+		enable:             true,
+		targets:            []ProofTarget{target, {Address: l2ToL1MessagePasserAddr}},
+		watchStartKeepCh:   make(chan *pathdb.KeepRecord),
+		notifyFinishKeepCh: make(chan error),
+	}
+	keeper := newProofKeeper(keeperOpts)
+	assert.NotNil(t, keeper)
+	assert.Equal(t, 2, len(keeper.opts.targets)) // withdrawal proof is not duplicated
+
+	err = keeper.Start(mockBlockChain, mockKeeperMetaDB)
+	assert.Nil(t, err)
+
+	keeper.putKeeperMetaRecord(&keeperMetaRecord{
+		BlockID:      10,
+		ProofID:      0,
+		KeepInterval: 10,
+		ProofCount:   2,
+	})
+	for i := uint64(0); i < 10; i++ {
+		keeper.putProofDataRecord(&proofDataRecord{
+			ProofID:      2 * i,
+			BlockID:      10 * (i + 1),
+			Address:      l2ToL1MessagePasserAddr,
+			StorageProof: []common.StorageResult{},
+		})
+		keeper.putProofDataRecord(&proofDataRecord{
+			ProofID: 2*i + 1,
+			BlockID: 10 * (i + 1),
+			Address: target.Address,
+			StorageProof: []common.StorageResult{
+				{Key: common.HexToHash("0x01").Hex()},
+				{Key: common.HexToHash("0x02").Hex()},
+			},
+		})
+	}
+	keeper.latestBlockID = 100
+
+	assert.True(t, keeper.IsProposeProofQuery(target.Address, target.StorageKeys, 50))
+	assert.False(t, keeper.IsProposeProofQuery(target.Address, nil, 50))
+
+	result, err := keeper.QueryProposeProof(target.Address, target.StorageKeys, 50, common.Hash{})
+	assert.Nil(t, err)
+	assert.Equal(t, target.Address, result.Address)
+	assert.Equal(t, 2, len(result.StorageProof))
+
+	result, err = keeper.QueryProposeProof(l2ToL1MessagePasserAddr, nil, 50, common.Hash{})
+	assert.Nil(t, err)
+	assert.Equal(t, l2ToL1MessagePasserAddr, result.Address)
+
+	result, err = keeper.QueryProposeProof(target.Address, target.StorageKeys[:1], 50, common.Hash{}) // keys mismatch
+	assert.NotNil(t, err)
+	assert.Nil(t, result)
+
+	err = keeper.Stop()
+	assert.Nil(t, err)
+
+	cleanupTestEnv()
+}
+
+func TestKeptProofsExportImport(t *testing.T) {
+	var (
+		srcDB  = rawdb.NewMemoryDatabase()
+		destDB = rawdb.NewMemoryDatabase()
+	)
+	srcFreezer, err := rawdb.NewProofFreezer(t.TempDir(), false)
+	assert.Nil(t, err)
+	defer srcFreezer.Close()
+	destFreezer, err := rawdb.NewProofFreezer(t.TempDir(), false)
+	assert.Nil(t, err)
+	defer destFreezer.Close()
+
+	writeKeeperMetaRecord(srcDB, &keeperMetaRecord{BlockID: 10, ProofID: 0, KeepInterval: 10, ProofCount: 2})
+	for i := uint64(0); i < 10; i++ {
+		for j := uint64(0); j < 2; j++ {
+			assert.Nil(t, writeProofDataRecord(srcFreezer, &proofDataRecord{
+				ProofID:      2*i + j,
+				BlockID:      10 * (i + 1),
+				Address:      l2ToL1MessagePasserAddr,
+				AccountProof: []string{},
+				Balance:      new(big.Int),
+				StorageProof: []common.StorageResult{},
+			}))
+		}
+	}
+	// Garbage collect the proofs of the blocks before 30.
+	rawdb.TruncateProofDataTail(srcFreezer, 4)
+
+	proofs, err := ReadKeptProofs(srcDB, srcFreezer)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(proofs.Metas))
+	assert.Equal(t, 16, len(proofs.Proofs))
+
+	blob, err := json.Marshal(proofs)
+	assert.Nil(t, err)
+	decoded := new(KeptProofs)
+	assert.Nil(t, json.Unmarshal(blob, decoded))
+	assert.Equal(t, proofs, decoded)
+
+	blob, err = rlp.EncodeToBytes(proofs)
+	assert.Nil(t, err)
+	decoded = new(KeptProofs)
+	assert.Nil(t, rlp.DecodeBytes(blob, decoded))
+	assert.Equal(t, proofs, decoded)
+
+	// The imported proofs are renumbered from zero, the meta anchored at them.
+	assert.Nil(t, WriteKeptProofs(destDB, destFreezer, decoded))
+	assert.Equal(t, []*keeperMetaRecord{{BlockID: 30, ProofID: 0, KeepInterval: 10, ProofCount: 2}}, readKeeperMetaRecordList(destDB))
+	for i, proof := range proofs.Proofs {
+		record := readProofDataRecord(destFreezer, uint64(i))
+		assert.NotNil(t, record)
+		assert.Equal(t, proof.BlockID, record.BlockID)
+	}
+	assert.NotNil(t, WriteKeptProofs(destDB, destFreezer, decoded)) // not empty any more
+}
+
+func TestVerifyKeptProof(t *testing.T) {
+	var (
+		addr   = common.HexToAddress("0x4200000000000000000000000000000000000007")
+		absent = common.HexToAddress("0x4200000000000000000000000000000000000008")
+		key    = common.HexToHash("0x01")
+		sdb    = state.NewDatabase(rawdb.NewMemoryDatabase())
+	)
+	statedb, _ := state.New(types.EmptyRootHash, sdb, nil)
+	statedb.SetBalance(addr, uint256.NewInt(1000))
+	statedb.SetNonce(addr, 3)
+	statedb.SetState(addr, key, common.HexToHash("0x2a"))
+	root, err := statedb.Commit(1, false)
+	assert.Nil(t, err)
+	statedb, err = state.New(root, sdb, nil)
+	assert.Nil(t, err)
+
+	prove := func(address common.Address) *KeptProof {
+		accountTrie, err := sdb.OpenTrie(root)
+		assert.Nil(t, err)
+		var accountProof common.ProofList
+		assert.Nil(t, accountTrie.Prove(crypto.Keccak256(address.Bytes()), &accountProof))
+		proof := &KeptProof{
+			StateRoot:    root,
+			Address:      address,
+			AccountProof: accountProof,
+			Balance:      statedb.GetBalance(address).ToBig(),
+			CodeHash:     statedb.GetCodeHash(address),
+			Nonce:        statedb.GetNonce(address),
+			StorageHash:  statedb.GetStorageRoot(address),
+			StorageProof: []common.StorageResult{{Key: hexutil.Encode(key[:]), Value: statedb.GetState(address, key).Big(), Proof: []string{}}},
+		}
+		if proof.StorageHash != (common.Hash{}) {
+			storageTrie, err := sdb.OpenStorageTrie(root, address, proof.StorageHash, accountTrie)
+			assert.Nil(t, err)
+			var storageProof common.ProofList
+			assert.Nil(t, storageTrie.Prove(crypto.Keccak256(key.Bytes()), &storageProof))
+			proof.StorageProof[0].Proof = storageProof
+		}
+		return proof
+	}
+	proof := prove(addr)
+	assert.Nil(t, VerifyKeptProof(proof, root))
+	assert.NotNil(t, VerifyKeptProof(proof, common.Hash{1})) // state root mismatch
+	assert.Nil(t, VerifyKeptProof(prove(absent), root))
+
+	proof.Balance = big.NewInt(1001)
+	assert.NotNil(t, VerifyKeptProof(proof, root))
+
+	proof = prove(addr)
+	proof.StorageProof[0].Value = big.NewInt(43)
+	assert.NotNil(t, VerifyKeptProof(proof, root))
+
+	proof = prove(addr)
+	proof.AccountProof = proof.AccountProof[1:]
+	assert.NotNil(t, VerifyKeptProof(proof, root))
+}
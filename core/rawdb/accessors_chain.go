@@ -774,9 +774,13 @@ func ReadBlock(db ethdb.Reader, hash common.Hash, number uint64) *types.Block {
 }
 
 // WriteBlock serializes a block into the database, header and body separately.
+// The TxDAG sidecar is stored along with the body if the block carries one.
 func WriteBlock(db ethdb.KeyValueWriter, block *types.Block) {
 	WriteBody(db, block.Hash(), block.NumberU64(), block.Body())
 	WriteHeader(db, block.Header())
+	if dag := block.TxDAG(); dag != nil {
+		WriteTxDAG(db, block.Hash(), block.NumberU64(), dag)
+	}
 }
 
 // WriteAncientBlocks writes entire block data into ancient store and returns the total written size.
@@ -830,6 +834,7 @@ func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
+	DeleteTxDAG(db, hash, number)
 }
 
 // DeleteBlockWithoutNumber removes all block data associated with a hash, except
@@ -839,6 +844,7 @@ func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number
 	deleteHeaderWithoutNumber(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
+	DeleteTxDAG(db, hash, number)
 }
 
 const badBlockToKeep = 10
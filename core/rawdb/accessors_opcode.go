@@ -0,0 +1,121 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadOptimizedCode retrieves the optimized code of the given code hash, which
+// is generated by the opcode compiler of the given version.
+func ReadOptimizedCode(db ethdb.KeyValueReader, version common.Hash, hash common.Hash) []byte {
+	data, _ := db.Get(optimizedCodeKey(version, hash))
+	return data
+}
+
+// WriteOptimizedCode stores the optimized code of the given code hash.
+func WriteOptimizedCode(db ethdb.KeyValueWriter, version common.Hash, hash common.Hash, code []byte) {
+	if err := db.Put(optimizedCodeKey(version, hash), code); err != nil {
+		log.Crit("Failed to store optimized code", "err", err)
+	}
+}
+
+// DeleteOptimizedCode removes the optimized code of the given code hash.
+func DeleteOptimizedCode(db ethdb.KeyValueWriter, version common.Hash, hash common.Hash) {
+	if err := db.Delete(optimizedCodeKey(version, hash)); err != nil {
+		log.Crit("Failed to delete optimized code", "err", err)
+	}
+}
+
+// ReadOptimizedBitvec retrieves the jumpdest analysis of the optimized code of
+// the given code hash.
+func ReadOptimizedBitvec(db ethdb.KeyValueReader, version common.Hash, hash common.Hash) []byte {
+	data, _ := db.Get(optimizedBitvecKey(version, hash))
+	return data
+}
+
+// WriteOptimizedBitvec stores the jumpdest analysis of the optimized code of
+// the given code hash.
+func WriteOptimizedBitvec(db ethdb.KeyValueWriter, version common.Hash, hash common.Hash, bitvec []byte) {
+	if err := db.Put(optimizedBitvecKey(version, hash), bitvec); err != nil {
+		log.Crit("Failed to store optimized code bitvec", "err", err)
+	}
+}
+
+// DeleteOptimizedBitvec removes the jumpdest analysis of the optimized code of
+// the given code hash.
+func DeleteOptimizedBitvec(db ethdb.KeyValueWriter, version common.Hash, hash common.Hash) {
+	if err := db.Delete(optimizedBitvecKey(version, hash)); err != nil {
+		log.Crit("Failed to delete optimized code bitvec", "err", err)
+	}
+}
+
+// DeleteStaleOptimizedCodes removes the optimized codes and their bitvecs which
+// are generated by any opcode compiler other than the given version, returning
+// the number of deleted entries.
+func DeleteStaleOptimizedCodes(db ethdb.KeyValueStore, version common.Hash) (int, error) {
+	var (
+		batch = db.NewBatch()
+		stale int
+	)
+	for _, prefix := range [][]byte{optimizedCodePrefix, optimizedBitvecPrefix} {
+		it := NewKeyLengthIterator(db.NewIterator(prefix, nil), len(prefix)+2*common.HashLength)
+		for it.Next() {
+			if bytes.Equal(it.Key()[len(prefix):len(prefix)+common.HashLength], version.Bytes()) {
+				continue
+			}
+			if err := batch.Delete(it.Key()); err != nil {
+				it.Release()
+				return stale, err
+			}
+			stale++
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return stale, err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return stale, err
+		}
+	}
+	return stale, batch.Write()
+}
+
+// IterateOptimizedCodes iterates over the optimized codes generated by the
+// opcode compiler of the given version along with their bitvecs, until the
+// callback returns false.
+func IterateOptimizedCodes(db ethdb.KeyValueStore, version common.Hash, fn func(hash common.Hash, code []byte, bitvec []byte) bool) error {
+	prefix := append(common.CopyBytes(optimizedCodePrefix), version.Bytes()...)
+	it := NewKeyLengthIterator(db.NewIterator(prefix, nil), len(prefix)+common.HashLength)
+	defer it.Release()
+
+	for it.Next() {
+		hash := common.BytesToHash(it.Key()[len(prefix):])
+		if !fn(hash, common.CopyBytes(it.Value()), ReadOptimizedBitvec(db, version, hash)) {
+			break
+		}
+	}
+	return it.Error()
+}
@@ -160,6 +160,29 @@ func DeleteTrieJournal(db ethdb.KeyValueWriter) {
 	}
 }
 
+// ReadNodeBufferListJournal retrieves the serialized checkpoint of the node
+// buffer list.
+func ReadNodeBufferListJournal(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(nodeBufferListJournalKey)
+	return data
+}
+
+// WriteNodeBufferListJournal stores the serialized checkpoint of the node
+// buffer list.
+func WriteNodeBufferListJournal(db ethdb.KeyValueWriter, journal []byte) {
+	if err := db.Put(nodeBufferListJournalKey, journal); err != nil {
+		log.Crit("Failed to store node buffer list journal", "err", err)
+	}
+}
+
+// DeleteNodeBufferListJournal deletes the serialized checkpoint of the node
+// buffer list.
+func DeleteNodeBufferListJournal(db ethdb.KeyValueWriter) {
+	if err := db.Delete(nodeBufferListJournalKey); err != nil {
+		log.Crit("Failed to remove node buffer list journal", "err", err)
+	}
+}
+
 // ReadStateHistoryMeta retrieves the metadata corresponding to the specified
 // state history. Compute the position of state history in freezer by minus
 // one since the id of first state history starts from one(zero for initial
@@ -294,3 +317,141 @@ func WriteStateHistoryWithTrieNodes(db ethdb.AncientWriter, id uint64, meta []by
 		return nil
 	})
 }
+
+// ReadHistoryLookupHead retrieves the id of the latest state history indexed by
+// the accounts and storage slots it touches, zero if none.
+func ReadHistoryLookupHead(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(historyLookupHeadKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteHistoryLookupHead stores the id of the latest indexed state history.
+func WriteHistoryLookupHead(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Put(historyLookupHeadKey, encodeBlockNumber(id)); err != nil {
+		log.Crit("Failed to store the state history lookup head", "err", err)
+	}
+}
+
+// ReadHistoryArchiveRange retrieves the range of the state histories archived
+// below the freezer tail, zeros if there are none.
+func ReadHistoryArchiveRange(db ethdb.KeyValueReader) (uint64, uint64) {
+	data, _ := db.Get(historyArchiveRangeKey)
+	if len(data) != 16 {
+		return 0, 0
+	}
+	return binary.BigEndian.Uint64(data), binary.BigEndian.Uint64(data[8:])
+}
+
+// WriteHistoryArchiveRange stores the range of the archived state histories.
+func WriteHistoryArchiveRange(db ethdb.KeyValueWriter, first, last uint64) {
+	if err := db.Put(historyArchiveRangeKey, append(encodeBlockNumber(first), encodeBlockNumber(last)...)); err != nil {
+		log.Crit("Failed to store the state history archive range", "err", err)
+	}
+}
+
+// DeleteHistoryArchiveRange removes the range of the archived state histories.
+func DeleteHistoryArchiveRange(db ethdb.KeyValueWriter) {
+	if err := db.Delete(historyArchiveRangeKey); err != nil {
+		log.Crit("Failed to delete the state history archive range", "err", err)
+	}
+}
+
+// ReadArchivedHistory retrieves the archived state history of the given id.
+func ReadArchivedHistory(db ethdb.KeyValueReader, id uint64) []byte {
+	data, _ := db.Get(historyArchiveKey(id))
+	return data
+}
+
+// WriteArchivedHistory stores the archived state history of the given id.
+func WriteArchivedHistory(db ethdb.KeyValueWriter, id uint64, data []byte) {
+	if err := db.Put(historyArchiveKey(id), data); err != nil {
+		log.Crit("Failed to store archived state history", "err", err)
+	}
+}
+
+// DeleteArchivedHistory removes the archived state history of the given id.
+func DeleteArchivedHistory(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Delete(historyArchiveKey(id)); err != nil {
+		log.Crit("Failed to delete archived state history", "err", err)
+	}
+}
+
+// WriteAccountHistoryLookup marks the account as touched by the state history.
+func WriteAccountHistoryLookup(db ethdb.KeyValueWriter, address common.Address, id uint64) {
+	if err := db.Put(accountHistoryLookupKey(address, id), nil); err != nil {
+		log.Crit("Failed to store account history lookup", "err", err)
+	}
+}
+
+// DeleteAccountHistoryLookup unmarks the account as touched by the state history.
+func DeleteAccountHistoryLookup(db ethdb.KeyValueWriter, address common.Address, id uint64) {
+	if err := db.Delete(accountHistoryLookupKey(address, id)); err != nil {
+		log.Crit("Failed to delete account history lookup", "err", err)
+	}
+}
+
+// ReadAccountHistoryLookup returns the id of the first state history touching the
+// account from the given id on, and false if there is none.
+func ReadAccountHistoryLookup(db ethdb.Iteratee, address common.Address, from uint64) (uint64, bool) {
+	return readHistoryLookup(db, accountHistoryLookupKey(address, 0)[:len(accountHistoryLookupPrefix)+common.AddressLength], from)
+}
+
+// WriteStorageHistoryLookup marks the storage slot as touched by the state history.
+func WriteStorageHistoryLookup(db ethdb.KeyValueWriter, address common.Address, slot common.Hash, id uint64) {
+	if err := db.Put(storageHistoryLookupKey(address, slot, id), nil); err != nil {
+		log.Crit("Failed to store storage history lookup", "err", err)
+	}
+}
+
+// DeleteStorageHistoryLookup unmarks the storage slot as touched by the state history.
+func DeleteStorageHistoryLookup(db ethdb.KeyValueWriter, address common.Address, slot common.Hash, id uint64) {
+	if err := db.Delete(storageHistoryLookupKey(address, slot, id)); err != nil {
+		log.Crit("Failed to delete storage history lookup", "err", err)
+	}
+}
+
+// ReadStorageHistoryLookup returns the id of the first state history touching the
+// storage slot from the given id on, and false if there is none.
+func ReadStorageHistoryLookup(db ethdb.Iteratee, address common.Address, slot common.Hash, from uint64) (uint64, bool) {
+	return readHistoryLookup(db, storageHistoryLookupKey(address, slot, 0)[:len(storageHistoryLookupPrefix)+common.AddressLength+common.HashLength], from)
+}
+
+// WriteIncompleteHistoryLookup marks the storage of the account as incompletely
+// recorded by the state history.
+func WriteIncompleteHistoryLookup(db ethdb.KeyValueWriter, address common.Address, id uint64) {
+	if err := db.Put(incompleteHistoryLookupKey(address, id), nil); err != nil {
+		log.Crit("Failed to store incomplete history lookup", "err", err)
+	}
+}
+
+// DeleteIncompleteHistoryLookup unmarks the storage of the account as incompletely
+// recorded by the state history.
+func DeleteIncompleteHistoryLookup(db ethdb.KeyValueWriter, address common.Address, id uint64) {
+	if err := db.Delete(incompleteHistoryLookupKey(address, id)); err != nil {
+		log.Crit("Failed to delete incomplete history lookup", "err", err)
+	}
+}
+
+// ReadIncompleteHistoryLookup returns the id of the first state history recording
+// the storage of the account incompletely from the given id on, and false if
+// there is none.
+func ReadIncompleteHistoryLookup(db ethdb.Iteratee, address common.Address, from uint64) (uint64, bool) {
+	return readHistoryLookup(db, incompleteHistoryLookupKey(address, 0)[:len(incompleteHistoryLookupPrefix)+common.AddressLength], from)
+}
+
+// readHistoryLookup returns the first state history id indexed under the prefix
+// from the given id on.
+func readHistoryLookup(db ethdb.Iteratee, prefix []byte, from uint64) (uint64, bool) {
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+8 {
+			return binary.BigEndian.Uint64(key[len(prefix):]), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadUnsafeTxDAG retrieves the missing dependencies of the TxDAG carried in
+// the block, nil is returned if the block is not marked as unsafe.
+func ReadUnsafeTxDAG(db ethdb.KeyValueReader, hash common.Hash) []types.TxDAGMissingDep {
+	blob, err := db.Get(unsafeTxDAGKey(hash))
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	var missing []types.TxDAGMissingDep
+	if err := rlp.DecodeBytes(blob, &missing); err != nil {
+		log.Error("Invalid unsafe TxDAG RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return missing
+}
+
+// HasUnsafeTxDAG checks whether the TxDAG carried in the block is marked as
+// unsafe for the parallel execution.
+func HasUnsafeTxDAG(db ethdb.KeyValueReader, hash common.Hash) bool {
+	has, err := db.Has(unsafeTxDAGKey(hash))
+	return err == nil && has
+}
+
+// WriteUnsafeTxDAG marks the TxDAG carried in the block as unsafe for the
+// parallel execution, along with the missing dependencies.
+func WriteUnsafeTxDAG(db ethdb.KeyValueWriter, hash common.Hash, missing []types.TxDAGMissingDep) {
+	blob, err := rlp.EncodeToBytes(missing)
+	if err != nil {
+		log.Crit("Failed to RLP encode unsafe TxDAG", "err", err)
+	}
+	if err := db.Put(unsafeTxDAGKey(hash), blob); err != nil {
+		log.Crit("Failed to store unsafe TxDAG", "err", err)
+	}
+}
+
+// ReadTxDAGRLP retrieves the TxDAG sidecar of the block in the encoding of
+// types.EncodeTxDAG, nil is returned if the block has no sidecar.
+func ReadTxDAGRLP(db ethdb.Reader, hash common.Hash, number uint64) []byte {
+	// The sidecars of the canonical blocks are moved into the ancient store
+	// along with the bodies, check the key-value store first.
+	data, _ := db.BlockStoreReader().Get(txDAGKey(number, hash))
+	if len(data) > 0 {
+		return data
+	}
+	var canon bool
+	db.BlockStoreReader().ReadAncients(func(reader ethdb.AncientReaderOp) error {
+		canon = isCanon(reader, number, hash)
+		return nil
+	})
+	if canon {
+		data, _ = db.BlockStoreReader().Ancient(ChainFreezerTxDAGTable, number)
+	}
+	return data
+}
+
+// ReadTxDAG retrieves the TxDAG sidecar of the block, nil is returned if the
+// block has no sidecar.
+func ReadTxDAG(db ethdb.Reader, hash common.Hash, number uint64) types.TxDAG {
+	data := ReadTxDAGRLP(db, hash, number)
+	if len(data) == 0 {
+		return nil
+	}
+	dag, err := types.DecodeTxDAG(data)
+	if err != nil {
+		log.Error("Invalid TxDAG sidecar", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	return dag
+}
+
+// WriteTxDAG stores the TxDAG sidecar of the block.
+func WriteTxDAG(db ethdb.KeyValueWriter, hash common.Hash, number uint64, dag types.TxDAG) {
+	data, err := types.EncodeTxDAG(dag)
+	if err != nil {
+		log.Crit("Failed to encode TxDAG sidecar", "err", err)
+	}
+	WriteTxDAGRLP(db, hash, number, data)
+}
+
+// WriteTxDAGRLP stores the encoded TxDAG sidecar of the block.
+func WriteTxDAGRLP(db ethdb.KeyValueWriter, hash common.Hash, number uint64, data []byte) {
+	if err := db.Put(txDAGKey(number, hash), data); err != nil {
+		log.Crit("Failed to store TxDAG sidecar", "err", err)
+	}
+}
+
+// DeleteTxDAG removes the TxDAG sidecar of the block.
+func DeleteTxDAG(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(txDAGKey(number, hash)); err != nil {
+		log.Crit("Failed to delete TxDAG sidecar", "err", err)
+	}
+}
@@ -34,6 +34,10 @@ const (
 
 	// ChainFreezerDifficultyTable indicates the name of the freezer total difficulty table.
 	ChainFreezerDifficultyTable = "diffs"
+
+	// ChainFreezerTxDAGTable indicates the name of the freezer TxDAG sidecar table,
+	// which is kept in a separate ancient store next to the chain segments.
+	ChainFreezerTxDAGTable = "txdags"
 )
 
 // chainFreezerNoSnappy configures whether compression is disabled for the ancient-tables.
@@ -46,6 +50,12 @@ var chainFreezerNoSnappy = map[string]bool{
 	ChainFreezerDifficultyTable: true,
 }
 
+// txDAGFreezerNoSnappy configures whether compression is disabled for the
+// TxDAG sidecar table.
+var txDAGFreezerNoSnappy = map[string]bool{
+	ChainFreezerTxDAGTable: false,
+}
+
 const (
 	// stateHistoryTableSize defines the maximum size of freezer data files.
 	stateHistoryTableSize = 2 * 1000 * 1000 * 1000
@@ -81,6 +91,7 @@ var (
 	ChainFreezerName = "chain" // the folder name of chain segment ancient store.
 	StateFreezerName = "state" // the folder name of reverse diff ancient store.
 	ProofFreezerName = "proof" // the folder name of propose withdraw proof store.
+	TxDAGFreezerName = "txdag" // the folder name of TxDAG sidecar store, nested in the chain one.
 )
 
 // freezers the collections of all builtin freezers.
@@ -53,6 +53,8 @@ type chainFreezer struct {
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 
 	multiDatabase bool
+
+	txdags *txDAGFreezer // Ancient store of the TxDAG sidecars, nil if unavailable
 }
 
 // newChainFreezer initializes the freezer for ancient chain data.
@@ -68,6 +70,10 @@ func newChainFreezer(datadir string, namespace string, readonly, multiDatabase b
 		multiDatabase: multiDatabase,
 	}
 	cf.threshold.Store(params.FullImmutabilityThreshold)
+	if err := cf.openTxDAGFreezer(datadir, namespace, readonly); err != nil {
+		freezer.Close()
+		return nil, err
+	}
 	return &cf, nil
 }
 
@@ -79,6 +85,11 @@ func (f *chainFreezer) Close() error {
 		close(f.quit)
 	}
 	f.wg.Wait()
+	if f.txdags != nil {
+		if err := f.txdags.Close(); err != nil {
+			log.Error("Failed to close TxDAG sidecar store", "err", err)
+		}
+	}
 	return f.Freezer.Close()
 }
 
@@ -277,6 +288,10 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 		if err := f.Sync(); err != nil {
 			log.Crit("Failed to flush frozen tables", "err", err)
 		}
+		// The sidecars are wiped out along with the blocks, never lose them
+		if err := f.freezeTxDAGs(nfdb, first, ancients); err != nil {
+			log.Crit("Failed to freeze TxDAG sidecars", "err", err)
+		}
 		// Wipe out all data from the active database
 		batch := db.NewBatch()
 		for i := 0; i < len(ancients); i++ {
@@ -0,0 +1,279 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// txDAGFreezer is the ancient store of the TxDAG sidecars. It's kept apart from
+// the chain segments so that it can be attached to an existing chain freezer,
+// the items are indexed by block number and the blocks frozen before the store
+// was created are hidden behind its tail.
+type txDAGFreezer struct {
+	*ResettableFreezer
+	tail atomic.Uint64 // the tail the store is recreated with
+}
+
+// newTxDAGFreezer opens the TxDAG sidecar store, which is initialized with the
+// given tail if it doesn't exist yet.
+func newTxDAGFreezer(datadir string, namespace string, readonly bool, tail uint64) (*txDAGFreezer, error) {
+	if err := cleanup(datadir); err != nil {
+		return nil, err
+	}
+	f := new(txDAGFreezer)
+	f.tail.Store(tail)
+
+	opener := func() (*Freezer, error) {
+		if !readonly {
+			if err := initFreezerTableTail(datadir, ChainFreezerTxDAGTable, txDAGFreezerNoSnappy[ChainFreezerTxDAGTable], f.tail.Load()); err != nil {
+				return nil, err
+			}
+		}
+		return NewFreezer(datadir, namespace, readonly, false, freezerTableSize, txDAGFreezerNoSnappy)
+	}
+	freezer, err := opener()
+	if err != nil {
+		return nil, err
+	}
+	f.ResettableFreezer = &ResettableFreezer{
+		freezer: freezer,
+		opener:  opener,
+		datadir: datadir,
+	}
+	return f, nil
+}
+
+// resetTo wipes the store and recreates it with the given tail.
+func (f *txDAGFreezer) resetTo(tail uint64) error {
+	f.tail.Store(tail)
+	return f.Reset()
+}
+
+// truncateHead discards the sidecars above the given item count, the store is
+// recreated if the count is below its tail.
+func (f *txDAGFreezer) truncateHead(items uint64) error {
+	tail, err := f.Tail()
+	if err != nil {
+		return err
+	}
+	if items < tail {
+		return f.resetTo(items)
+	}
+	_, err = f.TruncateHead(items)
+	return err
+}
+
+// truncateTail discards the sidecars below the given item count, the store is
+// recreated if the count is above its head.
+func (f *txDAGFreezer) truncateTail(tail uint64) error {
+	head, err := f.Ancients()
+	if err != nil {
+		return err
+	}
+	if tail > head {
+		return f.resetTo(tail)
+	}
+	_, err = f.TruncateTail(tail)
+	return err
+}
+
+// initFreezerTableTail creates the index file of a new freezer table with the
+// given number of leading items hidden. The first index entry carries the item
+// offset of the table, which becomes its tail when the metadata is created.
+func initFreezerTableTail(datadir string, name string, noCompression bool, tail uint64) error {
+	if tail > math.MaxUint32 {
+		return fmt.Errorf("freezer table tail %d out of range", tail)
+	}
+	idxName := fmt.Sprintf("%s.cidx", name)
+	if noCompression {
+		idxName = fmt.Sprintf("%s.ridx", name)
+	}
+	path := filepath.Join(datadir, idxName)
+	if _, err := os.Stat(path); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(datadir, 0755); err != nil {
+		return err
+	}
+	entry := indexEntry{filenum: 0, offset: uint32(tail)}
+	return os.WriteFile(path, entry.append(nil), 0644)
+}
+
+// openTxDAGFreezer attaches the TxDAG sidecar store to the chain freezer. The
+// sidecars are unavailable in the read-only mode if the store was never created.
+func (f *chainFreezer) openTxDAGFreezer(datadir string, namespace string, readonly bool) error {
+	frozen, err := f.Freezer.Ancients()
+	if err != nil {
+		return err
+	}
+	txdags, err := newTxDAGFreezer(filepath.Join(datadir, TxDAGFreezerName), namespace+"txdag/", readonly, frozen)
+	if err != nil {
+		if readonly {
+			log.Warn("TxDAG sidecar store is unavailable", "err", err)
+			return nil
+		}
+		return err
+	}
+	f.txdags = txdags
+	if readonly {
+		return nil
+	}
+	// Align the sidecars with the chain segments, the missing ones are filled
+	// in the next freeze cycle.
+	tail, err := txdags.Tail()
+	if err != nil {
+		return err
+	}
+	if frozen < tail {
+		return txdags.resetTo(frozen)
+	}
+	return txdags.truncateHead(frozen)
+}
+
+// freezeTxDAGs moves the sidecars of the given canonical blocks starting at the
+// number into the sidecar store. The blocks without sidecars are stored as
+// empty items. A long gap, left by the chain segments written directly into the
+// ancient store, is skipped by recreating the store.
+func (f *chainFreezer) freezeTxDAGs(nfdb *nofreezedb, number uint64, hashes []common.Hash) error {
+	if f.txdags == nil || len(hashes) == 0 {
+		return nil
+	}
+	head, err := f.txdags.Ancients()
+	if err != nil {
+		return err
+	}
+	switch {
+	case head > number:
+		if err := f.txdags.truncateHead(number); err != nil {
+			return err
+		}
+	case number-head > freezerBatchLimit:
+		if err := f.txdags.resetTo(number); err != nil {
+			return err
+		}
+	}
+	head, err = f.txdags.Ancients()
+	if err != nil {
+		return err
+	}
+	_, err = f.txdags.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for limit := number + uint64(len(hashes)); head < limit; head++ {
+			var hash common.Hash
+			if head < number {
+				hash = ReadCanonicalHash(nfdb, head)
+			} else {
+				hash = hashes[head-number]
+			}
+			data, _ := nfdb.BlockStoreReader().Get(txDAGKey(head, hash))
+			if err := op.AppendRaw(ChainFreezerTxDAGTable, head, data); err != nil {
+				return fmt.Errorf("can't write TxDAG to Freezer: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.txdags.Sync()
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists,
+// the TxDAG sidecars are looked up in their own store.
+func (f *chainFreezer) HasAncient(kind string, number uint64) (bool, error) {
+	if kind == ChainFreezerTxDAGTable {
+		if f.txdags == nil {
+			return false, nil
+		}
+		return f.txdags.HasAncient(kind, number)
+	}
+	return f.Freezer.HasAncient(kind, number)
+}
+
+// Ancient retrieves an ancient binary blob, the TxDAG sidecars are looked up
+// in their own store.
+func (f *chainFreezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if kind == ChainFreezerTxDAGTable {
+		if f.txdags == nil {
+			return nil, errUnknownTable
+		}
+		return f.txdags.Ancient(kind, number)
+	}
+	return f.Freezer.Ancient(kind, number)
+}
+
+// AncientSize returns the ancient size of the specified category, the TxDAG
+// sidecars are looked up in their own store.
+func (f *chainFreezer) AncientSize(kind string) (uint64, error) {
+	if kind == ChainFreezerTxDAGTable {
+		if f.txdags == nil {
+			return 0, errUnknownTable
+		}
+		return f.txdags.AncientSize(kind)
+	}
+	return f.Freezer.AncientSize(kind)
+}
+
+// TruncateHead discards the chain segments above the given item count, along
+// with the TxDAG sidecars.
+func (f *chainFreezer) TruncateHead(items uint64) (uint64, error) {
+	old, err := f.Freezer.TruncateHead(items)
+	if err != nil {
+		return old, err
+	}
+	if f.txdags != nil {
+		if err := f.txdags.truncateHead(items); err != nil {
+			return old, err
+		}
+	}
+	return old, nil
+}
+
+// TruncateTail discards the chain segments below the given item count, along
+// with the TxDAG sidecars.
+func (f *chainFreezer) TruncateTail(tail uint64) (uint64, error) {
+	old, err := f.Freezer.TruncateTail(tail)
+	if err != nil {
+		return old, err
+	}
+	if f.txdags != nil {
+		if err := f.txdags.truncateTail(tail); err != nil {
+			return old, err
+		}
+	}
+	return old, nil
+}
+
+// Sync flushes the chain segments and the TxDAG sidecars to disk.
+func (f *chainFreezer) Sync() error {
+	if err := f.Freezer.Sync(); err != nil {
+		return err
+	}
+	if f.txdags != nil {
+		return f.txdags.Sync()
+	}
+	return nil
+}
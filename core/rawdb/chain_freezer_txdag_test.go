@@ -0,0 +1,155 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func makeTxDAGTestBlocks(n int) []*types.Block {
+	var (
+		blocks []*types.Block
+		parent types.Block
+	)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash:  parent.Hash(),
+			Number:      big.NewInt(int64(i)),
+			UncleHash:   types.EmptyUncleHash,
+			TxHash:      types.EmptyTxsHash,
+			ReceiptHash: types.EmptyReceiptsHash,
+		}
+		block := types.NewBlockWithHeader(header)
+		if i%2 == 1 {
+			dag := types.NewPlainTxDAG(2)
+			dag.TxDeps[1] = types.NewTxDep([]uint64{uint64(i)})
+			block = block.WithTxDAG(dag)
+		}
+		blocks = append(blocks, block)
+		parent = *block
+	}
+	return blocks
+}
+
+func TestTxDAGSidecarStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	blocks := makeTxDAGTestBlocks(2)
+	for _, block := range blocks {
+		WriteBlock(db, block)
+	}
+	if dag := ReadTxDAG(db, blocks[0].Hash(), 0); dag != nil {
+		t.Fatalf("unexpected sidecar: %v", dag)
+	}
+	// The sidecar is only read on demand, not along with the block
+	if dag := ReadBlock(db, blocks[1].Hash(), 1).TxDAG(); dag != nil {
+		t.Fatalf("unexpected sidecar attached to the block: %v", dag)
+	}
+	dag := ReadTxDAG(db, blocks[1].Hash(), 1)
+	if dag == nil {
+		t.Fatalf("sidecar not found")
+	}
+	if have, want := dag.TxDep(1).TxIndexes, blocks[1].TxDAG().TxDep(1).TxIndexes; len(have) != 1 || have[0] != want[0] {
+		t.Fatalf("sidecar mismatch, have %v, want %v", have, want)
+	}
+	DeleteBlock(db, blocks[1].Hash(), 1)
+	if ReadTxDAGRLP(db, blocks[1].Hash(), 1) != nil {
+		t.Fatalf("sidecar not deleted along with the block")
+	}
+}
+
+func TestTxDAGSidecarFreezer(t *testing.T) {
+	var (
+		frdir  = t.TempDir()
+		blocks = makeTxDAGTestBlocks(8)
+	)
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false, false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	// Import the first blocks directly into the ancient store, they are hidden
+	// behind the tail of the sidecar store created afterwards
+	if _, err := WriteAncientBlocks(db, blocks[:2], []types.Receipts{nil, nil}, big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	db.Close()
+	if err := os.RemoveAll(filepath.Join(resolveChainFreezerDir(frdir), TxDAGFreezerName)); err != nil {
+		t.Fatalf("failed to remove sidecar store: %v", err)
+	}
+	db, err = NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false, false)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	if tail, _ := db.(*freezerdb).AncientStore.(*chainFreezer).txdags.Tail(); tail != 2 {
+		t.Fatalf("sidecar store tail mismatch, have %d, want %d", tail, 2)
+	}
+	for _, block := range blocks[2:] {
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(100))
+	}
+	WriteHeadBlockHash(db, blocks[len(blocks)-1].Hash())
+	if err := db.(*freezerdb).Freeze(2); err != nil {
+		t.Fatalf("failed to freeze: %v", err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 6 {
+		t.Fatalf("frozen blocks mismatch, have %d, want %d", frozen, 6)
+	}
+	check := func(db ethdb.Database, limit uint64) {
+		for i, block := range blocks[2:limit] {
+			number := uint64(i + 2)
+			if has, _ := db.Has(txDAGKey(number, block.Hash())); has && number < 6 {
+				t.Fatalf("block %d: frozen sidecar left in key-value store", number)
+			}
+			have := ReadTxDAG(db, block.Hash(), number)
+			if (have != nil) != (block.TxDAG() != nil) {
+				t.Fatalf("block %d: sidecar mismatch, have %v, want %v", number, have, block.TxDAG())
+			}
+		}
+	}
+	check(db, 8)
+	db.Close()
+
+	// Check the frozen sidecars again after a restart
+	db, err = NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false, false)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	check(db, 6)
+
+	// Rewinding the chain truncates the sidecars along with the blocks
+	if _, err := db.TruncateHead(4); err != nil {
+		t.Fatalf("failed to truncate head: %v", err)
+	}
+	if head, _ := db.(*freezerdb).AncientStore.(*chainFreezer).txdags.Ancients(); head != 4 {
+		t.Fatalf("sidecar store head mismatch, have %d, want %d", head, 4)
+	}
+	if _, err := db.TruncateHead(1); err != nil {
+		t.Fatalf("failed to truncate head: %v", err)
+	}
+	if tail, _ := db.(*freezerdb).AncientStore.(*chainFreezer).txdags.Tail(); tail != 1 {
+		t.Fatalf("sidecar store tail mismatch, have %d, want %d", tail, 1)
+	}
+}
@@ -630,7 +630,7 @@ func DataTypeByKey(key []byte) DataType {
 		return BlockDataType
 	default:
 		for _, meta := range [][]byte{
-			fastTrieProgressKey, persistentStateIDKey, trieJournalKey, snapSyncStatusFlagKey} {
+			fastTrieProgressKey, persistentStateIDKey, trieJournalKey, nodeBufferListJournalKey, snapSyncStatusFlagKey} {
 			if bytes.Equal(key, meta) {
 				return StateDataType
 			}
@@ -685,6 +685,11 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		bloomBits       stat
 		beaconHeaders   stat
 		cliqueSnaps     stat
+		unsafeTxDAGs    stat
+		optimizedCodes  stat
+		txDAGs          stat
+		historyLookups  stat
+		historyArchive  stat
 
 		// Les statistic
 		chtTrieNodes   stat
@@ -747,6 +752,19 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			beaconHeaders.Add(size)
 		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
+		case bytes.HasPrefix(key, unsafeTxDAGPrefix) && len(key) == (len(unsafeTxDAGPrefix)+common.HashLength):
+			unsafeTxDAGs.Add(size)
+		case bytes.HasPrefix(key, txDAGPrefix) && len(key) == (len(txDAGPrefix)+8+common.HashLength):
+			txDAGs.Add(size)
+		case (bytes.HasPrefix(key, accountHistoryLookupPrefix) && len(key) == (len(accountHistoryLookupPrefix)+common.AddressLength+8)) ||
+			(bytes.HasPrefix(key, storageHistoryLookupPrefix) && len(key) == (len(storageHistoryLookupPrefix)+common.AddressLength+common.HashLength+8)) ||
+			(bytes.HasPrefix(key, incompleteHistoryLookupPrefix) && len(key) == (len(incompleteHistoryLookupPrefix)+common.AddressLength+8)):
+			historyLookups.Add(size)
+		case bytes.HasPrefix(key, historyArchivePrefix) && len(key) == (len(historyArchivePrefix)+8):
+			historyArchive.Add(size)
+		case (bytes.HasPrefix(key, optimizedCodePrefix) && len(key) == (len(optimizedCodePrefix)+2*common.HashLength)) ||
+			(bytes.HasPrefix(key, optimizedBitvecPrefix) && len(key) == (len(optimizedBitvecPrefix)+2*common.HashLength)):
+			optimizedCodes.Add(size)
 		case bytes.HasPrefix(key, ChtTablePrefix) ||
 			bytes.HasPrefix(key, ChtIndexTablePrefix) ||
 			bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
@@ -763,6 +781,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
 				persistentStateIDKey, trieJournalKey, snapshotSyncStatusKey, snapSyncStatusFlagKey,
+				historyLookupHeadKey, historyArchiveRangeKey, nodeBufferListJournalKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -803,10 +822,13 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				storageTries.Add(size)
 			case bytes.HasPrefix(key, PreimagePrefix) && len(key) == (len(PreimagePrefix)+common.HashLength):
 				preimages.Add(size)
+			case bytes.HasPrefix(key, historyArchivePrefix) && len(key) == (len(historyArchivePrefix)+8):
+				historyArchive.Add(size)
 			default:
 				var accounted bool
 				for _, meta := range [][]byte{
-					fastTrieProgressKey, persistentStateIDKey, trieJournalKey, snapSyncStatusFlagKey} {
+					fastTrieProgressKey, persistentStateIDKey, trieJournalKey, nodeBufferListJournalKey, snapSyncStatusFlagKey,
+					historyArchiveRangeKey} {
 					if bytes.Equal(key, meta) {
 						metadata.Add(size)
 						accounted = true
@@ -887,11 +909,16 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Path trie state lookups", stateLookups.Size(), stateLookups.Count()},
 		{"Key-Value store", "Path trie account nodes", accountTries.Size(), accountTries.Count()},
 		{"Key-Value store", "Path trie storage nodes", storageTries.Size(), storageTries.Count()},
+		{"Key-Value store", "Path state history lookups", historyLookups.Size(), historyLookups.Count()},
+		{"Key-Value store", "Path state history archive", historyArchive.Size(), historyArchive.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
 		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Beacon sync headers", beaconHeaders.Size(), beaconHeaders.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
+		{"Key-Value store", "Unsafe TxDAGs", unsafeTxDAGs.Size(), unsafeTxDAGs.Count()},
+		{"Key-Value store", "TxDAG sidecars", txDAGs.Size(), txDAGs.Count()},
+		{"Key-Value store", "Optimized codes", optimizedCodes.Size(), optimizedCodes.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
 		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
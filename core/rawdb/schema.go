@@ -76,6 +76,10 @@ var (
 	// trieJournalKey tracks the in-memory trie node layers across restarts.
 	trieJournalKey = []byte("TrieJournal")
 
+	// nodeBufferListJournalKey tracks the latest checkpoint of the node buffer
+	// list, used to recover it after an unclean shutdown.
+	nodeBufferListJournalKey = []byte("NodeBufferListJournal")
+
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
@@ -93,6 +97,13 @@ var (
 	// transitionStatusKey tracks the eth2 transition status.
 	transitionStatusKey = []byte("eth2-transition")
 
+	// historyLookupHeadKey tracks the id of the latest state history indexed.
+	historyLookupHeadKey = []byte("StateHistoryLookupHead")
+
+	// historyArchiveRangeKey tracks the range of the state histories archived
+	// below the tail of the state freezer.
+	historyArchiveRangeKey = []byte("StateHistoryArchiveRange")
+
 	// snapSyncStatusFlagKey flags that status of snap sync.
 	snapSyncStatusFlagKey = []byte("SnapSyncStatus")
 
@@ -120,10 +131,21 @@ var (
 	// which is used by proof keeper.
 	proofKeeperMetaPrefix = []byte("p") // proofKeeperMetaPrefix + num (uint64 big endian) -> proof keeper meta
 
+	accountHistoryLookupPrefix    = []byte("history-lookup-a-") // accountHistoryLookupPrefix + address + id (uint64 big endian) -> nil, state histories touching the account
+	storageHistoryLookupPrefix    = []byte("history-lookup-o-") // storageHistoryLookupPrefix + address + slot hash + id (uint64 big endian) -> nil, state histories touching the slot
+	incompleteHistoryLookupPrefix = []byte("history-lookup-i-") // incompleteHistoryLookupPrefix + address + id (uint64 big endian) -> nil, state histories with incomplete storage of the account
+	historyArchivePrefix          = []byte("history-archive-")  // historyArchivePrefix + id (uint64 big endian) -> state history archived below the freezer tail
+
 	PreimagePrefix = []byte("secure-key-")       // PreimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
 	genesisPrefix  = []byte("ethereum-genesis-") // genesis state prefix for the db
 
+	unsafeTxDAGPrefix = []byte("txdag-unsafe-")  // unsafeTxDAGPrefix + hash -> missing TxDAG dependencies
+	txDAGPrefix       = []byte("txdag-sidecar-") // txDAGPrefix + num (uint64 big endian) + hash -> TxDAG sidecar
+
+	optimizedCodePrefix   = []byte("fused-code-")   // optimizedCodePrefix + version + code hash -> optimized code
+	optimizedBitvecPrefix = []byte("fused-bitvec-") // optimizedBitvecPrefix + version + code hash -> bitvec of the optimized code
+
 	// BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 	BloomBitsIndexPrefix = []byte("iB")
 
@@ -258,6 +280,26 @@ func IsCodeKey(key []byte) (bool, []byte) {
 	return false, nil
 }
 
+// unsafeTxDAGKey = unsafeTxDAGPrefix + hash
+func unsafeTxDAGKey(hash common.Hash) []byte {
+	return append(unsafeTxDAGPrefix, hash.Bytes()...)
+}
+
+// txDAGKey = txDAGPrefix + num (uint64 big endian) + hash
+func txDAGKey(number uint64, hash common.Hash) []byte {
+	return append(append(txDAGPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// optimizedCodeKey = optimizedCodePrefix + version + code hash
+func optimizedCodeKey(version common.Hash, hash common.Hash) []byte {
+	return append(append(optimizedCodePrefix, version.Bytes()...), hash.Bytes()...)
+}
+
+// optimizedBitvecKey = optimizedBitvecPrefix + version + code hash
+func optimizedBitvecKey(version common.Hash, hash common.Hash) []byte {
+	return append(append(optimizedBitvecPrefix, version.Bytes()...), hash.Bytes()...)
+}
+
 // configKey = configPrefix + hash
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
@@ -273,6 +315,27 @@ func stateIDKey(root common.Hash) []byte {
 	return append(stateIDPrefix, root.Bytes()...)
 }
 
+// accountHistoryLookupKey = accountHistoryLookupPrefix + address + id (uint64 big endian)
+func accountHistoryLookupKey(address common.Address, id uint64) []byte {
+	return append(append(append([]byte{}, accountHistoryLookupPrefix...), address.Bytes()...), encodeBlockNumber(id)...)
+}
+
+// storageHistoryLookupKey = storageHistoryLookupPrefix + address + slot hash + id (uint64 big endian)
+func storageHistoryLookupKey(address common.Address, slot common.Hash, id uint64) []byte {
+	key := append(append([]byte{}, storageHistoryLookupPrefix...), address.Bytes()...)
+	return append(append(key, slot.Bytes()...), encodeBlockNumber(id)...)
+}
+
+// incompleteHistoryLookupKey = incompleteHistoryLookupPrefix + address + id (uint64 big endian)
+func incompleteHistoryLookupKey(address common.Address, id uint64) []byte {
+	return append(append(append([]byte{}, incompleteHistoryLookupPrefix...), address.Bytes()...), encodeBlockNumber(id)...)
+}
+
+// historyArchiveKey = historyArchivePrefix + id (uint64 big endian)
+func historyArchiveKey(id uint64) []byte {
+	return append(append([]byte{}, historyArchivePrefix...), encodeBlockNumber(id)...)
+}
+
 // accountTrieNodeKey = trieNodeAccountPrefix + nodePath.
 func accountTrieNodeKey(path []byte) []byte {
 	return append(trieNodeAccountPrefix, path...)
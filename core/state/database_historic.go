@@ -0,0 +1,130 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// errHistoricTrie is returned if a historic trie is mutated or iterated.
+var errHistoricTrie = errors.New("historic trie is read only")
+
+// historicDB is a state database opening the historical states, whose trie
+// nodes are gone, from the state histories of the path-based trie database.
+type historicDB struct {
+	Database
+}
+
+// NewHistoricDatabase wraps the given state database to open the historical
+// states from the indexed state histories. The opened states are read only,
+// and only supported by the path-based trie database.
+func NewHistoricDatabase(db Database) Database {
+	return &historicDB{Database: db}
+}
+
+// OpenTrie opens the historical account trie at a specific root hash.
+func (db *historicDB) OpenTrie(root common.Hash) (Trie, error) {
+	reader, err := db.TrieDB().HistoricReader(root)
+	if err != nil {
+		return nil, err
+	}
+	return &historicTrie{root: root, reader: reader}, nil
+}
+
+// OpenStorageTrie opens the historical storage trie of an account.
+func (db *historicDB) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self Trie) (Trie, error) {
+	tr, ok := self.(*historicTrie)
+	if !ok {
+		return nil, errors.New("historic account trie is required")
+	}
+	return &historicTrie{root: root, reader: tr.reader}, nil
+}
+
+// CopyTrie returns the given trie, as the historic tries are immutable.
+func (db *historicDB) CopyTrie(t Trie) Trie {
+	if tr, ok := t.(*historicTrie); ok {
+		return tr
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// historicTrie is a read only trie of a historical state, whose accounts and
+// storage slots are served by the historic reader of the path-based database.
+type historicTrie struct {
+	root   common.Hash
+	reader *pathdb.HistoricReader
+}
+
+// GetKey returns nil, as preimages are not available.
+func (t *historicTrie) GetKey([]byte) []byte {
+	return nil
+}
+
+// GetAccount returns the account at the historical state.
+func (t *historicTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	return t.reader.Account(address)
+}
+
+// GetStorage returns the storage slot of the account at the historical state.
+func (t *historicTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	return t.reader.Storage(addr, crypto.Keccak256Hash(key))
+}
+
+func (t *historicTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	return errHistoricTrie
+}
+
+func (t *historicTrie) UpdateStorage(addr common.Address, key, value []byte) error {
+	return errHistoricTrie
+}
+
+func (t *historicTrie) DeleteAccount(address common.Address) error {
+	return errHistoricTrie
+}
+
+func (t *historicTrie) DeleteStorage(addr common.Address, key []byte) error {
+	return errHistoricTrie
+}
+
+func (t *historicTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+// Hash returns the root hash of the historical trie.
+func (t *historicTrie) Hash() common.Hash {
+	return t.root
+}
+
+func (t *historicTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet, error) {
+	return common.Hash{}, nil, errHistoricTrie
+}
+
+func (t *historicTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errHistoricTrie
+}
+
+func (t *historicTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errHistoricTrie
+}
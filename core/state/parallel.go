@@ -0,0 +1,331 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	// ErrParallelCannotDelayGasFee is returned when a transaction executed on a
+	// parallel copy touches the gas fee receivers outside of the fee payment.
+	ErrParallelCannotDelayGasFee = errors.New("parallel tx touches gas fee receivers")
+
+	// ErrParallelInconsistentState is returned when the changes of a parallel
+	// copy cannot be applied on the target state.
+	ErrParallelInconsistentState = errors.New("parallel tx state is inconsistent")
+)
+
+// parallelAccountDiff is the account level mutation of a single transaction
+// executed on a parallel copy.
+type parallelAccountDiff struct {
+	created        bool
+	selfDestructed bool
+	balance        *uint256.Int
+	nonce          *uint64
+	codeHash       []byte
+	code           []byte
+	storage        map[common.Hash]common.Hash
+}
+
+// parallelTxRecorder records the read set and the state diff of the transaction
+// executed on a parallel copy. As MVStates does when generating the TxDAG, the
+// reads are only tracked until the gas fee distribution starts, the balances of
+// the fee receivers are merged as deltas afterwards.
+type parallelTxRecorder struct {
+	base              *StateDB // State the copy is taken from, its objects are copied on read
+	rwSet             *types.RWSet
+	recordingRead     bool
+	cannotDelayGasFee bool
+
+	feeReceivers []common.Address
+	feeBalances  map[common.Address]*uint256.Int // fee receiver balances before execution
+	feeTouched   map[common.Address]struct{}
+	accounts     map[common.Address]*parallelAccountDiff
+	preimages    map[common.Hash][]byte
+}
+
+func (r *parallelTxRecorder) isFeeReceiver(addr common.Address) bool {
+	return slices.Contains(r.feeReceivers, addr)
+}
+
+func (r *parallelTxRecorder) recordAccountRead(addr common.Address, state types.AccountState) {
+	if !r.recordingRead {
+		return
+	}
+	if r.isFeeReceiver(addr) {
+		r.cannotDelayGasFee = true
+		return
+	}
+	r.rwSet.RecordAccountRead(addr, state)
+}
+
+func (r *parallelTxRecorder) recordStorageRead(addr common.Address, slot common.Hash) {
+	if !r.recordingRead {
+		return
+	}
+	if r.isFeeReceiver(addr) {
+		r.cannotDelayGasFee = true
+		return
+	}
+	r.rwSet.RecordStorageRead(addr, slot)
+}
+
+// CopyForParallel creates a copy of the state to execute the transaction with
+// the given index in parallel with others. Instead of copying all the live
+// objects, the copy is an overlay on the original state: an object is copied
+// from the original state when it's first accessed, and the copy only keeps the
+// objects the transaction touches. The logs, the preimages and the prefetcher
+// are not copied. The reads and writes of the transaction are recorded, they can
+// be retrieved by ParallelRWSet and applied back on the original state by
+// MergeParallel.
+func (s *StateDB) CopyForParallel(txIndex int, feeReceivers []common.Address) *StateDB {
+	state := &StateDB{
+		db:                        s.db,
+		trie:                      s.db.CopyTrie(s.trie),
+		noTrie:                    s.noTrie,
+		originalRoot:              s.originalRoot,
+		snaps:                     s.snaps,
+		snap:                      s.snap,
+		accounts:                  make(map[common.Hash][]byte),
+		storages:                  make(map[common.Hash]map[common.Hash][]byte),
+		accountsOrigin:            make(map[common.Address][]byte),
+		storagesOrigin:            make(map[common.Address]map[common.Hash][]byte),
+		stateObjects:              make(map[common.Address]*stateObject),
+		stateObjectsPending:       make(map[common.Address]struct{}),
+		stateObjectsDirty:         make(map[common.Address]struct{}),
+		stateObjectsDestruct:      make(map[common.Address]*types.StateAccount),
+		stateObjectsDestructDirty: make(map[common.Address]*types.StateAccount),
+		refund:                    s.refund,
+		logs:                      make(map[common.Hash][]*types.Log),
+		logSize:                   s.logSize,
+		preimages:                 make(map[common.Hash][]byte),
+		journal:                   newJournal(),
+		accessList:                s.accessList.Copy(),
+		transientStorage:          s.transientStorage.Copy(),
+		hasher:                    crypto.NewKeccakState(),
+	}
+	state.parallel = &parallelTxRecorder{
+		base:          s,
+		rwSet:         types.NewRWSet(txIndex),
+		recordingRead: true,
+		feeReceivers:  feeReceivers,
+		feeBalances:   make(map[common.Address]*uint256.Int, len(feeReceivers)),
+		feeTouched:    make(map[common.Address]struct{}, len(feeReceivers)),
+		accounts:      make(map[common.Address]*parallelAccountDiff),
+		preimages:     make(map[common.Hash][]byte),
+	}
+	for _, addr := range feeReceivers {
+		balance := new(uint256.Int)
+		if obj := state.getStateObject(addr); obj != nil {
+			balance.Set(obj.Balance())
+		}
+		state.parallel.feeBalances[addr] = balance
+	}
+	return state
+}
+
+// parallelObject returns a copy of the live object of the state for the given
+// parallel copy, the second return value reports whether the object is live in
+// the state. A deleted object is copied as well, the parallel copy must not load
+// it again from the database.
+func (s *StateDB) parallelObject(addr common.Address, state *StateDB) (*stateObject, bool) {
+	s.parallelLock.RLock()
+	defer s.parallelLock.RUnlock()
+
+	obj := s.stateObjects[addr]
+	if obj == nil {
+		return nil, false
+	}
+	return obj.deepCopy(state), true
+}
+
+// ParallelRWSet returns the read/write set recorded by the parallel copy, nil is
+// returned if the state is not a parallel copy.
+func (s *StateDB) ParallelRWSet() *types.RWSet {
+	if s.parallel == nil {
+		return nil
+	}
+	return s.parallel.rwSet
+}
+
+// ParallelCannotDelayGasFee reports whether the transaction executed on the
+// parallel copy accessed the gas fee receivers before its fee payment.
+func (s *StateDB) ParallelCannotDelayGasFee() bool {
+	return s.parallel != nil && s.parallel.cannotDelayGasFee
+}
+
+// checkParallelFeeReceivers stops recording the reads of the parallel copy and
+// checks whether the fee receivers have been touched before the gas fee is paid.
+func (s *StateDB) checkParallelFeeReceivers() {
+	s.parallel.recordingRead = false
+	for _, addr := range s.parallel.feeReceivers {
+		if _, ok := s.stateObjectsDestructDirty[addr]; ok {
+			s.parallel.cannotDelayGasFee = true
+			return
+		}
+		if _, ok := s.journal.dirties[addr]; ok {
+			s.parallel.cannotDelayGasFee = true
+			return
+		}
+	}
+}
+
+// recordParallelDiff collects the mutations of the dirty objects before they
+// are finalised. It must be invoked before the journal is cleared.
+func (s *StateDB) recordParallelDiff(deleteEmptyObjects bool) {
+	r := s.parallel
+	for addr := range s.journal.dirties {
+		obj, exist := s.stateObjects[addr]
+		if !exist {
+			continue
+		}
+		if r.isFeeReceiver(addr) {
+			if obj.selfDestructed {
+				r.cannotDelayGasFee = true
+			}
+			r.feeTouched[addr] = struct{}{}
+			continue
+		}
+		diff, ok := r.accounts[addr]
+		if !ok {
+			diff = &parallelAccountDiff{storage: make(map[common.Hash]common.Hash)}
+			r.accounts[addr] = diff
+		}
+		if obj.created {
+			// the whole account is replaced, keep all the fields
+			diff.created = true
+			r.rwSet.RecordAccountWrite(addr, types.AccountSelf)
+		}
+		if obj.dirtyBalance != nil && (obj.created || !obj.dirtyBalance.Eq(obj.data.Balance)) {
+			diff.balance = new(uint256.Int).Set(obj.dirtyBalance)
+			r.rwSet.RecordAccountWrite(addr, types.AccountBalance)
+		}
+		if obj.dirtyNonce != nil && (obj.created || *obj.dirtyNonce != obj.data.Nonce) {
+			nonce := *obj.dirtyNonce
+			diff.nonce = &nonce
+			r.rwSet.RecordAccountWrite(addr, types.AccountNonce)
+		}
+		if obj.dirtyCodeHash != nil && (obj.created || !slices.Equal(obj.dirtyCodeHash, obj.data.CodeHash)) {
+			diff.codeHash = obj.dirtyCodeHash
+			diff.code = obj.code
+			r.rwSet.RecordAccountWrite(addr, types.AccountCodeHash)
+		}
+		for key, value := range obj.dirtyStorage {
+			if !obj.created && value == obj.GetCommittedState(key) {
+				continue
+			}
+			diff.storage[key] = value
+			r.rwSet.RecordStorageWrite(addr, key)
+		}
+		if obj.selfDestructed || (deleteEmptyObjects && obj.empty()) {
+			diff.selfDestructed = obj.selfDestructed
+			r.rwSet.RecordAccountWrite(addr, types.AccountSuicide)
+		}
+	}
+	for hash, preimage := range s.preimages {
+		r.preimages[hash] = preimage
+	}
+}
+
+// MergeParallel applies the state changes of the transaction executed on the
+// parallel copy src, in the same way as if the transaction was executed on the
+// state directly. The tx context must be set to the transaction before merging,
+// so that the logs are re-indexed in the block scope. The state is left intact
+// if an error is returned.
+func (s *StateDB) MergeParallel(src *StateDB, deleteEmptyObjects bool) error {
+	r := src.parallel
+	if r == nil {
+		return fmt.Errorf("%w: not a parallel copy", ErrParallelInconsistentState)
+	}
+	if r.cannotDelayGasFee {
+		return ErrParallelCannotDelayGasFee
+	}
+	// Read the copy before locking the state, the copy might read the state
+	feeBalances := make(map[common.Address]*uint256.Int, len(r.feeTouched))
+	for addr := range r.feeTouched {
+		balance := new(uint256.Int)
+		if obj := src.getStateObject(addr); obj != nil {
+			balance.Set(obj.Balance())
+		}
+		feeBalances[addr] = balance
+	}
+	// The live objects are mutated, block the parallel copies reading them
+	s.parallelLock.Lock()
+	defer s.parallelLock.Unlock()
+
+	// Check everything ahead, the state must not be partially merged
+	for addr, diff := range r.accounts {
+		if diff.created || s.getStateObject(addr) != nil {
+			continue
+		}
+		if diff.balance != nil || diff.nonce != nil || diff.codeHash != nil || len(diff.storage) > 0 {
+			return fmt.Errorf("%w: missing account %v", ErrParallelInconsistentState, addr)
+		}
+	}
+	feeDeltas := make(map[common.Address]*uint256.Int, len(r.feeTouched))
+	for addr, balance := range feeBalances {
+		prev := r.feeBalances[addr]
+		if balance.Lt(prev) {
+			return fmt.Errorf("%w: fee receiver %v balance decreased", ErrParallelInconsistentState, addr)
+		}
+		feeDeltas[addr] = balance.Sub(balance, prev)
+	}
+	for addr, diff := range r.accounts {
+		var obj *stateObject
+		if diff.created {
+			obj, _ = s.createObject(addr)
+		} else {
+			obj = s.getOrNewStateObject(addr)
+		}
+		obj.touch()
+		if diff.balance != nil {
+			obj.SetBalance(diff.balance)
+		}
+		if diff.nonce != nil {
+			obj.SetNonce(*diff.nonce)
+		}
+		if diff.codeHash != nil {
+			obj.SetCode(common.BytesToHash(diff.codeHash), diff.code)
+		}
+		for key, value := range diff.storage {
+			obj.SetState(key, value)
+		}
+		if diff.selfDestructed {
+			s.SelfDestruct(addr)
+		}
+	}
+	for addr, delta := range feeDeltas {
+		s.AddBalance(addr, delta)
+	}
+	for _, l := range src.logs[src.thash] {
+		s.AddLog(l)
+	}
+	for hash, preimage := range r.preimages {
+		s.AddPreimage(hash, preimage)
+	}
+	s.Finalise(deleteEmptyObjects)
+	return nil
+}
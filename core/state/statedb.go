@@ -117,7 +117,9 @@ type StateDB struct {
 	logSize uint
 
 	// parallel EVM related
-	mvStates *types.MVStates
+	mvStates     *types.MVStates
+	parallel     *parallelTxRecorder // Non-nil if the state is copied for parallel execution
+	parallelLock sync.RWMutex        // Guards the live objects read by the parallel copies
 
 	// Preimages occurred seen by VM in the scope of block.
 	preimages map[common.Hash][]byte
@@ -334,12 +336,20 @@ func (s *StateDB) SubRefund(gas uint64) {
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for self-destructed accounts.
 func (s *StateDB) Exist(addr common.Address) bool {
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountSelf)
+	}
 	return s.getStateObject(addr) != nil
 }
 
 // Empty returns whether the state object is either non-existent
 // or empty according to the EIP161 specification (balance = nonce = code = 0)
 func (s *StateDB) Empty(addr common.Address) bool {
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountBalance)
+		s.parallel.recordAccountRead(addr, types.AccountNonce)
+		s.parallel.recordAccountRead(addr, types.AccountCodeHash)
+	}
 	so := s.getStateObject(addr)
 	return so == nil || so.empty()
 }
@@ -349,6 +359,9 @@ func (s *StateDB) GetBalance(addr common.Address) *uint256.Int {
 	if s.mvStates != nil {
 		s.mvStates.RecordAccountRead(addr, types.AccountBalance)
 	}
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountBalance)
+	}
 
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
@@ -362,6 +375,9 @@ func (s *StateDB) GetNonce(addr common.Address) uint64 {
 	if s.mvStates != nil {
 		s.mvStates.RecordAccountRead(addr, types.AccountNonce)
 	}
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountNonce)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Nonce()
@@ -380,6 +396,34 @@ func (s *StateDB) GetStorageRoot(addr common.Address) common.Hash {
 	return common.Hash{}
 }
 
+// CheckConditional checks the known accounts of the transaction conditional
+// against the current state. The storage root of an account is only known up
+// to the last root computation, so an expected root of an account with storage
+// modified since fails the check.
+func (s *StateDB) CheckConditional(cond *types.TransactionConditional) error {
+	for addr, account := range cond.KnownAccounts {
+		if account.StorageRoot != nil {
+			root := types.EmptyRootHash
+			if obj := s.getStateObject(addr); obj != nil {
+				if len(obj.dirtyStorage) > 0 || len(obj.pendingStorage) > 0 {
+					return fmt.Errorf("%w: storage of %v modified", types.ErrConditionalKnownAcct, addr)
+				}
+				root = obj.Root()
+			}
+			if root != *account.StorageRoot {
+				return fmt.Errorf("%w: storage root of %v is %v, expected %v", types.ErrConditionalKnownAcct, addr, root, *account.StorageRoot)
+			}
+			continue
+		}
+		for slot, value := range account.StorageSlots {
+			if have := s.GetState(addr, slot); have != value {
+				return fmt.Errorf("%w: slot %v of %v is %v, expected %v", types.ErrConditionalKnownAcct, slot, addr, have, value)
+			}
+		}
+	}
+	return nil
+}
+
 // TxIndex returns the current transaction index set by Prepare.
 func (s *StateDB) TxIndex() int {
 	return s.txIndex
@@ -389,6 +433,9 @@ func (s *StateDB) GetCode(addr common.Address) []byte {
 	if s.mvStates != nil {
 		s.mvStates.RecordAccountRead(addr, types.AccountCodeHash)
 	}
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountCodeHash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.Code()
@@ -400,6 +447,9 @@ func (s *StateDB) GetCodeSize(addr common.Address) int {
 	if s.mvStates != nil {
 		s.mvStates.RecordAccountRead(addr, types.AccountCodeHash)
 	}
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountCodeHash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.CodeSize()
@@ -411,6 +461,9 @@ func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
 	if s.mvStates != nil {
 		s.mvStates.RecordAccountRead(addr, types.AccountCodeHash)
 	}
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountCodeHash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return common.BytesToHash(stateObject.CodeHash())
@@ -423,6 +476,9 @@ func (s *StateDB) GetState(addr common.Address, hash common.Hash) common.Hash {
 	if s.mvStates != nil {
 		s.mvStates.RecordStorageRead(addr, hash)
 	}
+	if s.parallel != nil {
+		s.parallel.recordStorageRead(addr, hash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetState(hash)
@@ -435,6 +491,9 @@ func (s *StateDB) GetCommittedState(addr common.Address, hash common.Hash) commo
 	if s.mvStates != nil {
 		s.mvStates.RecordStorageRead(addr, hash)
 	}
+	if s.parallel != nil {
+		s.parallel.recordStorageRead(addr, hash)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetCommittedState(hash)
@@ -448,6 +507,9 @@ func (s *StateDB) Database() Database {
 }
 
 func (s *StateDB) HasSelfDestructed(addr common.Address) bool {
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountSelf)
+	}
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.selfDestructed
@@ -464,6 +526,9 @@ func (s *StateDB) AddBalance(addr common.Address, amount *uint256.Int) {
 	if s.mvStates != nil {
 		s.mvStates.RecordAccountRead(addr, types.AccountBalance)
 	}
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountBalance)
+	}
 	stateObject := s.getOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.AddBalance(amount)
@@ -476,6 +541,9 @@ func (s *StateDB) SubBalance(addr common.Address, amount *uint256.Int) {
 	if s.mvStates != nil {
 		s.mvStates.RecordAccountRead(addr, types.AccountBalance)
 	}
+	if s.parallel != nil {
+		s.parallel.recordAccountRead(addr, types.AccountBalance)
+	}
 	stateObject := s.getOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SubBalance(amount)
@@ -664,6 +732,13 @@ func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
 	}
+	// The parallel copy takes the live objects of the original state on demand
+	if s.parallel != nil {
+		if obj, ok := s.parallel.base.parallelObject(addr, s); ok {
+			s.setStateObject(obj)
+			return obj
+		}
+	}
 	// If no live objects are available, attempt to use snapshots
 	var data *types.StateAccount
 	if s.snap != nil {
@@ -796,6 +871,12 @@ func (s *StateDB) CopyWithMvStates() *StateDB {
 // Copy creates a deep, independent copy of the state.
 // Snapshots of the copied state cannot be applied to the copy.
 func (s *StateDB) Copy() *StateDB {
+	return s.copyInternal(true)
+}
+
+// copyInternal creates a deep, independent copy of the state. The logs and
+// preimages of the block are only copied if withLogs is set.
+func (s *StateDB) copyInternal(withLogs bool) *StateDB {
 	// Copy all the basic fields, initialize the memory ones
 	state := &StateDB{
 		db:                        s.db,
@@ -870,18 +951,20 @@ func (s *StateDB) Copy() *StateDB {
 	state.accountsOrigin = copySet(state.accountsOrigin)
 	state.storagesOrigin = copy2DSet(state.storagesOrigin)
 
-	// Deep copy the logs occurred in the scope of block
-	for hash, logs := range s.logs {
-		cpy := make([]*types.Log, len(logs))
-		for i, l := range logs {
-			cpy[i] = new(types.Log)
-			*cpy[i] = *l
+	if withLogs {
+		// Deep copy the logs occurred in the scope of block
+		for hash, logs := range s.logs {
+			cpy := make([]*types.Log, len(logs))
+			for i, l := range logs {
+				cpy[i] = new(types.Log)
+				*cpy[i] = *l
+			}
+			state.logs[hash] = cpy
+		}
+		// Deep copy the preimages occurred in the scope of block
+		for hash, preimage := range s.preimages {
+			state.preimages[hash] = preimage
 		}
-		state.logs[hash] = cpy
-	}
-	// Deep copy the preimages occurred in the scope of block
-	for hash, preimage := range s.preimages {
-		state.preimages[hash] = preimage
 	}
 	// Do we need to copy the access list and transient storage?
 	// In practice: No. At the start of a transaction, these two lists are empty.
@@ -938,6 +1021,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 	if s.mvStates != nil {
 		feeReceivers = s.mvStates.FeeReceivers()
 	}
+	if s.parallel != nil {
+		s.recordParallelDiff(deleteEmptyObjects)
+	}
 	addressesToPrefetch := make([][]byte, 0, len(s.journal.dirties))
 
 	// finalise stateObjectsDestruct
@@ -1674,6 +1760,23 @@ func (s *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addre
 	return s.accessList.Contains(addr, slot)
 }
 
+// AccessList returns the addresses and the storage slots in the access list of
+// the current transaction, including the ones added in Prepare.
+func (s *StateDB) AccessList() types.AccessList {
+	list := make(types.AccessList, 0, len(s.accessList.addresses))
+	for addr, idx := range s.accessList.addresses {
+		tuple := types.AccessTuple{Address: addr}
+		if idx >= 0 {
+			tuple.StorageKeys = make([]common.Hash, 0, len(s.accessList.slots[idx]))
+			for slot := range s.accessList.slots[idx] {
+				tuple.StorageKeys = append(tuple.StorageKeys, slot)
+			}
+		}
+		list = append(list, tuple)
+	}
+	return list
+}
+
 // convertAccountSet converts a provided account set from address keyed to hash keyed.
 func (s *StateDB) convertAccountSet(set map[common.Address]*types.StateAccount) map[common.Hash]struct{} {
 	ret := make(map[common.Hash]struct{}, len(set))
@@ -1732,6 +1835,9 @@ func (s *StateDB) ResetMVStates(txCount int, feeReceivers []common.Address) *typ
 }
 
 func (s *StateDB) CheckFeeReceiversRWSet() {
+	if s.parallel != nil {
+		s.checkParallelFeeReceivers()
+	}
 	if s.mvStates == nil {
 		return
 	}
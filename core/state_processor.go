@@ -35,7 +35,20 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 )
 
-var processTxTimer = metrics.NewRegisteredTimer("process/tx/time", nil)
+var (
+	processTxTimer = metrics.NewRegisteredTimer("process/tx/time", nil)
+
+	txDAGVerifyMeter      = metrics.NewRegisteredMeter("chain/txdag/verify", nil)
+	txDAGInvalidMeter     = metrics.NewRegisteredMeter("chain/txdag/invalid", nil)
+	txDAGUnsafeMeter      = metrics.NewRegisteredMeter("chain/txdag/unsafe", nil)
+	txDAGMissingDepMeter  = metrics.NewRegisteredMeter("chain/txdag/missingdeps", nil)
+	txDAGSidecarMeter     = metrics.NewRegisteredMeter("chain/txdag/sidecar/checked", nil)
+	txDAGSidecarDropMeter = metrics.NewRegisteredMeter("chain/txdag/sidecar/dropped", nil)
+)
+
+// maxLoggedUnsafeTxs is the maximum number of the txs with missing dependencies
+// printed when an unsafe TxDAG is found.
+const maxLoggedUnsafeTxs = 32
 
 // StateProcessor is a basic Processor, which takes care of transitioning
 // state from one point to another.
@@ -92,17 +105,33 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		ProcessBeaconBlockRoot(*beaconRoot, vmenv, statedb)
 	}
 	statedb.MarkFullProcessed()
+	feeReceivers := []common.Address{context.Coinbase, params.OptimismBaseFeeRecipient, params.OptimismL1FeeRecipient}
 	if p.bc.enableTxDAG {
-		feeReceivers := []common.Address{context.Coinbase, params.OptimismBaseFeeRecipient, params.OptimismL1FeeRecipient}
 		statedb.ResetMVStates(len(block.Transactions()), feeReceivers).EnableAsyncGen()
 	}
+	// Execute the transactions in parallel if the block carries a TxDAG, the
+	// remaining ones are executed in sequence if it fails halfway.
+	var next int
+	if p.bc.parallelExecWorkers > 0 && cfg.Tracer == nil && p.config.IsByzantium(blockNumber) {
+		var err error
+		receipts, allLogs, next, err = p.processParallel(block, statedb, cfg, vmenv, feeReceivers, gp, usedGas)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+	}
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
+		if i < next {
+			continue
+		}
 		start := time.Now()
 		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
 		if err != nil {
 			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
+		if checker := p.bc.opcodeChecker; checker != nil && checker.sample() {
+			checker.check(p.config, block, context, statedb, msg, tx, i)
+		}
 		statedb.SetTxContext(tx.Hash(), i)
 		receipt, err := applyTransaction(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
 		if err != nil {
@@ -131,11 +160,16 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		// compare input TxDAG when it enable in consensus
 		dag, err := statedb.ResolveTxDAG(len(block.Transactions()))
 		if err == nil {
-			// TODO(galaio): check TxDAG correctness?
 			log.Debug("Process TxDAG result", "block", block.NumberU64(), "tx", len(block.Transactions()), "txDAG", dag.TxCount())
 			if metrics.EnabledExpensive {
 				go types.EvaluateTxDAGPerformance(dag)
 			}
+			if p.bc.verifyTxDAG {
+				if err := p.verifyTxDAG(block, dag); err != nil {
+					return nil, nil, 0, err
+				}
+			}
+			p.checkTxDAGSidecar(block, dag)
 		} else {
 			log.Error("ResolveTxDAG err", "block", block.NumberU64(), "tx", len(block.Transactions()), "err", err)
 		}
@@ -143,6 +177,74 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	return receipts, allLogs, *usedGas, nil
 }
 
+// verifyTxDAG checks the TxDAG carried in the block against the one resolved
+// from the read/write sets of the txs. If any dependency is missing, the block is
+// rejected if the unsafe TxDAG is not tolerated, otherwise the TxDAG is marked as
+// unsafe for the parallel execution once the block is validated and written.
+//
+// The sidecars are not committed to by the block hash, they are checked apart by
+// checkTxDAGSidecar.
+func (p *StateProcessor) verifyTxDAG(block *types.Block, resolved types.TxDAG) error {
+	var dag types.TxDAG
+	if txs := block.Transactions(); len(txs) > 0 {
+		dag, _ = types.DecodeTxDAGCalldata(txs[len(txs)-1].Data())
+	}
+	if dag == nil {
+		// the block carries no TxDAG, nothing to verify
+		return nil
+	}
+	txDAGVerifyMeter.Mark(1)
+	missing, err := types.FindMissingTxDeps(resolved, dag, len(block.Transactions()))
+	if err != nil {
+		// the invalid TxDAG is never used for the parallel execution
+		txDAGInvalidMeter.Mark(1)
+		log.Warn("Invalid TxDAG in block", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+		return nil
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	txDAGUnsafeMeter.Mark(1)
+	txDAGMissingDepMeter.Mark(int64(len(missing)))
+
+	var txs []uint64
+	for _, dep := range missing {
+		if len(txs) > 0 && txs[len(txs)-1] == dep.TxIndex {
+			continue
+		}
+		if len(txs) == maxLoggedUnsafeTxs {
+			break
+		}
+		txs = append(txs, dep.TxIndex)
+	}
+	log.Warn("Unsafe TxDAG in block", "number", block.NumberU64(), "hash", block.Hash(), "missing", len(missing), "txs", txs, "reject", p.bc.rejectUnsafeTxDAG)
+	if p.bc.rejectUnsafeTxDAG {
+		return fmt.Errorf("%w: %d dependencies missing", ErrUnsafeTxDAG, len(missing))
+	}
+	p.bc.unsafeTxDAGs.Add(block.Hash(), missing)
+	return nil
+}
+
+// checkTxDAGSidecar checks the TxDAG sidecar fetched for the block against the
+// one resolved from the read/write sets of the txs. The sidecar is delivered out
+// of band without authentication, so an invalid or unsafe one is dropped without
+// affecting the block, and a safe one is persisted once the block is written.
+func (p *StateProcessor) checkTxDAGSidecar(block *types.Block, resolved types.TxDAG) {
+	dag, ok := p.bc.txDAGSidecars.Get(block.Hash())
+	if !ok {
+		return
+	}
+	txDAGSidecarMeter.Mark(1)
+	missing, err := types.FindMissingTxDeps(resolved, dag, len(block.Transactions()))
+	if err != nil || len(missing) > 0 {
+		txDAGSidecarDropMeter.Mark(1)
+		log.Debug("Unsafe TxDAG sidecar dropped", "number", block.NumberU64(), "hash", block.Hash(), "missing", len(missing), "err", err)
+		p.bc.txDAGSidecars.Remove(block.Hash())
+		return
+	}
+	p.bc.safeTxDAGSidecars.Add(block.Hash(), dag)
+}
+
 func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, blockNumber *big.Int, blockHash common.Hash, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -32,7 +33,9 @@ import (
 )
 
 // TODO delete after debug performance metrics
-var DebugInnerExecutionDuration time.Duration
+// DebugInnerExecutionDuration is updated by the txs executed concurrently by
+// the parallel processor, it must only be accessed atomically.
+var DebugInnerExecutionDuration atomic.Int64
 
 // ExecutionResult includes all output after executing given evm
 // message no matter the execution itself is successful or not.
@@ -517,7 +520,8 @@ func (st *StateTransition) innerTransitionDb() (*ExecutionResult, error) {
 		st.state.SetNonce(msg.From, st.state.GetNonce(sender.Address())+1)
 		ret, st.gasRemaining, vmerr = st.evm.Call(sender, st.to(), msg.Data, st.gasRemaining, value)
 	}
-	DebugInnerExecutionDuration += time.Since(start)
+	// the txs may be executed concurrently by the parallel processor
+	DebugInnerExecutionDuration.Add(int64(time.Since(start)))
 
 	// if deposit: skip refunds, skip tipping coinbase
 	// Regolith changes this behaviour to report the actual gasUsed instead of always reporting all gas used.
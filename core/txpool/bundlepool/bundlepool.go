@@ -2,16 +2,20 @@ package bundlepool
 
 import (
 	"container/heap"
-	"context"
 	"errors"
+	"fmt"
 	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/miner"
-	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
 	"math/big"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -32,8 +36,15 @@ const (
 
 	maxMinTimestampFromNow = int64(300) // 5 minutes
 
-	dialAttempts = 3
-	dialSleep    = 3 * time.Second
+	// bundleStatusCacheCap is the number of bundles whose status is tracked.
+	bundleStatusCacheCap = 16384
+
+	// maxBundleTransitions is the number of transitions kept per bundle.
+	maxBundleTransitions = 64
+
+	// journalDirtyInterval is the interval the journal is regenerated at
+	// after bundles are cancelled, batching the rotations of a burst of them.
+	journalDirtyInterval = time.Second
 )
 
 var (
@@ -41,6 +52,9 @@ var (
 	slotsGauge          = metrics.NewRegisteredGauge("bundlepool/slots", nil)
 	bundleDeliverAll    = metrics.NewRegisteredCounter("bundle/deliver/all", nil)
 	bundleDeliverFailed = metrics.NewRegisteredCounter("bundle/deliver/failed", nil)
+	bundleCancelAll     = metrics.NewRegisteredCounter("bundle/cancel/all", nil)
+	bundleCancelFailed  = metrics.NewRegisteredCounter("bundle/cancel/failed", nil)
+	statusDroppedMeter  = metrics.NewRegisteredMeter("bundlepool/status/dropped", nil)
 )
 
 var (
@@ -53,6 +67,14 @@ var (
 	// ErrBundleGasPriceLow is returned if the bundle gas price is too low.
 	ErrBundleGasPriceLow = errors.New("bundle gas price is too low")
 
+	// ErrBundleExpired is returned if a journaled bundle can no longer be
+	// included.
+	ErrBundleExpired = errors.New("bundle expired")
+
+	// ErrBundleNotFound is returned if no bundle in the pool matches the
+	// cancellation.
+	ErrBundleNotFound = errors.New("bundle not found")
+
 	// ErrBundleAlreadyExist is returned if the bundle is already contained
 	// within the pool.
 	ErrBundleAlreadyExist = errors.New("bundle already exist")
@@ -74,8 +96,18 @@ type BlockChain interface {
 	StateAt(root common.Hash) (*state.StateDB, error)
 }
 
+// replacementKey is the sender-scoped replacement UUID of a bundle.
+type replacementKey struct {
+	sender common.Address
+	uuid   uuid.UUID
+}
+
 type BundleSimulator interface {
 	SimulateBundle(bundle *types.Bundle) (*big.Int, error)
+
+	// ChargeBundle charges the bundle accepted into the pool against the quotas
+	// of its senders, only checking them in a dry run.
+	ChargeBundle(bundle *types.Bundle, dryRun bool) error
 }
 
 type BundlePool struct {
@@ -85,13 +117,27 @@ type BundlePool struct {
 
 	bundles    map[common.Hash]*types.Bundle
 	bundleHeap BundleHeap
+	origins    map[common.Hash]*types.SendBundleArgs // Submitted arguments of the bundles, for the journal
+	uuids      map[replacementKey]common.Hash        // Bundles replaceable by the sender-scoped UUIDs
+	uuidKeys   map[common.Hash]replacementKey        // Reverse index of uuids, by the bundle hash
 	mu         sync.RWMutex
 
+	journal      *journal // Journal of accepted bundles to back up to disk
+	journalDirty bool     // Whether bundles left the pool which are still in the journal
+
 	slots uint64 // Number of slots currently allocated
 
 	simulator BundleSimulator
 
-	bundleReceiverClients map[string]*rpc.Client
+	forwarder *forwarder // Delivery of the bundles to the bundle receivers, nil if none
+
+	statuses   *lru.Cache[common.Hash, *types.BundleStatus] // Lifecycle of the recent bundles
+	statusLock sync.Mutex                                   // Serialises the transitions of the statuses
+	statusCh   chan *types.BundleStatusUpdate               // Transitions to publish by statusLoop
+	statusFeed event.Feed
+
+	wg     sync.WaitGroup // tracks loop and statusLoop
+	closed chan struct{}  // closed when the pool is shutting down
 }
 
 func New(config Config, mevConfig miner.MevConfig, chain BlockChain) *BundlePool {
@@ -99,49 +145,90 @@ func New(config Config, mevConfig miner.MevConfig, chain BlockChain) *BundlePool
 	config = (&config).sanitize()
 
 	pool := &BundlePool{
-		config:                config,
-		mevConfig:             mevConfig,
-		chain:                 chain,
-		bundles:               make(map[common.Hash]*types.Bundle),
-		bundleHeap:            make(BundleHeap, 0),
-		bundleReceiverClients: make(map[string]*rpc.Client),
+		config:     config,
+		mevConfig:  mevConfig,
+		chain:      chain,
+		bundles:    make(map[common.Hash]*types.Bundle),
+		bundleHeap: make(BundleHeap, 0),
+		origins:    make(map[common.Hash]*types.SendBundleArgs),
+		uuids:      make(map[replacementKey]common.Hash),
+		uuidKeys:   make(map[common.Hash]replacementKey),
+		statuses:   lru.NewCache[common.Hash, *types.BundleStatus](bundleStatusCacheCap),
+		statusCh:   make(chan *types.BundleStatusUpdate, 1024),
+		closed:     make(chan struct{}),
 	}
-	if pool.mevConfig.MevReceivers == nil {
-		return pool
+	if config.Journal != "" {
+		pool.journal = newBundleJournal(config.Journal)
 	}
-	for _, v := range mevConfig.MevReceivers {
-		pool.register(v)
+	pool.wg.Add(1)
+	go pool.statusLoop()
+	if len(mevConfig.MevReceivers) > 0 {
+		pool.forwarder = newForwarder(mevConfig.MevReceivers, mevConfig.MevReceiverKey)
 	}
-	if len(pool.bundleReceiverClients) == 0 {
-		log.Error("No valid bundleReceivers")
-	}
-
 	return pool
 }
 
-func (p *BundlePool) register(url string) {
-	var receiverClient *rpc.Client
-	if url != "" {
-		var err error
-		for i := 1; i < dialAttempts+1; i++ {
-			receiverClient, err = rpc.Dial(url)
-			if err != nil {
-				log.Error("failed to dial bundle receiver", "attempts", i, "url", url, "err", err)
-				if i == dialAttempts {
-					return
-				}
-				time.Sleep(dialSleep)
-				continue
-			}
-			p.bundleReceiverClients[url] = receiverClient
-			log.Info("success to dial bundle receiver", "url", url)
+// SetBundleSimulator sets the simulator pricing the bundles. As the journaled
+// bundles are re-simulated, the journal is loaded once the simulator is ready.
+func (p *BundlePool) SetBundleSimulator(simulator BundleSimulator) {
+	p.simulator = simulator
+
+	if p.journal != nil {
+		load := func(bundle *types.Bundle, args *types.SendBundleArgs) error {
+			return p.add(bundle, args, false)
+		}
+		if err := p.journal.load(load); err != nil {
+			log.Warn("Failed to load bundle journal", "err", err)
+		}
+		p.rotateJournal(false)
+
+		p.wg.Add(1)
+		go p.loop()
+	}
+}
+
+// loop is the bundle pool's main event loop, regenerating the journal
+// periodically, and shortly after bundles left the pool which must not be
+// loaded back.
+func (p *BundlePool) loop() {
+	defer p.wg.Done()
+
+	var (
+		journal = time.NewTicker(p.config.Rejournal)
+		dirty   = time.NewTicker(journalDirtyInterval)
+	)
+	defer journal.Stop()
+	defer dirty.Stop()
+
+	for {
+		select {
+		case <-p.closed:
+			p.rotateJournal(true)
 			return
+
+		case <-journal.C:
+			p.rotateJournal(false)
+
+		case <-dirty.C:
+			p.rotateJournal(true)
 		}
 	}
 }
 
-func (p *BundlePool) SetBundleSimulator(simulator BundleSimulator) {
-	p.simulator = simulator
+// rotateJournal regenerates the journal from the pool, if dirtyOnly is set only
+// if bundles left the pool since the last rotation.
+func (p *BundlePool) rotateJournal(dirtyOnly bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if dirtyOnly && !p.journalDirty {
+		return
+	}
+	if err := p.journal.rotate(p.toJournal()); err != nil {
+		log.Warn("Failed to rotate bundle journal", "err", err)
+		return
+	}
+	p.journalDirty = false
 }
 
 func (p *BundlePool) Init(gasTip uint64, head *types.Header, reserve txpool.AddressReserver) error {
@@ -157,8 +244,30 @@ func (p *BundlePool) FilterBundle(bundle *types.Bundle) bool {
 	return true
 }
 
+// statusLoop publishes the bundle state transitions, so that slow subscribers
+// don't stall the pool.
+func (p *BundlePool) statusLoop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.closed:
+			return
+
+		case update := <-p.statusCh:
+			p.statusFeed.Send(core.BundleStatusEvent{Update: update})
+		}
+	}
+}
+
 // AddBundle adds a mev bundle to the pool
 func (p *BundlePool) AddBundle(bundle *types.Bundle, originBundle *types.SendBundleArgs) error {
+	return p.add(bundle, originBundle, true)
+}
+
+// add simulates the bundle and adds it to the pool. Bundles are forwarded to
+// the bundle receivers only when submitted, not when loaded from the journal.
+func (p *BundlePool) add(bundle *types.Bundle, originBundle *types.SendBundleArgs, forward bool) error {
 	if p.simulator == nil {
 		return ErrSimulatorMissing
 	}
@@ -166,6 +275,23 @@ func (p *BundlePool) AddBundle(bundle *types.Bundle, originBundle *types.SendBun
 	if bundle.MinTimestamp > uint64(time.Now().Unix()+maxMinTimestampFromNow) {
 		return ErrBundleTimestampTooHigh
 	}
+	if !forward {
+		if head := p.chain.CurrentBlock(); expired(bundle, head.Number.Uint64(), uint64(time.Now().Unix())) {
+			return ErrBundleExpired
+		}
+	}
+
+	var (
+		key         replacementKey
+		replaceable = originBundle != nil && originBundle.ReplacementUUID != nil
+	)
+	if replaceable {
+		sender, err := p.sender(bundle)
+		if err != nil {
+			return err
+		}
+		key = replacementKey{sender: sender, uuid: *originBundle.ReplacementUUID}
+	}
 
 	price, err := p.simulator.SimulateBundle(bundle)
 	if err != nil {
@@ -181,35 +307,167 @@ func (p *BundlePool) AddBundle(bundle *types.Bundle, originBundle *types.SendBun
 		return ErrBundleAlreadyExist
 	}
 
-	if p.slots+numSlots(bundle) > p.config.GlobalSlots {
+	// The replaced bundle leaves the pool only if the new one is accepted
+	var replaced *types.Bundle
+	if replaceable {
+		replaced = p.bundles[p.uuids[key]]
+	}
+	// The quotas are charged for the newly submitted bundles only, checked before
+	// any bundle is dropped to make room
+	charge := forward && replaced == nil
+	if charge {
+		if err := p.simulator.ChargeBundle(bundle, true); err != nil {
+			return err
+		}
+	}
+	slots := p.slots
+	if replaced != nil {
+		slots -= numSlots(replaced)
+	}
+	if slots+numSlots(bundle) > p.config.GlobalSlots {
 		if !p.drop(bundle) {
 			return ErrBundleGasPriceLow
 		}
 	}
+	if charge {
+		if err := p.simulator.ChargeBundle(bundle, false); err != nil {
+			log.Warn("Failed to charge bundle", "hash", hash, "err", err)
+		}
+	}
+	head := p.chain.CurrentBlock().Number.Uint64()
+	if replaced != nil {
+		log.Debug("Replaced bundle", "hash", replaced.Hash(), "replacement", hash, "uuid", key.uuid)
+		p.deleteBundle(replaced.Hash())
+		p.record(replaced.Hash(), types.BundleReplaced, head, fmt.Errorf("replaced by %v", hash))
+	}
 
-	for url, cli := range p.bundleReceiverClients {
-		cli := cli
-		url := url
-		go func() {
-			var hash common.Hash
-			err := cli.CallContext(context.Background(), &hash, "eth_sendBundle", *originBundle)
-			if err != nil {
-				bundleDeliverFailed.Inc(1)
-				log.Error("failed to deliver bundle to receiver", "url", url, "err", err)
-			}
-		}()
-		bundleDeliverAll.Inc(1)
+	if forward && p.forwarder != nil {
+		p.forwarder.forward("eth_sendBundle", *originBundle, bundleDeliverAll, bundleDeliverFailed)
 	}
 
 	p.bundles[hash] = bundle
+	p.origins[hash] = originBundle
+	p.record(hash, types.BundlePending, head, nil)
+	if replaceable {
+		p.uuids[key] = hash
+		p.uuidKeys[hash] = key
+	}
 	heap.Push(&p.bundleHeap, bundle)
 	p.slots += numSlots(bundle)
 
+	if p.journal != nil {
+		if err := p.journal.insert(bundle, originBundle); err != nil {
+			log.Warn("Failed to journal bundle", "hash", hash, "err", err)
+		}
+	}
+
 	bundleGauge.Update(int64(len(p.bundles)))
 	slotsGauge.Update(int64(p.slots))
 	return nil
 }
 
+// CancelBundle removes the bundle registered under the replacement UUID by
+// the sender who signed the cancellation over the bundle hash. A validly signed
+// cancellation is forwarded to the bundle receivers even if the bundle is not
+// in the pool, as they might still hold it.
+func (p *BundlePool) CancelBundle(args *types.CancelBundleArgs) error {
+	if len(args.Signature) != crypto.SignatureLength {
+		return fmt.Errorf("invalid signature length %d", len(args.Signature))
+	}
+	sig := common.CopyBytes(args.Signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	}
+	pub, err := crypto.SigToPub(args.BundleHash.Bytes(), sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if p.forwarder != nil {
+		p.forwarder.forward("eth_cancelBundle", *args, bundleCancelAll, bundleCancelFailed)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The UUID is scoped to the sender. The bundle registered under it is
+	// cancelled even if its hash differs from the signed one, as the hash
+	// depends on the price simulated by each node.
+	key := replacementKey{sender: crypto.PubkeyToAddress(*pub), uuid: args.ReplacementUUID}
+	hash, ok := p.uuids[key]
+	if !ok {
+		return ErrBundleNotFound
+	}
+	p.deleteBundle(hash)
+	p.record(hash, types.BundleCancelled, p.chain.CurrentBlock().Number.Uint64(), nil)
+	log.Debug("Cancelled bundle", "hash", hash, "uuid", key.uuid, "sender", key.sender)
+
+	// The cancelled bundle is rotated out of the journal by the loop, it must
+	// not be loaded back after a restart
+	p.journalDirty = true
+
+	bundleGauge.Update(int64(len(p.bundles)))
+	slotsGauge.Update(int64(p.slots))
+	return nil
+}
+
+// ReportBundle records a state transition of a bundle outside the pool, at the
+// given block number.
+func (p *BundlePool) ReportBundle(hash common.Hash, state types.BundleState, number uint64, reason error) {
+	p.record(hash, state, number, reason)
+}
+
+// BundleStatus returns the current state of a bundle and its transitions, nil
+// if the bundle is unknown.
+func (p *BundlePool) BundleStatus(hash common.Hash) *types.BundleStatus {
+	status, _ := p.statuses.Get(hash)
+	return status
+}
+
+// SubscribeBundleStatus subscribes to the bundle state transitions.
+func (p *BundlePool) SubscribeBundleStatus(ch chan<- core.BundleStatusEvent) event.Subscription {
+	return p.statusFeed.Subscribe(ch)
+}
+
+// record adds a state transition to the status of the bundle, and publishes
+// it to the subscribers. Repeated transitions into the same state at the same
+// block, like merging the bundle into every rebuilt block, are folded.
+func (p *BundlePool) record(hash common.Hash, state types.BundleState, number uint64, reason error) {
+	transition := types.BundleTransition{
+		State:       state,
+		BlockNumber: hexutil.Uint64(number),
+		Time:        hexutil.Uint64(time.Now().Unix()),
+	}
+	if reason != nil {
+		transition.Reason = reason.Error()
+	}
+
+	p.statusLock.Lock()
+	defer p.statusLock.Unlock()
+
+	// The statuses are handed out, so they are replaced instead of modified
+	status := &types.BundleStatus{Hash: hash, BundleTransition: transition}
+	if prev, ok := p.statuses.Get(hash); ok {
+		if prev.State == state && prev.BlockNumber == transition.BlockNumber {
+			return
+		}
+		history := prev.History
+		if len(history) >= maxBundleTransitions {
+			history = history[len(history)-maxBundleTransitions+1:]
+		}
+		status.History = append(slices.Clone(history), transition)
+	} else {
+		status.History = []types.BundleTransition{transition}
+	}
+	p.statuses.Add(hash, status)
+
+	// The pool lock might be held, never wait for the slow subscribers. The
+	// dropped transition is still kept in the status of the bundle.
+	select {
+	case p.statusCh <- &types.BundleStatusUpdate{Hash: hash, BundleTransition: transition}:
+	default:
+		statusDroppedMeter.Mark(1)
+	}
+}
+
 func (p *BundlePool) GetBundle(hash common.Hash) *types.Bundle {
 	p.mu.RUnlock()
 	defer p.mu.RUnlock()
@@ -233,6 +491,7 @@ func (p *BundlePool) PendingBundles(blockNumber uint64, blockTimestamp uint64) [
 		if (bundle.MaxTimestamp != 0 && blockTimestamp > bundle.MaxTimestamp) ||
 			(bundle.MaxBlockNumber != 0 && blockNumber > bundle.MaxBlockNumber) {
 			p.deleteBundle(hash)
+			p.record(hash, types.BundleExpired, blockNumber, nil)
 			continue
 		}
 
@@ -266,6 +525,15 @@ func (p *BundlePool) Filter(tx *types.Transaction) bool {
 }
 
 func (p *BundlePool) Close() error {
+	close(p.closed)
+	p.wg.Wait()
+
+	if p.forwarder != nil {
+		p.forwarder.close()
+	}
+	if p.journal != nil {
+		p.journal.close()
+	}
 	log.Info("Bundle pool stopped")
 	return nil
 }
@@ -373,11 +641,11 @@ func (p *BundlePool) reset(newHead *types.Header) {
 	for hash, bundle := range p.bundles {
 		if (bundle.MaxTimestamp != 0 && newHead.Time > bundle.MaxTimestamp) ||
 			(bundle.MaxBlockNumber != 0 && newHead.Number.Cmp(new(big.Int).SetUint64(bundle.MaxBlockNumber)) > 0) {
-			p.slots -= numSlots(p.bundles[hash])
-			delete(p.bundles, hash)
+			p.deleteBundle(hash)
+			p.record(hash, types.BundleExpired, newHead.Number.Uint64(), nil)
 		} else if txSet.Contains(bundle.Txs[0].Hash()) {
-			p.slots -= numSlots(p.bundles[hash])
-			delete(p.bundles, hash)
+			p.deleteBundle(hash)
+			p.record(hash, types.BundleIncluded, newHead.Number.Uint64(), nil)
 		}
 	}
 	bundleGauge.Update(int64(len(p.bundles)))
@@ -393,6 +661,38 @@ func (p *BundlePool) deleteBundle(hash common.Hash) {
 
 	p.slots -= numSlots(p.bundles[hash])
 	delete(p.bundles, hash)
+	delete(p.origins, hash)
+	if key, ok := p.uuidKeys[hash]; ok {
+		delete(p.uuids, key)
+		delete(p.uuidKeys, hash)
+	}
+}
+
+// sender returns the sender of the bundle, which is the sender of its first
+// transaction.
+func (p *BundlePool) sender(bundle *types.Bundle) (common.Address, error) {
+	if len(bundle.Txs) == 0 {
+		return common.Address{}, errors.New("bundle missing txs")
+	}
+	return types.Sender(types.LatestSigner(p.chain.Config()), bundle.Txs[0])
+}
+
+// toJournal retrieves all the bundles that should be included in the journal,
+// leaving out the expired ones.
+// It assumes that the caller holds the pool's lock.
+func (p *BundlePool) toJournal() []*journalEntry {
+	var (
+		number    = p.chain.CurrentBlock().Number.Uint64()
+		timestamp = uint64(time.Now().Unix())
+		entries   = make([]*journalEntry, 0, len(p.bundles))
+	)
+	for hash, bundle := range p.bundles {
+		if expired(bundle, number, timestamp) {
+			continue
+		}
+		entries = append(entries, &journalEntry{Bundle: bundle, Args: p.origins[hash], Hash: hash})
+	}
+	return entries
 }
 
 // drop removes the bundle with the lowest gas price from the pool.
@@ -401,8 +701,10 @@ func (p *BundlePool) drop(bundle *types.Bundle) bool {
 	dropSlots := uint64(0)
 	for len(p.bundleHeap) > 0 {
 		if dropSlots >= numSlots(bundle) {
+			head := p.chain.CurrentBlock().Number.Uint64()
 			for _, dropBundle := range dropBundles {
 				p.deleteBundle(dropBundle.Hash())
+				p.record(dropBundle.Hash(), types.BundleDropped, head, ErrBundleGasPriceLow)
 			}
 			return true
 		}
@@ -442,6 +744,13 @@ func (p *BundlePool) SetMaxGas(maxGas uint64) {}
 
 // =====================================================================================================================
 
+// expired reports whether the bundle can no longer be included after the given
+// block number and timestamp.
+func expired(bundle *types.Bundle, number uint64, timestamp uint64) bool {
+	return (bundle.MaxTimestamp != 0 && timestamp > bundle.MaxTimestamp) ||
+		(bundle.MaxBlockNumber != 0 && number > bundle.MaxBlockNumber)
+}
+
 // numSlots calculates the number of slots needed for a single bundle.
 func numSlots(bundle *types.Bundle) uint64 {
 	return (bundle.Size() + bundleSlotSize - 1) / bundleSlotSize
@@ -2,12 +2,22 @@ package bundlepool
 
 import (
 	"container/heap"
+	"crypto/ecdsa"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/google/uuid"
 	"math/big"
 	"testing"
 )
@@ -16,6 +26,7 @@ type testBlockChain struct {
 	config   *params.ChainConfig
 	gasLimit uint64
 	statedb  *state.StateDB
+	head     uint64
 }
 
 func (bc *testBlockChain) Config() *params.ChainConfig {
@@ -24,7 +35,7 @@ func (bc *testBlockChain) Config() *params.ChainConfig {
 
 func (bc *testBlockChain) CurrentBlock() *types.Header {
 	return &types.Header{
-		Number: new(big.Int),
+		Number: new(big.Int).SetUint64(bc.head),
 	}
 }
 
@@ -134,3 +145,385 @@ func TestBundlePoolDrop(t *testing.T) {
 		heap.Push(&bundlepool.bundleHeap, leastPriceBundle)
 	}
 }
+
+type testBundleSimulator struct {
+	price   *big.Int
+	quota   error         // Error rejecting the charges, if any
+	charged []common.Hash // Bundles charged against the quotas
+}
+
+func (s *testBundleSimulator) SimulateBundle(bundle *types.Bundle) (*big.Int, error) {
+	return s.price, nil
+}
+
+func (s *testBundleSimulator) ChargeBundle(bundle *types.Bundle, dryRun bool) error {
+	if s.quota != nil {
+		return s.quota
+	}
+	if !dryRun {
+		s.charged = append(s.charged, bundle.Hash())
+	}
+	return nil
+}
+
+// Tests that the accepted bundles survive a pool restart through the journal,
+// while the expired ones are dropped.
+func TestBundlePoolJournaling(t *testing.T) {
+	config := Config{
+		GlobalSlots: 16,
+		Journal:     filepath.Join(t.TempDir(), "bundles.rlp"),
+		Rejournal:   time.Second,
+	}
+	mevConfig := miner.MevConfig{MevEnabled: true}
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 100000000, statedb)
+
+	pool := New(config, mevConfig, blockchain)
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(1)})
+
+	var (
+		live = &types.Bundle{
+			Txs:            types.Transactions{types.NewTx(&types.LegacyTx{Nonce: 0})},
+			MaxBlockNumber: 10,
+		}
+		stale = &types.Bundle{
+			Txs:            types.Transactions{types.NewTx(&types.LegacyTx{Nonce: 1})},
+			MaxBlockNumber: 2,
+		}
+	)
+	for _, bundle := range []*types.Bundle{live, stale} {
+		args := &types.SendBundleArgs{MaxBlockNumber: bundle.MaxBlockNumber}
+		if err := pool.AddBundle(bundle, args); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	hash := live.Hash()
+	pool.Close()
+
+	// Restart the pool past the deadline of the stale bundle
+	blockchain.head = 5
+	pool = New(config, mevConfig, blockchain)
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(2)})
+
+	bundles := pool.AllBundles()
+	if len(bundles) != 1 {
+		t.Fatalf("journaled bundles mismatch: have %d, want %d", len(bundles), 1)
+	}
+	if have, want := bundles[0].Txs[0].Hash(), live.Txs[0].Hash(); have != want {
+		t.Fatalf("journaled bundle mismatch: have %x, want %x", have, want)
+	}
+	if bundles[0].Price.Cmp(big.NewInt(2)) != 0 {
+		t.Fatalf("journaled bundle not re-simulated: have price %v, want %v", bundles[0].Price, 2)
+	}
+	if args := pool.origins[bundles[0].Hash()]; args == nil || args.MaxBlockNumber != live.MaxBlockNumber {
+		t.Fatalf("journaled bundle arguments mismatch: have %v", args)
+	}
+	pool.Close()
+
+	// The bundle keeps the hash it was accepted under, although priced anew
+	for _, price := range []int64{2, 3} {
+		pool = New(config, mevConfig, blockchain)
+		pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(price)})
+		if pool.bundles[hash] == nil {
+			t.Fatalf("journaled bundle not found by its hash at price %d", price)
+		}
+		pool.Close()
+	}
+
+	// The expired bundle is rotated out of the journal as well
+	blockchain.head = 11
+	pool = New(config, mevConfig, blockchain)
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(2)})
+	if bundles := pool.AllBundles(); len(bundles) != 0 {
+		t.Fatalf("expired bundles loaded: have %d, want %d", len(bundles), 0)
+	}
+	pool.Close()
+}
+
+// Tests that only the newly accepted bundles are charged against the quotas,
+// not the duplicates, the replacements or the ones loaded from the journal.
+func TestBundlePoolCharge(t *testing.T) {
+	config := Config{
+		GlobalSlots: 16,
+		Journal:     filepath.Join(t.TempDir(), "bundles.rlp"),
+		Rejournal:   time.Hour,
+	}
+	mevConfig := miner.MevConfig{MevEnabled: true}
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 100000000, statedb)
+
+	simulator := &testBundleSimulator{price: big.NewInt(1)}
+	pool := New(config, mevConfig, blockchain)
+	pool.SetBundleSimulator(simulator)
+
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		key, _ = crypto.GenerateKey()
+		id     = uuid.New()
+		args   = &types.SendBundleArgs{MaxBlockNumber: 10, ReplacementUUID: &id}
+	)
+	newBundle := func(nonce uint64) *types.Bundle {
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, Gas: 21000, GasPrice: big.NewInt(1)})
+		return &types.Bundle{Txs: types.Transactions{tx}, MaxBlockNumber: 10}
+	}
+	bundle := newBundle(0)
+	if err := pool.AddBundle(bundle, args); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if err := pool.AddBundle(newBundle(0), args); !errors.Is(err, ErrBundleAlreadyExist) {
+		t.Fatalf("duplicate bundle error mismatch: have %v, want %v", err, ErrBundleAlreadyExist)
+	}
+	if err := pool.AddBundle(newBundle(1), args); err != nil {
+		t.Fatalf("failed to replace bundle: %v", err)
+	}
+	if len(simulator.charged) != 1 || simulator.charged[0] != bundle.Hash() {
+		t.Fatalf("charged bundles mismatch: have %v, want [%v]", simulator.charged, bundle.Hash())
+	}
+	// The bundles over the quotas are rejected
+	simulator.quota = errors.New("quota exceeded")
+	if err := pool.AddBundle(newBundle(2), &types.SendBundleArgs{MaxBlockNumber: 10}); err != simulator.quota {
+		t.Fatalf("quota error mismatch: have %v, want %v", err, simulator.quota)
+	}
+	if bundles := pool.AllBundles(); len(bundles) != 1 {
+		t.Fatalf("bundles mismatch: have %d, want %d", len(bundles), 1)
+	}
+	pool.Close()
+
+	simulator = &testBundleSimulator{price: big.NewInt(1)}
+	pool = New(config, mevConfig, blockchain)
+	pool.SetBundleSimulator(simulator)
+	defer pool.Close()
+
+	if bundles := pool.AllBundles(); len(bundles) != 1 {
+		t.Fatalf("journaled bundles mismatch: have %d, want %d", len(bundles), 1)
+	}
+	if len(simulator.charged) != 0 {
+		t.Fatalf("journaled bundles charged: %v", simulator.charged)
+	}
+}
+
+// Tests that a cancelled bundle is not loaded back from the journal.
+func TestBundlePoolJournalingCancel(t *testing.T) {
+	config := Config{
+		GlobalSlots: 16,
+		Journal:     filepath.Join(t.TempDir(), "bundles.rlp"),
+		Rejournal:   time.Hour,
+	}
+	mevConfig := miner.MevConfig{MevEnabled: true}
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 100000000, statedb)
+
+	pool := New(config, mevConfig, blockchain)
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(1)})
+
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		key, _ = crypto.GenerateKey()
+		id     = uuid.New()
+		tx     = types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, Gas: 21000, GasPrice: big.NewInt(1)})
+		bundle = &types.Bundle{Txs: types.Transactions{tx}, MaxBlockNumber: 10}
+	)
+	if err := pool.AddBundle(bundle, &types.SendBundleArgs{MaxBlockNumber: 10, ReplacementUUID: &id}); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	sig, err := crypto.Sign(bundle.Hash().Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign cancellation: %v", err)
+	}
+	if err := pool.CancelBundle(&types.CancelBundleArgs{ReplacementUUID: id, BundleHash: bundle.Hash(), Signature: sig}); err != nil {
+		t.Fatalf("failed to cancel bundle: %v", err)
+	}
+	pool.Close()
+
+	pool = New(config, mevConfig, blockchain)
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(1)})
+	defer pool.Close()
+
+	if bundles := pool.AllBundles(); len(bundles) != 0 {
+		t.Fatalf("cancelled bundle loaded from the journal: have %d bundles", len(bundles))
+	}
+}
+
+// Tests that a bundle replaces the older one sent with the same replacement
+// UUID by the same sender, and that only the sender can cancel it.
+func TestBundlePoolReplaceAndCancel(t *testing.T) {
+	pool := setupBundlePool(Config{GlobalSlots: 16}, miner.MevConfig{MevEnabled: true})
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(1)})
+
+	var (
+		signer    = types.LatestSigner(params.TestChainConfig)
+		key, _    = crypto.GenerateKey()
+		other, _  = crypto.GenerateKey()
+		id        = uuid.New()
+		newBundle = func(key *ecdsa.PrivateKey, nonce uint64) *types.Bundle {
+			tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, Gas: 21000, GasPrice: big.NewInt(1)})
+			return &types.Bundle{Txs: types.Transactions{tx}, MaxBlockNumber: 10}
+		}
+		args = &types.SendBundleArgs{ReplacementUUID: &id}
+	)
+	first, second, foreign := newBundle(key, 0), newBundle(key, 1), newBundle(other, 0)
+	for _, bundle := range []*types.Bundle{first, second, foreign} {
+		if err := pool.AddBundle(bundle, args); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	// The second bundle replaces the first one, the UUID of the other sender
+	// is a different scope
+	if pool.bundles[first.Hash()] != nil {
+		t.Fatalf("replaced bundle still in the pool")
+	}
+	if pool.bundles[second.Hash()] == nil || pool.bundles[foreign.Hash()] == nil {
+		t.Fatalf("replacing bundles missing from the pool")
+	}
+	if want := numSlots(second) + numSlots(foreign); pool.slots != want {
+		t.Fatalf("slots mismatch: have %d, want %d", pool.slots, want)
+	}
+
+	sign := func(key *ecdsa.PrivateKey, bundle *types.Bundle) *types.CancelBundleArgs {
+		sig, err := crypto.Sign(bundle.Hash().Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign cancellation: %v", err)
+		}
+		return &types.CancelBundleArgs{ReplacementUUID: id, BundleHash: bundle.Hash(), Signature: sig}
+	}
+	// The cancellation must be signed by the sender of the bundle
+	stranger, _ := crypto.GenerateKey()
+	if err := pool.CancelBundle(sign(stranger, second)); err != ErrBundleNotFound {
+		t.Fatalf("cancellation by other sender mismatch: have %v, want %v", err, ErrBundleNotFound)
+	}
+	if err := pool.CancelBundle(sign(key, second)); err != nil {
+		t.Fatalf("failed to cancel bundle: %v", err)
+	}
+	if pool.bundles[second.Hash()] != nil || pool.bundles[foreign.Hash()] == nil {
+		t.Fatalf("cancelled bundles mismatch")
+	}
+	if len(pool.uuids) != 1 || len(pool.uuidKeys) != 1 {
+		t.Fatalf("replacement index mismatch: have %d/%d, want 1/1", len(pool.uuids), len(pool.uuidKeys))
+	}
+}
+
+// Tests that a validly signed cancellation is forwarded to the bundle receivers
+// even if the bundle is not in the pool, but an invalid one is not.
+func TestBundlePoolForwardCancel(t *testing.T) {
+	receiver := new(testReceiver)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	pool := setupBundlePool(Config{GlobalSlots: 16}, miner.MevConfig{MevEnabled: true, MevReceivers: []string{server.URL}})
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(1)})
+	defer pool.Close()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		hash   = common.Hash{0x1}
+	)
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign cancellation: %v", err)
+	}
+	if err := pool.CancelBundle(&types.CancelBundleArgs{ReplacementUUID: uuid.New(), BundleHash: hash, Signature: make([]byte, crypto.SignatureLength)}); err == nil {
+		t.Fatalf("invalid signature accepted")
+	}
+	if err := pool.CancelBundle(&types.CancelBundleArgs{ReplacementUUID: uuid.New(), BundleHash: hash, Signature: sig}); err != ErrBundleNotFound {
+		t.Fatalf("cancellation of unknown bundle mismatch: have %v, want %v", err, ErrBundleNotFound)
+	}
+	for i := 0; i < 100; i++ {
+		if _, delivered, _ := receiver.results(); len(delivered) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, delivered, _ := receiver.results(); len(delivered) != 1 || delivered[0] != "eth_cancelBundle" {
+		t.Fatalf("forwarded cancellations mismatch: %v", delivered)
+	}
+}
+
+// Tests that the bundle state transitions are tracked and published.
+func TestBundlePoolStatus(t *testing.T) {
+	pool := setupBundlePool(Config{GlobalSlots: 16}, miner.MevConfig{MevEnabled: true})
+	pool.SetBundleSimulator(&testBundleSimulator{price: big.NewInt(1)})
+	defer pool.Close()
+
+	events := make(chan core.BundleStatusEvent, 16)
+	sub := pool.SubscribeBundleStatus(events)
+	defer sub.Unsubscribe()
+
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		key, _ = crypto.GenerateKey()
+		tx     = types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, Gas: 21000, GasPrice: big.NewInt(1)})
+		bundle = &types.Bundle{Txs: types.Transactions{tx}, MaxBlockNumber: 3}
+	)
+	if err := pool.AddBundle(bundle, &types.SendBundleArgs{}); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	hash := bundle.Hash()
+
+	// Merging into the rebuilt blocks of the same height is folded
+	pool.ReportBundle(hash, types.BundleMerged, 1, nil)
+	pool.ReportBundle(hash, types.BundleMerged, 1, nil)
+	pool.ReportBundle(hash, types.BundleMerged, 2, nil)
+	pool.PendingBundles(4, 0)
+
+	want := []types.BundleTransition{
+		{State: types.BundlePending, BlockNumber: 0},
+		{State: types.BundleMerged, BlockNumber: 1},
+		{State: types.BundleMerged, BlockNumber: 2},
+		{State: types.BundleExpired, BlockNumber: 4},
+	}
+	status := pool.BundleStatus(hash)
+	if status == nil {
+		t.Fatalf("missing bundle status")
+	}
+	if status.State != types.BundleExpired || len(status.History) != len(want) {
+		t.Fatalf("status mismatch: have %v with %d transitions, want %v with %d", status.State, len(status.History), types.BundleExpired, len(want))
+	}
+	for i, transition := range status.History {
+		if transition.State != want[i].State || transition.BlockNumber != want[i].BlockNumber {
+			t.Errorf("transition %d mismatch: have %v at %d, want %v at %d", i, transition.State, transition.BlockNumber, want[i].State, want[i].BlockNumber)
+		}
+	}
+	for i := range want {
+		select {
+		case ev := <-events:
+			if ev.Update.Hash != hash || ev.Update.State != want[i].State || ev.Update.BlockNumber != want[i].BlockNumber {
+				t.Errorf("event %d mismatch: have %v at %d, want %v at %d", i, ev.Update.State, ev.Update.BlockNumber, want[i].State, want[i].BlockNumber)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not published", i)
+		}
+	}
+	if status := pool.BundleStatus(common.Hash{0x01}); status != nil {
+		t.Errorf("unknown bundle has status %v", status.State)
+	}
+}
+
+// Tests that a subscriber not consuming the status updates never stalls the
+// pool, the updates overflowing the buffer are dropped.
+func TestBundlePoolStatusSlowSubscriber(t *testing.T) {
+	pool := setupBundlePool(Config{GlobalSlots: 16}, miner.MevConfig{MevEnabled: true})
+	defer pool.Close()
+
+	events := make(chan core.BundleStatusEvent)
+	sub := pool.SubscribeBundleStatus(events)
+	defer sub.Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*cap(pool.statusCh); i++ {
+			pool.ReportBundle(common.Hash{0x01}, types.BundleMerged, uint64(i), nil)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("status updates stalled by slow subscriber")
+	}
+	if status := pool.BundleStatus(common.Hash{0x01}); status == nil || status.BlockNumber != hexutil.Uint64(2*cap(pool.statusCh)-1) {
+		t.Fatalf("status not tracked while updates are dropped: %v", status)
+	}
+}
@@ -1,16 +1,24 @@
 package bundlepool
 
 import (
+	"time"
+
 	"github.com/ethereum/go-ethereum/log"
 )
 
 type Config struct {
 	GlobalSlots uint64 // Maximum number of bundle slots for all accounts
+
+	Journal   string        // Journal of accepted bundles to survive node restarts
+	Rejournal time.Duration // Time interval to regenerate the bundle journal
 }
 
 // DefaultConfig contains the default configurations for the bundle pool.
 var DefaultConfig = Config{
 	GlobalSlots: 4096,
+
+	Journal:   "bundles.rlp",
+	Rejournal: time.Hour,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -21,5 +29,9 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid bundlepool bundle slots", "provided", conf.GlobalSlots, "updated", DefaultConfig.GlobalSlots)
 		conf.GlobalSlots = DefaultConfig.GlobalSlots
 	}
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid bundlepool journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
 	return conf
 }
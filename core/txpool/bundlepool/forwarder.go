@@ -0,0 +1,265 @@
+package bundlepool
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// forwardQueueSize is the number of requests queued per bundle receiver,
+	// the requests beyond are dropped.
+	forwardQueueSize = 1024
+
+	forwardRetries    = 5                      // Attempts after the first failed one
+	forwardMinBackoff = 100 * time.Millisecond // Delay before the first retry
+	forwardMaxBackoff = 10 * time.Second       // Upper bound of the doubling delay
+	forwardTimeout    = 5 * time.Second        // Timeout of a single attempt, dialing included
+
+	// bundleSignatureHeader carries the signature of the forwarded request,
+	// in the same format as the flashbots relays expect.
+	bundleSignatureHeader = "X-Flashbots-Signature"
+)
+
+// forwardRequest is a call to deliver to a bundle receiver.
+type forwardRequest struct {
+	method string
+	args   interface{}
+	failed metrics.Counter // counts the requests failing on any receiver
+}
+
+// bundleReceiver is a bundle receiver endpoint with its queue of requests.
+type bundleReceiver struct {
+	url    string
+	queue  chan *forwardRequest
+	client *rpc.Client // nil until dialed, and after a connection failure
+
+	latency  metrics.Timer
+	failures metrics.Counter
+	dropped  metrics.Counter
+}
+
+// forwarder delivers the bundles and cancellations accepted by the pool to the
+// bundle receivers. Each receiver has a bounded queue consumed in order, so a
+// cancellation never overtakes the bundle it cancels, and the failed calls are
+// retried with an exponential backoff, redialing the receiver in between.
+type forwarder struct {
+	key       *ecdsa.PrivateKey // Key to sign the requests with, nil if unsigned
+	receivers []*bundleReceiver
+
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	timeout    time.Duration
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newForwarder creates a forwarder to the given receiver urls and starts
+// delivering to them. The receivers are dialed on the first request.
+func newForwarder(urls []string, key *ecdsa.PrivateKey) *forwarder {
+	f := &forwarder{
+		key:        key,
+		retries:    forwardRetries,
+		minBackoff: forwardMinBackoff,
+		maxBackoff: forwardMaxBackoff,
+		timeout:    forwardTimeout,
+		quit:       make(chan struct{}),
+	}
+	for _, rawurl := range urls {
+		if rawurl == "" {
+			continue
+		}
+		name := rawurl
+		if u, err := url.Parse(rawurl); err == nil && u.Host != "" {
+			name = u.Host
+		}
+		r := &bundleReceiver{
+			url:      rawurl,
+			queue:    make(chan *forwardRequest, forwardQueueSize),
+			latency:  metrics.GetOrRegisterTimer("bundle/forward/"+name+"/latency", nil),
+			failures: metrics.GetOrRegisterCounter("bundle/forward/"+name+"/failures", nil),
+			dropped:  metrics.GetOrRegisterCounter("bundle/forward/"+name+"/dropped", nil),
+		}
+		f.receivers = append(f.receivers, r)
+
+		f.wg.Add(1)
+		go f.loop(r)
+	}
+	if len(f.receivers) == 0 {
+		log.Error("No valid bundleReceivers")
+	}
+	return f
+}
+
+// forward queues the call to every receiver, counting it into all. The
+// calls not delivered to some receiver are counted into failed.
+func (f *forwarder) forward(method string, args interface{}, all, failed metrics.Counter) {
+	req := &forwardRequest{method: method, args: args, failed: failed}
+	for _, r := range f.receivers {
+		select {
+		case r.queue <- req:
+		default:
+			r.dropped.Inc(1)
+			failed.Inc(1)
+			log.Warn("Bundle receiver queue is full, dropping request", "url", r.url, "method", method)
+		}
+		all.Inc(1)
+	}
+}
+
+// loop delivers the queued requests of the receiver one at a time.
+func (f *forwarder) loop(r *bundleReceiver) {
+	defer f.wg.Done()
+	defer func() {
+		if r.client != nil {
+			r.client.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-f.quit:
+			return
+
+		case req := <-r.queue:
+			f.deliver(r, req)
+		}
+	}
+}
+
+// deliver calls the receiver until it succeeds, rejects the request, or the
+// retries run out.
+func (f *forwarder) deliver(r *bundleReceiver, req *forwardRequest) {
+	backoff := f.minBackoff
+	for attempt := 0; ; attempt++ {
+		err := f.call(r, req)
+		if err == nil {
+			return
+		}
+		r.failures.Inc(1)
+
+		if rejected(err) {
+			req.failed.Inc(1)
+			log.Warn("Bundle receiver rejected request", "url", r.url, "method", req.method, "err", err)
+			return
+		}
+		if attempt == f.retries {
+			req.failed.Inc(1)
+			log.Error("Failed to deliver request to bundle receiver", "url", r.url, "method", req.method, "attempts", attempt+1, "err", err)
+			return
+		}
+		log.Debug("Retrying bundle receiver request", "url", r.url, "method", req.method, "attempt", attempt+1, "backoff", backoff, "err", err)
+
+		// Reconnect on the next attempt, the connection may be broken
+		if r.client != nil {
+			r.client.Close()
+			r.client = nil
+		}
+		select {
+		case <-f.quit:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > f.maxBackoff {
+			backoff = f.maxBackoff
+		}
+	}
+}
+
+// call makes a single attempt of the request, dialing the receiver if needed.
+func (f *forwarder) call(r *bundleReceiver, req *forwardRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	if r.client == nil {
+		var opts []rpc.ClientOption
+		if f.key != nil {
+			opts = append(opts, rpc.WithHTTPClient(&http.Client{Transport: &signingTransport{key: f.key, base: http.DefaultTransport}}))
+		}
+		client, err := rpc.DialOptions(ctx, r.url, opts...)
+		if err != nil {
+			return err
+		}
+		r.client = client
+	}
+	start := time.Now()
+	if err := r.client.CallContext(ctx, nil, req.method, req.args); err != nil {
+		return err
+	}
+	r.latency.UpdateSince(start)
+	return nil
+}
+
+// close stops the delivery, the queued requests are discarded.
+func (f *forwarder) close() {
+	close(f.quit)
+	f.wg.Wait()
+}
+
+// rejected reports whether the error is the answer of the receiver to the
+// request, rather than a failure to reach it, so that retrying is pointless.
+func rejected(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		code := httpErr.StatusCode
+		return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+	}
+	return false
+}
+
+// signingTransport signs the body of the HTTP requests, so that the receivers
+// can authenticate the sender of the bundles. The signature is of the text hash
+// of the hex encoded keccak256 hash of the body, and is sent together with the
+// signer address as <address>:<signature>.
+type signingTransport struct {
+	key  *ecdsa.PrivateKey
+	base http.RoundTripper
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	signature, err := signBundleRequest(t.key, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.Header.Set(bundleSignatureHeader, signature)
+	return t.base.RoundTrip(req)
+}
+
+// signBundleRequest returns the signature header value of the request body.
+func signBundleRequest(key *ecdsa.PrivateKey, body []byte) (string, error) {
+	hash := accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(body))))
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(key.PublicKey).Hex() + ":" + hexutil.Encode(sig), nil
+}
@@ -0,0 +1,112 @@
+package bundlepool
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// testReceiver is a bundle receiver failing the first requests with the given
+// HTTP status, and recording the delivered ones.
+type testReceiver struct {
+	failures int
+	status   int
+
+	lock      sync.Mutex
+	attempts  int
+	delivered []string
+	signers   []string
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.attempts++
+	if r.attempts <= r.failures {
+		w.WriteHeader(r.status)
+		return
+	}
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	json.Unmarshal(body, &msg)
+
+	// Recover the signer of the request, if signed
+	if header := req.Header.Get(bundleSignatureHeader); header != "" {
+		parts := strings.SplitN(header, ":", 2)
+		sig, _ := hexutil.Decode(parts[1])
+		pub, err := crypto.SigToPub(accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(body)))), sig)
+		if err == nil && crypto.PubkeyToAddress(*pub).Hex() == parts[0] {
+			r.signers = append(r.signers, parts[0])
+		}
+	}
+	r.delivered = append(r.delivered, msg.Method)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(msg.ID) + `,"result":null}`))
+}
+
+func (r *testReceiver) results() (int, []string, []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.attempts, r.delivered, r.signers
+}
+
+func TestBundleForwarding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		flaky    = &testReceiver{failures: 2, status: http.StatusServiceUnavailable}
+		rejector = &testReceiver{failures: 100, status: http.StatusBadRequest}
+	)
+	flakyServer, rejectorServer := httptest.NewServer(flaky), httptest.NewServer(rejector)
+	defer flakyServer.Close()
+	defer rejectorServer.Close()
+
+	f := newForwarder([]string{flakyServer.URL, rejectorServer.URL}, key)
+	f.minBackoff, f.maxBackoff = time.Millisecond, 10*time.Millisecond
+	defer f.close()
+
+	var (
+		all    = metrics.NewCounterForced()
+		failed = metrics.NewCounterForced()
+	)
+	f.forward("eth_sendBundle", map[string]interface{}{}, all, failed)
+	f.forward("eth_cancelBundle", map[string]interface{}{}, all, failed)
+
+	// The flaky receiver gets both requests in order after the retries, the
+	// rejecting one is not retried
+	for i := 0; i < 100; i++ {
+		if _, delivered, _ := flaky.results(); len(delivered) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	attempts, delivered, signers := flaky.results()
+	if attempts != 4 || len(delivered) != 2 || delivered[0] != "eth_sendBundle" || delivered[1] != "eth_cancelBundle" {
+		t.Fatalf("flaky receiver mismatch: attempts %d, delivered %v", attempts, delivered)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey).Hex(); len(signers) != 2 || signers[0] != want || signers[1] != want {
+		t.Errorf("signers mismatch: have %v, want %v", signers, want)
+	}
+	if attempts, _, _ := rejector.results(); attempts != 2 {
+		t.Errorf("rejecting receiver attempts mismatch: have %d, want %d", attempts, 2)
+	}
+	if all.Snapshot().Count() != 4 || failed.Snapshot().Count() != 2 {
+		t.Errorf("counters mismatch: all %d, failed %d", all.Snapshot().Count(), failed.Snapshot().Count())
+	}
+}
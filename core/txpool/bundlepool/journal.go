@@ -0,0 +1,163 @@
+package bundlepool
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// errNoActiveJournal is returned if a bundle is attempted to be inserted
+// into the journal, but no such file is currently open.
+var errNoActiveJournal = errors.New("no active journal")
+
+// devNull is a WriteCloser that just discards anything written into it. Its
+// goal is to allow the bundle journal to write into a fake journal when
+// loading bundles on startup without printing warnings due to no file
+// being read for write.
+type devNull struct{}
+
+func (*devNull) Write(p []byte) (n int, err error) { return len(p), nil }
+func (*devNull) Close() error                      { return nil }
+
+// journalEntry is a bundle accepted by the pool, together with the arguments
+// it was submitted with and the hash it was accepted under.
+type journalEntry struct {
+	Bundle *types.Bundle
+	Args   *types.SendBundleArgs
+	Hash   common.Hash `rlp:"optional"`
+}
+
+// journal is a rotating log of bundles with the aim of storing the accepted
+// bundles to allow non-expired ones to survive node restarts.
+type journal struct {
+	path   string         // Filesystem path to store the bundles at
+	writer io.WriteCloser // Output stream to write new bundles into
+}
+
+// newBundleJournal creates a new bundle journal to
+func newBundleJournal(path string) *journal {
+	return &journal{
+		path: path,
+	}
+}
+
+// load parses a bundle journal dump from disk, loading its contents into
+// the specified pool.
+func (journal *journal) load(add func(*types.Bundle, *types.SendBundleArgs) error) error {
+	// Open the journal for loading any past bundles
+	input, err := os.Open(journal.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the journal file doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// Temporarily discard any journal additions (don't double add on load)
+	journal.writer = new(devNull)
+	defer func() { journal.writer = nil }()
+
+	// Inject all bundles from the journal into the pool
+	stream := rlp.NewStream(input, 0)
+	total, dropped := 0, 0
+
+	var failure error
+	for {
+		// Parse the next bundle and terminate on error
+		entry := new(journalEntry)
+		if err = stream.Decode(entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			break
+		}
+		total++
+
+		// The bundle is simulated anew, keep the hash it was accepted under as
+		// its price changes. The entries without one carry the accepted price.
+		if entry.Hash != (common.Hash{}) {
+			entry.Bundle.SetHash(entry.Hash)
+		} else {
+			entry.Bundle.Hash()
+		}
+		if err := add(entry.Bundle, entry.Args); err != nil {
+			log.Debug("Failed to add journaled bundle", "err", err)
+			dropped++
+		}
+	}
+	log.Info("Loaded bundle journal", "bundles", total, "dropped", dropped)
+
+	return failure
+}
+
+// insert adds the specified bundle to the local disk journal.
+func (journal *journal) insert(bundle *types.Bundle, args *types.SendBundleArgs) error {
+	if journal.writer == nil {
+		return errNoActiveJournal
+	}
+	if err := rlp.Encode(journal.writer, &journalEntry{Bundle: bundle, Args: args, Hash: bundle.Hash()}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// rotate regenerates the bundle journal based on the current contents of
+// the bundle pool.
+func (journal *journal) rotate(all []*journalEntry) error {
+	// Close the current journal (if any is open)
+	if journal.writer != nil {
+		if err := journal.writer.Close(); err != nil {
+			return err
+		}
+		journal.writer = nil
+	}
+	// Generate a new journal with the contents of the current pool
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	for _, entry := range all {
+		if err = rlp.Encode(replacement, entry); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	replacement.Close()
+
+	// Replace the live journal with the newly generated one
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	sink, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	journal.writer = sink
+
+	logger := log.Info
+	if len(all) == 0 {
+		logger = log.Debug
+	}
+	logger("Regenerated bundle journal", "bundles", len(all))
+
+	return nil
+}
+
+// close flushes the bundle journal contents to disk and closes the file.
+func (journal *journal) close() error {
+	var err error
+
+	if journal.writer != nil {
+		err = journal.writer.Close()
+		journal.writer = nil
+	}
+	return err
+}
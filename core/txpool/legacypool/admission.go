@@ -0,0 +1,374 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+)
+
+const (
+	// maxReputation is the reputation of a well behaving sender or peer, entitled
+	// to all the slots of an account or all the relayed transactions.
+	maxReputation = 100
+
+	invalidPenalty     = 10 // Reputation lost for an invalid transaction
+	underpricedPenalty = 2  // Reputation lost for an underpriced transaction or replacement
+	replacePenalty     = 1  // Reputation lost for a successful replacement
+
+	// reputationRecovery is the time to recover one reputation point.
+	reputationRecovery = 6 * time.Second
+
+	// maxAdmissionRecords is the maximum number of senders and peers tracked by
+	// the admission control, on top of which new ones are admitted untracked.
+	maxAdmissionRecords = 65536
+)
+
+// bucket is a token bucket limiting the rate of admitted transactions.
+type bucket struct {
+	tokens  float64   // Transactions still admissible
+	updated time.Time // Time the tokens were last refilled
+}
+
+// refill adds the tokens accumulated since the last refill at the given rate,
+// up to the burst.
+func (b *bucket) refill(rate, burst float64, now time.Time) {
+	if b.updated.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	}
+	b.updated = now
+}
+
+// take takes up to n tokens from the bucket, returning the number taken.
+func (b *bucket) take(n int, rate, burst float64, now time.Time) int {
+	b.refill(rate, burst, now)
+
+	taken := n
+	if available := int(b.tokens); available < taken {
+		taken = available
+	}
+	b.tokens -= float64(taken)
+	return taken
+}
+
+// full reports whether the bucket was refilled up to the burst.
+func (b *bucket) full(rate, burst float64, now time.Time) bool {
+	b.refill(rate, burst, now)
+	return b.tokens >= burst
+}
+
+// faults are the validation errors a transaction is invalid by itself with,
+// whoever signed or relayed it could have known in advance. The errors which
+// only hold against the current pool or chain state are not faults, as they are
+// met by the stale transactions anyone can replay.
+var faults = []error{
+	txpool.ErrInvalidSender,
+	txpool.ErrNegativeValue,
+	txpool.ErrOversizedData,
+	core.ErrMaxInitCodeSizeExceeded,
+	core.ErrFeeCapVeryHigh,
+	core.ErrTipVeryHigh,
+	core.ErrTipAboveFeeCap,
+	core.ErrIntrinsicGas,
+}
+
+// isUnderpriced reports whether the validation error rejects a transaction or a
+// replacement paying too little. It depends on the pool prices, so it is only
+// penalized lightly, and only against the senders submitting directly, as the
+// peers relay between pools with different price floors.
+func isUnderpriced(err error) bool {
+	return errors.Is(err, txpool.ErrUnderpriced) || errors.Is(err, txpool.ErrReplaceUnderpriced)
+}
+
+// isFault reports whether the validation error is a fault of the transaction.
+func isFault(err error) bool {
+	for _, fault := range faults {
+		if errors.Is(err, fault) {
+			return true
+		}
+	}
+	return false
+}
+
+// record is the admission record of a transaction sender or a network peer.
+type record struct {
+	bucket
+
+	penalty   float64   // Reputation lost as of the last penalty
+	penalized time.Time // Time of the last penalty, the lost reputation recovering since
+}
+
+// reputation returns the reputation of the record at the given time.
+func (s *record) reputation(now time.Time) int {
+	penalty := s.penalty - float64(now.Sub(s.penalized))/float64(reputationRecovery)
+	switch {
+	case penalty <= 0:
+		return maxReputation
+	case penalty >= maxReputation:
+		return 0
+	default:
+		return maxReputation - int(math.Ceil(penalty))
+	}
+}
+
+// penalize lowers the reputation of the record by the given points.
+func (s *record) penalize(points int, now time.Time) {
+	s.penalty = float64(maxReputation - s.reputation(now) + points)
+	if s.penalty > maxReputation {
+		s.penalty = maxReputation
+	}
+	s.penalized = now
+}
+
+// admission rate limits the remote transactions admitted into the pool from
+// each sender and each network peer, and scores the reputation of the senders
+// by their replacements and the senders or peers by their invalid submissions.
+type admission struct {
+	senderRate  float64 // Transactions per second admitted per sender, 0 if unlimited
+	senderBurst float64 // Transactions admitted at once per sender
+	peerRate    float64 // Transactions per second admitted per peer, 0 if unlimited
+	peerBurst   float64 // Transactions admitted at once per peer
+
+	senders map[common.Address]*record
+	peers   map[string]*record
+	lock    sync.Mutex
+}
+
+func newAdmission(config *Config) *admission {
+	return &admission{
+		senderRate:  float64(config.SenderRate),
+		senderBurst: float64(config.SenderBurst),
+		peerRate:    float64(config.PeerRate),
+		peerBurst:   float64(config.PeerBurst),
+		senders:     make(map[common.Address]*record),
+		peers:       make(map[string]*record),
+	}
+}
+
+// sender returns the admission record of the address, creating it if there is
+// still room for it.
+//
+// Note, this method assumes the admission lock is held!
+func (a *admission) sender(addr common.Address) *record {
+	if s := a.senders[addr]; s != nil {
+		return s
+	}
+	if len(a.senders) >= maxAdmissionRecords {
+		return nil
+	}
+	s := new(record)
+	a.senders[addr] = s
+	return s
+}
+
+// peer returns the admission record of the peer, creating it if there is
+// still room for it.
+//
+// Note, this method assumes the admission lock is held!
+func (a *admission) peer(id string) *record {
+	if p := a.peers[id]; p != nil {
+		return p
+	}
+	if len(a.peers) >= maxAdmissionRecords {
+		return nil
+	}
+	p := new(record)
+	a.peers[id] = p
+	return p
+}
+
+// allowSender reports whether a transaction from the sender is within its
+// admission rate, taking its token if so.
+func (a *admission) allowSender(addr common.Address, now time.Time) bool {
+	if a.senderRate == 0 {
+		return true
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	s := a.sender(addr)
+	if s == nil {
+		return true
+	}
+	return s.take(1, a.senderRate, a.senderBurst, now) == 1
+}
+
+// allowPeer returns how many of n transactions from the peer are within its
+// admission rate, taking their tokens. A peer with a lowered reputation is only
+// allowed its share of them, rounded up.
+func (a *admission) allowPeer(id string, n int, now time.Time) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	p := a.peers[id]
+	if p == nil {
+		if a.peerRate == 0 {
+			return n
+		}
+		if p = a.peer(id); p == nil {
+			return n
+		}
+	}
+	if a.peerRate > 0 {
+		n = p.take(n, a.peerRate, a.peerBurst, now)
+	}
+	if reputation := p.reputation(now); reputation < maxReputation {
+		n = (n*reputation + maxReputation - 1) / maxReputation
+	}
+	return n
+}
+
+// penalize lowers the reputation of the sender by the given points.
+func (a *admission) penalize(addr common.Address, points int, now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if s := a.sender(addr); s != nil {
+		s.penalize(points, now)
+		admissionPenaltyMeter.Mark(int64(points))
+	}
+}
+
+// penalizePeer lowers the reputation of the peer by the given points.
+func (a *admission) penalizePeer(id string, points int, now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if p := a.peer(id); p != nil {
+		p.penalize(points, now)
+		admissionPenaltyMeter.Mark(int64(points))
+	}
+}
+
+// report scores the outcome of the admission of a remote transaction into the
+// pool. Only the faults of the transaction itself are penalized, and they are
+// attributed to the peer relaying it if there is one, as anyone can relay the
+// transactions signed by others. The underpriced submissions are penalized only
+// if not relayed, and the replacements are made by the sender.
+func (a *admission) report(addr common.Address, peer string, replaced bool, err error, now time.Time) {
+	var points int
+	switch {
+	case err == nil:
+		if replaced {
+			a.penalize(addr, replacePenalty, now)
+		}
+		return
+	case isFault(err):
+		points = invalidPenalty
+	case isUnderpriced(err) && peer == "":
+		points = underpricedPenalty
+	default:
+		// Stale or not the fault of the transaction, e.g. a used nonce
+		return
+	}
+	if peer != "" {
+		a.penalizePeer(peer, points, now)
+	} else if addr != (common.Address{}) {
+		a.penalize(addr, points, now)
+	}
+}
+
+// reputation returns the reputation of the sender, between 0 and maxReputation.
+func (a *admission) reputation(addr common.Address, now time.Time) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if s := a.senders[addr]; s != nil {
+		return s.reputation(now)
+	}
+	return maxReputation
+}
+
+// slots returns the share of the given slot allowance the sender is entitled
+// to by its reputation, at least one slot.
+func (a *admission) slots(addr common.Address, allowance uint64, now time.Time) uint64 {
+	reputation := a.reputation(addr, now)
+	if reputation == maxReputation {
+		return allowance
+	}
+	slots := (allowance*uint64(reputation) + maxReputation - 1) / maxReputation
+	if slots < 1 {
+		slots = 1
+	}
+	return slots
+}
+
+// prune drops the records of the senders and peers back to their full rate
+// and reputation, and updates the admission metrics.
+func (a *admission) prune(now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for addr, s := range a.senders {
+		if s.reputation(now) == maxReputation && (a.senderRate == 0 || s.full(a.senderRate, a.senderBurst, now)) {
+			delete(a.senders, addr)
+		}
+	}
+	for id, p := range a.peers {
+		if p.reputation(now) == maxReputation && (a.peerRate == 0 || p.full(a.peerRate, a.peerBurst, now)) {
+			delete(a.peers, id)
+		}
+	}
+	stats := a.statsLocked(now)
+	lowReputationGauge.Update(int64(stats.LowReputation))
+	throttledSendersGauge.Update(int64(stats.ThrottledSenders))
+	throttledPeersGauge.Update(int64(stats.ThrottledPeers))
+}
+
+// stats summarizes the state of the admission control.
+func (a *admission) stats(now time.Time) txpool.AdmissionStats {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.statsLocked(now)
+}
+
+// statsLocked summarizes the state of the admission control.
+//
+// Note, this method assumes the admission lock is held!
+func (a *admission) statsLocked(now time.Time) txpool.AdmissionStats {
+	var stats txpool.AdmissionStats
+	for _, s := range a.senders {
+		if s.reputation(now) < maxReputation {
+			stats.LowReputation++
+		}
+		if a.senderRate > 0 {
+			if s.refill(a.senderRate, a.senderBurst, now); s.tokens < 1 {
+				stats.ThrottledSenders++
+			}
+		}
+	}
+	for _, p := range a.peers {
+		if p.reputation(now) < maxReputation {
+			stats.LowReputation++
+		}
+		if a.peerRate > 0 {
+			if p.refill(a.peerRate, a.peerBurst, now); p.tokens < 1 {
+				stats.ThrottledPeers++
+			}
+		}
+	}
+	return stats
+}
@@ -61,6 +61,14 @@ var (
 	// ErrTxPoolOverflow is returned if the transaction pool is full and can't accept
 	// another remote transaction.
 	ErrTxPoolOverflow = errors.New("txpool is full")
+
+	// ErrSenderRateLimited is returned if the sender of a remote transaction
+	// exceeds its admission rate.
+	ErrSenderRateLimited = errors.New("sender rate limited")
+
+	// ErrPeerRateLimited is returned if the peer relaying a transaction exceeds
+	// its admission rate.
+	ErrPeerRateLimited = errors.New("peer rate limited")
 )
 
 var (
@@ -103,6 +111,22 @@ var (
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 	localGauge   = metrics.NewRegisteredGauge("txpool/local", nil)
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
+	privateGauge = metrics.NewRegisteredGauge("txpool/private", nil)
+
+	// privateExpiredMeter counts the private transactions dropped at their deadline
+	privateExpiredMeter = metrics.NewRegisteredMeter("txpool/private/expired", nil)
+
+	// conditionalEvictedMeter counts the conditional transactions dropped as
+	// their conditions failed
+	conditionalEvictedMeter = metrics.NewRegisteredMeter("txpool/conditional/evicted", nil)
+
+	// Metrics for the admission control of senders and peers
+	senderThrottleMeter   = metrics.NewRegisteredMeter("txpool/admission/sender/throttle", nil)
+	peerThrottleMeter     = metrics.NewRegisteredMeter("txpool/admission/peer/throttle", nil)
+	admissionPenaltyMeter = metrics.NewRegisteredMeter("txpool/admission/penalty", nil)
+	lowReputationGauge    = metrics.NewRegisteredGauge("txpool/admission/lowreputation", nil)
+	throttledSendersGauge = metrics.NewRegisteredGauge("txpool/admission/sender/throttled", nil)
+	throttledPeersGauge   = metrics.NewRegisteredGauge("txpool/admission/peer/throttled", nil)
 
 	reheapTimer = metrics.NewRegisteredTimer("txpool/reheap", nil)
 
@@ -188,6 +212,14 @@ type Config struct {
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
 
+	PrivateLifetime uint64 // Number of blocks a private transaction is kept in the pool
+
+	SenderRate  uint64 // Remote transactions per second admitted from each sender (0 = unlimited)
+	SenderBurst uint64 // Remote transactions admitted at once from each sender above its rate
+	PeerRate    uint64 // Transactions per second admitted from each network peer (0 = unlimited)
+	PeerBurst   uint64 // Transactions admitted at once from each network peer above its rate
+	Reputation  bool   // Whether to reduce the slots of remote senders and the admission of peers submitting invalid transactions
+
 	EffectiveGasCeil uint64 // if non-zero, a gas ceiling to enforce independent of the header's gaslimit value
 
 	ReannounceTime    time.Duration // Duration for announcing local pending transactions again
@@ -209,6 +241,8 @@ var DefaultConfig = Config{
 
 	Lifetime: 3 * time.Hour,
 
+	PrivateLifetime: 100,
+
 	ReannounceTime: 10 * 365 * 24 * time.Hour,
 }
 
@@ -248,6 +282,18 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.PrivateLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool private lifetime", "provided", conf.PrivateLifetime, "updated", DefaultConfig.PrivateLifetime)
+		conf.PrivateLifetime = DefaultConfig.PrivateLifetime
+	}
+	if conf.SenderRate > 0 && conf.SenderBurst < conf.SenderRate {
+		log.Warn("Sanitizing invalid txpool sender burst", "provided", conf.SenderBurst, "updated", conf.SenderRate)
+		conf.SenderBurst = conf.SenderRate
+	}
+	if conf.PeerRate > 0 && conf.PeerBurst < conf.PeerRate {
+		log.Warn("Sanitizing invalid txpool peer burst", "provided", conf.PeerBurst, "updated", conf.PeerRate)
+		conf.PeerBurst = conf.PeerRate
+	}
 	if conf.ReannounceTime < time.Minute {
 		log.Warn("Sanitizing invalid txpool reannounce time", "provided", conf.ReannounceTime, "updated", time.Minute)
 		conf.ReannounceTime = time.Minute
@@ -287,6 +333,11 @@ type LegacyPool struct {
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *journal    // Journal of local transaction to back up to disk
+	private *privateTxs // Set of transactions never announced to the network
+
+	admission *admission // Rate limits and reputation of remote senders and peers
+
+	conditionals map[common.Hash]*types.Transaction // Transactions with conditions of inclusion, all private
 
 	reserve txpool.AddressReserver       // Address reserver to ensure exclusivity across subpools
 	pending map[common.Address]*list     // All currently processable transactions
@@ -333,6 +384,9 @@ func New(config Config, chain BlockChain) *LegacyPool {
 		queue:           make(map[common.Address]*list, config.GlobalQueue),
 		beats:           make(map[common.Address]time.Time),
 		all:             newLookup(),
+		private:         newPrivateTxs(),
+		admission:       newAdmission(&config),
+		conditionals:    make(map[common.Hash]*types.Transaction),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
 		queueTxEventCh:  make(chan *types.Transaction),
@@ -480,6 +534,7 @@ func (pool *LegacyPool) loop() {
 				}
 			}
 			pool.mu.Unlock()
+			pool.admission.prune(time.Now())
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -518,6 +573,7 @@ func (pool *LegacyPool) loop() {
 			}()
 			reannMutexTimer.UpdateSince(t0)
 			pool.mu.RUnlock()
+			reannoTxs = pool.private.filter(reannoTxs)
 			staledMeter.Mark(int64(len(reannoTxs)))
 			if len(reannoTxs) > 0 {
 				pool.reannoTxFeed.Send(core.ReannoTxsEvent{Txs: reannoTxs})
@@ -713,6 +769,10 @@ func (pool *LegacyPool) Pending(filter txpool.PendingFilter) map[common.Address]
 				}
 			}
 		}
+		// If only the announceable transactions are requested, skip the private ones
+		if filter.OnlyPublicTxs {
+			txs = pool.private.filter(txs)
+		}
 		if len(txs) > 0 {
 			lazies := make([]*txpool.LazyTransaction, len(txs))
 			for i := 0; i < len(txs); i++ {
@@ -814,6 +874,10 @@ func (pool *LegacyPool) validateTx(tx *types.Transaction, local bool) error {
 			if list := pool.queue[addr]; list != nil {
 				have += list.Len()
 			}
+			// Senders with a low reputation are only allowed a share of the slots
+			if pool.config.Reputation && !local {
+				return have, int(pool.allowance(addr, pool.config.AccountSlots+pool.config.AccountQueue)) - have
+			}
 			return have, math.MaxInt
 		},
 		ExistingExpenditure: func(addr common.Address) *big.Int {
@@ -872,6 +936,12 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	// already validated by this point
 	from, _ := types.Sender(pool.signer, tx)
 
+	// Throttle the remote senders submitting above their admission rate
+	if !isLocal && !pool.admission.allowSender(from, time.Now()) {
+		log.Trace("Discarding rate limited transaction", "hash", hash, "from", from)
+		senderThrottleMeter.Mark(1)
+		return false, ErrSenderRateLimited
+	}
 	// If the address is not yet known, request exclusivity to track the account
 	// only by this subpool until all transactions are evicted
 	var (
@@ -1077,6 +1147,10 @@ func (pool *LegacyPool) journalTx(from common.Address, tx *types.Transaction) {
 	if pool.journal == nil || (!pool.config.JournalRemote && !pool.locals.contains(from)) {
 		return
 	}
+	// Private transactions would be announced if resurrected from the journal
+	if pool.private.contains(tx.Hash()) {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -1156,6 +1230,138 @@ func (pool *LegacyPool) addRemotesSync(txs []*types.Transaction) []error {
 	return pool.Add(txs, false, true)
 }
 
+// AddPrivate enqueues a transaction into the pool without announcing it to the
+// network, neither to the transaction subscribers. It is included and replaced
+// like a remote transaction, and dropped if not included within the private
+// lifetime.
+func (pool *LegacyPool) AddPrivate(tx *types.Transaction) error {
+	return pool.addPrivate(tx, pool.currentHead.Load().Number.Uint64()+pool.config.PrivateLifetime)
+}
+
+// addPrivate adds the private transaction, expiring at the given head number.
+func (pool *LegacyPool) addPrivate(tx *types.Transaction, deadline uint64) error {
+	hash := tx.Hash()
+	// A known transaction may have been announced already
+	if pool.all.Get(hash) != nil {
+		knownTxMeter.Mark(1)
+		return txpool.ErrAlreadyKnown
+	}
+	// Mark the transaction before adding, so that the reorg never announces it
+	pool.private.add(hash, deadline)
+	if err := pool.Add([]*types.Transaction{tx}, false, false)[0]; err != nil {
+		pool.private.remove(hash)
+		return err
+	}
+	privateGauge.Update(int64(pool.private.len()))
+	return nil
+}
+
+// AddConditional enqueues a transaction into the pool, to be included only in
+// blocks satisfying the given conditions. The conditions are checked against
+// the current head on admission, and the transaction is evicted once they
+// fail, either on a new head or while building a block. Other nodes could not
+// enforce the conditions, so the transaction is private.
+func (pool *LegacyPool) AddConditional(tx *types.Transaction, cond *types.TransactionConditional) error {
+	if err := cond.Validate(); err != nil {
+		return err
+	}
+	pool.mu.Lock()
+	head := pool.currentHead.Load()
+	err := cond.CheckExpiry(head)
+	if err == nil {
+		err = pool.currentState.CheckConditional(cond)
+	}
+	pool.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	deadline := head.Number.Uint64() + pool.config.PrivateLifetime
+	if limit := cond.BlockNumberMax; limit != nil && limit.ToInt().IsUint64() && limit.ToInt().Uint64() < deadline {
+		deadline = limit.ToInt().Uint64()
+	}
+	tx.SetConditional(cond)
+	if err := pool.addPrivate(tx, deadline); err != nil {
+		return err
+	}
+	pool.mu.Lock()
+	pool.conditionals[tx.Hash()] = tx
+	pool.mu.Unlock()
+	return nil
+}
+
+// AddFromPeer enqueues a batch of remote transactions relayed by the given
+// network peer, admitting only as many as the rate of the peer allows.
+func (pool *LegacyPool) AddFromPeer(peer string, txs []*types.Transaction) []error {
+	admitted := pool.admission.allowPeer(peer, len(txs), time.Now())
+	if admitted == len(txs) {
+		return pool.addTxs(txs, false, false, peer)
+	}
+	errs := make([]error, len(txs))
+	copy(errs, pool.addTxs(txs[:admitted], false, false, peer))
+	for i := admitted; i < len(txs); i++ {
+		errs[i] = ErrPeerRateLimited
+	}
+	log.Trace("Discarding rate limited transactions", "peer", peer, "count", len(txs)-admitted)
+	peerThrottleMeter.Mark(int64(len(txs) - admitted))
+	return errs
+}
+
+// AdmissionStats returns the state of the admission control of the remote
+// senders and peers.
+func (pool *LegacyPool) AdmissionStats() txpool.AdmissionStats {
+	return pool.admission.stats(time.Now())
+}
+
+// allowance returns the share of the given per account slot allowance granted
+// to the account by its reputation.
+func (pool *LegacyPool) allowance(addr common.Address, slots uint64) uint64 {
+	if !pool.config.Reputation || pool.locals.contains(addr) {
+		return slots
+	}
+	return pool.admission.slots(addr, slots, time.Now())
+}
+
+// evictConditionals drops the conditional transactions whose conditions can
+// no longer hold on top of the given head, or failed in a block being built.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) evictConditionals(head *types.Header) {
+	for hash, tx := range pool.conditionals {
+		if pool.all.Get(hash) == nil {
+			delete(pool.conditionals, hash)
+			continue
+		}
+		cond := tx.Conditional()
+		err := cond.Rejected()
+		if err == nil {
+			err = cond.CheckExpiry(head)
+		}
+		if err == nil {
+			err = pool.currentState.CheckConditional(cond)
+		}
+		if err != nil {
+			log.Debug("Evicting conditional transaction", "hash", hash, "err", err)
+			pool.removeTx(hash, true, true)
+			delete(pool.conditionals, hash)
+			conditionalEvictedMeter.Mark(1)
+		}
+	}
+}
+
+// expirePrivate drops the private transactions not included before the given
+// head reached their deadline.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) expirePrivate(head *types.Header) {
+	known := func(hash common.Hash) bool { return pool.all.Get(hash) != nil }
+	for _, hash := range pool.private.expire(head.Number.Uint64(), known) {
+		log.Trace("Dropping expired private transaction", "hash", hash)
+		pool.removeTx(hash, true, true)
+		privateExpiredMeter.Mark(1)
+	}
+	privateGauge.Update(int64(pool.private.len()))
+}
+
 // This is like addRemotes with a single transaction, but waits for pool reorganization. Tests use this method.
 func (pool *LegacyPool) addRemoteSync(tx *types.Transaction) error {
 	return pool.Add([]*types.Transaction{tx}, false, true)[0]
@@ -1167,6 +1373,12 @@ func (pool *LegacyPool) addRemoteSync(tx *types.Transaction) error {
 // If sync is set, the method will block until all internal maintenance related
 // to the add is finished. Only use this during tests for determinism!
 func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error {
+	return pool.addTxs(txs, local, sync, "")
+}
+
+// addTxs enqueues a batch of transactions into the pool like Add, the peer is
+// the one relaying the remote transactions, empty if they are not from network.
+func (pool *LegacyPool) addTxs(txs []*types.Transaction, local, sync bool, peer string) []error {
 	start := time.Now()
 	defer func(t0 time.Time) {
 		if len(txs) > 0 {
@@ -1176,6 +1388,15 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 	// Do not treat as local if local transactions have been disabled
 	local = local && !pool.config.NoLocals
 
+	// Score the admission of the remote transactions if the reputation is enabled
+	var report func(tx *types.Transaction, replaced bool, err error)
+	if pool.config.Reputation && !local {
+		report = func(tx *types.Transaction, replaced bool, err error) {
+			from, _ := types.Sender(pool.signer, tx) // zero if invalid, the fault goes to the peer
+			pool.admission.report(from, peer, replaced, err, time.Now())
+		}
+	}
+
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
 		errs = make([]error, len(txs))
@@ -1195,6 +1416,10 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 			errs[i] = err
 			log.Trace("Discarding invalid transaction", "hash", tx.Hash(), "err", err)
 			invalidTxMeter.Mark(1)
+
+			if report != nil {
+				report(tx, false, err)
+			}
 			continue
 		}
 		// Accumulate all unknown transactions for deeper processing
@@ -1210,7 +1435,7 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 	pool.mu.Lock()
 	addWaitLockTimer.UpdateSince(tw)
 	t0 := time.Now()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, local, report)
 	if len(news) > 0 {
 		addWithLockTimer.Update(time.Since(t0) / time.Duration(len(news)))
 	}
@@ -1232,9 +1457,10 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 	return errs
 }
 
-// addTxsLocked attempts to queue a batch of transactions if they are valid.
+// addTxsLocked attempts to queue a batch of transactions if they are valid, the
+// admission outcome of the remote ones is reported if report is non-nil.
 // The transaction pool lock must be held.
-func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool, report func(tx *types.Transaction, replaced bool, err error)) ([]error, *accountSet) {
 	dirty := newAccountSet(pool.signer)
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
@@ -1244,6 +1470,9 @@ func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool) ([]er
 			dirty.addTx(tx)
 			validTxMeter.Mark(1)
 		}
+		if report != nil && !pool.locals.containsTx(tx) {
+			report(tx, replaced, err)
+		}
 	}
 	return errs, dirty
 }
@@ -1517,6 +1746,8 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	if reset != nil {
 		// Reset from the old head to the new, rescheduling any reorged transactions
 		demoteAddrs = pool.reset(reset.oldHead, reset.newHead)
+		pool.evictConditionals(pool.currentHead.Load())
+		pool.expirePrivate(pool.currentHead.Load())
 
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
@@ -1583,8 +1814,11 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 		for _, set := range events {
 			txs = append(txs, set.Flatten()...)
 		}
-		pool.txFeed.Send(core.NewTxsEvent{Txs: txs})
-		sendFeed = len(txs)
+		// Private transactions are never announced
+		if txs = pool.private.filter(txs); len(txs) > 0 {
+			pool.txFeed.Send(core.NewTxsEvent{Txs: txs})
+			sendFeed = len(txs)
+		}
 	}
 	sendfeedDur = time.Since(t0)
 }
@@ -1724,7 +1958,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) (demoteAddrs []com
 	// Inject any transactions discarded due to reorgs
 	log.Debug("reset state of txpool", "reinject", len(reinject), "depth", depth)
 	core.SenderCacher.Recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, false, nil)
 	return
 }
 
@@ -1794,7 +2028,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		// Drop all transactions over the allowed limit
 		var caps types.Transactions
 		if !pool.locals.contains(addr) {
-			caps = list.Cap(int(pool.config.AccountQueue))
+			caps = list.Cap(int(pool.allowance(addr, pool.config.AccountQueue)))
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
@@ -1835,7 +2069,7 @@ func (pool *LegacyPool) truncatePending() {
 	spammers := prque.New[int64, common.Address](nil)
 	for addr, list := range pool.pending {
 		// Only evict transactions from high rollers
-		if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
+		if !pool.locals.contains(addr) && uint64(list.Len()) > pool.allowance(addr, pool.config.AccountSlots) {
 			spammers.Push(addr, int64(list.Len()))
 		}
 	}
@@ -1882,7 +2116,8 @@ func (pool *LegacyPool) truncatePending() {
 
 	// If still above threshold, reduce to limit or min allowance
 	if pending > pool.config.GlobalSlots && len(offenders) > 0 {
-		for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.config.AccountSlots {
+		last := offenders[len(offenders)-1]
+		for pending > pool.config.GlobalSlots && uint64(pool.pending[last].Len()) > pool.allowance(last, pool.config.AccountSlots) {
 			for _, addr := range offenders {
 				list := pool.pending[addr]
 
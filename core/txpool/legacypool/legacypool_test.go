@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -2665,6 +2666,389 @@ func TestReplacementDynamicFee(t *testing.T) {
 	}
 }
 
+// Tests that private transactions are included and replaced as usual, but are
+// never announced, and are dropped at their deadline.
+func TestPrivateTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	events := make(chan core.NewTxsEvent, 32)
+	sub := pool.txFeed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	// The private transaction is pending, but not announced
+	private := pricedTransaction(0, 100000, big.NewInt(1), key)
+	if err := pool.AddPrivate(private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	<-pool.requestReset(nil, nil)
+	if err := validateEvents(events, 0); err != nil {
+		t.Fatalf("private transaction announced: %v", err)
+	}
+	if err := pool.AddPrivate(private); !errors.Is(err, txpool.ErrAlreadyKnown) {
+		t.Errorf("duplicate private transaction error mismatch: have %v, want %v", err, txpool.ErrAlreadyKnown)
+	}
+	// The public transaction following it is announced
+	public := pricedTransaction(1, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if err := validateEvents(events, 1); err != nil {
+		t.Fatalf("public transaction event mismatch: %v", err)
+	}
+	if pending := pool.Pending(txpool.PendingFilter{}); len(pending[from]) != 2 {
+		t.Fatalf("pending transactions mismatch: have %d, want %d", len(pending[from]), 2)
+	}
+	if pending := pool.Pending(txpool.PendingFilter{OnlyPublicTxs: true}); len(pending[from]) != 1 || pending[from][0].Hash != public.Hash() {
+		t.Fatalf("public pending transactions mismatch: have %v", pending[from])
+	}
+	// The private replacement is not announced either
+	replacement := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.AddPrivate(replacement); err != nil {
+		t.Fatalf("failed to replace private transaction: %v", err)
+	}
+	<-pool.requestReset(nil, nil)
+	if err := validateEvents(events, 0); err != nil {
+		t.Fatalf("private replacement announced: %v", err)
+	}
+	if pool.Get(private.Hash()) != nil || pool.Get(replacement.Hash()) == nil {
+		t.Fatalf("private transaction not replaced")
+	}
+	if pool.private.len() != 1 {
+		t.Errorf("private transactions mismatch: have %d, want %d", pool.private.len(), 1)
+	}
+	// The private transaction is dropped at its deadline
+	pool.mu.Lock()
+	pool.expirePrivate(&types.Header{Number: new(big.Int).SetUint64(testTxPoolConfig.PrivateLifetime - 1)})
+	pool.mu.Unlock()
+	if pool.Get(replacement.Hash()) == nil {
+		t.Fatalf("private transaction dropped before its deadline")
+	}
+	pool.mu.Lock()
+	pool.expirePrivate(&types.Header{Number: new(big.Int).SetUint64(testTxPoolConfig.PrivateLifetime)})
+	pool.mu.Unlock()
+	if pool.Get(replacement.Hash()) != nil || pool.private.len() != 0 {
+		t.Fatalf("private transaction not dropped at its deadline")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that conditional transactions are admitted only if their conditions
+// hold at the current head, and evicted once they fail.
+func TestConditionalTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	events := make(chan core.NewTxsEvent, 32)
+	sub := pool.txFeed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	var (
+		contract = common.HexToAddress("0xc0")
+		slot     = common.HexToHash("0x01")
+		value    = common.HexToHash("0x02")
+		root     = types.EmptyRootHash
+	)
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, value)
+	pool.mu.Unlock()
+
+	conditional := func(value common.Hash) *types.TransactionConditional {
+		return &types.TransactionConditional{
+			KnownAccounts: map[common.Address]types.KnownAccount{
+				contract:                    {StorageSlots: map[common.Hash]common.Hash{slot: value}},
+				common.HexToAddress("0xc1"): {StorageRoot: &root},
+			},
+		}
+	}
+	// Conditions failing at the current head are rejected
+	tx := pricedTransaction(0, 100000, big.NewInt(1), key)
+	if err := pool.AddConditional(tx, conditional(common.Hash{})); !errors.Is(err, types.ErrConditionalKnownAcct) {
+		t.Errorf("known account mismatch error mismatch: have %v, want %v", err, types.ErrConditionalKnownAcct)
+	}
+	expired := conditional(value)
+	expired.BlockNumberMax = (*hexutil.Big)(pool.currentHead.Load().Number)
+	if err := pool.AddConditional(tx, expired); !errors.Is(err, types.ErrConditionalBlockNumber) {
+		t.Errorf("expired conditional error mismatch: have %v, want %v", err, types.ErrConditionalBlockNumber)
+	}
+	invalid := conditional(value)
+	invalid.TimestampMin, invalid.TimestampMax = new(hexutil.Uint64), new(hexutil.Uint64)
+	*invalid.TimestampMin = 2
+	if err := pool.AddConditional(tx, invalid); !errors.Is(err, types.ErrConditionalInvalid) {
+		t.Errorf("invalid conditional error mismatch: have %v, want %v", err, types.ErrConditionalInvalid)
+	}
+	// Conditions holding are admitted, without announcing the transaction
+	if err := pool.AddConditional(tx, conditional(value)); err != nil {
+		t.Fatalf("failed to add conditional transaction: %v", err)
+	}
+	<-pool.requestReset(nil, nil)
+	if err := validateEvents(events, 0); err != nil {
+		t.Fatalf("conditional transaction announced: %v", err)
+	}
+	if pending := pool.Pending(txpool.PendingFilter{}); len(pending[from]) != 1 || pending[from][0].Resolve().Conditional() == nil {
+		t.Fatalf("conditional transaction not pending")
+	}
+	// The transaction is evicted once its conditions fail on the head state
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, common.Hash{})
+	pool.evictConditionals(pool.currentHead.Load())
+	pool.mu.Unlock()
+	if pool.Get(tx.Hash()) != nil {
+		t.Fatalf("conditional transaction not evicted on known account mismatch")
+	}
+	// The transaction is evicted once rejected by the miner
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, value)
+	pool.mu.Unlock()
+
+	tx = pricedTransaction(0, 100000, big.NewInt(1), key)
+	if err := pool.AddConditional(tx, conditional(value)); err != nil {
+		t.Fatalf("failed to add conditional transaction: %v", err)
+	}
+	tx.Conditional().Reject(types.ErrConditionalKnownAcct)
+	pool.mu.Lock()
+	pool.evictConditionals(pool.currentHead.Load())
+	pool.mu.Unlock()
+	if pool.Get(tx.Hash()) != nil || len(pool.conditionals) != 0 {
+		t.Fatalf("conditional transaction not evicted on rejection")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the remote transactions of a sender are admitted only within the
+// configured rate, while locals and other senders are unaffected.
+func TestSenderRateLimiting(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.SenderRate = 1
+	config.SenderBurst = 2
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000))
+
+	errs := pool.addRemotesSync([]*types.Transaction{
+		transaction(0, 100000, key),
+		transaction(1, 100000, key),
+		transaction(2, 100000, key),
+		transaction(0, 100000, other),
+	})
+	for i, want := range []error{nil, nil, ErrSenderRateLimited, nil} {
+		if !errors.Is(errs[i], want) {
+			t.Errorf("tx %d: admission error mismatch: have %v, want %v", i, errs[i], want)
+		}
+	}
+	if err := pool.addLocal(transaction(2, 100000, key)); err != nil {
+		t.Errorf("local transaction rate limited: %v", err)
+	}
+	if stats := pool.AdmissionStats(); stats.ThrottledSenders != 1 {
+		t.Errorf("throttled senders mismatch: have %d, want %d", stats.ThrottledSenders, 1)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the transactions relayed by a peer are admitted only within the
+// configured rate, independently of other peers.
+func TestPeerRateLimiting(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.PeerRate = 1
+	config.PeerBurst = 2
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	errs := pool.AddFromPeer("spammer", []*types.Transaction{
+		transaction(0, 100000, key),
+		transaction(1, 100000, key),
+		transaction(2, 100000, key),
+	})
+	for i, want := range []error{nil, nil, ErrPeerRateLimited} {
+		if !errors.Is(errs[i], want) {
+			t.Errorf("tx %d: admission error mismatch: have %v, want %v", i, errs[i], want)
+		}
+	}
+	if errs := pool.AddFromPeer("honest", []*types.Transaction{transaction(2, 100000, key)}); errs[0] != nil {
+		t.Errorf("transaction from other peer rate limited: %v", errs[0])
+	}
+	if stats := pool.AdmissionStats(); stats.ThrottledPeers != 1 {
+		t.Errorf("throttled peers mismatch: have %d, want %d", stats.ThrottledPeers, 1)
+	}
+}
+
+// Tests that invalid, underpriced and replacing submissions lower the reputation
+// of a sender, but not the stale ones, and that a low reputation reduces the
+// slots of the sender.
+func TestSenderReputation(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Reputation = true
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	// Invalid, underpriced and replacing transactions cost reputation
+	if err := pool.addRemoteSync(transaction(0, 0, key)); !errors.Is(err, core.ErrIntrinsicGas) {
+		t.Fatalf("intrinsic gas error mismatch: have %v, want %v", err, core.ErrIntrinsicGas)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100001, big.NewInt(1), key)); !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+		t.Fatalf("replacement error mismatch: have %v, want %v", err, txpool.ErrReplaceUnderpriced)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	// Neither do the stale ones anyone can replay
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), key)); !errors.Is(err, txpool.ErrAlreadyKnown) {
+		t.Fatalf("known transaction error mismatch: have %v, want %v", err, txpool.ErrAlreadyKnown)
+	}
+	want := maxReputation - invalidPenalty - underpricedPenalty - replacePenalty
+	if have := pool.admission.reputation(from, time.Now()); have != want {
+		t.Fatalf("reputation mismatch: have %d, want %d", have, want)
+	}
+	if stats := pool.AdmissionStats(); stats.LowReputation != 1 {
+		t.Errorf("low reputation senders mismatch: have %d, want %d", stats.LowReputation, 1)
+	}
+	// A low reputation reduces the slots of the sender
+	pool.admission.penalize(from, maxReputation/2, time.Now())
+
+	slots := int(pool.allowance(from, config.AccountSlots+config.AccountQueue))
+	if total := int(config.AccountSlots + config.AccountQueue); slots >= total/2 {
+		t.Fatalf("slots not reduced: have %d, total %d", slots, total)
+	}
+	for nonce := 1; nonce < slots; nonce++ {
+		if err := pool.addRemoteSync(pricedTransaction(uint64(nonce), 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add transaction %d within reduced slots: %v", nonce, err)
+		}
+	}
+	if err := pool.addRemoteSync(pricedTransaction(uint64(slots), 100000, big.NewInt(1), key)); !errors.Is(err, txpool.ErrAccountLimitExceeded) {
+		t.Fatalf("account limit error mismatch: have %v, want %v", err, txpool.ErrAccountLimitExceeded)
+	}
+	// Local transactions are not limited by reputation
+	if err := pool.addLocal(pricedTransaction(uint64(slots), 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("local transaction limited by reputation: %v", err)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the invalid transactions relayed by a peer lower the reputation of
+// the peer instead of their sender, but not the underpriced ones, and that a peer
+// with a low reputation gets only a share of its transactions admitted.
+func TestPeerReputation(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Reputation = true
+
+	pool := New(config, blockchain)
+	pool.Init(config.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	if errs := pool.AddFromPeer("relayer", []*types.Transaction{transaction(0, 0, key)}); !errors.Is(errs[0], core.ErrIntrinsicGas) {
+		t.Fatalf("intrinsic gas error mismatch: have %v, want %v", errs[0], core.ErrIntrinsicGas)
+	}
+	if have := pool.admission.reputation(from, time.Now()); have != maxReputation {
+		t.Fatalf("sender penalized for a relayed transaction: reputation %d", have)
+	}
+	// Underpriced transactions are not penalized, the pools differ in prices
+	if errs := pool.AddFromPeer("gossiper", []*types.Transaction{pricedTransaction(0, 100000, big.NewInt(0), key)}); !errors.Is(errs[0], txpool.ErrUnderpriced) {
+		t.Fatalf("underpriced error mismatch: have %v, want %v", errs[0], txpool.ErrUnderpriced)
+	}
+	if have := pool.admission.reputation(from, time.Now()); have != maxReputation {
+		t.Fatalf("sender penalized for a relayed transaction: reputation %d", have)
+	}
+	pool.admission.lock.Lock()
+	_, penalized := pool.admission.peers["gossiper"]
+	pool.admission.lock.Unlock()
+	if penalized {
+		t.Fatalf("peer penalized for an underpriced transaction")
+	}
+	// Stale transactions are not penalized, they might be replayed
+	testSetNonce(pool, from, 1)
+	if errs := pool.AddFromPeer("replayer", []*types.Transaction{transaction(0, 100000, key)}); !errors.Is(errs[0], core.ErrNonceTooLow) {
+		t.Fatalf("nonce error mismatch: have %v, want %v", errs[0], core.ErrNonceTooLow)
+	}
+	if stats := pool.AdmissionStats(); stats.LowReputation != 1 {
+		t.Fatalf("low reputation mismatch: have %d, want %d", stats.LowReputation, 1)
+	}
+	// The penalized peer still gets a single transaction admitted
+	if admitted := pool.admission.allowPeer("relayer", 1, time.Now()); admitted != 1 {
+		t.Fatalf("single transaction of penalized peer not admitted: %d", admitted)
+	}
+	// The penalized peer gets only a share of its transactions admitted
+	pool.admission.penalizePeer("relayer", maxReputation/2-invalidPenalty, time.Now())
+
+	txs := make([]*types.Transaction, 10)
+	for i := range txs {
+		txs[i] = transaction(uint64(i+1), 100000, key)
+	}
+	errs := pool.AddFromPeer("relayer", txs)
+	for i, err := range errs {
+		if i < len(txs)/2 && err != nil {
+			t.Fatalf("tx %d: failed to add: %v", i, err)
+		}
+		if i >= len(txs)/2 && !errors.Is(err, ErrPeerRateLimited) {
+			t.Fatalf("tx %d: admission error mismatch: have %v, want %v", i, err, ErrPeerRateLimited)
+		}
+	}
+	if errs := pool.AddFromPeer("replayer", txs[len(txs)/2:]); errs[0] != nil {
+		t.Fatalf("transaction from other peer limited: %v", errs[0])
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestJournaling(t *testing.T)         { testJournaling(t, false, false) }
@@ -0,0 +1,105 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// privateTxs is the set of transactions submitted privately. They are included
+// and replaced like any other transaction, but never announced to the network,
+// and are dropped if not included before their deadline.
+type privateTxs struct {
+	deadlines map[common.Hash]uint64 // Head number at which each transaction expires
+	lock      sync.RWMutex
+}
+
+func newPrivateTxs() *privateTxs {
+	return &privateTxs{deadlines: make(map[common.Hash]uint64)}
+}
+
+// add marks the transaction as private until the given head number.
+func (p *privateTxs) add(hash common.Hash, deadline uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.deadlines[hash] = deadline
+}
+
+// remove unmarks the transaction.
+func (p *privateTxs) remove(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.deadlines, hash)
+}
+
+// contains reports whether the transaction is private.
+func (p *privateTxs) contains(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.deadlines[hash]
+	return ok
+}
+
+// len returns the number of private transactions.
+func (p *privateTxs) len() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.deadlines)
+}
+
+// filter returns the transactions which are not private.
+func (p *privateTxs) filter(txs []*types.Transaction) []*types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if len(p.deadlines) == 0 {
+		return txs
+	}
+	public := make([]*types.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if _, ok := p.deadlines[tx.Hash()]; !ok {
+			public = append(public, tx)
+		}
+	}
+	return public
+}
+
+// expire unmarks the transactions no longer known to the pool, and returns
+// the ones expiring at the given head number, unmarked as well.
+func (p *privateTxs) expire(number uint64, known func(common.Hash) bool) []common.Hash {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var expired []common.Hash
+	for hash, deadline := range p.deadlines {
+		switch {
+		case !known(hash):
+			delete(p.deadlines, hash)
+		case number >= deadline:
+			delete(p.deadlines, hash)
+			expired = append(expired, hash)
+		}
+	}
+	return expired
+}
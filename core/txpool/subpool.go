@@ -83,6 +83,8 @@ type PendingFilter struct {
 
 	OnlyPlainTxs bool // Return only plain EVM transactions (peer-join announces, block space filling)
 	OnlyBlobTxs  bool // Return only blob transactions (block blob-space filling)
+
+	OnlyPublicTxs bool // Return only the transactions which may be announced (peer-join announces)
 }
 
 // SubPool represents a specialized transaction pool that lives on its own (e.g.
@@ -168,6 +170,41 @@ type SubPool interface {
 	Status(hash common.Hash) TxStatus
 }
 
+// PrivateSubpool is a subpool accepting transactions which are never announced
+// to the network.
+type PrivateSubpool interface {
+	// AddPrivate enqueues a transaction into the pool if it is valid, without
+	// announcing it.
+	AddPrivate(tx *types.Transaction) error
+}
+
+// ConditionalSubpool is a subpool accepting transactions to be included only
+// in blocks satisfying some conditions.
+type ConditionalSubpool interface {
+	// AddConditional enqueues a transaction into the pool if it is valid and
+	// its conditions hold at the current head.
+	AddConditional(tx *types.Transaction, cond *types.TransactionConditional) error
+}
+
+// AdmissionStats summarizes the admission control of a pool over the remote
+// senders and the network peers.
+type AdmissionStats struct {
+	LowReputation    int // Senders and peers with a reputation lowered by their submissions
+	ThrottledSenders int // Senders currently exceeding their admission rate
+	ThrottledPeers   int // Peers currently exceeding their admission rate
+}
+
+// AdmissionSubpool is a subpool rate limiting the transactions admitted from
+// each remote sender and network peer.
+type AdmissionSubpool interface {
+	// AddFromPeer enqueues a batch of remote transactions relayed by the given
+	// network peer, admitting only as many as the rate of the peer allows.
+	AddFromPeer(peer string, txs []*types.Transaction) []error
+
+	// AdmissionStats returns the state of the admission control.
+	AdmissionStats() AdmissionStats
+}
+
 type BundleSubpool interface {
 	// FilterBundle is a selector used to decide whether a bundle would be added
 	// to this particular subpool.
@@ -184,4 +221,18 @@ type BundleSubpool interface {
 
 	// PruneBundle removes a bundle from the pool.
 	PruneBundle(hash common.Hash)
+
+	// CancelBundle removes a bundle from the pool by its replacement UUID.
+	CancelBundle(args *types.CancelBundleArgs) error
+
+	// ReportBundle records a state transition of a bundle outside the pool,
+	// at the given block number.
+	ReportBundle(hash common.Hash, state types.BundleState, number uint64, reason error)
+
+	// BundleStatus returns the current state of a bundle and its transitions,
+	// nil if the bundle is unknown.
+	BundleStatus(hash common.Hash) *types.BundleStatus
+
+	// SubscribeBundleStatus subscribes to the bundle state transitions.
+	SubscribeBundleStatus(ch chan<- core.BundleStatusEvent) event.Subscription
 }
@@ -311,6 +311,26 @@ func (p *TxPool) Get(hash common.Hash) *types.Transaction {
 // to the large transaction churn, add may postpone fully integrating the tx
 // to a later point to batch multiple ones together.
 func (p *TxPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	return p.add(txs, func(subpool SubPool, txs []*types.Transaction) []error {
+		return subpool.Add(txs, local, sync)
+	})
+}
+
+// AddFromPeer enqueues a batch of remote transactions relayed by the given
+// network peer, subject to the admission rate of the peer in the subpools
+// limiting it.
+func (p *TxPool) AddFromPeer(peer string, txs []*types.Transaction) []error {
+	return p.add(txs, func(subpool SubPool, txs []*types.Transaction) []error {
+		if admissionSubpool, ok := subpool.(AdmissionSubpool); ok {
+			return admissionSubpool.AddFromPeer(peer, txs)
+		}
+		return subpool.Add(txs, false, false)
+	})
+}
+
+// add splits a batch of transactions between the subpools accepting them and
+// adds them with the given function.
+func (p *TxPool) add(txs []*types.Transaction, add func(SubPool, []*types.Transaction) []error) []error {
 	// Split the input transactions between the subpools. It shouldn't really
 	// happen that we receive merged batches, but better graceful than strange
 	// errors.
@@ -337,7 +357,7 @@ func (p *TxPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
 	// back the errors into the original sort order.
 	errsets := make([][]error, len(p.subpools))
 	for i := 0; i < len(p.subpools); i++ {
-		errsets[i] = p.subpools[i].Add(txsets[i], local, sync)
+		errsets[i] = add(p.subpools[i], txsets[i])
 	}
 	errs := make([]error, len(txs))
 	for i, split := range splits {
@@ -353,6 +373,34 @@ func (p *TxPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
 	return errs
 }
 
+// AddPrivate enqueues a transaction into the subpool accepting it, without
+// announcing it to the network.
+func (p *TxPool) AddPrivate(tx *types.Transaction) error {
+	for _, subpool := range p.subpools {
+		if subpool.Filter(tx) {
+			if privateSubpool, ok := subpool.(PrivateSubpool); ok {
+				return privateSubpool.AddPrivate(tx)
+			}
+			return errors.New("no subpool accepts the private transaction")
+		}
+	}
+	return core.ErrTxTypeNotSupported
+}
+
+// AddConditional enqueues a transaction into the subpool accepting it, to be
+// included only in blocks satisfying the conditions.
+func (p *TxPool) AddConditional(tx *types.Transaction, cond *types.TransactionConditional) error {
+	for _, subpool := range p.subpools {
+		if subpool.Filter(tx) {
+			if conditionalSubpool, ok := subpool.(ConditionalSubpool); ok {
+				return conditionalSubpool.AddConditional(tx, cond)
+			}
+			return errors.New("no subpool accepts the conditional transaction")
+		}
+	}
+	return core.ErrTxTypeNotSupported
+}
+
 // AddBundle enqueues a bundle into the pool if it is valid.
 func (p *TxPool) AddBundle(bundle *types.Bundle, originBundle *types.SendBundleArgs) error {
 	// Try to find a sub pool that accepts the bundle
@@ -376,6 +424,48 @@ func (p *TxPool) PruneBundle(hash common.Hash) {
 	}
 }
 
+// CancelBundle removes a bundle from the pool by its replacement UUID.
+func (p *TxPool) CancelBundle(args *types.CancelBundleArgs) error {
+	for _, subpool := range p.subpools {
+		if bundleSubpool, ok := subpool.(BundleSubpool); ok {
+			return bundleSubpool.CancelBundle(args)
+		}
+	}
+	return errors.New("no subpool accepts the bundle")
+}
+
+// ReportBundle records a state transition of a bundle outside the pool, at the
+// given block number.
+func (p *TxPool) ReportBundle(hash common.Hash, state types.BundleState, number uint64, reason error) {
+	for _, subpool := range p.subpools {
+		if bundleSubpool, ok := subpool.(BundleSubpool); ok {
+			bundleSubpool.ReportBundle(hash, state, number, reason)
+			return
+		}
+	}
+}
+
+// BundleStatus returns the current state of a bundle and its transitions, nil
+// if the bundle is unknown.
+func (p *TxPool) BundleStatus(hash common.Hash) *types.BundleStatus {
+	for _, subpool := range p.subpools {
+		if bundleSubpool, ok := subpool.(BundleSubpool); ok {
+			return bundleSubpool.BundleStatus(hash)
+		}
+	}
+	return nil
+}
+
+// SubscribeBundleStatus subscribes to the bundle state transitions.
+func (p *TxPool) SubscribeBundleStatus(ch chan<- core.BundleStatusEvent) event.Subscription {
+	for _, subpool := range p.subpools {
+		if bundleSubpool, ok := subpool.(BundleSubpool); ok {
+			return bundleSubpool.SubscribeBundleStatus(ch)
+		}
+	}
+	return event.JoinSubscriptions()
+}
+
 // PendingBundles retrieves all currently processable bundles.
 func (p *TxPool) PendingBundles(blockNumber uint64, blockTimestamp uint64) []*types.Bundle {
 	for _, subpool := range p.subpools {
@@ -464,6 +554,22 @@ func (p *TxPool) Stats() (int, int) {
 	return runnable, blocked
 }
 
+// AdmissionStats retrieves the state of the admission control of the subpools
+// limiting the remote senders and network peers.
+func (p *TxPool) AdmissionStats() AdmissionStats {
+	var stats AdmissionStats
+	for _, subpool := range p.subpools {
+		if admissionSubpool, ok := subpool.(AdmissionSubpool); ok {
+			sub := admissionSubpool.AdmissionStats()
+
+			stats.LowReputation += sub.LowReputation
+			stats.ThrottledSenders += sub.ThrottledSenders
+			stats.ThrottledPeers += sub.ThrottledPeers
+		}
+	}
+	return stats
+}
+
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
 func (p *TxPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
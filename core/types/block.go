@@ -196,6 +196,10 @@ type Block struct {
 	transactions Transactions
 	withdrawals  Withdrawals
 
+	// txDAG is the TxDAG sidecar kept out-of-band, it's not part of the
+	// block hash nor the block encoding.
+	txDAG TxDAG
+
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -342,6 +346,9 @@ func (b *Block) Uncles() []*Header          { return b.uncles }
 func (b *Block) Transactions() Transactions { return b.transactions }
 func (b *Block) Withdrawals() Withdrawals   { return b.withdrawals }
 
+// TxDAG returns the TxDAG sidecar of the block, nil if there is none.
+func (b *Block) TxDAG() TxDAG { return b.txDAG }
+
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
 		if transaction.Hash() == hash {
@@ -450,6 +457,18 @@ func (b *Block) WithSeal(header *Header) *Block {
 		transactions: b.transactions,
 		uncles:       b.uncles,
 		withdrawals:  b.withdrawals,
+		txDAG:        b.txDAG,
+	}
+}
+
+// WithTxDAG returns a copy of the block with the given TxDAG sidecar.
+func (b *Block) WithTxDAG(dag TxDAG) *Block {
+	return &Block{
+		header:       b.header,
+		transactions: b.transactions,
+		uncles:       b.uncles,
+		withdrawals:  b.withdrawals,
+		txDAG:        dag,
 	}
 }
 
@@ -7,6 +7,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/google/uuid"
 )
 
 const (
@@ -23,6 +24,20 @@ type SendBundleArgs struct {
 	MinTimestamp      *uint64         `json:"minTimestamp"`
 	MaxTimestamp      *uint64         `json:"maxTimestamp"`
 	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+
+	// ReplacementUUID scopes the bundle to its sender, a newer bundle with the
+	// same UUID from the same sender replaces the older one.
+	ReplacementUUID *uuid.UUID `json:"replacementUuid,omitempty" rlp:"optional"`
+}
+
+// CancelBundleArgs represents the arguments for cancelling a bundle.
+type CancelBundleArgs struct {
+	ReplacementUUID uuid.UUID   `json:"replacementUuid"`
+	BundleHash      common.Hash `json:"bundleHash"`
+
+	// Signature is the signature of the bundle sender over the BundleHash of
+	// the bundle to cancel.
+	Signature hexutil.Bytes `json:"signature"`
 }
 
 type Bundle struct {
@@ -45,6 +60,9 @@ type SimulatedBundle struct {
 	BundleGasFees  *big.Int
 	BundleGasPrice *big.Int
 	BundleGasUsed  uint64
+	SponsoredGas   uint64 // gas used by the gasless txs
+
+	AccessList AccessList // storage slots accessed by the bundle, collected for the merge search
 }
 
 func (bundle *Bundle) Size() uint64 {
@@ -69,3 +87,10 @@ func (bundle *Bundle) Hash() common.Hash {
 	bundle.hash.Store(h)
 	return h
 }
+
+// SetHash overrides the hash of the bundle, which is otherwise derived from its
+// contents including the price. A bundle priced anew, like the one reloaded from
+// a journal, keeps the hash it was accepted under.
+func (bundle *Bundle) SetHash(hash common.Hash) {
+	bundle.hash.Store(hash)
+}
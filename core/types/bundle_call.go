@@ -0,0 +1,37 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// CallBundleArgs represents the arguments for simulating a bundle.
+type CallBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// CallBundleTxResult is the result of a transaction in a simulated bundle.
+type CallBundleTxResult struct {
+	TxHash       common.Hash    `json:"txHash"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	GasPrice     *hexutil.Big   `json:"gasPrice"`     // effective gas price paid
+	GasFees      *hexutil.Big   `json:"gasFees"`      // gasUsed * gasPrice
+	CoinbaseDiff *hexutil.Big   `json:"coinbaseDiff"` // balance change of the coinbase, fees and transfers
+	Logs         []*Log         `json:"logs"`
+	Error        string         `json:"error,omitempty"`
+	Revert       hexutil.Bytes  `json:"revert,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+}
+
+// CallBundleResult is the result of a simulated bundle.
+type CallBundleResult struct {
+	Results          []*CallBundleTxResult `json:"results"`
+	BundleGasUsed    hexutil.Uint64        `json:"bundleGasUsed"`
+	BundleGasFees    *hexutil.Big          `json:"bundleGasFees"`
+	BundleGasPrice   *hexutil.Big          `json:"bundleGasPrice"` // effective price the builder ranks the bundle by
+	CoinbaseDiff     *hexutil.Big          `json:"coinbaseDiff"`
+	StateRoot        common.Hash           `json:"stateRoot"`
+	StateBlockNumber hexutil.Uint64        `json:"stateBlockNumber"`
+	Error            string                `json:"error,omitempty"` // why the builder would reject the bundle
+}
@@ -0,0 +1,42 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// BundleState is a state in the lifecycle of a bundle.
+type BundleState string
+
+const (
+	BundlePending   BundleState = "pending"   // accepted by the pool
+	BundleFailed    BundleState = "failed"    // failed the simulation in the miner, pruned
+	BundleDropped   BundleState = "dropped"   // dropped from the full pool for a higher priced one
+	BundleExpired   BundleState = "expired"   // past its max block number or timestamp
+	BundleReplaced  BundleState = "replaced"  // replaced by a bundle with the same replacement UUID
+	BundleCancelled BundleState = "cancelled" // cancelled by its sender
+	BundleMerged    BundleState = "merged"    // merged into a block being built
+	BundleIncluded  BundleState = "included"  // included in a block of the chain
+)
+
+// BundleTransition is a transition of a bundle into a state.
+type BundleTransition struct {
+	State       BundleState    `json:"state"`
+	Reason      string         `json:"reason,omitempty"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber,omitempty"` // block the transition happened at
+	Time        hexutil.Uint64 `json:"time"`                  // unix time of the transition
+}
+
+// BundleStatusUpdate is a transition of the bundle with the given hash.
+type BundleStatusUpdate struct {
+	Hash common.Hash `json:"hash"`
+	BundleTransition
+}
+
+// BundleStatus is the current state of a bundle, with the transitions that led
+// to it, oldest first.
+type BundleStatus struct {
+	Hash common.Hash `json:"hash"`
+	BundleTransition
+	History []BundleTransition `json:"history"`
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 
+	"golang.org/x/exp/slices"
+
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -44,6 +47,7 @@ func init() {
 const (
 	EmptyTxDAGType byte = iota
 	PlainTxDAGType
+	RangeTxDAGType
 )
 
 var (
@@ -133,6 +137,15 @@ func DecodeTxDAG(enc []byte) (TxDAG, error) {
 			return nil, err
 		}
 		return dag, nil
+	case RangeTxDAGType:
+		dag := new(RangeTxDAG)
+		if err := rlp.DecodeBytes(enc[1:], dag); err != nil {
+			return nil, err
+		}
+		if err := dag.expand(); err != nil {
+			return nil, err
+		}
+		return dag, nil
 	default:
 		return nil, errors.New("unsupported TxDAG bytes")
 	}
@@ -146,7 +159,7 @@ func ValidateTxDAG(d TxDAG, txCnt int) error {
 	switch d.Type() {
 	case EmptyTxDAGType:
 		return nil
-	case PlainTxDAGType:
+	case PlainTxDAGType, RangeTxDAGType:
 		return ValidatePlainTxDAG(d, txCnt)
 	default:
 		return fmt.Errorf("unsupported TxDAG type: %v", d.Type())
@@ -178,8 +191,12 @@ func ValidatePlainTxDAG(d TxDAG, txCnt int) error {
 }
 
 // GetTxDAG return TxDAG bytes from block if there is any, or return nil if not exist
-// the txDAG is stored in the calldata of the last transaction of the block
+// the txDAG is stored in the calldata of the last transaction of the block, unless
+// the block carries it as an out-of-band sidecar.
 func GetTxDAG(block *Block) (TxDAG, error) {
+	if dag := block.TxDAG(); dag != nil {
+		return dag, nil
+	}
 	txs := block.Transactions()
 	if txs.Len() <= 0 {
 		return nil, fmt.Errorf("no txdag found")
@@ -317,6 +334,218 @@ func (d *PlainTxDAG) Size() int {
 	return len(enc)
 }
 
+// RangeTxDAG is a compact encoding of the PlainTxDAG. The dependencies of each tx
+// are encoded as ranges of the distances to the tx, so the consecutive txs with
+// the same relative dependencies, e.g. the independent txs or a chain of txs,
+// are merged into a single item.
+type RangeTxDAG struct {
+	Items []RangeTxDep
+
+	txDeps []TxDep // the expanded dependencies, the index is equal to TxIndex
+	dirty  bool    // whether the dependencies are set since the items are compacted
+}
+
+// rangeTxDAGRLP is the RLP encoding of the RangeTxDAG.
+type rangeTxDAGRLP struct {
+	Items []RangeTxDep
+}
+
+// RangeTxDep is the dependencies shared by the Count consecutive txs. Ranges are
+// the pairs of the gap from the end of the previous range and the length of the
+// range, the distances of each range start from 1 and are in ascending order.
+type RangeTxDep struct {
+	Count  uint64
+	Ranges []uint64
+	Flags  *uint8 `rlp:"optional"`
+}
+
+const (
+	// maxRangeTxDAGTxs and maxRangeTxDAGDeps limit the expanded size of the
+	// decoded RangeTxDAG, which is much larger than the encoding.
+	maxRangeTxDAGTxs  = 1 << 16
+	maxRangeTxDAGDeps = 1 << 24
+)
+
+// NewRangeTxDAG converts the TxDAG into the RangeTxDAG.
+func NewRangeTxDAG(dag TxDAG) (*RangeTxDAG, error) {
+	if err := ValidatePlainTxDAG(dag, dag.TxCount()); err != nil {
+		return nil, err
+	}
+	d := &RangeTxDAG{
+		txDeps: make([]TxDep, dag.TxCount()),
+	}
+	for i := range d.txDeps {
+		d.txDeps[i] = dag.TxDep(i).Copy()
+	}
+	d.compact()
+	return d, nil
+}
+
+func (d *RangeTxDAG) Type() byte {
+	return RangeTxDAGType
+}
+
+func (d *RangeTxDAG) Inner() interface{} {
+	return d
+}
+
+func (d *RangeTxDAG) DelayGasFeeDistribution() bool {
+	return true
+}
+
+// TxDep returns a copy of the dependencies of the tx, which are set by SetTxDep
+// only, as the items are compacted from them.
+func (d *RangeTxDAG) TxDep(i int) *TxDep {
+	dep := d.txDeps[i].Copy()
+	return &dep
+}
+
+func (d *RangeTxDAG) TxCount() int {
+	return len(d.txDeps)
+}
+
+func (d *RangeTxDAG) SetTxDep(i int, dep TxDep) error {
+	if i < 0 || i > len(d.txDeps) {
+		return fmt.Errorf("SetTxDep with wrong index: %d", i)
+	}
+	// The items encode the distances between the ascending dependencies.
+	for j, index := range dep.TxIndexes {
+		if index >= uint64(i) {
+			return fmt.Errorf("SetTxDep with the exceed range dependency, tx: %d", i)
+		}
+		if j > 0 && index <= dep.TxIndexes[j-1] {
+			return fmt.Errorf("SetTxDep with unordered dependency, tx: %d", i)
+		}
+	}
+	if i < len(d.txDeps) {
+		d.txDeps[i] = dep.Copy()
+	} else {
+		d.txDeps = append(d.txDeps, dep.Copy())
+	}
+	d.dirty = true
+	return nil
+}
+
+// EncodeRLP implements rlp.Encoder, compacting the items from the dependencies
+// set since the last encoding.
+func (d *RangeTxDAG) EncodeRLP(w io.Writer) error {
+	if d.dirty {
+		d.compact()
+		d.dirty = false
+	}
+	return rlp.Encode(w, &rangeTxDAGRLP{Items: d.Items})
+}
+
+func (d *RangeTxDAG) String() string {
+	return (&PlainTxDAG{TxDeps: d.txDeps}).String()
+}
+
+func (d *RangeTxDAG) Size() int {
+	enc, err := EncodeTxDAG(d)
+	if err != nil {
+		return 0
+	}
+	return len(enc)
+}
+
+// compact encodes the expanded dependencies into the items.
+func (d *RangeTxDAG) compact() {
+	d.Items = d.Items[:0]
+	for i, dep := range d.txDeps {
+		ranges := make([]uint64, 0, 2)
+		end := uint64(1)
+		// the distances are in the reverse order of the tx indexes
+		for j := len(dep.TxIndexes) - 1; j >= 0; {
+			start := uint64(i) - dep.TxIndexes[j]
+			length := uint64(1)
+			for j--; j >= 0 && uint64(i)-dep.TxIndexes[j] == start+length; j-- {
+				length++
+			}
+			ranges = append(ranges, start-end, length)
+			end = start + length
+		}
+		if n := len(d.Items); n > 0 && equalFlags(d.Items[n-1].Flags, dep.Flags) && slices.Equal(d.Items[n-1].Ranges, ranges) {
+			d.Items[n-1].Count++
+			continue
+		}
+		item := RangeTxDep{Count: 1, Ranges: ranges}
+		if dep.Flags != nil {
+			item.Flags = new(uint8)
+			*item.Flags = *dep.Flags
+		}
+		d.Items = append(d.Items, item)
+	}
+}
+
+// expand decodes the items into the dependencies of each tx.
+func (d *RangeTxDAG) expand() error {
+	if len(d.Items) == 0 {
+		return errors.New("empty RangeTxDAG")
+	}
+	var total uint64
+	d.txDeps = d.txDeps[:0]
+	for _, item := range d.Items {
+		if item.Count == 0 || len(item.Ranges)%2 != 0 {
+			return errors.New("RangeTxDAG contains invalid item")
+		}
+		if item.Count > maxRangeTxDAGTxs-uint64(len(d.txDeps)) {
+			return errors.New("RangeTxDAG contains too many txs")
+		}
+		for c := uint64(0); c < item.Count; c++ {
+			var (
+				i       = uint64(len(d.txDeps))
+				indexes = make([]uint64, 0, len(item.Ranges)/2)
+				end     = uint64(1)
+			)
+			for j := 0; j < len(item.Ranges); j += 2 {
+				gap, length := item.Ranges[j], item.Ranges[j+1]
+				if length == 0 || gap > i || length > i || end+gap > i+1-length {
+					return fmt.Errorf("RangeTxDAG contains the exceed range dependency, tx: %v", i)
+				}
+				if total += length; total > maxRangeTxDAGDeps {
+					return errors.New("RangeTxDAG contains too many dependencies")
+				}
+				start := end + gap
+				for dist := start; dist < start+length; dist++ {
+					indexes = append(indexes, i-dist)
+				}
+				end = start + length
+			}
+			slices.Reverse(indexes)
+			dep := TxDep{TxIndexes: indexes}
+			if item.Flags != nil {
+				dep.Flags = new(uint8)
+				*dep.Flags = *item.Flags
+			}
+			d.txDeps = append(d.txDeps, dep)
+		}
+	}
+	return nil
+}
+
+func equalFlags(a, b *uint8) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// CompactTxDAG returns the TxDAG in the encoding with the smallest size.
+func CompactTxDAG(dag TxDAG) TxDAG {
+	plain, ok := dag.(*PlainTxDAG)
+	if !ok || plain.TxCount() == 0 {
+		return dag
+	}
+	compact, err := NewRangeTxDAG(plain)
+	if err != nil {
+		return dag
+	}
+	if compact.Size() < plain.Size() {
+		return compact
+	}
+	return dag
+}
+
 // TxDep store the current tx dependency relation with other txs
 type TxDep struct {
 	TxIndexes []uint64
@@ -338,6 +567,16 @@ func NewTxDep(indexes []uint64, flags ...uint8) TxDep {
 	return dep
 }
 
+// Copy returns a deep copy of the dependencies.
+func (d *TxDep) Copy() TxDep {
+	cpy := TxDep{TxIndexes: slices.Clone(d.TxIndexes)}
+	if d.Flags != nil {
+		cpy.Flags = new(uint8)
+		*cpy.Flags = *d.Flags
+	}
+	return cpy
+}
+
 func (d *TxDep) AppendDep(i int) {
 	d.TxIndexes = append(d.TxIndexes, uint64(i))
 }
@@ -385,6 +624,82 @@ func (d *TxDep) ClearFlag(flag uint8) {
 	*d.Flags &= ^flag
 }
 
+// TxDAGMissingDep is a dependency of the tx resolved from the read/write sets,
+// which is not covered by the TxDAG, neither directly nor transitively.
+type TxDAGMissingDep struct {
+	TxIndex  uint64 `json:"txIndex"`
+	DepIndex uint64 `json:"depIndex"`
+}
+
+// FindMissingTxDeps checks whether the actual TxDAG covers all the dependencies
+// of the expected one, which is usually resolved by MVStates from the executed
+// read/write sets. The excluded txs are executed in sequence, so they depend on
+// all the previous txs, and in the actual TxDAG the subsequent txs depend on them
+// as well. The EmptyTxDAG means all txs are executed in sequence.
+func FindMissingTxDeps(expected, actual TxDAG, txCnt int) ([]TxDAGMissingDep, error) {
+	// nothing can go wrong if execute all txs in sequence
+	if actual == nil || actual.Type() == EmptyTxDAGType {
+		return nil, nil
+	}
+	if err := ValidateTxDAG(actual, txCnt); err != nil {
+		return nil, err
+	}
+	sequential := expected == nil || expected.Type() == EmptyTxDAGType
+	if !sequential {
+		if err := ValidateTxDAG(expected, txCnt); err != nil {
+			return nil, err
+		}
+	}
+	// resolve all the direct and transitive dependencies in the actual TxDAG
+	var (
+		words     = (txCnt + 63) / 64
+		ancestors = make([][]uint64, txCnt)
+		barrier   = -1
+	)
+	for i := 0; i < txCnt; i++ {
+		ancestors[i] = make([]uint64, words)
+		if actual.TxDep(i).CheckFlag(ExcludedTxFlag) {
+			for j := 0; j < i; j++ {
+				ancestors[i][j/64] |= 1 << (j % 64)
+			}
+			barrier = i
+			continue
+		}
+		deps := TxDependency(actual, i)
+		if barrier >= 0 {
+			deps = append([]uint64{uint64(barrier)}, deps...)
+		}
+		for _, dep := range deps {
+			ancestors[i][dep/64] |= 1 << (dep % 64)
+			for w, bits := range ancestors[dep] {
+				ancestors[i][w] |= bits
+			}
+		}
+	}
+	var missing []TxDAGMissingDep
+	check := func(i int, dep uint64) {
+		if ancestors[i][dep/64]&(1<<(dep%64)) == 0 {
+			missing = append(missing, TxDAGMissingDep{TxIndex: uint64(i), DepIndex: dep})
+		}
+	}
+	for i := 1; i < txCnt; i++ {
+		switch {
+		case sequential:
+			// it's enough to check the previous one, as the ancestors are transitive
+			check(i, uint64(i-1))
+		case expected.TxDep(i).CheckFlag(ExcludedTxFlag):
+			for j := 0; j < i; j++ {
+				check(i, uint64(j))
+			}
+		default:
+			for _, dep := range TxDependency(expected, i) {
+				check(i, dep)
+			}
+		}
+	}
+	return missing, nil
+}
+
 var (
 	totalTxMeter    = metrics.NewRegisteredMeter("dag/txcnt", nil)
 	totalNoDepMeter = metrics.NewRegisteredMeter("dag/nodepcnt", nil)
@@ -401,3 +716,125 @@ func EvaluateTxDAGPerformance(dag TxDAG) {
 		}
 	}
 }
+
+// TxDAGStats is the parallelism statistics of the TxDAGs in one or more blocks.
+// The weight of each tx is its gas used if the receipts are known, otherwise
+// all txs are weighted equally.
+type TxDAGStats struct {
+	Blocks         uint64 `json:"blocks"`
+	NoTxDAGBlocks  uint64 `json:"noTxDAGBlocks"` // the blocks without a valid TxDAG, executed in sequence
+	TxCount        uint64 `json:"txCount"`
+	ExcludedTxs    uint64 `json:"excludedTxs"`
+	CriticalPath   uint64 `json:"criticalPath"`   // the number of txs on the longest dependency chains
+	MaxParallelism uint64 `json:"maxParallelism"` // the maximum number of txs that can be executed at the same time
+
+	TotalWeight        uint64  `json:"totalWeight"`
+	CriticalPathWeight uint64  `json:"criticalPathWeight"`
+	Speedup            float64 `json:"speedup"` // the estimated speedup with unlimited workers
+}
+
+// NewBlockTxDAGStats evaluates the TxDAG carried in the block, the receipts are
+// optional to weight the txs by gas.
+func NewBlockTxDAGStats(block *Block, receipts Receipts) *TxDAGStats {
+	var (
+		txs        = block.Transactions()
+		gas        []uint64
+		cumulative uint64
+	)
+	if len(receipts) == len(txs) {
+		gas = make([]uint64, len(receipts))
+		for i, receipt := range receipts {
+			// the raw receipts from database only carry the cumulative gas
+			gas[i] = receipt.CumulativeGasUsed - cumulative
+			cumulative = receipt.CumulativeGasUsed
+		}
+	}
+	dag, err := GetTxDAG(block)
+	if err == nil {
+		err = ValidateTxDAG(dag, len(txs))
+	}
+	if err != nil {
+		stats := EvaluateTxDAG(NewEmptyTxDAG(), len(txs), gas)
+		stats.NoTxDAGBlocks = 1
+		return stats
+	}
+	return EvaluateTxDAG(dag, len(txs), gas)
+}
+
+// EvaluateTxDAG evaluates the parallelism of the valid TxDAG, the weights of the
+// txs are optional. The txs are scheduled as soon as all their dependencies are
+// finished, the excluded txs are executed in sequence with all the others.
+func EvaluateTxDAG(dag TxDAG, txCnt int, weights []uint64) *TxDAGStats {
+	var (
+		stats      = &TxDAGStats{Blocks: 1, TxCount: uint64(txCnt)}
+		sequential = dag.Type() == EmptyTxDAGType
+		levels     = make([]uint64, txCnt) // the number of txs on the longest chain ending with the tx
+		paths      = make([]uint64, txCnt) // the weight of the heaviest chain ending with the tx
+		widths     = make(map[uint64]uint64)
+
+		barrierLevel, barrierPath uint64
+		maxLevel, maxPath         uint64
+	)
+	for i := 0; i < txCnt; i++ {
+		weight := uint64(1)
+		if len(weights) == txCnt {
+			weight = weights[i]
+		}
+		stats.TotalWeight += weight
+
+		if sequential || dag.TxDep(i).CheckFlag(ExcludedTxFlag) {
+			if !sequential {
+				stats.ExcludedTxs++
+			}
+			levels[i], paths[i] = maxLevel+1, maxPath+weight
+			barrierLevel, barrierPath = levels[i], paths[i]
+		} else {
+			levels[i], paths[i] = barrierLevel+1, barrierPath+weight
+			for _, dep := range TxDependency(dag, i) {
+				if levels[dep]+1 > levels[i] {
+					levels[i] = levels[dep] + 1
+				}
+				if paths[dep]+weight > paths[i] {
+					paths[i] = paths[dep] + weight
+				}
+			}
+		}
+		if levels[i] > maxLevel {
+			maxLevel = levels[i]
+		}
+		if paths[i] > maxPath {
+			maxPath = paths[i]
+		}
+		widths[levels[i]]++
+		if widths[levels[i]] > stats.MaxParallelism {
+			stats.MaxParallelism = widths[levels[i]]
+		}
+	}
+	stats.CriticalPath = maxLevel
+	stats.CriticalPathWeight = maxPath
+	stats.updateSpeedup()
+	return stats
+}
+
+// Merge accumulates the statistics of the subsequent blocks, which are executed
+// one after another.
+func (s *TxDAGStats) Merge(other *TxDAGStats) {
+	s.Blocks += other.Blocks
+	s.NoTxDAGBlocks += other.NoTxDAGBlocks
+	s.TxCount += other.TxCount
+	s.ExcludedTxs += other.ExcludedTxs
+	s.CriticalPath += other.CriticalPath
+	if other.MaxParallelism > s.MaxParallelism {
+		s.MaxParallelism = other.MaxParallelism
+	}
+	s.TotalWeight += other.TotalWeight
+	s.CriticalPathWeight += other.CriticalPathWeight
+	s.updateSpeedup()
+}
+
+func (s *TxDAGStats) updateSpeedup() {
+	s.Speedup = 0
+	if s.CriticalPathWeight > 0 {
+		s.Speedup = float64(s.TotalWeight) / float64(s.CriticalPathWeight)
+	}
+}
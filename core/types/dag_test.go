@@ -238,3 +238,232 @@ func TestTxDep_Flags(t *testing.T) {
 	require.True(t, dep.CheckFlag(NonDependentRelFlag))
 	require.False(t, dep.CheckFlag(ExcludedTxFlag))
 }
+
+func TestFindMissingTxDeps(t *testing.T) {
+	plain := func(deps ...TxDep) TxDAG {
+		return &PlainTxDAG{TxDeps: deps}
+	}
+	tests := []struct {
+		expected TxDAG
+		actual   TxDAG
+		missing  []TxDAGMissingDep
+		err      bool
+	}{
+		// sequential execution is always safe
+		{
+			expected: plain(NewTxDep(nil), NewTxDep([]uint64{0}), NewTxDep([]uint64{1})),
+			actual:   NewEmptyTxDAG(),
+		},
+		// the same dependencies
+		{
+			expected: plain(NewTxDep(nil), NewTxDep([]uint64{0}), NewTxDep([]uint64{1})),
+			actual:   plain(NewTxDep(nil), NewTxDep([]uint64{0}), NewTxDep([]uint64{1})),
+		},
+		// transitive dependency
+		{
+			expected: plain(NewTxDep(nil), NewTxDep([]uint64{0}), NewTxDep([]uint64{0})),
+			actual:   plain(NewTxDep(nil), NewTxDep([]uint64{0}), NewTxDep([]uint64{1})),
+		},
+		// redundant dependency with NonDependentRelFlag
+		{
+			expected: plain(NewTxDep(nil), NewTxDep(nil), NewTxDep([]uint64{1})),
+			actual:   plain(NewTxDep(nil), NewTxDep(nil), NewTxDep(nil, NonDependentRelFlag)),
+		},
+		// missing dependency
+		{
+			expected: plain(NewTxDep(nil), NewTxDep([]uint64{0}), NewTxDep([]uint64{0, 1})),
+			actual:   plain(NewTxDep(nil), NewTxDep(nil), NewTxDep([]uint64{1})),
+			missing:  []TxDAGMissingDep{{TxIndex: 1, DepIndex: 0}, {TxIndex: 2, DepIndex: 0}},
+		},
+		// the excluded tx is a barrier in the actual TxDAG
+		{
+			expected: plain(NewTxDep(nil), NewTxDep(nil), NewTxDep([]uint64{0})),
+			actual:   plain(NewTxDep(nil), NewTxDep(nil, ExcludedTxFlag), NewTxDep(nil)),
+		},
+		// the excluded tx depends on all the previous txs
+		{
+			expected: plain(NewTxDep(nil), NewTxDep(nil), NewTxDep(nil, ExcludedTxFlag)),
+			actual:   plain(NewTxDep(nil), NewTxDep(nil), NewTxDep([]uint64{1})),
+			missing:  []TxDAGMissingDep{{TxIndex: 2, DepIndex: 0}},
+		},
+		// the txs must be executed in sequence
+		{
+			expected: NewEmptyTxDAG(),
+			actual:   plain(NewTxDep(nil), NewTxDep([]uint64{0}), NewTxDep(nil)),
+			missing:  []TxDAGMissingDep{{TxIndex: 2, DepIndex: 1}},
+		},
+		// invalid TxDAG
+		{
+			expected: plain(NewTxDep(nil), NewTxDep(nil), NewTxDep(nil)),
+			actual:   plain(NewTxDep(nil), NewTxDep([]uint64{1})),
+			err:      true,
+		},
+	}
+	for i, item := range tests {
+		missing, err := FindMissingTxDeps(item.expected, item.actual, 3)
+		if item.err {
+			require.Error(t, err, i)
+			continue
+		}
+		require.NoError(t, err, i)
+		require.Equal(t, item.missing, missing, i)
+	}
+}
+
+func TestRangeTxDAG_Encode_Decode(t *testing.T) {
+	tests := []TxDAG{
+		mockSimpleDAG(),
+		mockRandomDAG(100),
+		mockSystemTxDAG(),
+		mockSystemTxDAG2(),
+		mockSystemTxDAGWithLargeDeps(),
+		mockChainDAG(100),
+	}
+	for i, expect := range tests {
+		dag, err := NewRangeTxDAG(expect)
+		require.NoError(t, err, i)
+		require.NoError(t, ValidateTxDAG(dag, expect.TxCount()), i)
+		enc, err := EncodeTxDAG(dag)
+		require.NoError(t, err, i)
+		actual, err := DecodeTxDAG(enc)
+		require.NoError(t, err, i)
+		require.Equal(t, RangeTxDAGType, actual.Type(), i)
+		require.Equal(t, expect.TxCount(), actual.TxCount(), i)
+		for j := 0; j < expect.TxCount(); j++ {
+			require.Equal(t, TxDependency(expect, j), TxDependency(actual, j), i)
+			require.Equal(t, expect.TxDep(j).Flags, actual.TxDep(j).Flags, i)
+		}
+	}
+}
+
+func TestRangeTxDAG_SetTxDep(t *testing.T) {
+	dag, err := NewRangeTxDAG(mockSimpleDAG())
+	require.NoError(t, err)
+	require.NoError(t, dag.SetTxDep(9, NewTxDep([]uint64{0, 1, 2, 8})))
+	require.NoError(t, dag.SetTxDep(10, NewTxDep(nil, NonDependentRelFlag)))
+	require.Error(t, dag.SetTxDep(12, NewTxDep(nil)))
+	require.Error(t, dag.SetTxDep(5, NewTxDep([]uint64{5})))
+	require.Error(t, dag.SetTxDep(9, NewTxDep([]uint64{2, 1})))
+	require.Error(t, dag.SetTxDep(9, NewTxDep([]uint64{1, 1, 2})))
+
+	actual, err := DecodeTxDAG(mustEncodeTxDAG(t, dag))
+	require.NoError(t, err)
+	require.Equal(t, 11, actual.TxCount())
+	require.Equal(t, []uint64{0, 1, 2, 8}, actual.TxDep(9).TxIndexes)
+	require.True(t, actual.TxDep(10).CheckFlag(NonDependentRelFlag))
+}
+
+func TestRangeTxDAG_TxDep(t *testing.T) {
+	dag, err := NewRangeTxDAG(mockSimpleDAG())
+	require.NoError(t, err)
+	enc := mustEncodeTxDAG(t, dag)
+
+	// the returned dependencies are not written through
+	dep := dag.TxDep(9)
+	dep.AppendDep(8)
+	dep.SetFlag(ExcludedTxFlag)
+	require.Equal(t, TxDependency(mockSimpleDAG(), 9), TxDependency(dag, 9))
+	require.False(t, dag.TxDep(9).CheckFlag(ExcludedTxFlag))
+	require.Equal(t, enc, mustEncodeTxDAG(t, dag))
+
+	// the items are compacted from the set dependencies once encoded
+	for i := 0; i < dag.TxCount(); i++ {
+		require.NoError(t, dag.SetTxDep(i, NewTxDep(nil, NonDependentRelFlag)))
+	}
+	actual, err := DecodeTxDAG(mustEncodeTxDAG(t, dag))
+	require.NoError(t, err)
+	require.Len(t, actual.(*RangeTxDAG).Items, 1)
+	for i := 0; i < dag.TxCount(); i++ {
+		require.True(t, actual.TxDep(i).CheckFlag(NonDependentRelFlag), i)
+	}
+}
+
+func TestDecodeRangeTxDAG(t *testing.T) {
+	tests := []struct {
+		items []RangeTxDep
+		err   bool
+	}{
+		{items: []RangeTxDep{{Count: 3}, {Count: 2, Ranges: []uint64{0, 2}}}},
+		{items: nil, err: true},
+		{items: []RangeTxDep{{Count: 0}}, err: true},
+		{items: []RangeTxDep{{Count: 1, Ranges: []uint64{0}}}, err: true},
+		{items: []RangeTxDep{{Count: 1, Ranges: []uint64{0, 1}}}, err: true},
+		{items: []RangeTxDep{{Count: 2}, {Count: 1, Ranges: []uint64{0, 3}}}, err: true},
+		{items: []RangeTxDep{{Count: 2}, {Count: 1, Ranges: []uint64{1, 2}}}, err: true},
+		{items: []RangeTxDep{{Count: 3}, {Count: 1, Ranges: []uint64{0, 1, 1, 1}}}},
+		{items: []RangeTxDep{{Count: 2}, {Count: 1, Ranges: []uint64{0, 0}}}, err: true},
+		{items: []RangeTxDep{{Count: 1 << 20}}, err: true},
+	}
+	for i, item := range tests {
+		enc, err := EncodeTxDAG(&RangeTxDAG{Items: item.items})
+		require.NoError(t, err, i)
+		_, err = DecodeTxDAG(enc)
+		if item.err {
+			require.Error(t, err, i)
+			continue
+		}
+		require.NoError(t, err, i)
+	}
+}
+
+func TestCompactTxDAG(t *testing.T) {
+	// the chain of txs is encoded as a single item
+	dag := CompactTxDAG(mockChainDAG(1000))
+	require.Equal(t, RangeTxDAGType, dag.Type())
+	require.Len(t, dag.(*RangeTxDAG).Items, 2)
+
+	// keep the smaller plain encoding
+	plain := NewPlainTxDAG(3)
+	plain.TxDeps[0] = NewTxDep([]uint64{})
+	plain.TxDeps[1] = NewTxDep([]uint64{0})
+	plain.TxDeps[2] = NewTxDep([]uint64{})
+	require.Equal(t, PlainTxDAGType, CompactTxDAG(plain).Type())
+
+	require.Equal(t, EmptyTxDAGType, CompactTxDAG(NewEmptyTxDAG()).Type())
+	t.Log("random", mockRandomDAG(1000).(*PlainTxDAG).Size(), CompactTxDAG(mockRandomDAG(1000)).(interface{ Size() int }).Size())
+}
+
+func mustEncodeTxDAG(t *testing.T, dag TxDAG) []byte {
+	enc, err := EncodeTxDAG(dag)
+	require.NoError(t, err)
+	return enc
+}
+
+func mockChainDAG(txLen int) TxDAG {
+	dag := NewPlainTxDAG(txLen)
+	dag.TxDeps[0] = NewTxDep([]uint64{})
+	for i := 1; i < txLen; i++ {
+		dag.TxDeps[i] = NewTxDep([]uint64{uint64(i - 1)})
+	}
+	return dag
+}
+
+func TestEvaluateTxDAGStats(t *testing.T) {
+	// mockSystemTxDAG: 0,1,2 -> 3,4,5 -> 6 -> 7, 8 -> 9, then 2 excluded txs
+	stats := EvaluateTxDAG(mockSystemTxDAG(), 12, nil)
+	require.Equal(t, uint64(12), stats.TxCount)
+	require.Equal(t, uint64(2), stats.ExcludedTxs)
+	require.Equal(t, uint64(6), stats.CriticalPath)
+	require.Equal(t, uint64(4), stats.MaxParallelism)
+	require.Equal(t, float64(2), stats.Speedup)
+
+	// weighted by gas
+	weights := []uint64{10, 10, 10, 10, 10, 100, 10, 10, 10, 10, 10, 10}
+	stats = EvaluateTxDAG(mockSimpleDAG(), 10, weights[:10])
+	require.Equal(t, uint64(4), stats.CriticalPath)
+	require.Equal(t, uint64(130), stats.CriticalPathWeight)
+	require.Equal(t, float64(190)/130, stats.Speedup)
+
+	// all in sequence
+	stats = EvaluateTxDAG(NewEmptyTxDAG(), 5, nil)
+	require.Equal(t, uint64(5), stats.CriticalPath)
+	require.Equal(t, uint64(1), stats.MaxParallelism)
+	require.Equal(t, float64(1), stats.Speedup)
+
+	stats.Merge(EvaluateTxDAG(mockSimpleDAG(), 10, nil))
+	require.Equal(t, uint64(2), stats.Blocks)
+	require.Equal(t, uint64(15), stats.TxCount)
+	require.Equal(t, uint64(9), stats.CriticalPath)
+	require.Equal(t, uint64(4), stats.MaxParallelism)
+	require.Equal(t, float64(15)/9, stats.Speedup)
+}
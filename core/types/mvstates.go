@@ -715,6 +715,46 @@ func (s *MVStates) stopAsyncRecorder() {
 	}
 }
 
+// RecordRWSet records the read/write set of a tx executed outside the statedb,
+// e.g. on a parallel state copy, as if it was recorded during the execution.
+// The AccountSelf states are skipped, the resolver appends the AccountSelf reads
+// itself, and they are never recorded as writes by the statedb.
+func (s *MVStates) RecordRWSet(rwSet *RWSet) {
+	if !s.asyncRunning {
+		return
+	}
+	s.RecordNewTx(rwSet.index)
+	for addr, sub := range rwSet.accReadSet {
+		for state := range sub {
+			if state != AccountSelf {
+				s.RecordAccountRead(addr, state)
+			}
+		}
+	}
+	for addr, sub := range rwSet.slotReadSet {
+		for slot := range sub {
+			s.RecordStorageRead(addr, slot)
+		}
+	}
+	s.RecordReadDone()
+	for addr, sub := range rwSet.accWriteSet {
+		for state := range sub {
+			if state != AccountSelf {
+				s.RecordAccountWrite(addr, state)
+			}
+		}
+	}
+	for addr, sub := range rwSet.slotWriteSet {
+		for slot := range sub {
+			s.RecordStorageWrite(addr, slot)
+		}
+	}
+	if rwSet.cannotGasFeeDelay {
+		s.RecordCannotDelayGasFee()
+	}
+	s.RecordWriteDone()
+}
+
 // FinaliseWithRWSet it will put target write set into pending writes.
 func (s *MVStates) FinaliseWithRWSet(rwSet *RWSet) error {
 	s.lock.Lock()
@@ -64,6 +64,8 @@ type Transaction struct {
 
 	// cache of details to compute the data availability fee
 	rollupCostData atomic.Value
+
+	conditional *TransactionConditional // Conditions of inclusion, set by the pool before publishing
 }
 
 // NewTx creates a new transaction.
@@ -555,6 +557,18 @@ func (tx *Transaction) Time() time.Time {
 	return tx.time
 }
 
+// SetConditional sets the conditions the transaction may be included under.
+// It must be set before the transaction is shared, e.g. added to a pool.
+func (tx *Transaction) SetConditional(cond *TransactionConditional) {
+	tx.conditional = cond
+}
+
+// Conditional returns the conditions the transaction may be included under,
+// nil if it is unconditional.
+func (tx *Transaction) Conditional() *TransactionConditional {
+	return tx.conditional
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
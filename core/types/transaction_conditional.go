@@ -0,0 +1,149 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// MaxConditionalCost is the maximum number of accounts and slots the known
+// accounts of a conditional may check.
+const MaxConditionalCost = 1000
+
+var (
+	ErrConditionalInvalid     = errors.New("invalid transaction conditional")
+	ErrConditionalBlockNumber = errors.New("transaction conditional block number out of range")
+	ErrConditionalTimestamp   = errors.New("transaction conditional timestamp out of range")
+	ErrConditionalKnownAcct   = errors.New("transaction conditional known account mismatch")
+)
+
+// KnownAccount is the expected storage of an account, either its storage root
+// or the values of some of its slots.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// MarshalJSON encodes the storage root as a hash, and the slots as an object.
+func (ka KnownAccount) MarshalJSON() ([]byte, error) {
+	if ka.StorageRoot != nil {
+		return json.Marshal(ka.StorageRoot)
+	}
+	return json.Marshal(ka.StorageSlots)
+}
+
+// UnmarshalJSON decodes either a storage root hash or an object of slots.
+func (ka *KnownAccount) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var root common.Hash
+		if err := json.Unmarshal(input, &root); err != nil {
+			return err
+		}
+		ka.StorageRoot, ka.StorageSlots = &root, nil
+		return nil
+	}
+	var slots map[common.Hash]common.Hash
+	if err := json.Unmarshal(input, &slots); err != nil {
+		return err
+	}
+	if slots == nil {
+		return errors.New("known account must be a storage root or an object of slots")
+	}
+	ka.StorageRoot, ka.StorageSlots = nil, slots
+	return nil
+}
+
+// TransactionConditional is the set of conditions a transaction is included
+// under: the expected storage of some accounts, and the range of the number
+// and the timestamp of the including block. Unset bounds are not checked.
+type TransactionConditional struct {
+	KnownAccounts  map[common.Address]KnownAccount `json:"knownAccounts"`
+	BlockNumberMin *hexutil.Big                    `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Big                    `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64                 `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64                 `json:"timestampMax,omitempty"`
+
+	rejected atomic.Pointer[error] // Reason the conditional failed in a block being built
+}
+
+// Cost returns the number of accounts and slots checked by the conditional.
+func (c *TransactionConditional) Cost() int {
+	cost := 0
+	for _, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			cost++
+		} else {
+			cost += len(account.StorageSlots)
+		}
+	}
+	if c.BlockNumberMin != nil || c.BlockNumberMax != nil {
+		cost++
+	}
+	if c.TimestampMin != nil || c.TimestampMax != nil {
+		cost++
+	}
+	return cost
+}
+
+// Validate checks the conditional is well formed and cheap enough to check.
+func (c *TransactionConditional) Validate() error {
+	if cost := c.Cost(); cost > MaxConditionalCost {
+		return fmt.Errorf("%w: cost %d exceeds %d", ErrConditionalInvalid, cost, MaxConditionalCost)
+	}
+	if c.BlockNumberMin != nil && c.BlockNumberMax != nil && c.BlockNumberMin.ToInt().Cmp(c.BlockNumberMax.ToInt()) > 0 {
+		return fmt.Errorf("%w: block number min %v above max %v", ErrConditionalInvalid, c.BlockNumberMin, c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil && c.TimestampMax != nil && *c.TimestampMin > *c.TimestampMax {
+		return fmt.Errorf("%w: timestamp min %d above max %d", ErrConditionalInvalid, *c.TimestampMin, *c.TimestampMax)
+	}
+	return nil
+}
+
+// CheckBlock checks the number and the timestamp of the including block
+// against the ranges of the conditional.
+func (c *TransactionConditional) CheckBlock(header *Header) error {
+	if c.BlockNumberMin != nil && header.Number.Cmp(c.BlockNumberMin.ToInt()) < 0 {
+		return fmt.Errorf("%w: block %v below %v", ErrConditionalBlockNumber, header.Number, c.BlockNumberMin)
+	}
+	if c.BlockNumberMax != nil && header.Number.Cmp(c.BlockNumberMax.ToInt()) > 0 {
+		return fmt.Errorf("%w: block %v above %v", ErrConditionalBlockNumber, header.Number, c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil && header.Time < uint64(*c.TimestampMin) {
+		return fmt.Errorf("%w: timestamp %d below %d", ErrConditionalTimestamp, header.Time, *c.TimestampMin)
+	}
+	if c.TimestampMax != nil && header.Time > uint64(*c.TimestampMax) {
+		return fmt.Errorf("%w: timestamp %d above %d", ErrConditionalTimestamp, header.Time, *c.TimestampMax)
+	}
+	return nil
+}
+
+// CheckExpiry checks that a block after the given one may still satisfy the
+// ranges of the conditional.
+func (c *TransactionConditional) CheckExpiry(header *Header) error {
+	if c.BlockNumberMax != nil && header.Number.Cmp(c.BlockNumberMax.ToInt()) >= 0 {
+		return fmt.Errorf("%w: expired at block %v, max %v", ErrConditionalBlockNumber, header.Number, c.BlockNumberMax)
+	}
+	if c.TimestampMax != nil && header.Time >= uint64(*c.TimestampMax) {
+		return fmt.Errorf("%w: expired at timestamp %d, max %d", ErrConditionalTimestamp, header.Time, *c.TimestampMax)
+	}
+	return nil
+}
+
+// Reject records the reason the conditional failed in a block being built,
+// for the pool to evict the transaction.
+func (c *TransactionConditional) Reject(err error) {
+	c.rejected.CompareAndSwap(nil, &err)
+}
+
+// Rejected returns the reason the conditional failed in a block being built,
+// nil if it never did.
+func (c *TransactionConditional) Rejected() error {
+	if err := c.rejected.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
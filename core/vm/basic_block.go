@@ -0,0 +1,157 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// basicBlockKey identifies the analysis of a code within a call tree, which
+// runs with a single jump table.
+type basicBlockKey struct {
+	codeHash  common.Hash
+	optimized bool // the optimized code shares the hash with the original one
+}
+
+// basicBlock is a straight-line sequence of instructions, entered only at the
+// first one and left only after the last one. The constant gas of the whole
+// block is charged at the entry, and the stack bounds of every instruction are
+// folded into the bounds of the stack height at the entry.
+type basicBlock struct {
+	start       uint64 // pc of the first instruction
+	last        uint64 // pc of the last instruction
+	constantGas uint64 // sum of the constant gas of the instructions
+	minStack    int    // minimum stack height at the entry
+	maxStack    int    // maximum stack height at the entry
+}
+
+// basicBlocks is the basic blocks of a code, sorted by the start pc.
+type basicBlocks struct {
+	blocks []basicBlock
+	index  []uint32 // 1-based index of the block starting at each pc, 0 if none
+}
+
+// lookup returns the basic block starting at the given pc, nil if no block
+// starts there.
+func (b *basicBlocks) lookup(pc uint64) *basicBlock {
+	if pc >= uint64(len(b.index)) {
+		return nil
+	}
+	if i := b.index[pc]; i != 0 {
+		return &b.blocks[i-1]
+	}
+	return nil
+}
+
+// fusedStackDelta is the stack height change of the fused instructions whose
+// stack bounds are looser than their effects.
+var fusedStackDelta = map[OpCode]int{
+	Swap1PopSwap2Swap1: -1,
+	PopSwap2Swap1Pop:   -2,
+	Swap2Pop:           -1,
+}
+
+// stackDelta returns the stack height change of the instruction.
+func stackDelta(op OpCode, operation *operation) int {
+	if delta, ok := fusedStackDelta[op]; ok {
+		return delta
+	}
+	// maxStack is StackLimit + pops - pushes
+	return int(params.StackLimit) - operation.maxStack
+}
+
+// instructionSkip returns the number of bytes skipped by the instruction after
+// the opcode, which are either the immediate data or the nops left by the fusion.
+func instructionSkip(op OpCode) uint64 {
+	switch {
+	case op >= PUSH1 && op <= PUSH32:
+		return uint64(op - PUSH1 + 1)
+	}
+	switch op {
+	case AndSwap1PopSwap2Swap1, JumpIfZero:
+		return 4
+	case Swap1PopSwap2Swap1, PopSwap2Swap1Pop, Push1Push1, Push2JumpI:
+		return 3
+	case Push1Add, Push1Shl, Push1Dup1:
+		return 2
+	case Swap1Pop, Pop2, Swap2Swap1, Swap2Pop, Dup2LT:
+		return 1
+	}
+	return 0
+}
+
+// endsBasicBlock reports whether the instruction is the last one of its basic
+// block. Besides the instructions leaving the block, the ones depending on the
+// gas left end the block, so that the gas of the following instructions is not
+// charged before them.
+func endsBasicBlock(op OpCode) bool {
+	switch op {
+	case STOP, JUMP, JUMPI, RETURN, REVERT, INVALID, SELFDESTRUCT,
+		GAS, SSTORE, CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE, CREATE2,
+		Push2Jump, Push2JumpI, JumpIfZero, PopJump, Swap2Swap1PopJump:
+		return true
+	}
+	return false
+}
+
+// analyseBasicBlocks splits the code into basic blocks, and precomputes the
+// constant gas and the stack requirements of each block by the jump table.
+func analyseBasicBlocks(code []byte, table *JumpTable) *basicBlocks {
+	var (
+		blocks []basicBlock
+		block  *basicBlock
+		height int // stack height change since the block entry
+	)
+	for pc := uint64(0); pc < uint64(len(code)); pc++ {
+		op := OpCode(code[pc])
+		if op == JUMPDEST && block != nil {
+			blocks = append(blocks, *block)
+			block = nil
+		}
+		if block == nil {
+			block = &basicBlock{start: pc, maxStack: math.MaxInt}
+			height = 0
+		}
+		operation := table[op]
+		block.last = pc
+		block.constantGas += operation.constantGas
+		if min := operation.minStack - height; min > block.minStack {
+			block.minStack = min
+		}
+		if max := operation.maxStack - height; max < block.maxStack {
+			block.maxStack = max
+		}
+		height += stackDelta(op, operation)
+
+		pc += instructionSkip(op)
+		if endsBasicBlock(op) {
+			blocks = append(blocks, *block)
+			block = nil
+		}
+	}
+	if block != nil {
+		blocks = append(blocks, *block)
+	}
+	index := make([]uint32, len(code))
+	for i, block := range blocks {
+		index[block.start] = uint32(i + 1)
+	}
+	return &basicBlocks{blocks: blocks, index: index}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func TestAnalyseBasicBlocks(t *testing.T) {
+	// push(10) jumpdest push(1) swap1 sub dup1 push(2) jumpi stop
+	code := common.FromHex("600a5b600190038060025700")
+	blocks := analyseBasicBlocks(code, &cancunInstructionSet)
+
+	want := []basicBlock{
+		{start: 0, last: 0, constantGas: GasFastestStep, minStack: 0, maxStack: 1023},
+		{start: 2, last: 10, constantGas: 5*GasFastestStep + GasSlowStep + params.JumpdestGas, minStack: 1, maxStack: 1022},
+		{start: 11, last: 11, constantGas: 0, minStack: 0, maxStack: 1024},
+	}
+	if len(blocks.blocks) != len(want) {
+		t.Fatalf("block count mismatch, have %d, want %d", len(blocks.blocks), len(want))
+	}
+	for i := range want {
+		if blocks.blocks[i] != want[i] {
+			t.Errorf("block %d mismatch, have %+v, want %+v", i, blocks.blocks[i], want[i])
+		}
+		if blocks.lookup(want[i].start) != &blocks.blocks[i] {
+			t.Errorf("block %d lookup mismatch", i)
+		}
+	}
+	if blocks.lookup(3) != nil || blocks.lookup(uint64(len(code))) != nil {
+		t.Errorf("block lookup mismatch")
+	}
+}
+
+// TestBasicBlockGas checks the execution charging the gas per basic block against
+// the one charging per instruction, with every gas limit around the failures.
+func TestBasicBlockGas(t *testing.T) {
+	var (
+		address = common.BytesToAddress([]byte("contract"))
+		vmctx   = BlockContext{
+			Transfer:    func(StateDB, common.Address, common.Address, *uint256.Int) {},
+			BlockNumber: common.Big1,
+		}
+	)
+	codes := []string{
+		// push(1) push(2) add push(0) mstore push(32) push(0) return
+		"600160020160005260206000f3",
+		// push(10) jumpdest push(1) swap1 sub dup1 push(2) jumpi stop
+		"600a5b600190038060025700",
+		// push(1) add (stack underflow) push(1) push(1)
+		"60010160016001",
+		// push(1) push(0x100000) mstore (memory expansion) push(1) push(1) add pop stop
+		"600162100000526001600101500000",
+		// push(1) push(64) mstore push(1) push(0) push(0) returndatacopy (out of bounds) push(1)... stop
+		"60016040526001600060003e" + strings.Repeat("6001", 10) + "00",
+		// gas push(0) sstore push(1) push(1) add
+		"5a60005560016001",
+		// push(1) push(2) add pop, running off the end of the code
+		"600160020150",
+	}
+	run := func(code []byte, gas uint64, optimize, blockGas bool) string {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.CreateAccount(address)
+		statedb.SetCode(address, code)
+		statedb.Finalise(true)
+		statedb.AddAddressToAccessList(address)
+
+		evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{
+			EnableOpcodeOptimizations: optimize,
+			ForceOpcodeOptimizations:  optimize,
+			EnableBasicBlockGas:       blockGas,
+		})
+		ret, left, err := evm.Call(AccountRef(common.Address{}), address, nil, gas, new(uint256.Int))
+		return fmt.Sprintf("ret %x, gas %d, err %v, root %x", ret, left, err, statedb.IntermediateRoot(true))
+	}
+	for _, optimize := range []bool{false, true} {
+		for _, hex := range codes {
+			code := common.FromHex(hex)
+			for _, gas := range gasLimits() {
+				want := run(code, gas, optimize, false)
+				if have := run(code, gas, optimize, true); have != want {
+					t.Fatalf("code %s, optimize %v, gas %d: result mismatch\nhave %s\nwant %s", hex, optimize, gas, have, want)
+				}
+			}
+		}
+	}
+}
+
+func gasLimits() []uint64 {
+	var limits []uint64
+	for gas := uint64(0); gas < 400; gas++ {
+		limits = append(limits, gas)
+	}
+	for gas := uint64(2200); gas < 2500; gas++ {
+		limits = append(limits, gas)
+	}
+	for gas := uint64(22000); gas < 23000; gas += 3 {
+		limits = append(limits, gas)
+	}
+	return append(limits, 100000)
+}
@@ -52,6 +52,8 @@ type Contract struct {
 	jumpdests map[common.Hash]bitvec // Aggregated result of JUMPDEST analysis.
 	analysis  bitvec                 // Locally cached result of JUMPDEST analysis
 
+	basicBlockTables map[basicBlockKey]*basicBlocks // Aggregated result of basic block analysis
+
 	Code     []byte
 	CodeHash common.Hash
 	CodeAddr *common.Address
@@ -69,6 +71,7 @@ func NewContract(caller ContractRef, object ContractRef, value *uint256.Int, gas
 	if parent, ok := caller.(*Contract); ok {
 		// Reuse JUMPDEST analysis from parent context if available.
 		c.jumpdests = parent.jumpdests
+		c.basicBlockTables = parent.basicBlockTables
 	} else {
 		c.jumpdests = make(map[common.Hash]bitvec)
 	}
@@ -96,6 +99,25 @@ func (c *Contract) validJumpdest(dest *uint256.Int) bool {
 	return c.isCode(udest)
 }
 
+// basicBlocks returns the basic blocks of the contract code by the jump table,
+// nil is returned for the code without hash, which runs only once. The analysis
+// is shared with the parent context like the JUMPDEST one.
+func (c *Contract) basicBlocks(table *JumpTable) *basicBlocks {
+	if c.CodeHash == (common.Hash{}) {
+		return nil
+	}
+	key := basicBlockKey{codeHash: c.CodeHash, optimized: c.optimized}
+	if blocks, ok := c.basicBlockTables[key]; ok {
+		return blocks
+	}
+	if c.basicBlockTables == nil {
+		c.basicBlockTables = make(map[basicBlockKey]*basicBlocks)
+	}
+	blocks := analyseBasicBlocks(c.Code, table)
+	c.basicBlockTables[key] = blocks
+	return blocks
+}
+
 // isCode returns true if the provided PC location is an actual opcode, as
 // opposed to a data-segment following a PUSHN operation.
 func (c *Contract) isCode(udest uint64) bool {
@@ -138,6 +138,9 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// creating counts the contract creations in progress, whose code and the
+	// code they call run unoptimized
+	creating int
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -163,9 +166,6 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil, blockCtx.Time),
 	}
 	evm.interpreter = NewEVMInterpreter(evm)
-	if config.EnableOpcodeOptimizations {
-		compiler.EnableOptimization()
-	}
 	return evm
 }
 
@@ -486,7 +486,13 @@ func tryGetOptimizedCode(evm *EVM, codeHash common.Hash, rawCode []byte) (bool,
 	var code []byte
 	optimized := false
 	code = rawCode
+	if evm.creating > 0 {
+		return optimized, code
+	}
 	optCode := compiler.LoadOptimizedCode(codeHash)
+	if len(optCode) == 0 && evm.Config.ForceOpcodeOptimizations {
+		optCode, _ = compiler.GenerateOptimizedCode(rawCode)
+	}
 	if len(optCode) != 0 {
 		code = optCode
 		optimized = true
@@ -555,15 +561,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	}
 	// We don't optimize creation code as it run only once.
 	contract.optimized = false
-	if evm.Config.EnableOpcodeOptimizations {
-		compiler.DisableOptimization()
-	}
+	evm.creating++
 	ret, err := evm.interpreter.Run(contract, nil, false)
-
-	// After creation, retrieve to optimization
-	if evm.Config.EnableOpcodeOptimizations {
-		compiler.EnableOptimization()
-	}
+	evm.creating--
 
 	// Check whether the max code size has been exceeded, assign err if the case.
 	if err == nil && evm.chainRules.IsEIP158 && len(ret) > params.MaxCodeSize {
@@ -35,6 +35,9 @@ type Config struct {
 	ExtraEips                   []int               // Additional EIPS that are to be enabled
 	OptimismPrecompileOverrides PrecompileOverrides // Precompile overrides for Optimism
 	EnableOpcodeOptimizations   bool                // Enable opcode optimization
+	ForceOpcodeOptimizations    bool                // Generate the optimized code in place if not cached, instead of in background
+	EnableBasicBlockGas         bool                // Charge the constant gas and check the stack once per basic block
+	OpcodeProfiler              *OpcodeProfiler     // Profiler of the executed opcode n-grams, nil if disabled
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
@@ -154,7 +157,16 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		logged  bool   // deferred EVMLogger should ignore already logged steps
 		res     []byte // result of the opcode execution function
 		debug   = in.evm.Config.Tracer != nil
+
+		// basic blocks whose constant gas and stack are checked at the entry
+		blocks    *basicBlocks
+		inBlock   bool   // whether the current instruction is in a prepaid block
+		blockLast uint64 // pc of the last instruction of the prepaid block
+		prepaid   uint64 // constant gas prepaid for the rest of the block
 	)
+	if in.evm.Config.EnableBasicBlockGas && !debug {
+		blocks = contract.basicBlocks(in.table)
+	}
 	// Don't move this deferred function, it's placed before the capturestate-deferred method,
 	// so that it gets executed _after_: the capturestate needs the stacks before
 	// they are returned to the pools
@@ -163,6 +175,14 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}()
 	contract.Input = input
 
+	// Record the executed opcodes of the deployed code for the profiler
+	var trace *[]OpCode
+	if profiler := in.evm.Config.OpcodeProfiler; profiler != nil && contract.CodeHash != (common.Hash{}) {
+		trace = profiler.newTrace()
+		defer func() {
+			profiler.record(contract.CodeHash, trace)
+		}()
+	}
 	if debug {
 		defer func() {
 			if err != nil {
@@ -186,16 +206,51 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
+		if trace != nil && len(*trace) < opcodeProfileTraceLimit {
+			*trace = append(*trace, op)
+		}
 		operation := in.table[op]
 		cost = operation.constantGas // For tracing
-		// Validate stack
-		if sLen := stack.len(); sLen < operation.minStack {
-			return nil, &ErrStackUnderflow{stackLen: sLen, required: operation.minStack}
-		} else if sLen > operation.maxStack {
-			return nil, &ErrStackOverflow{stackLen: sLen, limit: operation.maxStack}
+		if blocks != nil {
+			// Leave the prepaid block if the execution skips over its end,
+			// refunding the gas of the instructions not executed.
+			if inBlock && pc > blockLast {
+				contract.Gas += prepaid
+				inBlock, prepaid = false, 0
+			}
+			// Enter the block starting here if the stack and gas suffice for all
+			// of its instructions, otherwise it's checked per instruction.
+			if !inBlock {
+				if block := blocks.lookup(pc); block != nil {
+					if sLen := stack.len(); sLen >= block.minStack && sLen <= block.maxStack && contract.Gas >= block.constantGas {
+						contract.Gas -= block.constantGas
+						inBlock, blockLast, prepaid = true, block.last, block.constantGas
+					}
+				}
+			}
+		}
+		if inBlock && cost > prepaid {
+			// The execution deviates from the analysis, fall back to the check
+			// per instruction.
+			contract.Gas += prepaid
+			inBlock, prepaid = false, 0
 		}
-		if !contract.UseGas(cost) {
-			return nil, ErrOutOfGas
+		if inBlock {
+			prepaid -= cost
+			if pc == blockLast {
+				contract.Gas += prepaid
+				inBlock, prepaid = false, 0
+			}
+		} else {
+			// Validate stack
+			if sLen := stack.len(); sLen < operation.minStack {
+				return nil, &ErrStackUnderflow{stackLen: sLen, required: operation.minStack}
+			} else if sLen > operation.maxStack {
+				return nil, &ErrStackOverflow{stackLen: sLen, limit: operation.maxStack}
+			}
+			if !contract.UseGas(cost) {
+				return nil, ErrOutOfGas
+			}
 		}
 		if operation.dynamicGas != nil {
 			// All ops with a dynamic memory usage also has a dynamic gas cost.
@@ -220,6 +275,13 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			var dynamicCost uint64
 			dynamicCost, err = operation.dynamicGas(in.evm, contract, stack, mem, memorySize)
 			cost += dynamicCost // for tracing
+			if err == nil && prepaid > 0 && contract.Gas < dynamicCost {
+				// Refund the gas prepaid for the rest of the block, and check the
+				// rest per instruction, so that the out-of-gas happens exactly
+				// where it would without the prepayment.
+				contract.Gas += prepaid
+				inBlock, prepaid = false, 0
+			}
 			if err != nil || !contract.UseGas(dynamicCost) {
 				return nil, ErrOutOfGas
 			}
@@ -0,0 +1,232 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+)
+
+const (
+	opcodeProfileMinGram    = 2       // Shortest n-gram counted
+	opcodeProfileMaxGram    = 5       // Longest n-gram counted, as long as the longest fused pattern
+	opcodeProfileCodes      = 4096    // Number of code hashes whose n-grams are kept
+	opcodeProfileCodeGrams  = 4096    // Maximum number of distinct n-grams kept per code hash
+	opcodeProfileTraceLimit = 1 << 16 // Maximum number of opcodes recorded per call frame
+	opcodeProfileTopCodes   = 3       // Number of code hashes reported per candidate
+
+	// DefaultOpcodeProfileCandidates is the number of candidates reported if
+	// not specified.
+	DefaultOpcodeProfileCandidates = 100
+)
+
+// opcodeGram is a sequence of up to 7 opcodes packed into an integer, the last
+// opcode in the lowest byte and the length in the highest one.
+type opcodeGram uint64
+
+func (g opcodeGram) len() int {
+	return int(g >> 56)
+}
+
+// ops returns the opcodes of the n-gram in execution order.
+func (g opcodeGram) ops() []OpCode {
+	n := g.len()
+	ops := make([]OpCode, n)
+	for i := 0; i < n; i++ {
+		ops[i] = OpCode(g >> (8 * (n - 1 - i)))
+	}
+	return ops
+}
+
+func (g opcodeGram) String() string {
+	names := make([]string, 0, g.len())
+	for _, op := range g.ops() {
+		names = append(names, op.String())
+	}
+	return strings.Join(names, " ")
+}
+
+// breaksGram reports whether the opcode may transfer the control flow, the
+// opcodes executed around it are not adjacent in the code and can't be fused.
+func breaksGram(op OpCode) bool {
+	switch op {
+	case JUMP, JUMPI, Push2Jump, Push2JumpI, JumpIfZero, PopJump, Swap2Swap1PopJump:
+		return true
+	}
+	return false
+}
+
+// codeOpcodeProfile is the n-gram counts of the code with a given hash.
+type codeOpcodeProfile struct {
+	steps uint64
+	grams map[opcodeGram]uint64
+}
+
+// OpcodeProfiler counts the opcode n-grams executed by the interpreter per code
+// hash, to find the candidates of new superinstructions for the opcode fusion.
+// The n-grams never span a jump, the opcodes around it are not adjacent in the
+// code. The executed opcodes are the fused ones if the optimization is enabled.
+type OpcodeProfiler struct {
+	codes  lru.BasicLRU[common.Hash, *codeOpcodeProfile]
+	traces sync.Pool
+	lock   sync.Mutex
+}
+
+// NewOpcodeProfiler creates an empty opcode n-gram profiler.
+func NewOpcodeProfiler() *OpcodeProfiler {
+	return &OpcodeProfiler{
+		codes: lru.NewBasicLRU[common.Hash, *codeOpcodeProfile](opcodeProfileCodes),
+		traces: sync.Pool{
+			New: func() interface{} {
+				trace := make([]OpCode, 0, 1024)
+				return &trace
+			},
+		},
+	}
+}
+
+// newTrace returns an empty buffer to record the opcodes of a call frame.
+func (p *OpcodeProfiler) newTrace() *[]OpCode {
+	trace := p.traces.Get().(*[]OpCode)
+	*trace = (*trace)[:0]
+	return trace
+}
+
+// record counts the n-grams of the opcodes executed in a call frame, and
+// returns the buffer for reuse.
+func (p *OpcodeProfiler) record(codeHash common.Hash, trace *[]OpCode) {
+	defer p.traces.Put(trace)
+
+	ops := *trace
+	if len(ops) == 0 {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	profile, ok := p.codes.Get(codeHash)
+	if !ok {
+		profile = &codeOpcodeProfile{grams: make(map[opcodeGram]uint64)}
+		p.codes.Add(codeHash, profile)
+	}
+	profile.steps += uint64(len(ops))
+
+	start := 0 // the first opcode of the current basic block
+	for i := range ops {
+		if i > 0 && breaksGram(ops[i-1]) {
+			start = i
+		}
+		var gram opcodeGram
+		for n := 1; n <= opcodeProfileMaxGram && i-n+1 >= start; n++ {
+			gram = gram&(1<<56-1) | opcodeGram(ops[i-n+1])<<(8*(n-1)) | opcodeGram(n)<<56
+			if n < opcodeProfileMinGram {
+				continue
+			}
+			if _, ok := profile.grams[gram]; ok || len(profile.grams) < opcodeProfileCodeGrams {
+				profile.grams[gram]++
+			}
+		}
+	}
+}
+
+// OpcodeProfileCode is the number of times a code executes an n-gram.
+type OpcodeProfileCode struct {
+	CodeHash common.Hash `json:"codeHash"`
+	Count    uint64      `json:"count"`
+}
+
+// OpcodeProfileCandidate is an opcode n-gram frequently executed, as a
+// candidate of new superinstruction.
+type OpcodeProfileCandidate struct {
+	Pattern string              `json:"pattern"` // the opcodes of the n-gram, separated by spaces
+	Length  int                 `json:"length"`  // the number of opcodes in the n-gram
+	Count   uint64              `json:"count"`   // the number of times the n-gram is executed
+	Saved   uint64              `json:"saved"`   // the opcode dispatches saved if the n-gram is fused
+	Codes   []OpcodeProfileCode `json:"codes"`   // the code hashes executing the n-gram most
+}
+
+// OpcodeProfile is the summary of the opcode n-grams executed so far.
+type OpcodeProfile struct {
+	Steps      uint64                    `json:"steps"` // the number of opcodes executed in the profiled codes
+	Codes      int                       `json:"codes"` // the number of profiled code hashes
+	Candidates []*OpcodeProfileCandidate `json:"candidates"`
+}
+
+// Profile returns the given number of n-grams saving the most opcode dispatches
+// if fused.
+func (p *OpcodeProfiler) Profile(count int) *OpcodeProfile {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var (
+		result     = &OpcodeProfile{Codes: p.codes.Len()}
+		candidates = make(map[opcodeGram]*OpcodeProfileCandidate)
+	)
+	for _, hash := range p.codes.Keys() {
+		profile, _ := p.codes.Peek(hash)
+		result.Steps += profile.steps
+
+		for gram, n := range profile.grams {
+			candidate, ok := candidates[gram]
+			if !ok {
+				candidate = &OpcodeProfileCandidate{Pattern: gram.String(), Length: gram.len()}
+				candidates[gram] = candidate
+			}
+			candidate.Count += n
+			candidate.Saved += n * uint64(gram.len()-1)
+			candidate.Codes = append(candidate.Codes, OpcodeProfileCode{CodeHash: hash, Count: n})
+		}
+	}
+	result.Candidates = make([]*OpcodeProfileCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		result.Candidates = append(result.Candidates, candidate)
+	}
+	sort.Slice(result.Candidates, func(i, j int) bool {
+		a, b := result.Candidates[i], result.Candidates[j]
+		if a.Saved != b.Saved {
+			return a.Saved > b.Saved
+		}
+		return a.Pattern < b.Pattern
+	})
+	if count >= 0 && len(result.Candidates) > count {
+		result.Candidates = result.Candidates[:count]
+	}
+	for _, candidate := range result.Candidates {
+		sort.Slice(candidate.Codes, func(i, j int) bool {
+			return candidate.Codes[i].Count > candidate.Codes[j].Count
+		})
+		if len(candidate.Codes) > opcodeProfileTopCodes {
+			candidate.Codes = candidate.Codes[:opcodeProfileTopCodes]
+		}
+	}
+	return result
+}
+
+// WriteFile dumps the given number of top candidates into the file in JSON.
+func (p *OpcodeProfiler) WriteFile(file string, count int) error {
+	blob, err := json.MarshalIndent(p.Profile(count), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, blob, 0644)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func TestOpcodeProfilerRecord(t *testing.T) {
+	profiler := NewOpcodeProfiler()
+
+	trace := profiler.newTrace()
+	*trace = append(*trace, PUSH1, PUSH1, ADD, JUMP, JUMPDEST, PUSH1, PUSH1, ADD)
+	profiler.record(common.Hash{0x1}, trace)
+
+	profile := profiler.Profile(-1)
+	if profile.Steps != 8 || profile.Codes != 1 {
+		t.Fatalf("summary mismatch, have steps %d codes %d, want 8 and 1", profile.Steps, profile.Codes)
+	}
+	counts := make(map[string]uint64)
+	for _, candidate := range profile.Candidates {
+		counts[candidate.Pattern] = candidate.Count
+	}
+	for pattern, want := range map[string]uint64{
+		"PUSH1 PUSH1":          2,
+		"PUSH1 ADD":            2,
+		"PUSH1 PUSH1 ADD":      2,
+		"ADD JUMP":             1,
+		"PUSH1 PUSH1 ADD JUMP": 1,
+		"JUMP JUMPDEST":        0,
+		"JUMPDEST PUSH1":       1,
+	} {
+		if have := counts[pattern]; have != want {
+			t.Errorf("pattern %q: count mismatch, have %d, want %d", pattern, have, want)
+		}
+	}
+	if top := profile.Candidates[0]; top.Pattern != "PUSH1 PUSH1 ADD" || top.Saved != 4 {
+		t.Errorf("top candidate mismatch, have %q saving %d", top.Pattern, top.Saved)
+	}
+	if profile := profiler.Profile(2); len(profile.Candidates) != 2 {
+		t.Errorf("candidates not truncated, have %d, want 2", len(profile.Candidates))
+	}
+}
+
+func TestOpcodeProfilerInterpreter(t *testing.T) {
+	var (
+		address  = common.BytesToAddress([]byte("contract"))
+		profiler = NewOpcodeProfiler()
+		vmctx    = BlockContext{
+			Transfer: func(StateDB, common.Address, common.Address, *uint256.Int) {},
+		}
+	)
+	// push(1) push(2) add push(3) add pop stop
+	code := common.Hex2Bytes("600160020160030150" + "00")
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, code)
+	statedb.Finalise(true)
+
+	evm := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{OpcodeProfiler: profiler})
+	for i := 0; i < 2; i++ {
+		if _, _, err := evm.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(uint256.Int)); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+	profile := profiler.Profile(-1)
+	if profile.Steps != 14 || profile.Codes != 1 {
+		t.Fatalf("summary mismatch, have steps %d codes %d, want 14 and 1", profile.Steps, profile.Codes)
+	}
+	for _, candidate := range profile.Candidates {
+		if candidate.Pattern == "PUSH1 ADD" {
+			if candidate.Count != 4 || candidate.Codes[0].CodeHash != statedb.GetCodeHash(address) {
+				t.Fatalf("candidate mismatch: %+v", candidate)
+			}
+			return
+		}
+	}
+	t.Fatalf("candidate PUSH1 ADD not found")
+}
@@ -141,3 +141,16 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// ListKeptProofs lists the proofs kept by the proof keeper at the blocks from
+// first to last, both included, or to the current head if last is nil.
+func (api *AdminAPI) ListKeptProofs(first uint64, last *uint64) ([]*core.KeptProofInfo, error) {
+	if last == nil {
+		head := api.eth.BlockChain().CurrentHeader().Number.Uint64()
+		last = &head
+	}
+	if first > *last {
+		return nil, fmt.Errorf("invalid block range %d-%d", first, *last)
+	}
+	return api.eth.BlockChain().ProofKeeper().ListKeptProofs(first, *last)
+}
@@ -213,7 +213,7 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	if header == nil {
 		return nil, nil, fmt.Errorf("header %w", ethereum.NotFound)
 	}
-	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	stateDb, err := b.stateAt(header.Root)
 	if err != nil {
 		return nil, header, err
 	}
@@ -235,7 +235,7 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 		if blockNrOrHash.RequireCanonical && b.eth.blockchain.GetCanonicalHash(header.Number.Uint64()) != hash {
 			return nil, nil, errors.New("hash is not currently canonical")
 		}
-		stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+		stateDb, err := b.stateAt(header.Root)
 		if err != nil {
 			return nil, header, err
 		}
@@ -244,6 +244,19 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 	return nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 }
 
+// stateAt returns the state of the given root, falling back to the historical
+// state served from the state histories if the trie nodes are gone.
+func (b *EthAPIBackend) stateAt(root common.Hash) (*state.StateDB, error) {
+	stateDb, err := b.eth.BlockChain().StateAt(root)
+	if err == nil || !b.eth.config.StateHistoryIndex {
+		return stateDb, err
+	}
+	if historic, herr := b.eth.BlockChain().HistoricStateAt(root); herr == nil {
+		return historic, nil
+	}
+	return nil, err
+}
+
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
 	return b.eth.blockchain.GetReceiptsByHash(hash), nil
 }
@@ -324,14 +337,88 @@ func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add([]*types.Transaction{signedTx}, true, false)[0]
 }
 
+// SendPrivateTx adds the transaction to the pool without announcing it. Nodes
+// with a sequencer forward it privately to the sequencer.
+func (b *EthAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	if b.ChainConfig().IsOptimism() && signedTx.Type() == types.BlobTxType {
+		return types.ErrTxTypeNotSupported
+	}
+	if b.eth.seqRPCService != nil {
+		data, err := signedTx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := b.eth.seqRPCService.CallContext(ctx, nil, "eth_sendPrivateRawTransaction", hexutil.Encode(data)); err != nil {
+			return err
+		}
+		if b.disableTxPool {
+			return nil
+		}
+		// Retain tx in local tx pool after forwarding, for local RPC usage.
+		if err := b.eth.txPool.AddPrivate(signedTx); err != nil {
+			log.Warn("successfully sent private tx to sequencer, but failed to persist in local tx pool", "err", err, "tx", signedTx.Hash())
+		}
+		return nil
+	}
+	if b.disableTxPool {
+		return nil
+	}
+	return b.eth.txPool.AddPrivate(signedTx)
+}
+
+// SendConditionalTx adds the conditional transaction to the pool. Nodes with a
+// sequencer forward it with its conditions to the sequencer.
+func (b *EthAPIBackend) SendConditionalTx(ctx context.Context, signedTx *types.Transaction, cond *types.TransactionConditional) error {
+	if b.ChainConfig().IsOptimism() && signedTx.Type() == types.BlobTxType {
+		return types.ErrTxTypeNotSupported
+	}
+	if b.eth.seqRPCService != nil {
+		data, err := signedTx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := b.eth.seqRPCService.CallContext(ctx, nil, "eth_sendRawTransactionConditional", hexutil.Encode(data), cond); err != nil {
+			return err
+		}
+		if b.disableTxPool {
+			return nil
+		}
+		// Retain tx in local tx pool after forwarding, for local RPC usage.
+		if err := b.eth.txPool.AddConditional(signedTx, cond); err != nil {
+			log.Warn("successfully sent conditional tx to sequencer, but failed to persist in local tx pool", "err", err, "tx", signedTx.Hash())
+		}
+		return nil
+	}
+	if b.disableTxPool {
+		return nil
+	}
+	return b.eth.txPool.AddConditional(signedTx, cond)
+}
+
 func (b *EthAPIBackend) SendBundle(ctx context.Context, bundle *types.Bundle, originBundle *types.SendBundleArgs) error {
 	return b.eth.txPool.AddBundle(bundle, originBundle)
 }
 
+func (b *EthAPIBackend) CancelBundle(ctx context.Context, args *types.CancelBundleArgs) error {
+	return b.eth.txPool.CancelBundle(args)
+}
+
+func (b *EthAPIBackend) BundleStatus(hash common.Hash) *types.BundleStatus {
+	return b.eth.txPool.BundleStatus(hash)
+}
+
+func (b *EthAPIBackend) SubscribeBundleStatusEvent(ch chan<- core.BundleStatusEvent) event.Subscription {
+	return b.eth.txPool.SubscribeBundleStatus(ch)
+}
+
 func (b *EthAPIBackend) SimulateGaslessBundle(bundle *types.Bundle) (*types.SimulateGaslessBundleResp, error) {
 	return b.Miner().SimulateGaslessBundle(bundle)
 }
 
+func (b *EthAPIBackend) CallBundle(ctx context.Context, bundle *types.Bundle, parent *types.Header, state *state.StateDB, override func(*types.Header)) (*types.CallBundleResult, error) {
+	return b.Miner().CallBundle(bundle, parent, state, override)
+}
+
 func (b *EthAPIBackend) BundlePrice() *big.Int {
 	bundles := b.eth.txPool.AllBundles()
 	gasFloor := big.NewInt(b.eth.config.Miner.Mev.MevBundleGasPriceFloor)
@@ -399,6 +486,10 @@ func (b *EthAPIBackend) Stats() (runnable int, blocked int) {
 	return b.eth.txPool.Stats()
 }
 
+func (b *EthAPIBackend) AdmissionStats() txpool.AdmissionStats {
+	return b.eth.txPool.AdmissionStats()
+}
+
 func (b *EthAPIBackend) TxPoolContent() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
 	return b.eth.txPool.Content()
 }
@@ -24,9 +24,11 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
@@ -135,6 +137,131 @@ func (api *DebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error)
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
+// GetUnsafeTxDAG returns the missing dependencies of the TxDAG carried in the
+// block with the given hash, which are found by the TxDAG verification during
+// the block import. An empty list is returned if the TxDAG isn't marked unsafe.
+func (api *DebugAPI) GetUnsafeTxDAG(ctx context.Context, hash common.Hash) ([]types.TxDAGMissingDep, error) {
+	missing := rawdb.ReadUnsafeTxDAG(api.eth.chainDb, hash)
+	if missing == nil {
+		return []types.TxDAGMissingDep{}, nil
+	}
+	return missing, nil
+}
+
+// maxTxDAGStatsBlocks is the maximum number of blocks evaluated by TxDAGStats.
+const maxTxDAGStatsBlocks = 10000
+
+// TxDAGResult is the decoded TxDAG carried in a block.
+type TxDAGResult struct {
+	Type     byte       `json:"type"`
+	TxCount  int        `json:"txCount"`
+	Deps     [][]uint64 `json:"deps"`     // the dependencies of each tx
+	Excluded []uint64   `json:"excluded"` // the txs executed in sequence with all the others
+}
+
+// GetTxDAG returns the decoded TxDAG carried in the block.
+func (api *DebugAPI) GetTxDAG(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*TxDAGResult, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	dag, err := api.eth.blockchain.GetTxDAG(block)
+	if err != nil {
+		return nil, fmt.Errorf("no TxDAG in block %d: %w", block.NumberU64(), err)
+	}
+	result := &TxDAGResult{
+		Type:     dag.Type(),
+		TxCount:  dag.TxCount(),
+		Deps:     make([][]uint64, dag.TxCount()),
+		Excluded: []uint64{},
+	}
+	for i := 0; i < dag.TxCount(); i++ {
+		result.Deps[i] = types.TxDependency(dag, i)
+		if result.Deps[i] == nil {
+			result.Deps[i] = []uint64{}
+		}
+		if dag.TxDep(i).CheckFlag(types.ExcludedTxFlag) {
+			result.Excluded = append(result.Excluded, uint64(i))
+		}
+	}
+	return result, nil
+}
+
+// TxDAGStats evaluates the parallelism of the TxDAGs carried in the blocks of
+// the given range, both ends included. The txs are weighted by their gas used.
+func (api *DebugAPI) TxDAGStats(ctx context.Context, from, to rpc.BlockNumber) (*types.TxDAGStats, error) {
+	start, err := api.eth.APIBackend.HeaderByNumber(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := api.eth.APIBackend.HeaderByNumber(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if start == nil || end == nil {
+		return nil, errors.New("block not found")
+	}
+	first, last := start.Number.Uint64(), end.Number.Uint64()
+	if first > last {
+		return nil, fmt.Errorf("invalid block range %d-%d", first, last)
+	}
+	if last-first >= maxTxDAGStatsBlocks {
+		return nil, fmt.Errorf("block range %d-%d exceeds the limit of %d blocks", first, last, maxTxDAGStatsBlocks)
+	}
+	stats := new(types.TxDAGStats)
+	for number := first; number <= last; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		if dag, err := api.eth.blockchain.GetTxDAG(block); err == nil {
+			block = block.WithTxDAG(dag)
+		}
+		stats.Merge(types.NewBlockTxDAGStats(block, api.eth.blockchain.GetReceiptsByHash(block.Hash())))
+	}
+	return stats, nil
+}
+
+// GetKeptProof returns the proof kept by the proof keeper with the given proof
+// id, as listed by admin_listKeptProofs.
+func (api *DebugAPI) GetKeptProof(proofID hexutil.Uint64) (*core.KeptProof, error) {
+	return api.eth.blockchain.ProofKeeper().GetKeptProof(uint64(proofID))
+}
+
+// OpcodeProfile returns the opcode n-grams executed in the imported blocks which
+// save the most opcode dispatches if fused, as the candidates of superinstructions.
+func (api *DebugAPI) OpcodeProfile(count *int) (*vm.OpcodeProfile, error) {
+	profiler := api.eth.blockchain.OpcodeProfiler()
+	if profiler == nil {
+		return nil, errors.New("opcode profiling is disabled")
+	}
+	limit := vm.DefaultOpcodeProfileCandidates
+	if count != nil {
+		limit = *count
+	}
+	return profiler.Profile(limit), nil
+}
+
+// WriteOpcodeProfile dumps the top candidates of the opcode profile into the
+// given file in JSON.
+func (api *DebugAPI) WriteOpcodeProfile(file string, count *int) error {
+	profiler := api.eth.blockchain.OpcodeProfiler()
+	if profiler == nil {
+		return errors.New("opcode profiling is disabled")
+	}
+	limit := vm.DefaultOpcodeProfileCandidates
+	if count != nil {
+		limit = *count
+	}
+	return profiler.WriteFile(file, limit)
+}
+
 // AccountRange enumerates all accounts in the given block and start point in paging request
 func (api *DebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, start hexutil.Bytes, maxResults int, nocode, nostorage, incompletes bool) (state.Dump, error) {
 	var stateDb *state.StateDB
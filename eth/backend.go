@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/opcodeCompiler/compiler"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
@@ -48,6 +49,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/protocols/txdag"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -174,6 +176,12 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			log.Error("Failed to recover state", "error", err)
 		}
 	}
+	// Warm the optimized codes up from the previous runs
+	if config.EnableOpcodeOptimizing {
+		if err := compiler.EnablePersistence(chainDb); err != nil {
+			log.Error("Failed to load optimized codes", "err", err)
+		}
+	}
 	// Transfer mining-related config to the ethash config.
 	chainConfig, err := core.LoadChainConfig(chainDb, config.Genesis)
 	if err != nil {
@@ -238,6 +246,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		vmConfig = vm.Config{
 			EnablePreimageRecording:   config.EnablePreimageRecording,
 			EnableOpcodeOptimizations: config.EnableOpcodeOptimizing,
+			EnableBasicBlockGas:       config.EnableBasicBlockGas,
 		}
 		cacheConfig = &core.CacheConfig{
 			TrieCleanLimit:       config.TrieCleanCache,
@@ -249,14 +258,18 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			Preimages:            config.Preimages,
 			NoTries:              config.NoTries,
 			StateHistory:         config.StateHistory,
+			StateHistoryIndex:    config.StateHistoryIndex,
+			StateHistoryArchive:  config.StateHistoryArchive,
 			StateScheme:          config.StateScheme,
 			TrieCommitInterval:   config.TrieCommitInterval,
 			PathNodeBuffer:       config.PathNodeBuffer,
 			ProposeBlockInterval: config.ProposeBlockInterval,
 			EnableProofKeeper:    config.EnableProofKeeper,
 			KeepProofBlockSpan:   config.KeepProofBlockSpan,
+			KeepProofTargets:     config.KeepProofTargets,
 			JournalFilePath:      journalFilePath,
 			JournalFile:          config.JournalFileEnabled,
+			CheckpointInterval:   config.CheckpointInterval,
 		}
 	)
 	// Override the chain config with provided settings.
@@ -287,6 +300,22 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.EnableParallelTxDAG {
 		eth.blockchain.SetupTxDAGGeneration()
 	}
+	if config.EnableParallelTxDAGExec {
+		eth.blockchain.SetupTxDAGExecution(config.ParallelTxDAGExecWorkers)
+	}
+	if config.EnableParallelTxDAGVerify {
+		eth.blockchain.SetupTxDAGVerification(config.RejectUnsafeTxDAG)
+	}
+	if config.EnableOpcodeProfiling {
+		eth.blockchain.SetupOpcodeProfiler()
+	}
+	if config.OpcodeCheckRate > 0 {
+		dir := config.OpcodeCheckDir
+		if dir == "" {
+			dir = stack.ResolvePath("opcode-divergences")
+		}
+		eth.blockchain.SetupOpcodeChecker(config.OpcodeCheckRate, dir)
+	}
 	if chainConfig := eth.blockchain.Config(); chainConfig.Optimism != nil { // config.Genesis.Config.ChainID cannot be used because it's based on CLI flags only, thus default to mainnet L1
 		config.NetworkId = chainConfig.ChainID.Uint64() // optimism defaults eth network ID to chain ID
 		eth.networkID = config.NetworkId
@@ -315,6 +344,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	bundlePool := &bundlepool.BundlePool{}
 	if config.Miner.Mev.MevEnabled {
+		if config.BundlePool.Journal != "" {
+			config.BundlePool.Journal = stack.ResolvePath(config.BundlePool.Journal)
+		}
 		bundlePool = bundlepool.New(config.BundlePool, config.Miner.Mev, eth.blockchain)
 		txPools = append(txPools, bundlePool)
 	}
@@ -613,9 +645,29 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.config.EnableParallelTxDAGSidecar {
+		protos = append(protos, txdag.MakeProtocols((*txdagHandler)(s.handler))...)
+	}
 	return protos
 }
 
+// RequestTxDAG requests the TxDAG sidecar of the block from the peers in the
+// background, it's kept in memory once delivered and picked up by the block
+// execution if it's not started yet. The block import never waits for it, and
+// the sidecar is only persisted once checked against the executed block.
+func (s *Ethereum) RequestTxDAG(block *types.Block) {
+	if !s.config.EnableParallelTxDAGSidecar {
+		return
+	}
+	go func() {
+		dag := s.handler.txdagFetcher.fetch(block.Hash(), txDAGFetchTimeout)
+		if dag == nil {
+			return
+		}
+		s.blockchain.AddTxDAGSidecar(block.Hash(), dag)
+	}()
+}
+
 // Start implements node.Lifecycle, starting all internal goroutines needed by the
 // Ethereum protocol implementation.
 func (s *Ethereum) Start() error {
@@ -654,6 +706,14 @@ func (s *Ethereum) Stop() error {
 	s.txPool.Close()
 	s.miner.Close()
 	s.blockchain.Stop()
+	compiler.DisablePersistence()
+	if profiler := s.blockchain.OpcodeProfiler(); profiler != nil && s.config.OpcodeProfileFile != "" {
+		if err := profiler.WriteFile(s.config.OpcodeProfileFile, vm.DefaultOpcodeProfileCandidates); err != nil {
+			log.Error("Failed to dump opcode profile", "file", s.config.OpcodeProfileFile, "err", err)
+		} else {
+			log.Info("Dumped opcode profile", "file", s.config.OpcodeProfileFile)
+		}
+	}
 	s.engine.Close()
 	if s.seqRPCService != nil {
 		s.seqRPCService.Close()
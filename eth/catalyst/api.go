@@ -678,6 +678,13 @@ func (api *ConsensusAPI) newPayload(params engine.ExecutableData, versionedHashe
 		log.Warn("State not available, ignoring new payload")
 		return engine.PayloadStatusV1{Status: engine.ACCEPTED}, nil
 	}
+	// Request the TxDAG sidecar kept out of the block from the peers, it's best
+	// effort and the block is imported without waiting for it.
+	if len(block.Transactions()) > 1 {
+		if _, err := api.eth.BlockChain().GetTxDAG(block); err != nil {
+			api.eth.RequestTxDAG(block)
+		}
+	}
 	log.Trace("Inserting block without sethead", "hash", block.Hash(), "number", block.Number)
 	if err := api.eth.BlockChain().InsertBlockWithoutSetHead(block); err != nil {
 		log.Warn("NewPayloadV1: inserting block failed", "error", err)
@@ -67,6 +67,7 @@ var Defaults = Config{
 	Miner:                  miner.DefaultConfig,
 	TxPool:                 legacypool.DefaultConfig,
 	BlobPool:               blobpool.DefaultConfig,
+	BundlePool:             bundlepool.DefaultConfig,
 	RPCGasCap:              50000000,
 	RPCEVMTimeout:          5 * time.Second,
 	GPO:                    FullNodeGPO,
@@ -93,6 +94,7 @@ var OpBNBDefaults = Config{
 	Miner:                  miner.DefaultConfig,
 	TxPool:                 legacypool.DefaultConfig,
 	BlobPool:               blobpool.DefaultConfig,
+	BundlePool:             bundlepool.DefaultConfig,
 	RPCGasCap:              50000000,
 	RPCEVMTimeout:          5 * time.Second,
 	GPO:                    FullNodeGPO,
@@ -123,9 +125,11 @@ type Config struct {
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
 
 	// Deprecated, use 'TransactionHistory' instead.
-	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
-	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
-	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
+	TxLookupLimit       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	TransactionHistory  uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	StateHistory        uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
+	StateHistoryIndex   bool   `toml:",omitempty"` // Whether to index state histories for historical state reads.
+	StateHistoryArchive bool   `toml:",omitempty"` // Whether to move the pruned state histories into the imported archive.
 
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
@@ -135,7 +139,9 @@ type Config struct {
 	ProposeBlockInterval uint64                `toml:",omitempty"` // Keep the same with op-proposer propose block interval
 	EnableProofKeeper    bool                  `toml:",omitempty"` // Whether to enable proof keeper
 	KeepProofBlockSpan   uint64                `toml:",omitempty"` // Span block of keep proof
+	KeepProofTargets     []core.ProofTarget    `toml:",omitempty"` // Accounts and storage slots to keep proofs of
 	JournalFileEnabled   bool                  `toml:",omitempty"` // Whether the TrieJournal is stored using journal file
+	CheckpointInterval   time.Duration         `toml:",omitempty"` // Interval to checkpoint the node buffer list
 
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
@@ -218,8 +224,18 @@ type Config struct {
 	RollupDisableTxPoolAdmission            bool
 	RollupHaltOnIncompatibleProtocolVersion string
 
-	EnableOpcodeOptimizing bool
-	EnableParallelTxDAG    bool
+	EnableOpcodeOptimizing     bool
+	EnableBasicBlockGas        bool // Charge the constant gas once per basic block in the interpreter
+	EnableOpcodeProfiling      bool
+	OpcodeProfileFile          string  // File the opcode profile is dumped into on shutdown
+	OpcodeCheckRate            float64 // Fraction of the imported txs checked against the opcode optimization
+	OpcodeCheckDir             string  // Directory the opcode optimization divergences are recorded into
+	EnableParallelTxDAG        bool
+	EnableParallelTxDAGExec    bool
+	ParallelTxDAGExecWorkers   int
+	EnableParallelTxDAGVerify  bool
+	RejectUnsafeTxDAG          bool
+	EnableParallelTxDAGSidecar bool
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain config.
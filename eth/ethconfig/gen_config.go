@@ -29,12 +29,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxLookupLimit                           uint64                 `toml:",omitempty"`
 		TransactionHistory                      uint64                 `toml:",omitempty"`
 		StateHistory                            uint64                 `toml:",omitempty"`
+		StateHistoryIndex                       bool                   `toml:",omitempty"`
+		StateHistoryArchive                     bool                   `toml:",omitempty"`
 		StateScheme                             string                 `toml:",omitempty"`
 		PathNodeBuffer                          pathdb.NodeBufferType  `toml:",omitempty"`
 		ProposeBlockInterval                    uint64                 `toml:",omitempty"`
 		EnableProofKeeper                       bool                   `toml:",omitempty"`
 		KeepProofBlockSpan                      uint64                 `toml:",omitempty"`
+		KeepProofTargets                        []core.ProofTarget     `toml:",omitempty"`
 		JournalFileEnabled                      bool                   `toml:",omitempty"`
+		CheckpointInterval                      time.Duration          `toml:",omitempty"`
 		RequiredBlocks                          map[uint64]common.Hash `toml:"-"`
 		LightServ                               int                    `toml:",omitempty"`
 		LightIngress                            int                    `toml:",omitempty"`
@@ -90,12 +94,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
+	enc.StateHistoryIndex = c.StateHistoryIndex
+	enc.StateHistoryArchive = c.StateHistoryArchive
 	enc.StateScheme = c.StateScheme
 	enc.PathNodeBuffer = c.PathNodeBuffer
 	enc.ProposeBlockInterval = c.ProposeBlockInterval
 	enc.EnableProofKeeper = c.EnableProofKeeper
 	enc.KeepProofBlockSpan = c.KeepProofBlockSpan
+	enc.KeepProofTargets = c.KeepProofTargets
 	enc.JournalFileEnabled = c.JournalFileEnabled
+	enc.CheckpointInterval = c.CheckpointInterval
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -155,12 +163,16 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxLookupLimit                           *uint64                `toml:",omitempty"`
 		TransactionHistory                      *uint64                `toml:",omitempty"`
 		StateHistory                            *uint64                `toml:",omitempty"`
+		StateHistoryIndex                       *bool                  `toml:",omitempty"`
+		StateHistoryArchive                     *bool                  `toml:",omitempty"`
 		StateScheme                             *string                `toml:",omitempty"`
 		PathNodeBuffer                          *pathdb.NodeBufferType `toml:",omitempty"`
 		ProposeBlockInterval                    *uint64                `toml:",omitempty"`
 		EnableProofKeeper                       *bool                  `toml:",omitempty"`
 		KeepProofBlockSpan                      *uint64                `toml:",omitempty"`
+		KeepProofTargets                        []core.ProofTarget     `toml:",omitempty"`
 		JournalFileEnabled                      *bool                  `toml:",omitempty"`
+		CheckpointInterval                      *time.Duration         `toml:",omitempty"`
 		RequiredBlocks                          map[uint64]common.Hash `toml:"-"`
 		LightServ                               *int                   `toml:",omitempty"`
 		LightIngress                            *int                   `toml:",omitempty"`
@@ -239,6 +251,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateHistory != nil {
 		c.StateHistory = *dec.StateHistory
 	}
+	if dec.StateHistoryIndex != nil {
+		c.StateHistoryIndex = *dec.StateHistoryIndex
+	}
+	if dec.StateHistoryArchive != nil {
+		c.StateHistoryArchive = *dec.StateHistoryArchive
+	}
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
@@ -254,9 +272,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.KeepProofBlockSpan != nil {
 		c.KeepProofBlockSpan = *dec.KeepProofBlockSpan
 	}
+	if dec.KeepProofTargets != nil {
+		c.KeepProofTargets = dec.KeepProofTargets
+	}
 	if dec.JournalFileEnabled != nil {
 		c.JournalFileEnabled = *dec.JournalFileEnabled
 	}
+	if dec.CheckpointInterval != nil {
+		c.CheckpointInterval = *dec.CheckpointInterval
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
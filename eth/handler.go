@@ -72,6 +72,10 @@ type txPool interface {
 	// Add should add the given transactions to the pool.
 	Add(txs []*types.Transaction, local bool, sync bool) []error
 
+	// AddFromPeer should add the given transactions relayed by a peer to the
+	// pool, subject to the admission rate of the peer.
+	AddFromPeer(peer string, txs []*types.Transaction) []error
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending(filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction
@@ -119,6 +123,7 @@ type handler struct {
 	downloader   *downloader.Downloader
 	blockFetcher *fetcher.BlockFetcher
 	txFetcher    *fetcher.TxFetcher
+	txdagFetcher *txDAGFetcher
 	peers        *peerSet
 	merger       *consensus.Merger
 
@@ -158,6 +163,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		noTxGossip:     config.NoTxGossip,
 		chain:          config.Chain,
 		peers:          newPeerSet(),
+		txdagFetcher:   newTxDAGFetcher(),
 		merger:         config.Merger,
 		requiredBlocks: config.RequiredBlocks,
 		quitSync:       make(chan struct{}),
@@ -304,8 +310,8 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return p.RequestTxs(hashes)
 	}
-	addTxs := func(txs []*types.Transaction) []error {
-		return h.txpool.Add(txs, false, false)
+	addTxs := func(peer string, txs []*types.Transaction) []error {
+		return h.txpool.AddFromPeer(peer, txs)
 	}
 	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, h.removePeer)
 	h.chainSync = newChainSyncer(h)
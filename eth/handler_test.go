@@ -93,6 +93,12 @@ func (p *testTxPool) Add(txs []*types.Transaction, local bool, sync bool) []erro
 	return make([]error, len(txs))
 }
 
+// AddFromPeer appends a batch of transactions relayed by a peer to the pool,
+// and notifies any listeners if the addition channel is non nil
+func (p *testTxPool) AddFromPeer(peer string, txs []*types.Transaction) []error {
+	return p.Add(txs, false, false)
+}
+
 // Pending returns all the transactions known to the pool
 func (p *testTxPool) Pending(filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction {
 	p.lock.RLock()
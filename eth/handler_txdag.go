@@ -0,0 +1,198 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/txdag"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	txDAGFetchTimeout = 200 * time.Millisecond // Maximum time a TxDAG sidecar request stays pending
+	txDAGFetchPeers   = 3                      // Number of peers the TxDAG sidecar is requested from
+)
+
+// txdagHandler implements the txdag.Backend interface to handle the various network
+// packets that are sent as replies or broadcasts.
+type txdagHandler handler
+
+func (h *txdagHandler) Chain() *core.BlockChain { return h.chain }
+
+// RunPeer is invoked when a peer joins on the `txdag` protocol.
+func (h *txdagHandler) RunPeer(peer *txdag.Peer, hand txdag.Handler) error {
+	h.txdagFetcher.register(peer)
+	defer h.txdagFetcher.unregister(peer)
+
+	return hand(peer)
+}
+
+// PeerInfo retrieves all known `txdag` information about a peer.
+func (h *txdagHandler) PeerInfo(id enode.ID) interface{} {
+	if p := h.txdagFetcher.peer(id.String()); p != nil {
+		return &txdagPeerInfo{Version: p.Version()}
+	}
+	return nil
+}
+
+// Handle is invoked from a peer's message handler when it receives a new remote
+// message that the handler couldn't consume and serve itself.
+func (h *txdagHandler) Handle(peer *txdag.Peer, packet txdag.Packet) error {
+	switch packet := packet.(type) {
+	case *txdag.TxDAGsPacket:
+		h.txdagFetcher.deliver(peer.ID(), packet)
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected txdag packet type: %T", packet)
+	}
+}
+
+// txdagPeerInfo represents a short summary of the `txdag` sub-protocol metadata
+// known about a connected peer.
+type txdagPeerInfo struct {
+	Version uint `json:"version"` // TxDAG protocol version negotiated
+}
+
+// txDAGFetcher retrieves the TxDAG sidecars kept out of the blocks from the peers
+// running the `txdag` protocol. The sidecars are not covered by the block hash,
+// a bogus one is caught by the conflict detection of the parallel execution and
+// at most turns it off for the block, it never gets the block rejected. It's
+// only persisted by the chain once checked against the executed block.
+type txDAGFetcher struct {
+	peers   map[string]*txdag.Peer
+	pending map[uint64]*txDAGRequest // Requests waiting for the sidecar, by id
+	lock    sync.Mutex
+}
+
+// txDAGRequest is a pending TxDAG sidecar request.
+type txDAGRequest struct {
+	peers map[string]struct{} // Peers the sidecar is requested from
+	ch    chan []byte         // Channel the first sidecar delivered is sent over
+}
+
+func newTxDAGFetcher() *txDAGFetcher {
+	return &txDAGFetcher{
+		peers:   make(map[string]*txdag.Peer),
+		pending: make(map[uint64]*txDAGRequest),
+	}
+}
+
+func (f *txDAGFetcher) register(peer *txdag.Peer) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.peers[peer.ID()] = peer
+}
+
+func (f *txDAGFetcher) unregister(peer *txdag.Peer) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	delete(f.peers, peer.ID())
+}
+
+func (f *txDAGFetcher) peer(id string) *txdag.Peer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.peers[id]
+}
+
+// deliver hands the sidecar over to the pending request if the peer is one it was
+// requested from, the empty responses are ignored in favor of the other peers.
+func (f *txDAGFetcher) deliver(peer string, packet *txdag.TxDAGsPacket) {
+	if len(packet.TxDAGs) == 0 || len(packet.TxDAGs[0]) == 0 {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	req, ok := f.pending[packet.ID]
+	if !ok {
+		return
+	}
+	if _, ok := req.peers[peer]; !ok {
+		log.Debug("Unrequested TxDAG sidecar delivered", "peer", peer, "id", packet.ID)
+		return
+	}
+	select {
+	case req.ch <- packet.TxDAGs[0]:
+	default:
+	}
+}
+
+// fetch requests the TxDAG sidecar of the block from a few peers, and returns
+// the first one delivered within the timeout.
+func (f *txDAGFetcher) fetch(hash common.Hash, timeout time.Duration) types.TxDAG {
+	var (
+		id    = rand.Uint64()
+		req   = &txDAGRequest{peers: make(map[string]struct{}), ch: make(chan []byte, 1)}
+		peers []*txdag.Peer
+	)
+	f.lock.Lock()
+	for _, peer := range f.peers {
+		if len(peers) >= txDAGFetchPeers {
+			break
+		}
+		peers = append(peers, peer)
+		req.peers[peer.ID()] = struct{}{}
+	}
+	if len(peers) > 0 {
+		f.pending[id] = req
+	}
+	f.lock.Unlock()
+
+	if len(peers) == 0 {
+		return nil
+	}
+	defer func() {
+		f.lock.Lock()
+		delete(f.pending, id)
+		f.lock.Unlock()
+	}()
+	for _, peer := range peers {
+		go func(peer *txdag.Peer) {
+			if err := peer.RequestTxDAGs(id, []common.Hash{hash}); err != nil {
+				peer.Log().Debug("Failed to request TxDAG sidecar", "hash", hash, "err", err)
+			}
+		}(peer)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case data := <-req.ch:
+		dag, err := types.DecodeTxDAG(data)
+		if err != nil {
+			log.Debug("Invalid TxDAG sidecar fetched", "hash", hash, "err", err)
+			return nil
+		}
+		return dag
+	case <-timer.C:
+		log.Debug("TxDAG sidecar not fetched in time", "hash", hash)
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/eth/protocols/txdag"
+)
+
+// Tests that the TxDAG sidecars are only accepted from the peers they are
+// requested from.
+func TestTxDAGFetcherDeliver(t *testing.T) {
+	fetcher := newTxDAGFetcher()
+	req := &txDAGRequest{peers: map[string]struct{}{"asked": {}}, ch: make(chan []byte, 1)}
+	fetcher.pending[1] = req
+
+	fetcher.deliver("unasked", &txdag.TxDAGsPacket{ID: 1, TxDAGs: [][]byte{{0x01}}})
+	fetcher.deliver("asked", &txdag.TxDAGsPacket{ID: 2, TxDAGs: [][]byte{{0x02}}})
+	fetcher.deliver("asked", &txdag.TxDAGsPacket{ID: 1, TxDAGs: [][]byte{{}}})
+	select {
+	case data := <-req.ch:
+		t.Fatalf("unexpected sidecar delivered: %x", data)
+	default:
+	}
+	fetcher.deliver("asked", &txdag.TxDAGsPacket{ID: 1, TxDAGs: [][]byte{{0x03}}})
+	select {
+	case data := <-req.ch:
+		if !bytes.Equal(data, []byte{0x03}) {
+			t.Fatalf("sidecar mismatch, have %x, want %x", data, []byte{0x03})
+		}
+	default:
+		t.Fatal("sidecar not delivered")
+	}
+}
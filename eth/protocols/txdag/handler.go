@@ -0,0 +1,178 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txdag
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// softResponseLimit is the target maximum size of replies to data retrievals.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxTxDAGsServe is the maximum number of TxDAG sidecars to serve. This
+	// number is there to limit the number of disk lookups.
+	maxTxDAGsServe = 1024
+)
+
+// Handler is a callback to invoke from an outside runner after the boilerplate
+// exchanges have passed.
+type Handler func(peer *Peer) error
+
+// Backend defines the data retrieval methods to serve remote requests and the
+// callback methods to invoke on remote deliveries.
+type Backend interface {
+	// Chain retrieves the blockchain object to serve data.
+	Chain() *core.BlockChain
+
+	// RunPeer is invoked when a peer joins on the `txdag` protocol. The handler
+	// should do any peer maintenance work. If all is passed, control should be
+	// given back to the `handler` to process the inbound messages going forward.
+	RunPeer(peer *Peer, handler Handler) error
+
+	// PeerInfo retrieves all known `txdag` information about a peer.
+	PeerInfo(id enode.ID) interface{}
+
+	// Handle is a callback to be invoked when a data packet is received from
+	// the remote peer. Only packets not consumed by the protocol handler will
+	// be forwarded to the backend.
+	Handle(peer *Peer, packet Packet) error
+}
+
+// MakeProtocols constructs the P2P protocol definitions for `txdag`.
+func MakeProtocols(backend Backend) []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure
+
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  protocolLengths[version],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return backend.RunPeer(NewPeer(version, p, rw), func(peer *Peer) error {
+					return Handle(backend, peer)
+				})
+			},
+			NodeInfo: func() interface{} {
+				return &NodeInfo{}
+			},
+			PeerInfo: func(id enode.ID) interface{} {
+				return backend.PeerInfo(id)
+			},
+		}
+	}
+	return protocols
+}
+
+// Handle is the callback invoked to manage the life cycle of a `txdag` peer.
+// When this function terminates, the peer is disconnected.
+func Handle(backend Backend, peer *Peer) error {
+	for {
+		if err := HandleMessage(backend, peer); err != nil {
+			peer.Log().Debug("Message handling failed in `txdag`", "err", err)
+			return err
+		}
+	}
+}
+
+// HandleMessage is invoked whenever an inbound message is received from a
+// remote peer on the `txdag` protocol. The remote connection is torn down upon
+// returning any error.
+func HandleMessage(backend Backend, peer *Peer) error {
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	defer msg.Discard()
+	start := time.Now()
+	// Track the amount of time it takes to serve the request and run the handler
+	if metrics.Enabled {
+		h := fmt.Sprintf("%s/%s/%d/%#02x", p2p.HandleHistName, ProtocolName, peer.Version(), msg.Code)
+		defer func(start time.Time) {
+			sampler := func() metrics.Sample {
+				return metrics.ResettingSample(
+					metrics.NewExpDecaySample(1028, 0.015),
+				)
+			}
+			metrics.GetOrRegisterHistogramLazy(h, nil, sampler).Update(time.Since(start).Microseconds())
+		}(start)
+	}
+	// Handle the message depending on its contents
+	switch {
+	case msg.Code == GetTxDAGsMsg:
+		// Decode the TxDAG sidecar retrieval request
+		var req GetTxDAGsPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		// Service the request, potentially returning nothing in case of errors
+		dags := ServiceGetTxDAGsQuery(backend.Chain(), &req)
+
+		// Send back anything accumulated (or empty in case of errors)
+		return p2p.Send(peer.rw, TxDAGsMsg, &TxDAGsPacket{
+			ID:     req.ID,
+			TxDAGs: dags,
+		})
+
+	case msg.Code == TxDAGsMsg:
+		// A batch of TxDAG sidecars arrived to one of our previous requests
+		res := new(TxDAGsPacket)
+		if err := msg.Decode(res); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		return backend.Handle(peer, res)
+
+	default:
+		return fmt.Errorf("%w: %v", errInvalidMsgCode, msg.Code)
+	}
+}
+
+// ServiceGetTxDAGsQuery assembles the response to a TxDAG sidecar query. It is
+// exposed to allow external packages to test protocol behavior.
+func ServiceGetTxDAGsQuery(chain *core.BlockChain, req *GetTxDAGsPacket) [][]byte {
+	hashes := req.Hashes
+	if len(hashes) > maxTxDAGsServe {
+		hashes = hashes[:maxTxDAGsServe]
+	}
+	var (
+		dags  = make([][]byte, 0, len(hashes))
+		bytes uint64
+	)
+	for _, hash := range hashes {
+		data := chain.GetTxDAGSidecarRLP(hash)
+		dags = append(dags, data)
+		if bytes += uint64(len(data)); bytes > softResponseLimit {
+			break
+		}
+	}
+	return dags
+}
+
+// NodeInfo represents a short summary of the `txdag` sub-protocol metadata
+// known about the host peer.
+type NodeInfo struct{}
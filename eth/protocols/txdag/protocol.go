@@ -0,0 +1,80 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txdag
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	TXDAG1 = 1
+)
+
+// ProtocolName is the official short name of the `txdag` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "txdag"
+
+// ProtocolVersions are the supported versions of the `txdag` protocol (first
+// is primary).
+var ProtocolVersions = []uint{TXDAG1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{TXDAG1: 2}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 10 * 1024 * 1024
+
+const (
+	GetTxDAGsMsg = 0x00
+	TxDAGsMsg    = 0x01
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+)
+
+// Packet represents a p2p message in the `txdag` protocol.
+type Packet interface {
+	Name() string // Name returns a string corresponding to the message type.
+	Kind() byte   // Kind returns the message type.
+}
+
+// GetTxDAGsPacket represents a TxDAG sidecar query.
+type GetTxDAGsPacket struct {
+	ID     uint64        // Request ID to match up responses with
+	Hashes []common.Hash // Block hashes of the sidecars to retrieve
+}
+
+// TxDAGsPacket represents a TxDAG sidecar query response. The sidecars are in
+// the encoding of types.EncodeTxDAG, an empty item is returned for a block
+// without sidecar.
+type TxDAGsPacket struct {
+	ID     uint64   // ID of the request this is a response for
+	TxDAGs [][]byte // Requested TxDAG sidecars
+}
+
+func (*GetTxDAGsPacket) Name() string { return "GetTxDAGs" }
+func (*GetTxDAGsPacket) Kind() byte   { return GetTxDAGsMsg }
+
+func (*TxDAGsPacket) Name() string { return "TxDAGs" }
+func (*TxDAGsPacket) Kind() byte   { return TxDAGsMsg }
@@ -84,6 +84,8 @@ func runTraceWithOption(tracer tracers.Tracer, vmctx *vmContext, chaincfg *param
 	}
 
 	if enableOpti {
+		compiler.EnableOptimization()
+		defer compiler.DisableOptimization()
 		// reset the code also require flush code cache.
 		compiler.DeleteCodeCache(contract.CodeHash)
 		optimized, _ := compiler.GenOrRewriteOptimizedCode(contract.CodeHash, contract.Code)
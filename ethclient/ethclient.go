@@ -634,6 +634,26 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(data))
 }
 
+// SendPrivateTransaction injects a signed transaction into the pending pool for
+// execution, without it being announced to the network.
+func (ec *Client) SendPrivateTransaction(ctx context.Context, tx *types.Transaction) error {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return ec.c.CallContext(ctx, nil, "eth_sendPrivateRawTransaction", hexutil.Encode(data))
+}
+
+// SendTransactionConditional injects a signed transaction into the pending pool
+// for execution, to be included only in a block satisfying the conditions.
+func (ec *Client) SendTransactionConditional(ctx context.Context, tx *types.Transaction, cond *types.TransactionConditional) error {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return ec.c.CallContext(ctx, nil, "eth_sendRawTransactionConditional", hexutil.Encode(data), cond)
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -694,6 +714,17 @@ func (ec *Client) SimulateGaslessBundle(ctx context.Context, args types.Simulate
 	return &bundle, nil
 }
 
+// CallBundle simulates a bundle on top of the given block. The block number can
+// be nil, in which case the bundle is simulated on top of the latest block.
+func (ec *Client) CallBundle(ctx context.Context, args types.CallBundleArgs, blockNumber *big.Int) (*types.CallBundleResult, error) {
+	var result types.CallBundleResult
+	err := ec.c.CallContext(ctx, &result, "eth_callBundle", args, toBlockNumArg(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // SendBundle sends a bundle
 func (ec *Client) SendBundle(ctx context.Context, args types.SendBundleArgs) (common.Hash, error) {
 	var hash common.Hash
@@ -704,6 +735,29 @@ func (ec *Client) SendBundle(ctx context.Context, args types.SendBundleArgs) (co
 	return hash, nil
 }
 
+// CancelBundle cancels the bundle sent with the replacement UUID
+func (ec *Client) CancelBundle(ctx context.Context, args types.CancelBundleArgs) error {
+	return ec.c.CallContext(ctx, nil, "eth_cancelBundle", args)
+}
+
+// BundleStatus returns the current state of a bundle and its transitions
+func (ec *Client) BundleStatus(ctx context.Context, hash common.Hash) (*types.BundleStatus, error) {
+	var status *types.BundleStatus
+	err := ec.c.CallContext(ctx, &status, "eth_getBundleStatus", hash)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil {
+		return nil, ethereum.NotFound
+	}
+	return status, nil
+}
+
+// SubscribeBundleStatus subscribes to the state transitions of the bundles
+func (ec *Client) SubscribeBundleStatus(ctx context.Context, ch chan<- *types.BundleStatusUpdate) (ethereum.Subscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "bundleStatus")
+}
+
 // BundlePrice returns the price of a bundle
 func (ec *Client) BundlePrice(ctx context.Context) (*big.Int, error) {
 	var price *big.Int
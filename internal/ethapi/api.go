@@ -220,12 +220,17 @@ func (s *TxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCT
 	return content
 }
 
-// Status returns the number of pending and queued transaction in the pool.
+// Status returns the number of pending and queued transaction in the pool, and
+// the number of remote senders and peers limited by the admission control.
 func (s *TxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
+	admission := s.b.AdmissionStats()
 	return map[string]hexutil.Uint{
-		"pending": hexutil.Uint(pending),
-		"queued":  hexutil.Uint(queue),
+		"pending":          hexutil.Uint(pending),
+		"queued":           hexutil.Uint(queue),
+		"lowReputation":    hexutil.Uint(admission.LowReputation),
+		"throttledSenders": hexutil.Uint(admission.ThrottledSenders),
+		"throttledPeers":   hexutil.Uint(admission.ThrottledPeers),
 	}
 }
 
@@ -713,8 +718,15 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 
 	defer func() {
 		if proofKeeper := s.b.ProofKeeper(); err != nil && proofKeeper != nil && header.Number != nil {
-			if proofKeeper.IsProposeProofQuery(address, storageKeys, header.Number.Uint64()) {
-				if innerResult, innerError := proofKeeper.QueryProposeProof(header.Number.Uint64(), header.Root); innerError == nil {
+			keys := make([]common.Hash, len(storageKeys))
+			for i, hexKey := range storageKeys {
+				var keyErr error
+				if keys[i], _, keyErr = decodeHash(hexKey); keyErr != nil {
+					return
+				}
+			}
+			if proofKeeper.IsProposeProofQuery(address, keys, header.Number.Uint64()) {
+				if innerResult, innerError := proofKeeper.QueryProposeProof(address, keys, header.Number.Uint64(), header.Root); innerError == nil {
 					result = &AccountResult{
 						Address:      innerResult.Address,
 						AccountProof: innerResult.AccountProof,
@@ -722,7 +734,15 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 						CodeHash:     innerResult.CodeHash,
 						Nonce:        hexutil.Uint64(innerResult.Nonce),
 						StorageHash:  innerResult.StorageHash,
-						StorageProof: make([]StorageResult, 0),
+						StorageProof: make([]StorageResult, len(innerResult.StorageProof)),
+					}
+					for i, storage := range innerResult.StorageProof {
+						// Echo the keys as requested, like the proofs made from the state
+						outputKey := storage.Key
+						if _, keyLength, _ := decodeHash(storageKeys[i]); keyLength != 32 {
+							outputKey = hexutil.EncodeBig(keys[i].Big())
+						}
+						result.StorageProof[i] = StorageResult{outputKey, (*hexutil.Big)(storage.Value), storage.Proof}
 					}
 					err = nil
 				}
@@ -1138,6 +1158,35 @@ func (diff *BlockOverrides) Apply(blockCtx *vm.BlockContext) {
 	}
 }
 
+// applyHeader overrides the given header fields into the given block header.
+// The blob base fee is derived from the header, so it is not overridden.
+func (diff *BlockOverrides) applyHeader(header *types.Header) {
+	if diff == nil {
+		return
+	}
+	if diff.Number != nil {
+		header.Number = diff.Number.ToInt()
+	}
+	if diff.Difficulty != nil {
+		header.Difficulty = diff.Difficulty.ToInt()
+	}
+	if diff.Time != nil {
+		header.Time = uint64(*diff.Time)
+	}
+	if diff.GasLimit != nil {
+		header.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		header.Coinbase = *diff.Coinbase
+	}
+	if diff.Random != nil {
+		header.MixDigest = *diff.Random
+	}
+	if diff.BaseFee != nil {
+		header.BaseFee = diff.BaseFee.ToInt()
+	}
+}
+
 // ChainContextBackend provides methods required to implement ChainContext.
 type ChainContextBackend interface {
 	Engine() consensus.Engine
@@ -2039,6 +2088,9 @@ func (s *TransactionAPI) SendTransaction(ctx context.Context, args TransactionAr
 	if err != nil {
 		return common.Hash{}, err
 	}
+	if args.Conditional != nil {
+		return submitConditionalTransaction(ctx, s.b, signed, args.Conditional)
+	}
 	return SubmitTransaction(ctx, s.b, signed)
 }
 
@@ -2071,6 +2123,64 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateRawTransaction will add the signed transaction to the transaction pool
+// without announcing it to the network. The transaction is included and can be
+// replaced like any other, but is dropped if not included within the configured
+// number of blocks.
+func (s *TransactionAPI) SendPrivateRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	if !s.b.UnprotectedAllowed() && !tx.Protected() {
+		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
+		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+	}
+	if err := s.b.SendPrivateTx(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	log.Debug("Submitted private transaction", "hash", tx.Hash().Hex(), "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value())
+	return tx.Hash(), nil
+}
+
+// SendRawTransactionConditional will add the signed transaction to the transaction
+// pool, to be included only in a block satisfying the given conditions: the
+// storage roots or slot values of the known accounts, and the ranges of the block
+// number and timestamp. The transaction is evicted once the conditions fail.
+func (s *TransactionAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, cond *types.TransactionConditional) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if cond == nil {
+		return common.Hash{}, errors.New("missing transaction conditional")
+	}
+	return submitConditionalTransaction(ctx, s.b, tx, cond)
+}
+
+// submitConditionalTransaction is a helper function that submits a conditional
+// tx to txPool and logs a message.
+func submitConditionalTransaction(ctx context.Context, b Backend, tx *types.Transaction, cond *types.TransactionConditional) (common.Hash, error) {
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	if !b.UnprotectedAllowed() && !tx.Protected() {
+		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
+		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+	}
+	if err := cond.Validate(); err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.SendConditionalTx(ctx, tx, cond); err != nil {
+		return common.Hash{}, err
+	}
+	log.Debug("Submitted conditional transaction", "hash", tx.Hash().Hex(), "nonce", tx.Nonce(), "recipient", tx.To(), "cost", cond.Cost())
+	return tx.Hash(), nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
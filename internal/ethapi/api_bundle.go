@@ -8,7 +8,10 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const InvalidBundleParamError = -38000
@@ -57,6 +60,52 @@ func (s *PrivateTxBundleAPI) SimulateGaslessBundle(_ context.Context, args types
 	return s.b.SimulateGaslessBundle(bundle)
 }
 
+// CallBundle simulates a bundle on top of the state of the given block number
+// or tag, as the builder does, and returns the result of each transaction.
+//
+// Additionally, the caller can specify a batch of contract for fields overriding,
+// and override the fields of the simulated block.
+func (s *PrivateTxBundleAPI) CallBundle(ctx context.Context, args types.CallBundleArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (*types.CallBundleResult, error) {
+	if len(args.Txs) == 0 {
+		return nil, newBundleError(errors.New("bundle missing txs"))
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+
+	var txs types.Transactions
+
+	for _, encodedTx := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encodedTx); err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+
+	bundle := &types.Bundle{
+		Txs:               txs,
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+
+	result, err := s.b.CallBundle(ctx, bundle, header, state, blockOverrides.applyHeader)
+	if err != nil {
+		return nil, err
+	}
+	result.StateBlockNumber = hexutil.Uint64(header.Number.Uint64())
+
+	return result, nil
+}
+
 // SendBundle will add the signed transaction to the transaction pool.
 // The sender is responsible for signing the transaction and using the correct nonce and ensuring validity
 func (s *PrivateTxBundleAPI) SendBundle(ctx context.Context, args types.SendBundleArgs) (common.Hash, error) {
@@ -131,6 +180,52 @@ func (s *PrivateTxBundleAPI) SendBundle(ctx context.Context, args types.SendBund
 	return bundle.Hash(), nil
 }
 
+// CancelBundle removes the bundle sent with the replacement UUID from the pool.
+// The cancellation is authenticated by the signature of the bundle sender, the
+// sender of its first transaction, over the bundle hash.
+func (s *PrivateTxBundleAPI) CancelBundle(ctx context.Context, args types.CancelBundleArgs) error {
+	if len(args.Signature) == 0 {
+		return newBundleError(errors.New("cancellation missing signature"))
+	}
+	return s.b.CancelBundle(ctx, &args)
+}
+
+// GetBundleStatus returns the current state of the bundle with the given hash,
+// with the transitions that led to it. Only the recent bundles are tracked.
+func (s *PrivateTxBundleAPI) GetBundleStatus(ctx context.Context, hash common.Hash) *types.BundleStatus {
+	return s.b.BundleStatus(hash)
+}
+
+// BundleStatus creates a subscription that is triggered each time a bundle
+// transitions into a new state.
+func (s *PrivateTxBundleAPI) BundleStatus(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan core.BundleStatusEvent, 128)
+		sub := s.b.SubscribeBundleStatusEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev.Update)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 func newBundleError(err error) *bundleError {
 	return &bundleError{
 		error: err,
@@ -47,6 +47,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -748,12 +749,30 @@ func (b testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) even
 func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	panic("implement me")
 }
+func (b testBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	panic("implement me")
+}
+func (b testBackend) SendConditionalTx(ctx context.Context, signedTx *types.Transaction, cond *types.TransactionConditional) error {
+	panic("implement me")
+}
 func (b testBackend) SimulateGaslessBundle(bundle *types.Bundle) (*types.SimulateGaslessBundleResp, error) {
 	panic("implement me")
 }
+func (b testBackend) CallBundle(ctx context.Context, bundle *types.Bundle, parent *types.Header, state *state.StateDB, override func(*types.Header)) (*types.CallBundleResult, error) {
+	panic("implement me")
+}
 func (b testBackend) SendBundle(ctx context.Context, bundle *types.Bundle, originBundle *types.SendBundleArgs) error {
 	panic("implement me")
 }
+func (b testBackend) CancelBundle(ctx context.Context, args *types.CancelBundleArgs) error {
+	panic("implement me")
+}
+func (b testBackend) BundleStatus(hash common.Hash) *types.BundleStatus {
+	panic("implement me")
+}
+func (b testBackend) SubscribeBundleStatusEvent(ch chan<- core.BundleStatusEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) BundlePrice() *big.Int {
 	panic("implement me")
 }
@@ -767,6 +786,9 @@ func (b testBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uin
 	return 0, nil
 }
 func (b testBackend) Stats() (pending int, queued int) { panic("implement me") }
+func (b testBackend) AdmissionStats() txpool.AdmissionStats {
+	panic("implement me")
+}
 func (b testBackend) TxPoolContent() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
 	panic("implement me")
 }
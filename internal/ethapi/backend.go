@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -77,14 +78,21 @@ type Backend interface {
 
 	// Transaction pool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error
+	SendConditionalTx(ctx context.Context, signedTx *types.Transaction, cond *types.TransactionConditional) error
 	SimulateGaslessBundle(bundle *types.Bundle) (*types.SimulateGaslessBundleResp, error)
+	CallBundle(ctx context.Context, bundle *types.Bundle, parent *types.Header, state *state.StateDB, override func(*types.Header)) (*types.CallBundleResult, error)
 	SendBundle(ctx context.Context, bundle *types.Bundle, originBundle *types.SendBundleArgs) error
+	CancelBundle(ctx context.Context, args *types.CancelBundleArgs) error
+	BundleStatus(hash common.Hash) *types.BundleStatus
+	SubscribeBundleStatusEvent(ch chan<- core.BundleStatusEvent) event.Subscription
 	BundlePrice() *big.Int
 	GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error)
 	GetPoolTransactions() (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
+	AdmissionStats() txpool.AdmissionStats
 	TxPoolContent() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction)
 	TxPoolContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
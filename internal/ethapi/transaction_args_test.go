@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -381,12 +382,28 @@ func (b *backendMock) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) eve
 	return nil
 }
 func (b *backendMock) SendTx(ctx context.Context, signedTx *types.Transaction) error { return nil }
+func (b *backendMock) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return nil
+}
+func (b *backendMock) SendConditionalTx(ctx context.Context, signedTx *types.Transaction, cond *types.TransactionConditional) error {
+	return nil
+}
 func (b *backendMock) SimulateGaslessBundle(bundle *types.Bundle) (*types.SimulateGaslessBundleResp, error) {
 	panic("implement me")
 }
+func (b *backendMock) CallBundle(ctx context.Context, bundle *types.Bundle, parent *types.Header, state *state.StateDB, override func(*types.Header)) (*types.CallBundleResult, error) {
+	return nil, nil
+}
 func (b *backendMock) SendBundle(ctx context.Context, bundle *types.Bundle, originBundle *types.SendBundleArgs) error {
 	return nil
 }
+func (b *backendMock) CancelBundle(ctx context.Context, args *types.CancelBundleArgs) error {
+	return nil
+}
+func (b *backendMock) BundleStatus(hash common.Hash) *types.BundleStatus { return nil }
+func (b *backendMock) SubscribeBundleStatusEvent(ch chan<- core.BundleStatusEvent) event.Subscription {
+	return nil
+}
 func (b *backendMock) BundlePrice() *big.Int { return nil }
 func (b *backendMock) GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error) {
 	return false, nil, [32]byte{}, 0, 0, nil
@@ -397,6 +414,9 @@ func (b *backendMock) GetPoolNonce(ctx context.Context, addr common.Address) (ui
 	return 0, nil
 }
 func (b *backendMock) Stats() (pending int, queued int) { return 0, 0 }
+func (b *backendMock) AdmissionStats() txpool.AdmissionStats {
+	return txpool.AdmissionStats{}
+}
 func (b *backendMock) TxPoolContent() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
 	return nil, nil
 }
@@ -190,6 +190,12 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'listKeptProofs',
+			call: 'admin_listKeptProofs',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -448,6 +454,40 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'getUnsafeTxDAG',
+			call: 'debug_getUnsafeTxDAG',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getTxDAG',
+			call: 'debug_getTxDAG',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter],
+		}),
+		new web3._extend.Method({
+			name: 'txDAGStats',
+			call: 'debug_txDAGStats',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+		}),
+		new web3._extend.Method({
+			name: 'getKeptProof',
+			call: 'debug_getKeptProof',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'opcodeProfile',
+			call: 'debug_opcodeProfile',
+			params: 1,
+			inputFormatter: [null],
+		}),
+		new web3._extend.Method({
+			name: 'writeOpcodeProfile',
+			call: 'debug_writeOpcodeProfile',
+			params: 2,
+			inputFormatter: [null, null],
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
@@ -0,0 +1,144 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// bundleCallTracer captures the outcome of the top call frame of a transaction.
+type bundleCallTracer struct {
+	output []byte
+	err    error
+}
+
+func (t *bundleCallTracer) CaptureTxStart(gasLimit uint64) {}
+func (t *bundleCallTracer) CaptureTxEnd(restGas uint64)    {}
+func (t *bundleCallTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+func (t *bundleCallTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.output, t.err = common.CopyBytes(output), err
+}
+func (t *bundleCallTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (t *bundleCallTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (t *bundleCallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+func (t *bundleCallTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// bundleTrace collects the per-transaction results of a simulated bundle.
+type bundleTrace struct {
+	tracer   *bundleCallTracer
+	coinbase *big.Int // coinbase balance before the current transaction
+
+	results []*types.CallBundleTxResult
+}
+
+func newBundleTrace() *bundleTrace {
+	return &bundleTrace{tracer: new(bundleCallTracer)}
+}
+
+// begin prepares the trace for the next transaction, returning the tracer to
+// execute it with.
+func (t *bundleTrace) begin(state *state.StateDB, coinbase common.Address) vm.EVMLogger {
+	t.tracer.output, t.tracer.err = nil, nil
+	t.coinbase = state.GetBalance(coinbase).ToBig()
+	return t.tracer
+}
+
+// end records the result of the transaction executed since begin.
+func (t *bundleTrace) end(tx *types.Transaction, receipt *types.Receipt, state *state.StateDB, coinbase common.Address, baseFee *big.Int) {
+	gasPrice, _ := tx.EffectiveGasTip(baseFee)
+	if baseFee != nil {
+		gasPrice.Add(gasPrice, baseFee)
+	}
+	result := &types.CallBundleTxResult{
+		TxHash:       tx.Hash(),
+		GasUsed:      hexutil.Uint64(receipt.GasUsed),
+		GasPrice:     (*hexutil.Big)(gasPrice),
+		GasFees:      (*hexutil.Big)(new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed))),
+		CoinbaseDiff: (*hexutil.Big)(new(big.Int).Sub(state.GetBalance(coinbase).ToBig(), t.coinbase)),
+		Logs:         receipt.Logs,
+	}
+	if result.Logs == nil {
+		result.Logs = []*types.Log{}
+	}
+	if err := t.tracer.err; err != nil {
+		result.Error = err.Error()
+		if errors.Is(err, vm.ErrExecutionReverted) && len(t.tracer.output) > 0 {
+			result.Revert = t.tracer.output
+			if reason, errUnpack := abi.UnpackRevert(t.tracer.output); errUnpack == nil {
+				result.RevertReason = reason
+			}
+		}
+	}
+	t.results = append(t.results, result)
+}
+
+// CallBundle simulates the bundle in a block on top of the given parent and
+// state, and returns the result of each transaction. The override is applied
+// to the header of the simulated block, if given.
+func (miner *Miner) CallBundle(bundle *types.Bundle, parent *types.Header, state *state.StateDB, override func(*types.Header)) (*types.CallBundleResult, error) {
+	env, err := miner.prepareSimulationEnvAt(parent, state)
+	if err != nil {
+		return nil, err
+	}
+	env.header.Coinbase = miner.worker.coinbase
+	if override != nil {
+		override(env.header)
+	}
+	return miner.worker.callBundle(env, bundle)
+}
+
+// callBundle simulates the bundle by simulateBundle, tracing the result of
+// each transaction. Bundles the builder would reject are reported with the
+// reason instead of failing the call.
+func (w *worker) callBundle(env *environment, bundle *types.Bundle) (*types.CallBundleResult, error) {
+	var (
+		trace    = newBundleTrace()
+		coinbase = env.state.GetBalance(env.header.Coinbase).ToBig()
+	)
+	simmed, err := w.simulateBundle(env, bundle, env.state, env.gasPool, 0, false, false, trace)
+	if err != nil && !errors.Is(err, errNonRevertingTxInBundleFailed) && !errors.Is(err, errBundlePriceTooLow) {
+		if n := len(trace.results); n < len(bundle.Txs) {
+			return nil, fmt.Errorf("tx %d [%v]: %w", n, bundle.Txs[n].Hash(), err)
+		}
+		return nil, err
+	}
+	result := &types.CallBundleResult{
+		Results:      trace.results,
+		CoinbaseDiff: (*hexutil.Big)(new(big.Int).Sub(env.state.GetBalance(env.header.Coinbase).ToBig(), coinbase)),
+		StateRoot:    env.state.IntermediateRoot(w.chainConfig.IsEIP158(env.header.Number)),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		// Rank the rejected bundle the same way as simulateBundle does, the
+		// transactions from the txpool are left out
+		var (
+			gasUsed uint64
+			gasFees = new(big.Int)
+		)
+		for i, res := range trace.results {
+			if !w.eth.TxPool().Has(bundle.Txs[i].Hash()) {
+				gasUsed += uint64(res.GasUsed)
+				gasFees.Add(gasFees, res.GasFees.ToInt())
+			}
+		}
+		simmed = &types.SimulatedBundle{BundleGasUsed: gasUsed, BundleGasFees: gasFees, BundleGasPrice: new(big.Int)}
+		if gasUsed != 0 {
+			simmed.BundleGasPrice.Div(gasFees, new(big.Int).SetUint64(gasUsed))
+		}
+	}
+	result.BundleGasUsed = hexutil.Uint64(simmed.BundleGasUsed)
+	result.BundleGasFees = (*hexutil.Big)(simmed.BundleGasFees)
+	result.BundleGasPrice = (*hexutil.Big)(simmed.BundleGasPrice)
+	return result, nil
+}
@@ -0,0 +1,147 @@
+package miner
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// revertCode returns the code reverting with the given reason.
+func revertCode(reason string) []byte {
+	// Error(string) selector, offset, length and the padded reason
+	data := append(crypto.Keccak256([]byte("Error(string)"))[:4], common.LeftPadBytes([]byte{0x20}, 32)...)
+	data = append(data, common.LeftPadBytes([]byte{byte(len(reason))}, 32)...)
+	data = append(data, common.RightPadBytes([]byte(reason), 32)...)
+
+	var code []byte
+	for i := 0; i < len(data); i += 32 {
+		word := common.RightPadBytes(data[i:], 32)[:32]
+		code = append(code, byte(vm.PUSH32))
+		code = append(code, word...)
+		code = append(code, byte(vm.PUSH1), byte(i), byte(vm.MSTORE))
+	}
+	return append(code, byte(vm.PUSH1), byte(len(data)), byte(vm.PUSH1), 0, byte(vm.REVERT))
+}
+
+func TestCallBundle(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		coinbase = common.Address{0xc0}
+		reverter = common.Address{0xee}
+		signer   = types.LatestSigner(params.TestChainConfig)
+		gasPrice = big.NewInt(2 * params.InitialBaseFee)
+	)
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), db, 0, nil, nil)
+	defer w.close()
+
+	newEnv := func() *environment {
+		parent := b.chain.CurrentBlock()
+		state, err := b.chain.StateAt(parent.Root)
+		if err != nil {
+			t.Fatalf("failed to get state: %v", err)
+		}
+		state.SetCode(reverter, revertCode("boom"))
+		return &environment{
+			header: &types.Header{
+				ParentHash: parent.Hash(),
+				Number:     new(big.Int).Add(parent.Number, common.Big1),
+				GasLimit:   parent.GasLimit,
+				Time:       parent.Time + 1,
+				Coinbase:   coinbase,
+				BaseFee:    big.NewInt(params.InitialBaseFee),
+				Difficulty: common.Big1,
+			},
+			state:   state,
+			signer:  signer,
+			gasPool: prepareGasPool(),
+		}
+	}
+	var (
+		transfer = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas,
+			GasPrice: gasPrice,
+		})
+		revert = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    1,
+			To:       &reverter,
+			Gas:      100000,
+			GasPrice: gasPrice,
+		})
+	)
+
+	// The reverting transaction is allowed to revert
+	bundle := &types.Bundle{Txs: types.Transactions{transfer, revert}, RevertingTxHashes: []common.Hash{revert.Hash()}}
+	result, err := w.callBundle(newEnv(), bundle)
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected bundle error: %v", result.Error)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("results mismatch: have %d, want %d", len(result.Results), 2)
+	}
+	var (
+		gasUsed uint64
+		tips    = new(big.Int)
+	)
+	for i, res := range result.Results {
+		if res.TxHash != bundle.Txs[i].Hash() {
+			t.Errorf("tx %d: hash mismatch: have %x, want %x", i, res.TxHash, bundle.Txs[i].Hash())
+		}
+		if res.GasPrice.ToInt().Cmp(gasPrice) != 0 {
+			t.Errorf("tx %d: gas price mismatch: have %v, want %v", i, res.GasPrice, gasPrice)
+		}
+		tip := new(big.Int).Mul(big.NewInt(params.InitialBaseFee), new(big.Int).SetUint64(uint64(res.GasUsed)))
+		if res.CoinbaseDiff.ToInt().Cmp(tip) != 0 {
+			t.Errorf("tx %d: coinbase diff mismatch: have %v, want %v", i, res.CoinbaseDiff, tip)
+		}
+		gasUsed += uint64(res.GasUsed)
+		tips.Add(tips, tip)
+	}
+	if uint64(result.Results[0].GasUsed) != params.TxGas || result.Results[0].Error != "" {
+		t.Errorf("transfer result mismatch: gas %d, error %q", result.Results[0].GasUsed, result.Results[0].Error)
+	}
+	if res := result.Results[1]; res.Error != vm.ErrExecutionReverted.Error() || res.RevertReason != "boom" {
+		t.Errorf("revert result mismatch: error %q, reason %q", res.Error, res.RevertReason)
+	}
+	if uint64(result.BundleGasUsed) != gasUsed || result.BundleGasPrice.ToInt().Cmp(gasPrice) != 0 {
+		t.Errorf("bundle mismatch: gas %d, price %v, want gas %d, price %v", result.BundleGasUsed, result.BundleGasPrice, gasUsed, gasPrice)
+	}
+	if result.CoinbaseDiff.ToInt().Cmp(tips) != 0 {
+		t.Errorf("bundle coinbase diff mismatch: have %v, want %v", result.CoinbaseDiff, tips)
+	}
+	if result.StateRoot == (common.Hash{}) {
+		t.Errorf("missing post-state root")
+	}
+
+	// The builder rejects the bundle if the transaction is not allowed to revert
+	bundle = &types.Bundle{Txs: types.Transactions{transfer, revert}}
+	result, err = w.callBundle(newEnv(), bundle)
+	if err != nil {
+		t.Fatalf("failed to call bundle: %v", err)
+	}
+	if !strings.Contains(result.Error, errNonRevertingTxInBundleFailed.Error()) {
+		t.Errorf("bundle error mismatch: have %q, want %q", result.Error, errNonRevertingTxInBundleFailed)
+	}
+	if len(result.Results) != 2 || result.Results[1].RevertReason != "boom" {
+		t.Errorf("rejected bundle results mismatch: %v", result.Results)
+	}
+
+	// Invalid transactions fail the call
+	bundle = &types.Bundle{Txs: types.Transactions{revert}}
+	if _, err := w.callBundle(newEnv(), bundle); err == nil {
+		t.Errorf("expected nonce error")
+	}
+}
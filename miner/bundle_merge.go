@@ -0,0 +1,301 @@
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// The fees captured by the price order and the searched one, in gwei
+	bundleMergeGreedyFeesCounter = metrics.NewRegisteredCounter("miner/bundle/merge/greedy/fees", nil)
+	bundleMergeSearchFeesCounter = metrics.NewRegisteredCounter("miner/bundle/merge/search/fees", nil)
+
+	bundleMergeImprovedMeter = metrics.NewRegisteredMeter("miner/bundle/merge/search/improved", nil)
+	bundleMergeOrderingsHist = metrics.NewRegisteredHistogram("miner/bundle/merge/search/orderings", nil, metrics.NewExpDecaySample(1028, 0.015))
+	bundleMergeSearchTimer   = metrics.NewRegisteredTimer("miner/bundle/merge/search/time", nil)
+)
+
+// bundleAccessList is the union of the storage slots accessed by the
+// transactions of a bundle.
+type bundleAccessList map[common.Address]map[common.Hash]struct{}
+
+func newBundleAccessList() *bundleAccessList {
+	list := make(bundleAccessList)
+	return &list
+}
+
+// add merges the storage slots of the access list of a transaction.
+func (l *bundleAccessList) add(accesses types.AccessList) {
+	for _, tuple := range accesses {
+		if len(tuple.StorageKeys) == 0 {
+			continue
+		}
+		slots := (*l)[tuple.Address]
+		if slots == nil {
+			slots = make(map[common.Hash]struct{})
+			(*l)[tuple.Address] = slots
+		}
+		for _, slot := range tuple.StorageKeys {
+			slots[slot] = struct{}{}
+		}
+	}
+}
+
+// list converts the accessed slots into an access list.
+func (l *bundleAccessList) list() types.AccessList {
+	list := make(types.AccessList, 0, len(*l))
+	for addr, slots := range *l {
+		tuple := types.AccessTuple{Address: addr, StorageKeys: make([]common.Hash, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		list = append(list, tuple)
+	}
+	return list
+}
+
+// bundleAccessKeys returns the state the bundle may conflict with others on,
+// modelled the same way as the txDAG ordering: the senders, the receivers of
+// value and the storage slots accessed at the top of the block.
+func bundleAccessKeys(signer types.Signer, bundle *types.SimulatedBundle) []txAccessKey {
+	keys := make(map[txAccessKey]struct{})
+	for _, tx := range bundle.OriginalBundle.Txs {
+		if from, err := types.Sender(signer, tx); err == nil {
+			keys[txAccessKey{addr: from, account: true}] = struct{}{}
+		}
+		if to := tx.To(); to != nil && tx.Value().Sign() > 0 {
+			keys[txAccessKey{addr: *to, account: true}] = struct{}{}
+		}
+	}
+	for _, tuple := range bundle.AccessList {
+		for _, slot := range tuple.StorageKeys {
+			keys[txAccessKey{addr: tuple.Address, slot: slot}] = struct{}{}
+		}
+	}
+	list := make([]txAccessKey, 0, len(keys))
+	for k := range keys {
+		list = append(list, k)
+	}
+	return list
+}
+
+// bundleConflicts returns the indexes of the bundles each bundle shares state
+// with, and whether any conflict exists at all.
+func bundleConflicts(signer types.Signer, bundles []*types.SimulatedBundle) ([][]int, bool) {
+	var (
+		touched   = make(map[txAccessKey][]int)
+		conflicts = make([][]int, len(bundles))
+		seen      = make([]map[int]struct{}, len(bundles))
+		found     bool
+	)
+	for i, bundle := range bundles {
+		seen[i] = make(map[int]struct{})
+		for _, k := range bundleAccessKeys(signer, bundle) {
+			for _, j := range touched[k] {
+				if _, ok := seen[i][j]; ok {
+					continue
+				}
+				seen[i][j], seen[j][i] = struct{}{}, struct{}{}
+				conflicts[i] = append(conflicts[i], j)
+				conflicts[j] = append(conflicts[j], i)
+				found = true
+			}
+			touched[k] = append(touched[k], i)
+		}
+	}
+	return conflicts, found
+}
+
+// independentFirst reorders the bundles by selecting, in the given order, the
+// ones conflicting with none selected before and fitting into the bundle gas
+// budget. The remaining ones are appended in the given order, they are still
+// merged if they happen to succeed on top of the selected ones.
+func independentFirst(order []int, bundles []*types.SimulatedBundle, conflicts [][]int) []int {
+	var (
+		picked   = make([]bool, len(bundles))
+		selected = make([]int, 0, len(order))
+		rest     []int
+		gas      uint64
+	)
+	for _, i := range order {
+		ok := gas+bundles[i].BundleGasUsed <= params.BundleGasLimit
+		for _, j := range conflicts[i] {
+			if !ok {
+				break
+			}
+			ok = !picked[j]
+		}
+		if !ok {
+			rest = append(rest, i)
+			continue
+		}
+		picked[i] = true
+		selected = append(selected, i)
+		gas += bundles[i].BundleGasUsed
+	}
+	return append(selected, rest...)
+}
+
+// bundleOrderings generates at most limit distinct orderings of the bundles
+// to evaluate. The first one is the given price order, followed by the
+// conflict-free selections by fees and by price, and then by the selections
+// over random permutations of the conflicting bundles.
+func bundleOrderings(bundles []*types.SimulatedBundle, conflicts [][]int, limit int, seed int64) [][]int {
+	var (
+		orderings [][]int
+		seen      = make(map[string]struct{})
+	)
+	add := func(order []int) {
+		var key strings.Builder
+		for _, i := range order {
+			key.WriteString(strconv.Itoa(i))
+			key.WriteByte(',')
+		}
+		if _, ok := seen[key.String()]; ok {
+			return
+		}
+		seen[key.String()] = struct{}{}
+		orderings = append(orderings, order)
+	}
+	byPrice := make([]int, len(bundles))
+	for i := range byPrice {
+		byPrice[i] = i
+	}
+	add(byPrice)
+
+	byFees := append([]int(nil), byPrice...)
+	slices.SortStableFunc(byFees, func(i, j int) int {
+		return bundles[j].BundleGasFees.Cmp(bundles[i].BundleGasFees)
+	})
+	add(independentFirst(byFees, bundles, conflicts))
+	add(independentFirst(byPrice, bundles, conflicts))
+
+	// Shuffle the positions of the conflicting bundles only, the order of the
+	// independent ones makes no difference
+	var positions []int
+	for i := range bundles {
+		if len(conflicts[i]) > 0 {
+			positions = append(positions, i)
+		}
+	}
+	rng := rand.New(rand.NewSource(seed))
+	for attempts := 0; len(orderings) < limit && attempts < 2*limit; attempts++ {
+		order := append([]int(nil), byPrice...)
+		perm := rng.Perm(len(positions))
+		for k, p := range perm {
+			order[positions[k]] = positions[p]
+		}
+		add(independentFirst(order, bundles, conflicts))
+	}
+	if len(orderings) > limit {
+		orderings = orderings[:limit]
+	}
+	return orderings
+}
+
+// evaluateBundles merges the bundles in the given order on a copy of the state,
+// the same way as mergeBundles but without pruning or reporting them, and
+// returns the fees captured.
+func (w *worker) evaluateBundles(env *environment, bundles []*types.SimulatedBundle) *big.Int {
+	var (
+		currentState = env.state.Copy()
+		gasPool      = prepareGasPool()
+		txCount      int
+		fees         = new(big.Int)
+	)
+	for _, bundle := range bundles {
+		prevState := currentState.Copy()
+		prevGasPool := new(core.GasPool).AddGas(gasPool.Gas())
+
+		simulatedBundle, err := w.simulateBundle(env, bundle.OriginalBundle, currentState, gasPool, txCount, false, false, nil)
+		if err != nil && errors.Is(err, core.ErrGasLimitReached) {
+			break
+		}
+		if err != nil || simulatedBundle.BundleGasPrice.Cmp(mergeFloorGasPrice(bundle)) <= 0 {
+			currentState = prevState
+			gasPool = prevGasPool
+			continue
+		}
+		fees.Add(fees, simulatedBundle.BundleGasFees)
+		txCount += len(bundle.OriginalBundle.Txs)
+	}
+	return fees
+}
+
+// searchBundles searches the order of the bundles capturing the most fees. The
+// greedy price order lets a conflicting bundle invalidate later ones paying
+// more in total, so a bounded number of orderings selecting non-conflicting
+// bundles within the gas budget are evaluated, until the time budget runs out.
+// The bundles are returned in the best order found, the price order if none
+// captures more.
+func (w *worker) searchBundles(env *environment, bundles []*types.SimulatedBundle) []*types.SimulatedBundle {
+	if len(bundles) < 2 || w.config.Mev.MevBundleMergeOrderings < 2 {
+		return bundles
+	}
+	conflicts, found := bundleConflicts(env.signer, bundles)
+	if !found {
+		var gas uint64
+		for _, bundle := range bundles {
+			gas += bundle.BundleGasUsed
+		}
+		// Every bundle is merged whatever the order
+		if gas <= params.BundleGasLimit {
+			return bundles
+		}
+	}
+	var (
+		start     = time.Now()
+		deadline  = start.Add(w.config.Mev.MevBundleMergeBudget)
+		orderings = bundleOrderings(bundles, conflicts, w.config.Mev.MevBundleMergeOrderings, env.header.Number.Int64())
+
+		greedy, best *big.Int
+		bestOrder    []int
+		evaluated    int
+	)
+	for _, order := range orderings {
+		if evaluated > 0 && time.Now().After(deadline) {
+			break
+		}
+		ordered := make([]*types.SimulatedBundle, len(order))
+		for i, idx := range order {
+			ordered[i] = bundles[idx]
+		}
+		fees := w.evaluateBundles(env, ordered)
+		if greedy == nil {
+			greedy = fees
+		}
+		if best == nil || fees.Cmp(best) > 0 {
+			best, bestOrder = fees, order
+		}
+		evaluated++
+	}
+	bundleMergeSearchTimer.UpdateSince(start)
+	bundleMergeOrderingsHist.Update(int64(evaluated))
+	bundleMergeGreedyFeesCounter.Inc(new(big.Int).Div(greedy, big.NewInt(params.GWei)).Int64())
+	bundleMergeSearchFeesCounter.Inc(new(big.Int).Div(best, big.NewInt(params.GWei)).Int64())
+
+	if best.Cmp(greedy) <= 0 {
+		return bundles
+	}
+	bundleMergeImprovedMeter.Mark(1)
+	log.Debug("Found better bundle ordering", "bundles", len(bundles), "orderings", evaluated,
+		"greedy", greedy, "fees", best, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	ordered := make([]*types.SimulatedBundle, len(bestOrder))
+	for i, idx := range bestOrder {
+		ordered[i] = bundles[idx]
+	}
+	return ordered
+}
@@ -0,0 +1,108 @@
+package miner
+
+import (
+	"bytes"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func TestSearchBundles(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		other  = common.Address{0xaa}
+		signer = types.LatestSigner(params.TestChainConfig)
+		config = *testConfig
+	)
+	config.Mev = DefaultMevConfig
+	config.Mev.MevBundleMergeStrategy = BundleMergeSearch
+	config.Mev.MevBundleMergeBudget = time.Second
+
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), db, 0, &config, nil)
+	defer w.close()
+
+	parent := b.chain.CurrentBlock()
+	state, err := b.chain.StateAt(parent.Root)
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	state.AddBalance(sender, uint256.NewInt(params.Ether))
+	env := &environment{
+		header: &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 1,
+			Coinbase:   testBankAddress,
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+			Difficulty: common.Big1,
+		},
+		state:   state,
+		signer:  signer,
+		gasPool: prepareGasPool(),
+	}
+	// Both bundles spend the same nonce, the cheap one pays a higher price but
+	// less fees in total
+	var (
+		cheap = &types.Bundle{Txs: types.Transactions{types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &other,
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(20 * params.GWei),
+		})}}
+		rich = &types.Bundle{Txs: types.Transactions{types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &other,
+			Gas:      200000,
+			GasPrice: big.NewInt(5 * params.GWei),
+			Data:     bytes.Repeat([]byte{0xff}, 5000),
+		})}}
+		independent = &types.Bundle{Txs: types.Transactions{types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &other,
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(10 * params.GWei),
+		})}}
+	)
+	simulated, err := w.simulateBundles(env, []*types.Bundle{cheap, rich, independent})
+	if err != nil || len(simulated) != 3 {
+		t.Fatalf("failed to simulate bundles: %v, %d simulated", err, len(simulated))
+	}
+	slices.SortStableFunc(simulated, func(i, j *types.SimulatedBundle) int {
+		return j.BundleGasPrice.Cmp(i.BundleGasPrice)
+	})
+	if simulated[0].OriginalBundle != cheap {
+		t.Fatalf("price order mismatch")
+	}
+	conflicts, found := bundleConflicts(signer, simulated)
+	if !found || len(conflicts[0]) != 1 || len(conflicts[1]) != 0 || len(conflicts[2]) != 1 {
+		t.Fatalf("conflicts mismatch: %v", conflicts)
+	}
+	greedy := w.evaluateBundles(env, simulated)
+
+	searched := w.searchBundles(env, simulated)
+	if searched[0].OriginalBundle != rich {
+		t.Fatalf("searched order mismatch: first bundle %x, want %x", searched[0].OriginalBundle.Hash(), rich.Hash())
+	}
+	fees := w.evaluateBundles(env, searched)
+	want := new(big.Int).Add(simulated[1].BundleGasFees, simulated[2].BundleGasFees)
+	if fees.Cmp(want) != 0 || fees.Cmp(greedy) <= 0 {
+		t.Fatalf("fees mismatch: have %v, want %v, greedy %v", fees, want, greedy)
+	}
+	// Without conflicts the order is left alone
+	alone := []*types.SimulatedBundle{simulated[0], simulated[1]}
+	if searched := w.searchBundles(env, alone); searched[0] != alone[0] {
+		t.Errorf("independent bundles reordered")
+	}
+}
@@ -0,0 +1,362 @@
+package miner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/naoina/toml"
+)
+
+const (
+	gaslessPolicyReload = 3 * time.Second // Interval to check the policy file for changes
+	gaslessQuotaWindow  = time.Hour       // Window of the hourly sender quota
+)
+
+var (
+	errGaslessPolicyUnavailable = errors.New("gasless policy unavailable")
+	errGaslessTargetNotAllowed  = errors.New("gasless tx target not allowed")
+	errGaslessMethodNotAllowed  = errors.New("gasless tx method not allowed")
+	errGaslessBlockQuota        = errors.New("gasless sender block quota exceeded")
+	errGaslessHourlyQuota       = errors.New("gasless sender hourly quota exceeded")
+	errGaslessBlockGas          = errors.New("gasless block gas exceeded")
+)
+
+// GaslessTarget is a contract the gasless transactions may call.
+type GaslessTarget struct {
+	Address common.Address  `json:"address" toml:"address"`
+	Methods []hexutil.Bytes `json:"methods" toml:"methods"` // 4-byte selectors, any method if empty
+}
+
+// GaslessPolicyConfig is the sponsorship policy of the gasless transactions,
+// the ones with zero gas fee caps, loaded from a TOML or JSON file. The zero
+// limits are unlimited.
+type GaslessPolicyConfig struct {
+	Targets           []GaslessTarget `json:"targets" toml:"targets"`                     // Allowed targets, any if empty
+	SenderBlockQuota  uint64          `json:"senderBlockQuota" toml:"senderBlockQuota"`   // Gasless txs per sender in a block
+	SenderHourlyQuota uint64          `json:"senderHourlyQuota" toml:"senderHourlyQuota"` // Gasless txs per sender admitted in an hour
+	MaxBlockGas       uint64          `json:"maxBlockGas" toml:"maxBlockGas"`             // Sponsored gas in a block
+}
+
+// gaslessRules is a loaded policy, with the targets indexed.
+type gaslessRules struct {
+	config  GaslessPolicyConfig
+	targets map[common.Address]map[[4]byte]struct{} // nil selectors for any method
+}
+
+// loadGaslessRules parses the policy file, as JSON if it has the .json
+// extension and as TOML otherwise.
+func loadGaslessRules(path string) (*gaslessRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config GaslessPolicyConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &config)
+	} else {
+		err = toml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules := &gaslessRules{config: config, targets: make(map[common.Address]map[[4]byte]struct{})}
+	for _, target := range config.Targets {
+		if len(target.Methods) == 0 {
+			rules.targets[target.Address] = nil
+			continue
+		}
+		selectors := rules.targets[target.Address]
+		if selectors == nil {
+			selectors = make(map[[4]byte]struct{})
+			rules.targets[target.Address] = selectors
+		}
+		for _, method := range target.Methods {
+			if len(method) != 4 {
+				return nil, fmt.Errorf("invalid method selector %v of target %v", method, target.Address)
+			}
+			selectors[[4]byte(method)] = struct{}{}
+		}
+	}
+	return rules, nil
+}
+
+// isGasless reports whether the gas of the transaction is sponsored.
+func isGasless(tx *types.Transaction) bool {
+	return tx.GasFeeCapIntCmp(common.Big0) == 0 && tx.GasTipCapIntCmp(common.Big0) == 0
+}
+
+// gaslessPolicy enforces the sponsorship policy of the gasless transactions,
+// both when the bundles are admitted and when they are merged into a block.
+// The policy file is reloaded when modified, the policy in use is kept if the
+// new one fails to load.
+type gaslessPolicy struct {
+	path    string
+	rules   *gaslessRules // nil if the policy never loaded, rejecting every gasless tx
+	modTime time.Time
+	lock    sync.RWMutex
+
+	admitted  map[common.Address][]time.Time // Admission times of the gasless txs in the quota window
+	quotaLock sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newGaslessPolicy loads the policy file and starts watching it for changes.
+func newGaslessPolicy(path string) *gaslessPolicy {
+	p := &gaslessPolicy{
+		path:     path,
+		admitted: make(map[common.Address][]time.Time),
+		quit:     make(chan struct{}),
+	}
+	if err := p.reload(); err != nil {
+		log.Error("Failed to load gasless policy, rejecting gasless transactions", "path", path, "err", err)
+	}
+	p.wg.Add(1)
+	go p.loop()
+	return p
+}
+
+// reload loads the policy file if it was modified since the last load.
+func (p *gaslessPolicy) reload() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	p.lock.RLock()
+	unchanged := p.rules != nil && info.ModTime().Equal(p.modTime)
+	p.lock.RUnlock()
+	if unchanged {
+		return nil
+	}
+	rules, err := loadGaslessRules(p.path)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.rules, p.modTime = rules, info.ModTime()
+	p.lock.Unlock()
+
+	log.Info("Loaded gasless policy", "path", p.path, "targets", len(rules.targets),
+		"blockQuota", rules.config.SenderBlockQuota, "hourlyQuota", rules.config.SenderHourlyQuota, "maxBlockGas", rules.config.MaxBlockGas)
+	return nil
+}
+
+// loop reloads the policy file on changes, and forgets the admissions out of
+// the quota window.
+func (p *gaslessPolicy) loop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(gaslessPolicyReload)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.quit:
+			return
+
+		case <-ticker.C:
+			if err := p.reload(); err != nil {
+				log.Warn("Failed to reload gasless policy", "path", p.path, "err", err)
+			}
+			p.quotaLock.Lock()
+			now := time.Now()
+			for sender := range p.admitted {
+				p.expire(sender, now)
+			}
+			p.quotaLock.Unlock()
+		}
+	}
+}
+
+func (p *gaslessPolicy) close() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+func (p *gaslessPolicy) current() *gaslessRules {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.rules
+}
+
+// expire drops the admissions of the sender out of the quota window. The
+// quota lock is held by the caller.
+func (p *gaslessPolicy) expire(sender common.Address, now time.Time) {
+	times := p.admitted[sender]
+	for len(times) > 0 && now.Sub(times[0]) >= gaslessQuotaWindow {
+		times = times[1:]
+	}
+	if len(times) == 0 {
+		delete(p.admitted, sender)
+	} else {
+		p.admitted[sender] = times
+	}
+}
+
+// check verifies the gasless transactions of the bundle against the targets
+// and the block quota, returning the number of gasless transactions of each
+// sender.
+func (p *gaslessPolicy) check(signer types.Signer, bundle *types.Bundle) (map[common.Address]uint64, error) {
+	var (
+		rules   = p.current()
+		senders = make(map[common.Address]uint64)
+	)
+	for _, tx := range bundle.Txs {
+		if !isGasless(tx) {
+			continue
+		}
+		if rules == nil {
+			return nil, errGaslessPolicyUnavailable
+		}
+		if err := rules.allow(tx); err != nil {
+			return nil, fmt.Errorf("%w: tx %v", err, tx.Hash())
+		}
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		senders[sender]++
+		if quota := rules.config.SenderBlockQuota; quota != 0 && senders[sender] > quota {
+			return nil, fmt.Errorf("%w: sender %v, quota %d", errGaslessBlockQuota, sender, quota)
+		}
+	}
+	return senders, nil
+}
+
+// allow checks the target and the method called by the gasless transaction.
+func (rules *gaslessRules) allow(tx *types.Transaction) error {
+	if len(rules.targets) == 0 {
+		return nil
+	}
+	to := tx.To()
+	if to == nil {
+		return errGaslessTargetNotAllowed
+	}
+	selectors, ok := rules.targets[*to]
+	if !ok {
+		return fmt.Errorf("%w: %v", errGaslessTargetNotAllowed, *to)
+	}
+	if selectors == nil {
+		return nil
+	}
+	if data := tx.Data(); len(data) >= 4 {
+		if _, ok := selectors[[4]byte(data[:4])]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %v", errGaslessMethodNotAllowed, *to)
+}
+
+// admit checks the bundle, along with its sponsored gas against the block limit
+// and its gasless transactions against the hourly quota of their senders. The
+// quota is only charged by charge, once the bundle is accepted.
+func (p *gaslessPolicy) admit(signer types.Signer, bundle *types.Bundle, sponsoredGas uint64) error {
+	senders, err := p.check(signer, bundle)
+	if err != nil || len(senders) == 0 {
+		return err
+	}
+	rules := p.current()
+	if limit := rules.config.MaxBlockGas; limit != 0 && sponsoredGas > limit {
+		return fmt.Errorf("%w: gas %d, limit %d", errGaslessBlockGas, sponsoredGas, limit)
+	}
+	p.quotaLock.Lock()
+	defer p.quotaLock.Unlock()
+
+	return p.checkQuota(rules, senders, time.Now())
+}
+
+// charge charges the gasless transactions of the accepted bundle against the
+// hourly quota of their senders, leaving it untouched if the bundle exceeds it
+// or in a dry run.
+func (p *gaslessPolicy) charge(signer types.Signer, bundle *types.Bundle, dryRun bool) error {
+	senders, err := p.check(signer, bundle)
+	if err != nil || len(senders) == 0 {
+		return err
+	}
+	rules := p.current()
+	if rules.config.SenderHourlyQuota == 0 {
+		return nil
+	}
+	p.quotaLock.Lock()
+	defer p.quotaLock.Unlock()
+
+	now := time.Now()
+	if err := p.checkQuota(rules, senders, now); err != nil || dryRun {
+		return err
+	}
+	for sender, n := range senders {
+		for i := uint64(0); i < n; i++ {
+			p.admitted[sender] = append(p.admitted[sender], now)
+		}
+	}
+	return nil
+}
+
+// checkQuota checks the gasless transactions of each sender against the hourly
+// quota. The quota lock is held by the caller.
+func (p *gaslessPolicy) checkQuota(rules *gaslessRules, senders map[common.Address]uint64, now time.Time) error {
+	quota := rules.config.SenderHourlyQuota
+	if quota == 0 {
+		return nil
+	}
+	for sender, n := range senders {
+		p.expire(sender, now)
+		if used := uint64(len(p.admitted[sender])); used+n > quota {
+			return fmt.Errorf("%w: sender %v, used %d, quota %d", errGaslessHourlyQuota, sender, used, quota)
+		}
+	}
+	return nil
+}
+
+// blockBudget returns the budget of the gasless transactions in a block, nil
+// if the policy is not enforced.
+func (p *gaslessPolicy) blockBudget() *gaslessBudget {
+	if p == nil {
+		return nil
+	}
+	return &gaslessBudget{policy: p, senders: make(map[common.Address]uint64)}
+}
+
+// gaslessBudget tracks the gasless transactions merged into a block, against
+// the per-sender block quota and the sponsored gas limit.
+type gaslessBudget struct {
+	policy  *gaslessPolicy
+	senders map[common.Address]uint64
+	gas     uint64
+}
+
+// consume charges the gasless transactions of the merged bundle to the budget,
+// leaving it untouched if the bundle exceeds it.
+func (b *gaslessBudget) consume(signer types.Signer, bundle *types.Bundle, sponsoredGas uint64) error {
+	senders, err := b.policy.check(signer, bundle)
+	if err != nil || len(senders) == 0 {
+		return err
+	}
+	config := b.policy.current().config
+	if limit := config.MaxBlockGas; limit != 0 && b.gas+sponsoredGas > limit {
+		return fmt.Errorf("%w: gas %d, limit %d", errGaslessBlockGas, b.gas+sponsoredGas, limit)
+	}
+	if quota := config.SenderBlockQuota; quota != 0 {
+		for sender, n := range senders {
+			if b.senders[sender]+n > quota {
+				return fmt.Errorf("%w: sender %v, quota %d", errGaslessBlockQuota, sender, quota)
+			}
+		}
+	}
+	for sender, n := range senders {
+		b.senders[sender] += n
+	}
+	b.gas += sponsoredGas
+	return nil
+}
@@ -0,0 +1,139 @@
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestGaslessPolicy(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		token   = common.Address{0x70}
+		other   = common.Address{0x0e}
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+	)
+	newTx := func(key, nonce int, to common.Address, data []byte, gasPrice int64) *types.Transaction {
+		k := key1
+		if key == 2 {
+			k = key2
+		}
+		return types.MustSignNewTx(k, signer, &types.LegacyTx{
+			Nonce:    uint64(nonce),
+			To:       &to,
+			Gas:      100000,
+			GasPrice: big.NewInt(gasPrice),
+			Data:     data,
+		})
+	}
+	transfer := []byte{0xa9, 0x05, 0x9c, 0xbb, 0x01}
+
+	path := filepath.Join(dir, "policy.toml")
+	if err := os.WriteFile(path, []byte(`
+senderBlockQuota = 2
+senderHourlyQuota = 3
+maxBlockGas = 50000
+
+[[targets]]
+address = "0x7000000000000000000000000000000000000000"
+methods = ["0xa9059cbb"]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy := newGaslessPolicy(path)
+	defer policy.close()
+
+	// The targets and methods are allow-listed, the paying txs are not checked
+	tests := []struct {
+		txs types.Transactions
+		err error
+	}{
+		{types.Transactions{newTx(1, 0, token, transfer, 0)}, nil},
+		{types.Transactions{newTx(1, 0, other, transfer, 1)}, nil},
+		{types.Transactions{newTx(1, 0, other, transfer, 0)}, errGaslessTargetNotAllowed},
+		{types.Transactions{newTx(1, 0, token, []byte{0x01, 0x02, 0x03, 0x04}, 0)}, errGaslessMethodNotAllowed},
+		{types.Transactions{newTx(1, 0, token, transfer, 0), newTx(1, 1, token, transfer, 0), newTx(1, 2, token, transfer, 0)}, errGaslessBlockQuota},
+	}
+	for i, tt := range tests {
+		if _, err := policy.check(signer, &types.Bundle{Txs: tt.txs}); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+
+	// The accepted gasless txs are charged against the hourly quota, which is
+	// only checked by the admission
+	pair := &types.Bundle{Txs: types.Transactions{newTx(1, 0, token, transfer, 0), newTx(1, 1, token, transfer, 0)}}
+	if err := policy.admit(signer, pair, 60000); !errors.Is(err, errGaslessBlockGas) {
+		t.Errorf("block gas error mismatch: have %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := policy.admit(signer, pair, 40000); err != nil {
+			t.Fatalf("failed to admit bundle: %v", err)
+		}
+	}
+	if err := policy.charge(signer, pair, true); err != nil {
+		t.Fatalf("failed to check bundle charge: %v", err)
+	}
+	if err := policy.charge(signer, pair, false); err != nil {
+		t.Fatalf("failed to charge bundle: %v", err)
+	}
+	if err := policy.admit(signer, pair, 40000); !errors.Is(err, errGaslessHourlyQuota) {
+		t.Errorf("hourly quota error mismatch: have %v", err)
+	}
+	if err := policy.charge(signer, pair, true); !errors.Is(err, errGaslessHourlyQuota) {
+		t.Errorf("hourly quota charge error mismatch: have %v", err)
+	}
+	single := &types.Bundle{Txs: types.Transactions{newTx(1, 2, token, transfer, 0)}}
+	if err := policy.admit(signer, single, 20000); err != nil {
+		t.Errorf("failed to admit bundle within quota: %v", err)
+	}
+	if err := policy.charge(signer, single, false); err != nil {
+		t.Errorf("failed to charge bundle within quota: %v", err)
+	}
+	if err := policy.charge(signer, single, false); !errors.Is(err, errGaslessHourlyQuota) {
+		t.Errorf("hourly quota charge error mismatch: have %v", err)
+	}
+
+	// The block budget accumulates over the merged bundles
+	budget := policy.blockBudget()
+	if err := budget.consume(signer, &types.Bundle{Txs: types.Transactions{newTx(2, 0, token, transfer, 0)}}, 30000); err != nil {
+		t.Fatalf("failed to consume budget: %v", err)
+	}
+	if err := budget.consume(signer, &types.Bundle{Txs: types.Transactions{newTx(1, 0, token, transfer, 0)}}, 30000); !errors.Is(err, errGaslessBlockGas) {
+		t.Errorf("block gas budget error mismatch: have %v", err)
+	}
+	if err := budget.consume(signer, &types.Bundle{Txs: types.Transactions{newTx(2, 1, token, transfer, 0), newTx(2, 2, token, transfer, 0)}}, 10000); !errors.Is(err, errGaslessBlockQuota) {
+		t.Errorf("block quota budget error mismatch: have %v", err)
+	}
+
+	// The JSON policy is loaded, and the broken modification is ignored
+	json := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(json, []byte(`{"targets": [{"address": "0x0e00000000000000000000000000000000000000"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy = newGaslessPolicy(json)
+	defer policy.close()
+	if _, err := policy.check(signer, &types.Bundle{Txs: types.Transactions{newTx(1, 0, other, nil, 0)}}); err != nil {
+		t.Errorf("reloaded policy rejected bundle: %v", err)
+	}
+	if err := os.WriteFile(json, []byte(`{"targets": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(json, time.Now(), time.Now().Add(time.Minute))
+	if err := policy.reload(); err == nil {
+		t.Errorf("broken policy loaded")
+	}
+	if _, err := policy.check(signer, &types.Bundle{Txs: types.Transactions{newTx(1, 0, token, transfer, 0)}}); !errors.Is(err, errGaslessTargetNotAllowed) {
+		t.Errorf("previous policy not kept: %v", err)
+	}
+}
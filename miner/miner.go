@@ -72,16 +72,30 @@ var (
 
 var defaultCoinBaseAddress = common.HexToAddress("0x4200000000000000000000000000000000000011")
 
+// The strategies to merge the simulated bundles into the block by.
+const (
+	BundleMergeGreedy = "greedy" // merge by the simulated gas price
+	BundleMergeSearch = "search" // search the orderings capturing the most fees
+)
+
 type MevConfig struct {
-	MevEnabled             bool     // Whether to enable Mev or not
-	MevReceivers           []string // The list of Mev bundle receivers
-	MevBundleGasPriceFloor int64    // The minimal bundle gas Price
+	MevEnabled              bool              // Whether to enable Mev or not
+	MevReceivers            []string          // The list of Mev bundle receivers
+	MevReceiverKey          *ecdsa.PrivateKey `toml:"-"` // Key to sign the requests to the bundle receivers with
+	MevBundleGasPriceFloor  int64             // The minimal bundle gas Price
+	MevBundleMergeStrategy  string            // The strategy to merge the bundles by
+	MevBundleMergeOrderings int               // Maximum number of bundle orderings evaluated by the search
+	MevBundleMergeBudget    time.Duration     // Time allowance of the bundle merge search
+	MevGaslessPolicy        string            // Sponsorship policy file of the gasless txs, unrestricted if empty
 }
 
 var DefaultMevConfig = MevConfig{
-	MevEnabled:             false,
-	MevReceivers:           nil,
-	MevBundleGasPriceFloor: 1,
+	MevEnabled:              false,
+	MevReceivers:            nil,
+	MevBundleGasPriceFloor:  1,
+	MevBundleMergeStrategy:  BundleMergeGreedy,
+	MevBundleMergeOrderings: 16,
+	MevBundleMergeBudget:    100 * time.Millisecond,
 }
 
 // Backend wraps all methods required for mining. Only full node is capable
@@ -112,6 +126,10 @@ type Config struct {
 	Mev MevConfig // Mev configuration
 
 	ParallelTxDAGSenderPriv *ecdsa.PrivateKey // The private key for the parallel tx DAG sender
+	ParallelTxDAGSidecar    bool              // Whether to keep the TxDAG as a block sidecar instead of a transaction
+
+	ParallelTxDAGOrdering          bool   // Whether to order the txs to shorten the critical path of the TxDAG
+	ParallelTxDAGOrderingTolerance uint64 // The percentage of the best tip the TxDAG ordering may give up
 }
 
 // DefaultConfig contains default settings for miner.
@@ -127,6 +145,8 @@ var DefaultConfig = Config{
 	NewPayloadTimeout: 2 * time.Second,
 
 	Mev: DefaultMevConfig,
+
+	ParallelTxDAGOrderingTolerance: 5,
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -318,6 +338,13 @@ func (miner *Miner) SimulateBundle(bundle *types.Bundle) (*big.Int, error) {
 		return nil, err
 	}
 
+	gasless := miner.worker.gasless
+	if gasless != nil {
+		if _, err := gasless.check(env.signer, bundle); err != nil {
+			return nil, err
+		}
+	}
+
 	s, err := miner.worker.simulateBundles(env, []*types.Bundle{bundle})
 	if err != nil {
 		return nil, err
@@ -327,9 +354,27 @@ func (miner *Miner) SimulateBundle(bundle *types.Bundle) (*big.Int, error) {
 		return nil, errors.New("no valid sim result")
 	}
 
+	if gasless != nil {
+		if err := gasless.admit(env.signer, bundle, s[0].SponsoredGas); err != nil {
+			return nil, err
+		}
+	}
+
 	return s[0].BundleGasPrice, nil
 }
 
+// ChargeBundle charges the gasless transactions of the bundle accepted into the
+// bundle pool against the hourly quota of their senders, only checking it in a
+// dry run.
+func (miner *Miner) ChargeBundle(bundle *types.Bundle, dryRun bool) error {
+	gasless := miner.worker.gasless
+	if gasless == nil {
+		return nil
+	}
+	signer := types.LatestSigner(miner.worker.chainConfig)
+	return gasless.charge(signer, bundle, dryRun)
+}
+
 func (miner *Miner) SimulateGaslessBundle(bundle *types.Bundle) (*types.SimulateGaslessBundleResp, error) {
 
 	env, err := miner.prepareSimulationEnv()
@@ -337,6 +382,12 @@ func (miner *Miner) SimulateGaslessBundle(bundle *types.Bundle) (*types.Simulate
 		return nil, err
 	}
 
+	if gasless := miner.worker.gasless; gasless != nil {
+		if _, err := gasless.check(env.signer, bundle); err != nil {
+			return nil, err
+		}
+	}
+
 	resp, err := miner.worker.simulateGaslessBundle(env, bundle)
 	if err != nil {
 		return nil, err
@@ -347,6 +398,16 @@ func (miner *Miner) SimulateGaslessBundle(bundle *types.Bundle) (*types.Simulate
 
 func (miner *Miner) prepareSimulationEnv() (*environment, error) {
 	parent := miner.eth.BlockChain().CurrentBlock()
+	state, err := miner.eth.BlockChain().StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	return miner.prepareSimulationEnvAt(parent, state.Copy())
+}
+
+// prepareSimulationEnvAt prepares the environment of a block on top of the
+// given parent, simulating on the given state.
+func (miner *Miner) prepareSimulationEnvAt(parent *types.Header, state *state.StateDB) (*environment, error) {
 	timestamp := int64(parent.Time + 1)
 
 	header := &types.Header{
@@ -385,14 +446,9 @@ func (miner *Miner) prepareSimulationEnv() (*environment, error) {
 		return nil, err
 	}
 
-	state, err := miner.eth.BlockChain().StateAt(parent.Root)
-	if err != nil {
-		return nil, err
-	}
-
 	env := &environment{
 		header:  header,
-		state:   state.Copy(),
+		state:   state,
 		signer:  types.MakeSigner(miner.worker.chainConfig, header.Number, header.Time),
 		gasPool: prepareGasPool(),
 	}
@@ -91,6 +91,9 @@ type transactionsByPriceAndNonce struct {
 	heads   txByPriceAndTime                             // Next transaction for each unique account (price heap)
 	signer  types.Signer                                 // Signer for the set of transactions
 	baseFee *uint256.Int                                 // Current base fee
+
+	dag      *txDAGOrdering // Optional ordering to shorten the critical path of the TxDAG
+	selected int            // Index of the head returned by the last Peek
 }
 
 // newTransactionsByPriceAndNonce creates a transaction set that can retrieve
@@ -126,32 +129,55 @@ func newTransactionsByPriceAndNonce(signer types.Signer, txs map[common.Address]
 	}
 }
 
-// Peek returns the next transaction by price.
+// withTxDAGOrdering makes the set pick the transactions by the TxDAG ordering
+// within its fee tolerance, instead of strictly by price.
+func (t *transactionsByPriceAndNonce) withTxDAGOrdering(dag *txDAGOrdering) *transactionsByPriceAndNonce {
+	t.dag = dag
+	return t
+}
+
+// Peek returns the next transaction by price, or the one picked by the TxDAG
+// ordering if configured.
 func (t *transactionsByPriceAndNonce) Peek() (*txpool.LazyTransaction, *uint256.Int) {
 	if len(t.heads) == 0 {
 		return nil, nil
 	}
-	return t.heads[0].tx, t.heads[0].fees
+	t.selected = 0
+	if t.dag != nil {
+		t.selected = t.dag.pick(t.heads)
+	}
+	return t.heads[t.selected].tx, t.heads[t.selected].fees
+}
+
+// Included records the state accessed by the peeked transaction which has been
+// included into the block. It's only needed by the TxDAG ordering.
+func (t *transactionsByPriceAndNonce) Included(tx *types.Transaction, accesses types.AccessList) {
+	if t.dag != nil && len(t.heads) > 0 {
+		t.dag.include(t.heads[t.selected].from, tx, accesses)
+	}
 }
 
 // Shift replaces the current best head with the next one from the same account.
 func (t *transactionsByPriceAndNonce) Shift() {
-	acc := t.heads[0].from
+	acc := t.heads[t.selected].from
 	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
 		if wrapped, err := newTxWithMinerFee(txs[0], acc, t.baseFee); err == nil {
-			t.heads[0], t.txs[acc] = wrapped, txs[1:]
-			heap.Fix(&t.heads, 0)
+			t.heads[t.selected], t.txs[acc] = wrapped, txs[1:]
+			heap.Fix(&t.heads, t.selected)
+			t.selected = 0
 			return
 		}
 	}
-	heap.Pop(&t.heads)
+	heap.Remove(&t.heads, t.selected)
+	t.selected = 0
 }
 
 // Pop removes the best transaction, *not* replacing it with the next one from
 // the same account. This should be used when a transaction cannot be executed
 // and hence all subsequent ones should be discarded from the same account.
 func (t *transactionsByPriceAndNonce) Pop() {
-	heap.Pop(&t.heads)
+	heap.Remove(&t.heads, t.selected)
+	t.selected = 0
 }
 
 // Empty returns if the price heap is empty. It can be used to check it simpler
@@ -162,5 +188,5 @@ func (t *transactionsByPriceAndNonce) Empty() bool {
 
 // Clear removes the entire content of the heap.
 func (t *transactionsByPriceAndNonce) Clear() {
-	t.heads, t.txs = nil, nil
+	t.heads, t.txs, t.selected = nil, nil, 0
 }
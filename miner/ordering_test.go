@@ -194,3 +194,76 @@ func TestTransactionTimeSort(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the TxDAG ordering interleaves the independent transactions ahead
+// of the conflicting ones within the fee tolerance, and keeps the fee order
+// beyond it.
+func TestTransactionTxDAGOrdering(t *testing.T) {
+	t.Parallel()
+
+	var (
+		signer   = types.HomesteadSigner{}
+		contract = common.HexToAddress("0xc0de")
+		slot     = common.HexToHash("0x01")
+		accesses = types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot}}}
+	)
+	// Make the slot known to be shared by the callers of the contract
+	tracker := newTxAccessTracker()
+	tracker.record(common.HexToAddress("0xa1"), types.NewTransaction(0, contract, common.Big0, 0, common.Big0, nil), accesses)
+	tracker.record(common.HexToAddress("0xa2"), types.NewTransaction(0, contract, common.Big0, 0, common.Big0, nil), accesses)
+
+	// 3 calls to the contract, 3 transfers at a slightly lower price and a cheap one
+	prices := []int64{100, 100, 100, 98, 98, 98, 50}
+	newTxs := func() map[common.Address][]*txpool.LazyTransaction {
+		groups := make(map[common.Address][]*txpool.LazyTransaction)
+		for i, price := range prices {
+			key, _ := crypto.GenerateKey()
+			to := contract
+			if i >= 3 {
+				to = common.BigToAddress(big.NewInt(int64(0x1000 + i)))
+			}
+			tx, _ := types.SignTx(types.NewTransaction(0, to, big.NewInt(1), 100000, big.NewInt(price), nil), signer, key)
+			tx.SetTime(time.Unix(0, int64(i)))
+			groups[crypto.PubkeyToAddress(key.PublicKey)] = []*txpool.LazyTransaction{{
+				Hash:      tx.Hash(),
+				Tx:        tx,
+				Time:      tx.Time(),
+				GasFeeCap: uint256.MustFromBig(tx.GasFeeCap()),
+				GasTipCap: uint256.MustFromBig(tx.GasTipCap()),
+				Gas:       tx.Gas(),
+			}}
+		}
+		return groups
+	}
+	var tests = []struct {
+		tolerance uint64
+		want      []int64
+	}{
+		{tolerance: 0, want: []int64{100, 100, 100, 98, 98, 98, 50}},
+		{tolerance: 5, want: []int64{100, 98, 98, 98, 100, 100, 50}},
+		{tolerance: 100, want: []int64{100, 98, 98, 98, 50, 100, 100}},
+	}
+	for _, tt := range tests {
+		txset := newTransactionsByPriceAndNonce(signer, newTxs(), nil).withTxDAGOrdering(newTxDAGOrdering(tracker, tt.tolerance))
+
+		var have []int64
+		for ltx, _ := txset.Peek(); ltx != nil; ltx, _ = txset.Peek() {
+			tx := ltx.Resolve()
+			have = append(have, tx.GasPrice().Int64())
+			if *tx.To() == contract {
+				txset.Included(tx, accesses)
+			} else {
+				txset.Included(tx, nil)
+			}
+			txset.Shift()
+		}
+		if len(have) != len(tt.want) {
+			t.Fatalf("tolerance %d: transaction count mismatch, have %d, want %d", tt.tolerance, len(have), len(tt.want))
+		}
+		for i := range have {
+			if have[i] != tt.want[i] {
+				t.Fatalf("tolerance %d: ordering mismatch, have %v, want %v", tt.tolerance, have, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/holiman/uint256"
+)
+
+const (
+	txDAGOrderingCandidates = 16   // Maximum number of heads evaluated on each pick
+	txAccessProfiles        = 4096 // Number of contracts and senders whose accesses are remembered
+	txAccessProfileSlots    = 1024 // Maximum number of slots tracked per profile before resetting
+)
+
+var (
+	txDAGOrderingPickMeter    = metrics.NewRegisteredMeter("miner/txdag/ordering/pick", nil)
+	txDAGOrderingReorderMeter = metrics.NewRegisteredMeter("miner/txdag/ordering/reorder", nil)
+)
+
+// txAccessKey is a piece of state the transactions may conflict on, either an
+// account or a storage slot of it.
+type txAccessKey struct {
+	addr    common.Address
+	slot    common.Hash
+	account bool
+}
+
+// txAccessProfile is the storage access history of a contract, or of a sender
+// for the contract creations.
+type txAccessProfile struct {
+	seen   map[txAccessKey]common.Address // the sender who accessed the slot
+	shared map[txAccessKey]struct{}       // the slots accessed by different senders
+}
+
+// txAccessTracker remembers the storage slots accessed by the recently executed
+// transactions. The read/write sets are only resolved by MVStates when the TxDAG
+// is generated, so the access lists of the executed transactions are taken as a
+// conservative approximation of the state the pending ones will touch.
+//
+// Only the slots accessed by different senders are used in the prediction, the
+// per-sender ones, e.g. the balances in a token, rarely conflict with others.
+type txAccessTracker struct {
+	profiles lru.BasicLRU[common.Address, *txAccessProfile]
+	lock     sync.Mutex
+}
+
+func newTxAccessTracker() *txAccessTracker {
+	return &txAccessTracker{
+		profiles: lru.NewBasicLRU[common.Address, *txAccessProfile](txAccessProfiles),
+	}
+}
+
+// profileKey returns the address the access history of the tx is kept under.
+func profileKey(from common.Address, tx *types.Transaction) common.Address {
+	if to := tx.To(); to != nil {
+		return *to
+	}
+	return from
+}
+
+// record remembers the storage slots accessed by the executed transaction.
+func (t *txAccessTracker) record(from common.Address, tx *types.Transaction, accesses types.AccessList) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	key := profileKey(from, tx)
+	profile, ok := t.profiles.Get(key)
+	if !ok {
+		profile = &txAccessProfile{
+			seen:   make(map[txAccessKey]common.Address),
+			shared: make(map[txAccessKey]struct{}),
+		}
+		t.profiles.Add(key, profile)
+	}
+	for _, tuple := range accesses {
+		for _, slot := range tuple.StorageKeys {
+			k := txAccessKey{addr: tuple.Address, slot: slot}
+			if _, ok := profile.shared[k]; ok {
+				continue
+			}
+			sender, ok := profile.seen[k]
+			switch {
+			case !ok:
+				if len(profile.seen) >= txAccessProfileSlots {
+					profile.seen = make(map[txAccessKey]common.Address)
+				}
+				profile.seen[k] = from
+			case sender != from:
+				delete(profile.seen, k)
+				if len(profile.shared) < txAccessProfileSlots {
+					profile.shared[k] = struct{}{}
+				}
+			}
+		}
+	}
+}
+
+// predict returns the state the transaction is expected to conflict on, which
+// is its sender, the recipient of the value, the declared access list and the
+// shared slots accessed by the previous calls to the same contract.
+func (t *txAccessTracker) predict(from common.Address, tx *types.Transaction) []txAccessKey {
+	keys := []txAccessKey{{addr: from, account: true}}
+	if to := tx.To(); to != nil && tx.Value().Sign() > 0 {
+		keys = append(keys, txAccessKey{addr: *to, account: true})
+	}
+	for _, tuple := range tx.AccessList() {
+		for _, slot := range tuple.StorageKeys {
+			keys = append(keys, txAccessKey{addr: tuple.Address, slot: slot})
+		}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if profile, ok := t.profiles.Peek(profileKey(from, tx)); ok {
+		for k := range profile.shared {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// txDAGOrdering picks the transactions for a block to shorten the critical path
+// of its TxDAG. Among the heads whose fees are within the tolerance of the best
+// one, the transaction with the shallowest predicted depth is picked first, so
+// the independent transactions are interleaved ahead of the ones waiting for a
+// conflicting predecessor. The fee order is kept beyond the tolerance.
+//
+// An ordering is shared by all the transaction sets filled into the same block.
+type txDAGOrdering struct {
+	tracker   *txAccessTracker
+	tolerance uint64                 // percentage of the best fee that may be given up
+	depths    map[txAccessKey]uint64 // depth of the last included tx touching the key
+}
+
+func newTxDAGOrdering(tracker *txAccessTracker, tolerance uint64) *txDAGOrdering {
+	if tolerance > 100 {
+		tolerance = 100
+	}
+	return &txDAGOrdering{
+		tracker:   tracker,
+		tolerance: tolerance,
+		depths:    make(map[txAccessKey]uint64),
+	}
+}
+
+// depth returns the depth the transaction touching the keys would have.
+func (o *txDAGOrdering) depth(keys []txAccessKey) uint64 {
+	var depth uint64
+	for _, k := range keys {
+		if d := o.depths[k]; d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+// pick returns the index of the head to be included next.
+func (o *txDAGOrdering) pick(heads txByPriceAndTime) int {
+	if len(heads) < 2 {
+		return 0
+	}
+	txDAGOrderingPickMeter.Mark(1)
+
+	// Collect the candidates by walking down the heap, the children never have
+	// higher fees than their parent.
+	var (
+		threshold = new(uint256.Int).Mul(heads[0].fees, uint256.NewInt(100-o.tolerance))
+		queue     = []int{0}
+		best      = -1
+		bestDepth uint64
+	)
+	threshold.Div(threshold, uint256.NewInt(100))
+	for n := 0; len(queue) > 0 && n < txDAGOrderingCandidates; n++ {
+		i := queue[0]
+		queue = queue[1:]
+		if heads[i].fees.Lt(threshold) {
+			continue
+		}
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < len(heads) {
+				queue = append(queue, child)
+			}
+		}
+		tx := heads[i].tx.Resolve()
+		if tx == nil {
+			// Let the worker drop the evicted transaction
+			return i
+		}
+		depth := o.depth(o.tracker.predict(heads[i].from, tx))
+		if best < 0 || depth < bestDepth || (depth == bestDepth && heads.Less(i, best)) {
+			best, bestDepth = i, depth
+		}
+	}
+	if best != 0 {
+		txDAGOrderingReorderMeter.Mark(1)
+	}
+	return best
+}
+
+// include records the state accessed by the transaction included in the block.
+func (o *txDAGOrdering) include(from common.Address, tx *types.Transaction, accesses types.AccessList) {
+	o.tracker.record(from, tx, accesses)
+
+	keys := []txAccessKey{{addr: from, account: true}}
+	if to := tx.To(); to != nil && tx.Value().Sign() > 0 {
+		keys = append(keys, txAccessKey{addr: *to, account: true})
+	}
+	for _, tuple := range accesses {
+		for _, slot := range tuple.StorageKeys {
+			keys = append(keys, txAccessKey{addr: tuple.Address, slot: slot})
+		}
+	}
+	depth := o.depth(keys)
+	for _, k := range keys {
+		o.depths[k] = depth
+	}
+}
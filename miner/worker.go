@@ -102,6 +102,7 @@ var (
 	txErrNotenoughblobgasMeter = metrics.NewRegisteredMeter("miner/tx/err/notenoughblobgas", nil)
 	txErrEvitedMeter           = metrics.NewRegisteredMeter("miner/tx/evited", nil)
 	txErrReplayMeter           = metrics.NewRegisteredMeter("miner/tx/replay", nil)
+	txErrConditionalMeter      = metrics.NewRegisteredMeter("miner/tx/err/conditional", nil)
 )
 
 var (
@@ -125,7 +126,8 @@ type environment struct {
 
 	UnRevertible mapset.Set[common.Hash]
 
-	gasForTxDAG uint64 // gas reserved for the txdag
+	gasForTxDAG uint64      // gas reserved for the txdag
+	txDAG       types.TxDAG // the txdag kept as the block sidecar
 }
 
 // copy creates a deep copy of environment.
@@ -138,6 +140,7 @@ func (env *environment) copy() *environment {
 		header:      types.CopyHeader(env.header),
 		receipts:    copyReceipts(env.receipts),
 		gasForTxDAG: env.gasForTxDAG,
+		txDAG:       env.txDAG,
 	}
 	if env.gasPool != nil {
 		gasPool := *env.gasPool
@@ -282,6 +285,12 @@ type worker struct {
 
 	// FixManager
 	stateFixManager *StateFixManager
+
+	// The state accessed by the recent txs, used by the TxDAG ordering
+	txAccessTracker *txAccessTracker
+
+	// The sponsorship policy of the gasless txs, nil if unrestricted
+	gasless *gaslessPolicy
 }
 
 func (w *worker) StartStateFix(id engine.PayloadID, parentHash common.Hash) error {
@@ -314,6 +323,12 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		bundleCache:        NewBundleCache(),
 		stateFixManager:    NewFixManager(),
 	}
+	if config.ParallelTxDAGOrdering {
+		worker.txAccessTracker = newTxAccessTracker()
+	}
+	if config.Mev.MevGaslessPolicy != "" {
+		worker.gasless = newGaslessPolicy(config.Mev.MevGaslessPolicy)
+	}
 	// Subscribe for transaction insertion events (whether from network or resurrects)
 	worker.txsSub = eth.TxPool().SubscribeTransactions(worker.txsCh, true)
 	// Subscribe events for blockchain
@@ -455,6 +470,10 @@ func (w *worker) close() {
 	w.running.Store(false)
 	close(w.exitCh)
 	w.wg.Wait()
+
+	if w.gasless != nil {
+		w.gasless.close()
+	}
 }
 
 // recalcRecommit recalculates the resubmitting interval upon feedback.
@@ -1001,6 +1020,24 @@ func (w *worker) commitTransactions(env *environment, plainTxs, blobTxs *transac
 			txErrReplayMeter.Mark(1)
 			continue
 		}
+		// Check the conditions of inclusion against the block being built. The
+		// ones failing on the state get the transaction evicted from the pool,
+		// the block ranges are rechecked by the pool on the next head.
+		if cond := tx.Conditional(); cond != nil {
+			if err := cond.CheckBlock(env.header); err != nil {
+				log.Trace("Skipping conditional transaction out of block range", "hash", ltx.Hash, "err", err)
+				txs.Pop()
+				txErrConditionalMeter.Mark(1)
+				continue
+			}
+			if err := env.state.CheckConditional(cond); err != nil {
+				log.Debug("Rejecting conditional transaction", "hash", ltx.Hash, "err", err)
+				cond.Reject(err)
+				txs.Pop()
+				txErrConditionalMeter.Mark(1)
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
@@ -1016,6 +1053,7 @@ func (w *worker) commitTransactions(env *environment, plainTxs, blobTxs *transac
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			env.tcount++
+			txs.Included(tx, env.state.AccessList())
 			txs.Shift()
 			txSuccMeter.Mark(1)
 
@@ -1051,6 +1089,16 @@ func (w *worker) appendTxDAG(env *environment) {
 	if !w.chain.TxDAGEnabledWhenMine() {
 		return
 	}
+	// keep the txDAG out of the block in the sidecar mode
+	if w.config.ParallelTxDAGSidecar {
+		txDAG, err := w.resolveTxDAG(env.state, env.tcount)
+		if err != nil {
+			log.Warn("failed to generate TxDAG sidecar", "err", err)
+			return
+		}
+		env.txDAG = txDAG
+		return
+	}
 	// TODO this is a placeholder for the tx DAG data that will be generated by the stateDB
 	txForDAG, err := w.generateDAGTx(env.state, env.signer, env.tcount, env.gasForTxDAG)
 	if err != nil {
@@ -1083,10 +1131,7 @@ func (w *worker) generateDAGTx(statedb *state.StateDB, signer types.Signer, txIn
 
 	// get txDAG data from the stateDB
 	// txIndex is the index of this txDAG transaction
-	defer func() {
-		statedb.MVStates().Stop()
-	}()
-	txDAG, err := statedb.ResolveTxDAG(txIndex, types.TxDep{Flags: &types.NonDependentRelFlag})
+	txDAG, err := w.resolveTxDAG(statedb, txIndex, types.TxDep{Flags: &types.NonDependentRelFlag})
 	if txDAG == nil {
 		return nil, err
 	}
@@ -1125,6 +1170,22 @@ func (w *worker) generateDAGTx(statedb *state.StateDB, signer types.Signer, txIn
 	return signedTx, nil
 }
 
+// resolveTxDAG resolves the txDAG of the txs executed in the statedb, in the
+// encoding with the smaller size.
+func (w *worker) resolveTxDAG(statedb *state.StateDB, txCnt int, extraTxDeps ...types.TxDep) (types.TxDAG, error) {
+	if statedb == nil {
+		return nil, fmt.Errorf("failed to get state db, env.state=nil")
+	}
+	defer func() {
+		statedb.MVStates().Stop()
+	}()
+	txDAG, err := statedb.ResolveTxDAG(txCnt, extraTxDeps...)
+	if txDAG == nil {
+		return nil, err
+	}
+	return types.CompactTxDAG(txDAG), nil
+}
+
 // generateParams wraps various of settings for generating sealing task.
 type generateParams struct {
 	timestamp   uint64            // The timestamp for sealing task
@@ -1315,8 +1376,9 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 
 	// Fill the block with all available pending transactions.
 	start = time.Now()
+	ordering := w.newTxDAGOrdering()
 	if len(localPlainTxs) > 0 || len(localBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, localPlainTxs, env.header.BaseFee)
+		plainTxs := newTransactionsByPriceAndNonce(env.signer, localPlainTxs, env.header.BaseFee).withTxDAGOrdering(ordering)
 		blobTxs := newTransactionsByPriceAndNonce(env.signer, localBlobTxs, env.header.BaseFee)
 
 		if err := w.commitTransactions(env, plainTxs, blobTxs, interrupt); err != nil {
@@ -1324,7 +1386,7 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 		}
 	}
 	if len(remotePlainTxs) > 0 || len(remoteBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, remotePlainTxs, env.header.BaseFee)
+		plainTxs := newTransactionsByPriceAndNonce(env.signer, remotePlainTxs, env.header.BaseFee).withTxDAGOrdering(ordering)
 		blobTxs := newTransactionsByPriceAndNonce(env.signer, remoteBlobTxs, env.header.BaseFee)
 
 		if err := w.commitTransactions(env, plainTxs, blobTxs, interrupt); err != nil {
@@ -1336,9 +1398,18 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 	return nil
 }
 
+// newTxDAGOrdering creates the ordering to shorten the critical path of the TxDAG
+// for a new block, nil is returned if the txs are ordered by price only.
+func (w *worker) newTxDAGOrdering() *txDAGOrdering {
+	if w.txAccessTracker == nil || !w.chain.TxDAGEnabledWhenMine() {
+		return nil
+	}
+	return newTxDAGOrdering(w.txAccessTracker, w.config.ParallelTxDAGOrderingTolerance)
+}
+
 func (w *worker) estimateGasForTxDAG(env *environment) uint64 {
 	var gas uint64 = 0
-	if w.chain.TxDAGEnabledWhenMine() {
+	if w.chain.TxDAGEnabledWhenMine() && !w.config.ParallelTxDAGSidecar {
 		// 1. a 10k-transactions block need at most 64kB to store its transaction, and its data size grows linearly with the number of transactions
 		// 2. 100M gaslimit block can include at most 4761 = (100M/21000) transactions
 		//
@@ -1360,9 +1431,9 @@ func (w *worker) estimateGasForTxDAG(env *environment) uint64 {
 // generateWork generates a sealing block based on the given parameters.
 func (w *worker) generateWork(genParams *generateParams) *newPayloadResult {
 	// TODO delete after debug performance metrics
-	core.DebugInnerExecutionDuration = 0
+	core.DebugInnerExecutionDuration.Store(0)
 	defer func() {
-		core.DebugInnerExecutionDuration = 0
+		core.DebugInnerExecutionDuration.Store(0)
 	}()
 
 	work, err := w.prepareWork(genParams)
@@ -1477,6 +1548,9 @@ func (w *worker) generateWork(genParams *generateParams) *newPayloadResult {
 	if block.Root() == (common.Hash{}) {
 		return &newPayloadResult{err: fmt.Errorf("empty block root")}
 	}
+	if work.txDAG != nil {
+		block = block.WithTxDAG(work.txDAG)
+	}
 
 	assembleBlockTimer.UpdateSince(start)
 	log.Debug("assembleBlockTimer", "duration", common.PrettyDuration(time.Since(start)), "parentHash", genParams.parentHash)
@@ -1491,7 +1565,7 @@ func (w *worker) generateWork(genParams *generateParams) *newPayloadResult {
 	storageHashTimer.Update(work.state.StorageHashes)                // Storage hashes are complete(in FinalizeAndAssemble)
 	txDAGGenerateTimer.Update(work.state.TxDAGGenerate)
 
-	innerExecutionTimer.Update(core.DebugInnerExecutionDuration)
+	innerExecutionTimer.Update(time.Duration(core.DebugInnerExecutionDuration.Load()))
 
 	log.Debug("build payload statedb metrics", "parentHash", genParams.parentHash, "accountReads", common.PrettyDuration(work.state.AccountReads), "storageReads", common.PrettyDuration(work.state.StorageReads), "snapshotAccountReads", common.PrettyDuration(work.state.SnapshotAccountReads), "snapshotStorageReads", common.PrettyDuration(work.state.SnapshotStorageReads), "accountUpdates", common.PrettyDuration(work.state.AccountUpdates), "storageUpdates", common.PrettyDuration(work.state.StorageUpdates), "accountHashes", common.PrettyDuration(work.state.AccountHashes), "storageHashes", common.PrettyDuration(work.state.StorageHashes))
 	return &newPayloadResult{
@@ -45,6 +45,19 @@ func (w *worker) fillTransactionsAndBundles(interrupt *atomic.Int32, env *enviro
 		return errFillBundleInterrupted
 	}
 
+	// leave out the bundles whose gasless txs the policy no longer allows
+	if w.gasless != nil {
+		allowed := bundles[:0]
+		for _, bundle := range bundles {
+			if _, err := w.gasless.check(env.signer, bundle); err != nil {
+				log.Debug("Skipping bundle rejected by gasless policy", "hash", bundle.Hash(), "err", err)
+				continue
+			}
+			allowed = append(allowed, bundle)
+		}
+		bundles = allowed
+	}
+
 	txs, _, err := w.generateOrderedBundles(env, bundles)
 	if err != nil {
 		log.Error("fail to generate ordered bundles", "err", err)
@@ -97,8 +110,9 @@ func (w *worker) fillTransactionsAndBundles(interrupt *atomic.Int32, env *enviro
 	}
 	// Fill the block with all available pending transactions.
 	start = time.Now()
+	ordering := w.newTxDAGOrdering()
 	if len(localPlainTxs) > 0 || len(localBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, localPlainTxs, env.header.BaseFee)
+		plainTxs := newTransactionsByPriceAndNonce(env.signer, localPlainTxs, env.header.BaseFee).withTxDAGOrdering(ordering)
 		blobTxs := newTransactionsByPriceAndNonce(env.signer, localBlobTxs, env.header.BaseFee)
 
 		if err := w.commitTransactions(env, plainTxs, blobTxs, interrupt); err != nil {
@@ -106,7 +120,7 @@ func (w *worker) fillTransactionsAndBundles(interrupt *atomic.Int32, env *enviro
 		}
 	}
 	if len(remotePlainTxs) > 0 || len(remoteBlobTxs) > 0 {
-		plainTxs := newTransactionsByPriceAndNonce(env.signer, remotePlainTxs, env.header.BaseFee)
+		plainTxs := newTransactionsByPriceAndNonce(env.signer, remotePlainTxs, env.header.BaseFee).withTxDAGOrdering(ordering)
 		blobTxs := newTransactionsByPriceAndNonce(env.signer, remoteBlobTxs, env.header.BaseFee)
 
 		if err := w.commitTransactions(env, plainTxs, blobTxs, interrupt); err != nil {
@@ -232,6 +246,11 @@ func (w *worker) generateOrderedBundles(
 		return priceJ.Cmp(priceI)
 	})
 
+	// search the ordering capturing more fees than the price order, if enabled
+	if w.config.Mev.MevBundleMergeStrategy == BundleMergeSearch {
+		simulatedBundles = w.searchBundles(env, simulatedBundles)
+	}
+
 	// merge bundles based on iterative state
 	includedTxs, mergedBundle, err := w.mergeBundles(env, simulatedBundles)
 	if err != nil {
@@ -261,7 +280,7 @@ func (w *worker) simulateBundles(env *environment, bundles []*types.Bundle) ([]*
 		go func(idx int, bundle *types.Bundle, state *state.StateDB) {
 			defer wg.Done()
 			gasPool := prepareGasPool()
-			simmed, err := w.simulateBundle(env, bundle, state, gasPool, 0, true, true)
+			simmed, err := w.simulateBundle(env, bundle, state, gasPool, 0, true, true, nil)
 			if err != nil {
 				log.Trace("Error computing gas for a simulateBundle", "error", err)
 				return
@@ -298,6 +317,7 @@ func (w *worker) mergeBundles(
 ) (types.Transactions, *types.SimulatedBundle, error) {
 	currentState := env.state.Copy()
 	gasPool := prepareGasPool()
+	gasless := w.gasless.blockBudget()
 	env.UnRevertible = mapset.NewSet[common.Hash]()
 
 	includedTxs := types.Transactions{}
@@ -311,11 +331,8 @@ func (w *worker) mergeBundles(
 		prevState := currentState.Copy()
 		prevGasPool := new(core.GasPool).AddGas(gasPool.Gas())
 
-		// the floor gas price is 99/100 what was simulated at the top of the block
-		floorGasPrice := new(big.Int).Mul(bundle.BundleGasPrice, big.NewInt(99))
-		floorGasPrice = floorGasPrice.Div(floorGasPrice, big.NewInt(100))
-
-		simulatedBundle, err := w.simulateBundle(env, bundle.OriginalBundle, currentState, gasPool, len(includedTxs), true, false)
+		floorGasPrice := mergeFloorGasPrice(bundle)
+		simulatedBundle, err := w.simulateBundle(env, bundle.OriginalBundle, currentState, gasPool, len(includedTxs), true, false, nil)
 
 		if err != nil && errors.Is(err, core.ErrGasLimitReached) {
 			log.Error("failed to merge bundle, interrupt merge process", "err", err)
@@ -328,6 +345,15 @@ func (w *worker) mergeBundles(
 			log.Error("failed to merge bundle", "floorGasPrice", floorGasPrice, "err", err)
 			continue
 		}
+		if gasless != nil {
+			if err := gasless.consume(env.signer, bundle.OriginalBundle, simulatedBundle.SponsoredGas); err != nil {
+				currentState = prevState
+				gasPool = prevGasPool
+
+				log.Debug("Skipping bundle over gasless budget", "hash", bundle.OriginalBundle.Hash(), "err", err)
+				continue
+			}
+		}
 
 		log.Info("included bundle",
 			"gasUsed", simulatedBundle.BundleGasUsed,
@@ -335,6 +361,7 @@ func (w *worker) mergeBundles(
 			"txcount", len(simulatedBundle.OriginalBundle.Txs))
 
 		includedTxs = append(includedTxs, bundle.OriginalBundle.Txs...)
+		w.eth.TxPool().ReportBundle(bundle.OriginalBundle.Hash(), types.BundleMerged, env.header.Number.Uint64(), nil)
 
 		mergedBundle.BundleGasFees.Add(mergedBundle.BundleGasFees, simulatedBundle.BundleGasFees)
 		mergedBundle.BundleGasUsed += simulatedBundle.BundleGasUsed
@@ -355,23 +382,43 @@ func (w *worker) mergeBundles(
 	return includedTxs, &mergedBundle, nil
 }
 
+// mergeFloorGasPrice returns the minimal gas price the bundle is merged at,
+// which is 99/100 what was simulated at the top of the block.
+func mergeFloorGasPrice(bundle *types.SimulatedBundle) *big.Int {
+	floorGasPrice := new(big.Int).Mul(bundle.BundleGasPrice, big.NewInt(99))
+	return floorGasPrice.Div(floorGasPrice, big.NewInt(100))
+}
+
 // simulateBundle computes the gas price for a whole simulateBundle based on the same ctx
 // named computeBundleGas in flashbots
 func (w *worker) simulateBundle(
 	env *environment, bundle *types.Bundle, state *state.StateDB, gasPool *core.GasPool, currentTxCount int,
-	prune, pruneGasExceed bool,
+	prune, pruneGasExceed bool, trace *bundleTrace,
 ) (*types.SimulatedBundle, error) {
 	var (
 		tempGasUsed   uint64
 		bundleGasUsed uint64
 		bundleGasFees = new(big.Int)
+		sponsoredGas  uint64
+		accesses      *bundleAccessList
 	)
+	if w.config.Mev.MevBundleMergeStrategy == BundleMergeSearch {
+		accesses = newBundleAccessList()
+	}
 
 	for i, tx := range bundle.Txs {
 		state.SetTxContext(tx.Hash(), i+currentTxCount)
 
-		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &w.coinbase, gasPool, state, env.header, tx,
-			&tempGasUsed, *w.chain.GetVMConfig())
+		var (
+			author   = &w.coinbase
+			vmConfig = *w.chain.GetVMConfig()
+		)
+		if trace != nil {
+			author = &env.header.Coinbase
+			vmConfig.Tracer = trace.begin(state, *author)
+		}
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, author, gasPool, state, env.header, tx,
+			&tempGasUsed, vmConfig)
 		if err != nil {
 			log.Warn("fail to simulate bundle", "hash", bundle.Hash().String(), "err", err)
 
@@ -380,24 +427,33 @@ func (w *worker) simulateBundle(
 					log.Warn("bundle gas limit exceed", "hash", bundle.Hash().String())
 				} else {
 					log.Warn("prune bundle", "hash", bundle.Hash().String(), "err", err)
-					w.eth.TxPool().PruneBundle(bundle.Hash())
+					w.pruneBundle(env, bundle, err)
 				}
 			}
 
 			return nil, err
 		}
+		if trace != nil {
+			trace.end(tx, receipt, state, *author, env.header.BaseFee)
+		}
+		if accesses != nil {
+			accesses.add(state.AccessList())
+		}
 
 		if receipt.Status == types.ReceiptStatusFailed && !containsHash(bundle.RevertingTxHashes, receipt.TxHash) {
 			err = errNonRevertingTxInBundleFailed
 			log.Warn("fail to simulate bundle", "hash", bundle.Hash().String(), "err", err)
 
 			if prune {
-				w.eth.TxPool().PruneBundle(bundle.Hash())
+				w.pruneBundle(env, bundle, err)
 				log.Warn("prune bundle", "hash", bundle.Hash().String())
 			}
 
 			return nil, err
 		}
+		if isGasless(tx) {
+			sponsoredGas += receipt.GasUsed
+		}
 		if !w.eth.TxPool().Has(tx.Hash()) {
 			bundleGasUsed += receipt.GasUsed
 
@@ -425,18 +481,23 @@ func (w *worker) simulateBundle(
 
 		if prune {
 			log.Warn("prune bundle", "hash", bundle.Hash().String())
-			w.eth.TxPool().PruneBundle(bundle.Hash())
+			w.pruneBundle(env, bundle, err)
 		}
 
 		return nil, err
 	}
 
-	return &types.SimulatedBundle{
+	simmed := &types.SimulatedBundle{
 		OriginalBundle: bundle,
 		BundleGasFees:  bundleGasFees,
 		BundleGasPrice: bundleGasPrice,
 		BundleGasUsed:  bundleGasUsed,
-	}, nil
+		SponsoredGas:   sponsoredGas,
+	}
+	if accesses != nil {
+		simmed.AccessList = accesses.list()
+	}
+	return simmed, nil
 }
 
 func (w *worker) simulateGaslessBundle(env *environment, bundle *types.Bundle) (*types.SimulateGaslessBundleResp, error) {
@@ -477,6 +538,13 @@ func (w *worker) simulateGaslessBundle(env *environment, bundle *types.Bundle) (
 	}, nil
 }
 
+// pruneBundle removes the bundle failing the simulation from the pool, with the
+// reason of the failure.
+func (w *worker) pruneBundle(env *environment, bundle *types.Bundle, reason error) {
+	w.eth.TxPool().ReportBundle(bundle.Hash(), types.BundleFailed, env.header.Number.Uint64(), reason)
+	w.eth.TxPool().PruneBundle(bundle.Hash())
+}
+
 func containsHash(arr []common.Hash, match common.Hash) bool {
 	for _, elem := range arr {
 		if elem == match {
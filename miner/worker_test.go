@@ -295,6 +295,59 @@ func generateTxDAGGaslessBlock(t *testing.T, enableMev, enableTxDAG bool) {
 	}
 }
 
+func TestGenerateTxDAGSidecarBlock(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = *params.AllCliqueProtocolChanges
+	)
+	config.Optimism = &params.OptimismConfig{
+		EIP1559Elasticity:        2,
+		EIP1559Denominator:       8,
+		EIP1559DenominatorCanyon: 8,
+	}
+	cfg := *testConfig
+	cfg.NewPayloadTimeout = 3 * time.Second
+	cfg.ParallelTxDAGSidecar = true
+	vmConfig := vm.Config{NoBaseFee: true}
+	engine := clique.New(config.Clique, db)
+
+	w, b := newTestWorker(t, &config, engine, db, 0, &cfg, &vmConfig)
+	defer w.close()
+	w.chain.SetupTxDAGGeneration()
+
+	b.txPool.Add([]*types.Transaction{b.newRandomTx(false)}, true, false)
+	time.Sleep(1 * time.Second) // Wait for txs to be promoted
+
+	block := w.getSealingBlock(&generateParams{
+		timestamp: uint64(time.Now().Unix()),
+		txs: types.Transactions{
+			types.NewTx(&types.DepositTx{
+				To:    nil, // contract creation
+				Value: big.NewInt(6),
+				Gas:   50,
+			})},
+	})
+	if block.err != nil {
+		t.Fatalf("failed to generate block: %v", block.err)
+	}
+	txs := block.block.Transactions()
+	if len(txs) != 2 {
+		t.Fatalf("tx count mismatch, have %d, want %d", len(txs), 2)
+	}
+	for _, tx := range txs {
+		if to := tx.To(); to != nil && *to == DefaultTxDAGAddress {
+			t.Fatalf("TxDAG transaction included in sidecar mode")
+		}
+	}
+	dag := block.block.TxDAG()
+	if dag == nil {
+		t.Fatalf("TxDAG sidecar missing")
+	}
+	if err := types.ValidateTxDAG(dag, len(txs)); err != nil {
+		t.Fatalf("invalid TxDAG sidecar: %v", err)
+	}
+}
+
 func TestEmptyWorkEthash(t *testing.T) {
 	t.Parallel()
 	testEmptyWork(t, ethashChainConfig, ethash.NewFaker())
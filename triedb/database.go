@@ -321,6 +321,17 @@ func (db *Database) Recover(target common.Hash) error {
 	return pdb.Recover(target, loader)
 }
 
+// HistoricReader returns a reader of the historical state with the given root,
+// which is served from the indexed state histories since then. It's only
+// supported by path-based database and will return an error for others.
+func (db *Database) HistoricReader(root common.Hash) (*pathdb.HistoricReader, error) {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok || db.config.IsVerkle {
+		return nil, errors.New("not supported")
+	}
+	return pdb.HistoricReader(root, trie.NewMerkleLoader(db))
+}
+
 // Recoverable returns the indicator if the specified state is enabled to be
 // recovered. It's only supported by path-based database and will return an
 // error for others.
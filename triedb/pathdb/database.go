@@ -112,7 +112,10 @@ type Config struct {
 	NotifyKeep           NotifyKeepFunc // NotifyKeep is used to keep the proof which maybe queried by op-proposer.
 	JournalFilePath      string         // The journal file path
 	JournalFile          bool           // Whether to use journal file mode
+	CheckpointInterval   time.Duration  // Interval to checkpoint the node buffer list, DefaultCheckpointInterval if zero
 	UseBase              bool           // Flag to use base and no other buffers for nodebufferlist, it's used for init genesis and unit tes
+	HistoryIndex         bool           // Flag to index state histories for historical state reads
+	HistoryArchive       bool           // Flag to move the pruned state histories into the imported archive below them
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -160,6 +163,7 @@ type Database struct {
 	diskdb       ethdb.Database           // Persistent storage for matured trie nodes
 	tree         *layerTree               // The group for all known layers
 	freezer      *rawdb.ResettableFreezer // Freezer for storing trie histories, nil possible in tests
+	indexer      *historyIndexer          // Indexer of state histories for historical state reads, nil if disabled
 	lock         sync.RWMutex             // Lock to prevent mutations from happening at the same time
 	capLock      sync.Mutex
 }
@@ -190,6 +194,13 @@ func New(diskdb ethdb.Database, config *Config) *Database {
 			log.Crit("Failed to open state history freezer", "err", err)
 		}
 		db.freezer = freezer
+		if config.HistoryIndex {
+			db.indexer = newHistoryIndexer(diskdb, freezer)
+		} else if rawdb.ReadHistoryLookupHead(diskdb) != 0 {
+			// The state histories are not indexed anymore, the index is to be
+			// rebuilt once enabled again, as it can't follow the truncations.
+			rawdb.WriteHistoryLookupHead(diskdb, 0)
+		}
 	}
 
 	// Construct the layer tree by resolving the in-disk singleton state
@@ -211,8 +222,10 @@ func New(diskdb ethdb.Database, config *Config) *Database {
 			if err != nil {
 				log.Crit("Failed to retrieve head of state history", "err", err)
 			}
-			if frozen != 0 {
-				err := db.freezer.Reset()
+			if first, _ := rawdb.ReadHistoryArchiveRange(db.diskdb); frozen != 0 || first != 0 {
+				_, err := db.indexer.truncateHead(0, func() (int, error) {
+					return 0, db.resetHistories()
+				})
 				if err != nil {
 					log.Crit("Failed to reset state histories", "err", err)
 				}
@@ -221,7 +234,9 @@ func New(diskdb ethdb.Database, config *Config) *Database {
 		} else {
 			// Truncate the extra state histories above in freezer in case
 			// it's not aligned with the disk layer.
-			pruned, err := truncateFromHead(db.diskdb, db.freezer, diskLayerID)
+			pruned, err := db.indexer.truncateHead(diskLayerID, func() (int, error) {
+				return truncateFromHead(db.diskdb, db.freezer, diskLayerID)
+			})
 			if err != nil {
 				log.Crit("Failed to truncate extra state histories", "err", err)
 			}
@@ -229,6 +244,7 @@ func New(diskdb ethdb.Database, config *Config) *Database {
 				log.Warn("Truncated extra state histories", "number", pruned)
 			}
 		}
+		db.indexer.start()
 	}
 	// Disable database in case node is still in the initial state sync stage.
 	if rawdb.ReadSnapSyncStatusFlag(diskdb) == rawdb.StateSyncRunning && !db.readOnly {
@@ -356,19 +372,22 @@ func (db *Database) Enable(root common.Hash) error {
 	if err := batch.Write(); err != nil {
 		return err
 	}
+	db.deleteCheckpoint()
 	// Clean up all state histories in freezer. Theoretically
 	// all root->id mappings should be removed as well. Since
 	// mappings can be huge and might take a while to clear
 	// them, just leave them in disk and wait for overwriting.
 	if db.freezer != nil {
-		if err := db.freezer.Reset(); err != nil {
+		if _, err := db.indexer.truncateHead(0, func() (int, error) {
+			return 0, db.resetHistories()
+		}); err != nil {
 			return err
 		}
 	}
 	// Re-construct a new disk layer backed by persistent state
 	// with **empty clean cache and node buffer**.
 	nb, err := NewTrieNodeBuffer(db.diskdb, db.config.TrieNodeBufferType, db.bufferSize, nil, 0, db.config.ProposeBlockInterval,
-		db.config.NotifyKeep, nil, false, false)
+		db.config.NotifyKeep, nil, false, false, db.newCheckpointConfig())
 	if err != nil {
 		log.Error("Failed to new trie node buffer", "error", err)
 		return err
@@ -425,7 +444,9 @@ func (db *Database) Recover(root common.Hash, loader triestate.TrieLoader) error
 		db.tree.reset(dl)
 	}
 	_ = db.DeleteTrieJournal(db.diskdb)
-	truncatedNumber, err := truncateFromHead(db.diskdb, db.freezer, dl.stateID())
+	truncatedNumber, err := db.indexer.truncateHead(dl.stateID(), func() (int, error) {
+		return truncateFromHead(db.diskdb, db.freezer, dl.stateID())
+	})
 	if err != nil {
 		return err
 	}
@@ -470,6 +491,14 @@ func (db *Database) recoverable(root common.Hash) bool {
 	}) == nil
 }
 
+// resetHistories removes all the state histories, including the archived ones.
+func (db *Database) resetHistories() error {
+	if err := deleteHistoryArchive(db.diskdb); err != nil {
+		return err
+	}
+	return db.freezer.Reset()
+}
+
 // Close closes the trie database and the held freezer.
 func (db *Database) Close() error {
 	db.lock.Lock()
@@ -482,6 +511,9 @@ func (db *Database) Close() error {
 	// Release the memory held by clean cache.
 	db.tree.bottom().resetCache()
 
+	// Stop the state history indexer before closing the freezer it reads.
+	db.indexer.close()
+
 	// Close the attached state history freezer.
 	if db.freezer == nil {
 		return nil
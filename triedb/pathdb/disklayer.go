@@ -126,17 +126,18 @@ func NewTrieNodeBuffer(
 	keepFunc NotifyKeepFunc,
 	freezer *rawdb.ResettableFreezer,
 	fastRecovery, useBase bool,
+	checkpoint *checkpointConfig,
 ) (trienodebuffer, error) {
 	log.Info("init trie node buffer", "type", nodeBufferTypeToString[trieNodeBufferType])
 	switch trieNodeBufferType {
 	case NodeBufferList:
-		return newNodeBufferList(db, uint64(limit), nodes, layers, proposeBlockInterval, keepFunc, freezer, fastRecovery, useBase)
+		return newNodeBufferList(db, uint64(limit), nodes, layers, proposeBlockInterval, keepFunc, freezer, fastRecovery, useBase, checkpoint)
 	case AsyncNodeBuffer:
 		return newAsyncNodeBuffer(limit, nodes, layers)
 	case SyncNodeBuffer:
 		return newNodeBuffer(limit, nodes, layers)
 	default:
-		return newNodeBufferList(db, uint64(limit), nodes, layers, proposeBlockInterval, keepFunc, freezer, fastRecovery, useBase)
+		return newNodeBufferList(db, uint64(limit), nodes, layers, proposeBlockInterval, keepFunc, freezer, fastRecovery, useBase, checkpoint)
 	}
 }
 
@@ -294,6 +295,7 @@ func (dl *diskLayer) commit(bottom *diffLayer, force bool) (*diskLayer, error) {
 		if err != nil {
 			return nil, err
 		}
+		dl.db.indexer.notify()
 		// Determine if the persisted history object has exceeded the configured
 		// limitation, set the overflow as true if so.
 		tail, err := dl.db.freezer.Tail()
@@ -355,7 +357,9 @@ func (dl *diskLayer) commit(bottom *diffLayer, force bool) (*diskLayer, error) {
 				persistentID, "truncate_tail", oldest)
 		}
 
-		pruned, err := truncateFromTail(ndl.db.diskdb, ndl.db.freezer, oldest-1)
+		pruned, err := ndl.db.indexer.truncateTail(oldest-1, func() (int, error) {
+			return pruneHistories(ndl.db.diskdb, ndl.db.freezer, oldest-1, ndl.db.config.HistoryArchive)
+		})
 		if err != nil {
 			log.Error("Failed to truncate from tail", "ntail", oldest-1, "error", err)
 			return nil, err
@@ -0,0 +1,683 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/era/e2store"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+// Entry types of the state history archive, see ExportHistoryArchive.
+const (
+	typeVersion                uint16 = 0x3265
+	typeHistoryMeta            uint16 = 0x10
+	typeHistoryAccountIndex    uint16 = 0x11
+	typeHistoryStorageIndex    uint16 = 0x12
+	typeCompressedAccountData  uint16 = 0x13
+	typeCompressedStorageData  uint16 = 0x14
+	typeCompressedTrieNodeData uint16 = 0x15
+	typeHistoryChecksum        uint16 = 0x16
+	typeHistoryIndex           uint16 = 0x3268
+)
+
+// MaxHistoryArchiveSize is the maximum number of state histories in an archive
+// file.
+const MaxHistoryArchiveSize = 1024
+
+// maxArchiveEntrySize is the maximum size of an archive entry accepted by the
+// e2store reader.
+const maxArchiveEntrySize = 50 * 1024 * 1024
+
+// emptyTrieNodes is the encoding of the trie nodes of a state history without
+// any, written if the freezer keeps trie nodes but the archive doesn't.
+var emptyTrieNodes = []byte{0xc0}
+
+// HistoryArchiveName returns a recognizable file name for the archive of the
+// state histories within [first, last].
+func HistoryArchiveName(first, last uint64) string {
+	return fmt.Sprintf("statehistory-%010d-%010d.e2hs", first, last)
+}
+
+// ExportHistoryArchive writes the state histories within [first, last] in the
+// freezer into an archive, at most MaxHistoryArchiveSize of them.
+//
+// The archive is an e2store file, structured as:
+//
+//	archive       := Version | history-tuple* | Checksum | HistoryIndex
+//	history-tuple := Meta | AccountIndex | StorageIndex | CompressedAccountData | CompressedStorageData | CompressedTrieNodeData?
+//
+// Each basic element is its own entry:
+//
+//	Version                = { type: [0x65, 0x32], data: nil }
+//	Meta                   = { type: [0x10, 0x00], data: history-meta }
+//	AccountIndex           = { type: [0x11, 0x00], data: account-index }
+//	StorageIndex           = { type: [0x12, 0x00], data: storage-index }
+//	CompressedAccountData  = { type: [0x13, 0x00], data: snappyFramed(account-data) }
+//	CompressedStorageData  = { type: [0x14, 0x00], data: snappyFramed(storage-data) }
+//	CompressedTrieNodeData = { type: [0x15, 0x00], data: snappyFramed(trie-nodes) }
+//	Checksum               = { type: [0x16, 0x00], data: keccak256(entries) }
+//	HistoryIndex           = { type: [0x68, 0x32], data: first-id | index | index | ... | count }
+//
+// The elements of the histories are the raw items of the state freezer, the
+// trie nodes are only present if the freezer keeps them. The checksum is the
+// hash of the types and values of all the preceding entries. Every index of
+// the history index is the offset of the history relative to the beginning of
+// the history index.
+func ExportHistoryArchive(freezer *rawdb.ResettableFreezer, w io.Writer, first, last uint64) error {
+	if first == 0 || first > last {
+		return fmt.Errorf("invalid state history range [%d, %d]", first, last)
+	}
+	if last-first >= MaxHistoryArchiveSize {
+		return fmt.Errorf("exceeds maximum archive size of %d", MaxHistoryArchiveSize)
+	}
+	tail, err := freezer.Tail()
+	if err != nil {
+		return err
+	}
+	head, err := freezer.Ancients()
+	if err != nil {
+		return err
+	}
+	if first <= tail || last > head {
+		return fmt.Errorf("state histories [%d, %d] are not available, available range: [%d, %d]", first, last, tail+1, head)
+	}
+	var (
+		aw      = newArchiveWriter(w)
+		indexes []uint64
+	)
+	if err := aw.write(typeVersion, nil); err != nil {
+		return err
+	}
+	for id := first; id <= last; id++ {
+		indexes = append(indexes, aw.written)
+
+		meta := rawdb.ReadStateHistoryMeta(freezer, id)
+		if len(meta) == 0 {
+			return fmt.Errorf("state history [%d] not found", id)
+		}
+		if err := aw.write(typeHistoryMeta, meta); err != nil {
+			return err
+		}
+		if err := aw.write(typeHistoryAccountIndex, rawdb.ReadStateAccountIndex(freezer, id)); err != nil {
+			return err
+		}
+		if err := aw.write(typeHistoryStorageIndex, rawdb.ReadStateStorageIndex(freezer, id)); err != nil {
+			return err
+		}
+		if err := aw.writeSnappy(typeCompressedAccountData, rawdb.ReadStateAccountHistory(freezer, id)); err != nil {
+			return err
+		}
+		if err := aw.writeSnappy(typeCompressedStorageData, rawdb.ReadStateStorageHistory(freezer, id)); err != nil {
+			return err
+		}
+		if nodes := rawdb.ReadStateTrieNodesHistory(freezer, id); len(nodes) > 0 {
+			if err := aw.writeSnappy(typeCompressedTrieNodeData, nodes); err != nil {
+				return err
+			}
+		}
+	}
+	if err := aw.write(typeHistoryChecksum, aw.hasher.Sum(nil)); err != nil {
+		return err
+	}
+	// The offsets are relative to the history index, see above.
+	var (
+		base  = aw.written
+		count = len(indexes)
+		index = make([]byte, 16+count*8)
+	)
+	binary.LittleEndian.PutUint64(index, first)
+	for i, offset := range indexes {
+		binary.LittleEndian.PutUint64(index[8+i*8:], uint64(int64(offset)-int64(base)))
+	}
+	binary.LittleEndian.PutUint64(index[8+count*8:], uint64(count))
+	return aw.write(typeHistoryIndex, index)
+}
+
+// archiveWriter writes the entries of an archive, hashing them meanwhile.
+type archiveWriter struct {
+	w       *e2store.Writer
+	hasher  crypto.KeccakState
+	written uint64
+	buf     *bytes.Buffer
+	snappy  *snappy.Writer
+}
+
+func newArchiveWriter(w io.Writer) *archiveWriter {
+	buf := bytes.NewBuffer(nil)
+	return &archiveWriter{
+		w:      e2store.NewWriter(w),
+		hasher: crypto.NewKeccakState(),
+		buf:    buf,
+		snappy: snappy.NewBufferedWriter(buf),
+	}
+}
+
+// write writes an entry into the archive.
+func (aw *archiveWriter) write(typ uint16, value []byte) error {
+	if len(value) > maxArchiveEntrySize {
+		return fmt.Errorf("entry larger than size limit %d: have %d", maxArchiveEntrySize, len(value))
+	}
+	hashEntry(aw.hasher, typ, value)
+	n, err := aw.w.Write(typ, value)
+	aw.written += uint64(n)
+	return err
+}
+
+// writeSnappy writes an entry with the snappy compressed value into the archive.
+func (aw *archiveWriter) writeSnappy(typ uint16, value []byte) error {
+	aw.buf.Reset()
+	aw.snappy.Reset(aw.buf)
+	if _, err := aw.snappy.Write(value); err != nil {
+		return fmt.Errorf("error snappy encoding: %w", err)
+	}
+	if err := aw.snappy.Flush(); err != nil {
+		return fmt.Errorf("error flushing snappy encoding: %w", err)
+	}
+	return aw.write(typ, aw.buf.Bytes())
+}
+
+// hashEntry feeds the type and value of an entry into the checksum.
+func hashEntry(hasher crypto.KeccakState, typ uint16, value []byte) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], typ)
+	hasher.Write(b[:])
+	hasher.Write(value)
+}
+
+// archivedHistory is a state history read from an archive, as the raw items of
+// the state freezer.
+type archivedHistory struct {
+	id           uint64
+	meta         []byte
+	accountIndex []byte
+	storageIndex []byte
+	accountData  []byte
+	storageData  []byte
+	trieNodes    []byte // Nil if absent
+}
+
+// readHistoryArchive reads all the state histories in the archive, invoking
+// the given callback on each of them. The callback is not invoked at all if
+// the archive is malformed or fails the checksum, which is verified first.
+func readHistoryArchive(r io.ReaderAt, fn func(*archivedHistory) error) error {
+	first, count, err := readArchive(r, nil)
+	if err != nil {
+		return err
+	}
+	if fn == nil {
+		return nil
+	}
+	id := first
+	_, _, err = readArchive(r, func(h *archivedHistory) error {
+		h.id, id = id, id+1
+		return fn(h)
+	})
+	if err == nil && id != first+count {
+		err = fmt.Errorf("unexpected number of state histories, want %d, got %d", count, id-first)
+	}
+	return err
+}
+
+// readArchive walks through the archive, verifying its structure and checksum,
+// and returns the first history id and the number of histories in the history
+// index. The given callback, if any, is invoked on each state history without
+// its id resolved.
+func readArchive(r io.ReaderAt, fn func(*archivedHistory) error) (uint64, uint64, error) {
+	var (
+		reader  = e2store.NewReader(r)
+		hasher  = crypto.NewKeccakState()
+		current *archivedHistory
+		count   uint64
+	)
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		h := current
+		current, count = nil, count+1
+		if fn == nil {
+			return nil
+		}
+		return fn(h)
+	}
+	entry, err := reader.Read()
+	if err != nil {
+		return 0, 0, err
+	}
+	if entry.Type != typeVersion {
+		return 0, 0, fmt.Errorf("unexpected first entry type %d", entry.Type)
+	}
+	hashEntry(hasher, entry.Type, entry.Value)
+
+	for {
+		entry, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("archive is truncated")
+			}
+			return 0, 0, err
+		}
+		if entry.Type == typeHistoryChecksum {
+			if !bytes.Equal(entry.Value, hasher.Sum(nil)) {
+				return 0, 0, errors.New("archive checksum mismatch")
+			}
+			break
+		}
+		hashEntry(hasher, entry.Type, entry.Value)
+
+		switch entry.Type {
+		case typeHistoryMeta:
+			if err := flush(); err != nil {
+				return 0, 0, err
+			}
+			current = &archivedHistory{meta: entry.Value}
+			continue
+		case typeHistoryAccountIndex, typeHistoryStorageIndex, typeCompressedAccountData, typeCompressedStorageData, typeCompressedTrieNodeData:
+		default:
+			return 0, 0, fmt.Errorf("unexpected entry type %d", entry.Type)
+		}
+		if current == nil {
+			return 0, 0, fmt.Errorf("entry type %d without state history meta", entry.Type)
+		}
+		value := entry.Value
+		switch entry.Type {
+		case typeCompressedAccountData, typeCompressedStorageData, typeCompressedTrieNodeData:
+			if value, err = io.ReadAll(snappy.NewReader(bytes.NewReader(entry.Value))); err != nil {
+				return 0, 0, fmt.Errorf("error snappy decoding: %w", err)
+			}
+		}
+		switch entry.Type {
+		case typeHistoryAccountIndex:
+			current.accountIndex = value
+		case typeHistoryStorageIndex:
+			current.storageIndex = value
+		case typeCompressedAccountData:
+			current.accountData = value
+		case typeCompressedStorageData:
+			current.storageData = value
+		case typeCompressedTrieNodeData:
+			current.trieNodes = value
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, 0, err
+	}
+	entry, err = reader.Read()
+	if err != nil {
+		return 0, 0, err
+	}
+	if entry.Type != typeHistoryIndex || len(entry.Value) < 16 || len(entry.Value)%8 != 0 {
+		return 0, 0, errors.New("invalid history index")
+	}
+	var (
+		first = binary.LittleEndian.Uint64(entry.Value)
+		n     = binary.LittleEndian.Uint64(entry.Value[len(entry.Value)-8:])
+	)
+	if n != count || uint64(len(entry.Value)) != 16+n*8 {
+		return 0, 0, fmt.Errorf("history index mismatch, want %d histories, got %d", count, n)
+	}
+	return first, n, nil
+}
+
+// ImportHistoryArchive imports the state histories in the archive, returning
+// the number of the imported ones. The histories already stored are skipped if
+// identical, while the others are:
+//
+//   - archived in the key-value store if they are at or below the freezer tail,
+//     as the freezer can't be prepended. The archived histories must be
+//     continuous, and are served the same as the ones in the freezer as long as
+//     they reach the freezer tail. The histories pruned afterwards are moved
+//     into the archive only if the database is configured with HistoryArchive,
+//     otherwise the archive is left behind by the pruning.
+//   - appended to the freezer if they extend the freezer head continuously, up
+//     to the persisted state. The ones above are refused, as the freezer is
+//     truncated to the disk layer on startup.
+//
+// The root->id lookups of the imported histories are stored as well, which
+// must not conflict with the ones of the disk database.
+func ImportHistoryArchive(db ethdb.KeyValueStore, freezer *rawdb.ResettableFreezer, r io.ReaderAt, writeTrieNodes bool) (int, error) {
+	tail, err := freezer.Tail()
+	if err != nil {
+		return 0, err
+	}
+	head, err := freezer.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	start, count, err := readArchive(r, nil)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		persisted   = rawdb.ReadPersistentStateID(db)
+		first, last = rawdb.ReadHistoryArchiveRange(db)
+		batch       = db.NewBatch()
+		imported    int
+		archived    int
+		prevID      uint64
+		prevRoot    common.Hash
+	)
+	// The histories to archive must be continuous with the archived ones.
+	if end := start + count - 1; first != 0 && start <= tail && (end+1 < first || start > last+1) {
+		return 0, fmt.Errorf("state histories [%d, %d] are not continuous with the archived ones [%d, %d]", start, end, first, last)
+	}
+	// stored returns the meta of the stored state history, nil if there is none.
+	stored := func(id uint64) ([]byte, error) {
+		if id > tail && id <= head {
+			return rawdb.ReadStateHistoryMeta(freezer, id), nil
+		}
+		if first != 0 && id >= first && id <= last {
+			h, err := readArchivedHistory(db, id)
+			if err != nil {
+				return nil, err
+			}
+			return h.meta, nil
+		}
+		return nil, nil
+	}
+	err = readHistoryArchive(r, func(h *archivedHistory) error {
+		blob, err := stored(h.id)
+		if err != nil {
+			return err
+		}
+		if blob != nil {
+			if !bytes.Equal(blob, h.meta) {
+				return fmt.Errorf("state history [%d] is mismatched with the stored one", h.id)
+			}
+			prevID, prevRoot = 0, common.Hash{}
+			return nil
+		}
+		if h.id > head {
+			if h.id != head+1 {
+				return fmt.Errorf("state history [%d] is not continuous with the freezer head %d", h.id, head)
+			}
+			if h.id > persisted {
+				return fmt.Errorf("state history [%d] is above the persisted state %d, which would be truncated on startup", h.id, persisted)
+			}
+		}
+		var dec history
+		dec.meta = new(meta)
+		if err := dec.meta.decode(h.meta); err != nil {
+			return err
+		}
+		if err := dec.decode(h.accountData, h.storageData, h.accountIndex, h.storageIndex, h.trieNodes); err != nil {
+			return fmt.Errorf("invalid state history [%d]: %w", h.id, err)
+		}
+		// Ensure the history is linked with the adjacent ones.
+		parent, known := prevRoot, prevID != 0 && prevID+1 == h.id
+		if !known {
+			blob, err := stored(h.id - 1)
+			if err != nil {
+				return err
+			}
+			if blob != nil {
+				var m meta
+				if err := m.decode(blob); err != nil {
+					return err
+				}
+				parent, known = m.root, true
+			}
+		}
+		if known && dec.meta.parent != parent {
+			return fmt.Errorf("state history [%d] is not linked, want parent %#x, got %#x", h.id, parent, dec.meta.parent)
+		}
+		blob, err = stored(h.id + 1)
+		if err != nil {
+			return err
+		}
+		if blob != nil {
+			var m meta
+			if err := m.decode(blob); err != nil {
+				return err
+			}
+			if m.parent != dec.meta.root {
+				return fmt.Errorf("state history [%d] is not linked, want root %#x, got %#x", h.id, m.parent, dec.meta.root)
+			}
+		}
+		id := rawdb.ReadStateID(db, dec.meta.root)
+		if id != nil && *id != h.id {
+			return fmt.Errorf("state history [%d] is mismatched with the stored state id %d", h.id, *id)
+		}
+		if h.id <= tail {
+			writeArchivedHistory(batch, h)
+			writeHistoryLookups(batch, &dec, h.id)
+			if id == nil {
+				rawdb.WriteStateID(batch, dec.meta.root, h.id)
+			}
+			// The archive range is only updated once all the histories are
+			// written, the leftovers of a crash are overwritten by a retry.
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+			archived++
+		} else {
+			if writeTrieNodes {
+				nodes := h.trieNodes
+				if nodes == nil {
+					nodes = emptyTrieNodes
+				}
+				rawdb.WriteStateHistoryWithTrieNodes(freezer, h.id, h.meta, h.accountIndex, h.storageIndex, h.accountData, h.storageData, nodes)
+			} else {
+				rawdb.WriteStateHistory(freezer, h.id, h.meta, h.accountIndex, h.storageIndex, h.accountData, h.storageData)
+			}
+			if n, err := freezer.Ancients(); err != nil || n != h.id {
+				return fmt.Errorf("failed to append state history [%d]", h.id)
+			}
+			if id == nil {
+				rawdb.WriteStateID(db, dec.meta.root, h.id)
+			}
+			head = h.id
+		}
+		prevID, prevRoot = h.id, dec.meta.root
+		imported++
+		return nil
+	})
+	if archived > 0 && err == nil {
+		end := min(start+count-1, tail)
+		if first == 0 {
+			first, last = start, end
+		} else {
+			first, last = min(first, start), max(last, end)
+		}
+		rawdb.WriteHistoryArchiveRange(batch, first, last)
+		err = batch.Write()
+		log.Debug("Archived state histories", "count", archived, "first", first, "last", last)
+	}
+	if imported > archived {
+		if serr := freezer.Sync(); err == nil {
+			err = serr
+		}
+		log.Debug("Imported state histories", "count", imported-archived, "head", head)
+	}
+	return imported, err
+}
+
+// archiveEntry is the encoding of a state history archived in the key-value
+// store, as the raw items of the state freezer except the trie nodes.
+type archiveEntry struct {
+	Meta         []byte
+	AccountIndex []byte
+	StorageIndex []byte
+	AccountData  []byte
+	StorageData  []byte
+}
+
+// writeArchivedHistory stores the state history in the archive.
+func writeArchivedHistory(db ethdb.KeyValueWriter, h *archivedHistory) {
+	blob, err := rlp.EncodeToBytes(&archiveEntry{
+		Meta:         h.meta,
+		AccountIndex: h.accountIndex,
+		StorageIndex: h.storageIndex,
+		AccountData:  h.accountData,
+		StorageData:  h.storageData,
+	})
+	if err != nil {
+		log.Crit("Failed to encode archived state history", "err", err)
+	}
+	rawdb.WriteArchivedHistory(db, h.id, blob)
+}
+
+// readArchivedHistory reads the state history of the given id in the archive.
+func readArchivedHistory(db ethdb.KeyValueReader, id uint64) (*archivedHistory, error) {
+	blob := rawdb.ReadArchivedHistory(db, id)
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("archived state history [%d] not found", id)
+	}
+	var entry archiveEntry
+	if err := rlp.DecodeBytes(blob, &entry); err != nil {
+		return nil, err
+	}
+	return &archivedHistory{
+		id:           id,
+		meta:         entry.Meta,
+		accountIndex: entry.AccountIndex,
+		storageIndex: entry.StorageIndex,
+		accountData:  entry.AccountData,
+		storageData:  entry.StorageData,
+	}, nil
+}
+
+// oldestHistory returns the id of the oldest state history served, which is
+// the first archived one if the archive reaches the given freezer tail, or the
+// first one in the freezer otherwise.
+func oldestHistory(db ethdb.KeyValueReader, tail uint64) uint64 {
+	if first, last := rawdb.ReadHistoryArchiveRange(db); first != 0 && last >= tail {
+		return first
+	}
+	return tail + 1
+}
+
+// readStoredHistory reads the state history of the given id, either in the
+// freezer or in the archive.
+func readStoredHistory(db ethdb.KeyValueReader, freezer *rawdb.ResettableFreezer, id uint64) (*history, error) {
+	h, err := readHistory(freezer, id, false)
+	if err == nil {
+		return h, nil
+	}
+	// The history may have been moved into the archive meanwhile.
+	if first, last := rawdb.ReadHistoryArchiveRange(db); first == 0 || id < first || id > last {
+		return nil, err
+	}
+	a, err := readArchivedHistory(db, id)
+	if err != nil {
+		return nil, err
+	}
+	var m meta
+	if err := m.decode(a.meta); err != nil {
+		return nil, err
+	}
+	dec := history{meta: &m}
+	if err := dec.decode(a.accountData, a.storageData, a.accountIndex, a.storageIndex, nil); err != nil {
+		return nil, err
+	}
+	return &dec, nil
+}
+
+// pruneHistories removes the state histories below the new tail. If archiving
+// is enabled and the archive reaches the tail, the histories are moved into the
+// archive instead. Otherwise they are deleted as usual, and the archive is left
+// untouched, no longer served once the tail moves past it.
+func pruneHistories(db ethdb.KeyValueStore, freezer *rawdb.ResettableFreezer, ntail uint64, archive bool) (int, error) {
+	if !archive {
+		return truncateFromTail(db, freezer, ntail)
+	}
+	ohead, err := freezer.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	otail, err := freezer.Tail()
+	if err != nil {
+		return 0, err
+	}
+	// Leave the out of range targets to truncateFromTail as well.
+	if otail >= ntail || ntail > ohead || oldestHistory(db, otail) > otail {
+		return truncateFromTail(db, freezer, ntail)
+	}
+	return archiveFromTail(db, freezer, otail, ntail)
+}
+
+// archiveFromTail moves the state histories below the new tail from the freezer
+// into the archive, keeping their root->id lookups. It returns the number of
+// items removed from the freezer.
+func archiveFromTail(db ethdb.KeyValueStore, freezer *rawdb.ResettableFreezer, otail, ntail uint64) (int, error) {
+	first, _ := rawdb.ReadHistoryArchiveRange(db)
+	batch := db.NewBatch()
+	for id := otail + 1; id <= ntail; id++ {
+		meta := rawdb.ReadStateHistoryMeta(freezer, id)
+		if len(meta) == 0 {
+			return 0, fmt.Errorf("state history [%d] not found", id)
+		}
+		writeArchivedHistory(batch, &archivedHistory{
+			id:           id,
+			meta:         meta,
+			accountIndex: rawdb.ReadStateAccountIndex(freezer, id),
+			storageIndex: rawdb.ReadStateStorageIndex(freezer, id),
+			accountData:  rawdb.ReadStateAccountHistory(freezer, id),
+			storageData:  rawdb.ReadStateStorageHistory(freezer, id),
+		})
+	}
+	// The archive may overlap the freezer after a crash in between, which
+	// is resolved by the next pruning.
+	rawdb.WriteHistoryArchiveRange(batch, first, ntail)
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	pruned, err := freezer.TruncateTail(ntail)
+	if err != nil {
+		return 0, err
+	}
+	return int(ntail - pruned), nil
+}
+
+// deleteHistoryArchive removes all the archived state histories. Their root->id
+// mappings and lookups are left in the disk, to be overwritten, as the ones of
+// the histories in the freezer when it's reset.
+func deleteHistoryArchive(db ethdb.KeyValueStore) error {
+	first, last := rawdb.ReadHistoryArchiveRange(db)
+	if first == 0 {
+		return nil
+	}
+	// Drop the range first, leaving no dangling range on a crash.
+	rawdb.DeleteHistoryArchiveRange(db)
+
+	batch := db.NewBatch()
+	for id := first; id <= last; id++ {
+		rawdb.DeleteArchivedHistory(batch, id)
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestHistoryArchive(t *testing.T) {
+	var (
+		hs         = makeHistories(10)
+		freezer, _ = openFreezer(t.TempDir(), false)
+	)
+	defer freezer.Close()
+
+	for i := 0; i < len(hs); i++ {
+		accountData, storageData, accountIndex, storageIndex, _ := hs[i].encode()
+		rawdb.WriteStateHistory(freezer, uint64(i+1), hs[i].meta.encode(), accountIndex, storageIndex, accountData, storageData)
+	}
+	// Export the histories into two archives.
+	var first, second bytes.Buffer
+	if err := ExportHistoryArchive(freezer, &first, 1, 6); err != nil {
+		t.Fatalf("Failed to export state histories: %v", err)
+	}
+	if err := ExportHistoryArchive(freezer, &second, 5, 10); err != nil {
+		t.Fatalf("Failed to export state histories: %v", err)
+	}
+	if err := ExportHistoryArchive(freezer, &bytes.Buffer{}, 5, 11); err == nil {
+		t.Fatal("Unexpected export of unavailable state histories")
+	}
+	// Import them in order, the overlapped histories are skipped.
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		target, _ = openFreezer(t.TempDir(), false)
+	)
+	defer target.Close()
+
+	rawdb.WritePersistentStateID(db, 8)
+	if _, err := ImportHistoryArchive(db, target, bytes.NewReader(second.Bytes()), false); err == nil {
+		t.Fatal("Unexpected import of discontinuous state histories")
+	}
+	if n, err := ImportHistoryArchive(db, target, bytes.NewReader(first.Bytes()), false); err != nil || n != 6 {
+		t.Fatalf("Failed to import state histories, imported %d: %v", n, err)
+	}
+	// The histories above the persisted state are truncated on startup, the
+	// ones below are still imported.
+	if n, err := ImportHistoryArchive(db, target, bytes.NewReader(second.Bytes()), false); err == nil || n != 2 {
+		t.Fatalf("Unexpected import of state histories above the persisted state, imported %d", n)
+	}
+	rawdb.WritePersistentStateID(db, 10)
+	if n, err := ImportHistoryArchive(db, target, bytes.NewReader(second.Bytes()), false); err != nil || n != 2 {
+		t.Fatalf("Failed to import state histories, imported %d: %v", n, err)
+	}
+	for i := 0; i < len(hs); i++ {
+		id := uint64(i + 1)
+		if !bytes.Equal(rawdb.ReadStateHistoryMeta(target, id), rawdb.ReadStateHistoryMeta(freezer, id)) ||
+			!bytes.Equal(rawdb.ReadStateAccountIndex(target, id), rawdb.ReadStateAccountIndex(freezer, id)) ||
+			!bytes.Equal(rawdb.ReadStateStorageIndex(target, id), rawdb.ReadStateStorageIndex(freezer, id)) ||
+			!bytes.Equal(rawdb.ReadStateAccountHistory(target, id), rawdb.ReadStateAccountHistory(freezer, id)) ||
+			!bytes.Equal(rawdb.ReadStateStorageHistory(target, id), rawdb.ReadStateStorageHistory(freezer, id)) {
+			t.Fatalf("State history %d is mismatched", id)
+		}
+		checkHistory(t, db, target, id, hs[i].meta.root, true)
+	}
+	// A corrupted archive is rejected as a whole.
+	corrupted := bytes.Clone(first.Bytes())
+	corrupted[len(corrupted)/2] ^= 0xff
+	if err := readHistoryArchive(bytes.NewReader(corrupted), nil); err == nil {
+		t.Fatal("Unexpected read of corrupted archive")
+	}
+}
+
+// importHistoryArchiveBelowTail prunes the oldest histories of a fresh tester,
+// imports them back as an archive and reopens the database with the config.
+func importHistoryArchiveBelowTail(t *testing.T, config *Config) *tester {
+	tester := newTester(t, 0)
+
+	// Export the oldest histories and prune them afterwards.
+	var archive bytes.Buffer
+	if err := ExportHistoryArchive(tester.db.freezer, &archive, 1, 64); err != nil {
+		t.Fatalf("Failed to export state histories: %v", err)
+	}
+	if _, err := truncateFromTail(tester.db.diskdb, tester.db.freezer, 32); err != nil {
+		t.Fatalf("Failed to prune state histories: %v", err)
+	}
+	if err := tester.db.Journal(tester.lastHash()); err != nil {
+		t.Fatalf("Failed to journal the layers: %v", err)
+	}
+	tester.db.Close()
+
+	ancient, _ := tester.db.diskdb.AncientDatadir()
+	freezer, err := rawdb.NewStateFreezer(ancient, false, false)
+	if err != nil {
+		t.Fatalf("Failed to open state freezer: %v", err)
+	}
+	if n, err := ImportHistoryArchive(tester.db.diskdb, freezer, bytes.NewReader(archive.Bytes()), false); err != nil || n != 32 {
+		t.Fatalf("Failed to import state histories, imported: %d, err: %v", n, err)
+	}
+	freezer.Close()
+
+	tester.db = New(tester.db.diskdb, config)
+	if first, last := rawdb.ReadHistoryArchiveRange(tester.db.diskdb); first != 1 || last != 32 {
+		t.Fatalf("Unexpected archived state histories, want: [1, 32], got: [%d, %d]", first, last)
+	}
+	return tester
+}
+
+func TestImportHistoryArchiveBelowTail(t *testing.T) {
+	config := &Config{UseBase: true, HistoryIndex: true, HistoryArchive: true}
+	tester := importHistoryArchiveBelowTail(t, config)
+	defer tester.release()
+
+	// The archived histories are kept and served once the database is reopened,
+	// with the histories pruned since then being archived as well.
+	if _, err := tester.db.indexer.truncateTail(48, func() (int, error) {
+		return pruneHistories(tester.db.diskdb, tester.db.freezer, 48, config.HistoryArchive)
+	}); err != nil {
+		t.Fatalf("Failed to prune state histories: %v", err)
+	}
+	if first, last := rawdb.ReadHistoryArchiveRange(tester.db.diskdb); first != 1 || last != 48 {
+		t.Fatalf("Unexpected archived state histories, want: [1, 48], got: [%d, %d]", first, last)
+	}
+	if err := tester.indexHistories(); err != nil {
+		t.Fatalf("Failed to index state histories: %v", err)
+	}
+	for _, i := range []int{0, 40} {
+		if id := rawdb.ReadStateID(tester.db.diskdb, tester.roots[i]); id == nil || *id != uint64(i+1) {
+			t.Fatalf("Unexpected state id of the archived history %d", i+1)
+		}
+		if err := tester.verifyHistoricState(i); err != nil {
+			t.Fatalf("Invalid historical state %d: %v", i, err)
+		}
+	}
+	// The histories are dropped along with the freezer.
+	if err := tester.db.resetHistories(); err != nil {
+		t.Fatalf("Failed to reset state histories: %v", err)
+	}
+	if first, _ := rawdb.ReadHistoryArchiveRange(tester.db.diskdb); first != 0 || len(rawdb.ReadArchivedHistory(tester.db.diskdb, 1)) != 0 {
+		t.Fatal("Unexpected archived state histories after reset")
+	}
+}
+
+func TestImportHistoryArchivePruned(t *testing.T) {
+	config := &Config{UseBase: true, HistoryIndex: true}
+	tester := importHistoryArchiveBelowTail(t, config)
+	defer tester.release()
+
+	// The pruned histories are deleted without the archive flag, leaving the
+	// imported archive untouched but disconnected from the retained ones.
+	if err := tester.indexHistories(); err != nil {
+		t.Fatalf("Failed to index state histories: %v", err)
+	}
+	if _, err := tester.db.indexer.truncateTail(48, func() (int, error) {
+		return pruneHistories(tester.db.diskdb, tester.db.freezer, 48, config.HistoryArchive)
+	}); err != nil {
+		t.Fatalf("Failed to prune state histories: %v", err)
+	}
+	if first, last := rawdb.ReadHistoryArchiveRange(tester.db.diskdb); first != 1 || last != 32 {
+		t.Fatalf("Unexpected archived state histories, want: [1, 32], got: [%d, %d]", first, last)
+	}
+	if tail, _ := tester.db.freezer.Tail(); tail != 48 {
+		t.Fatalf("Unexpected state history tail, want: 48, got: %d", tail)
+	}
+	if err := tester.verifyHistoricState(0); err == nil {
+		t.Fatal("Expected the disconnected archived history to be unavailable")
+	}
+	for _, addr := range tester.preimages {
+		if id, ok := rawdb.ReadAccountHistoryLookup(tester.db.diskdb, addr, 33); ok && id <= 48 {
+			t.Fatalf("Unexpected lookup of the pruned history %d", id)
+		}
+	}
+	if err := tester.verifyHistoricState(48); err != nil {
+		t.Fatalf("Invalid historical state 48: %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// historyIndexBatch is the maximum number of state histories indexed at
+	// once, holding off the truncation of the histories meanwhile.
+	historyIndexBatch = 128
+
+	// maxUnindexedHistories is the maximum number of state histories not indexed
+	// yet which are scanned by a historical state read.
+	maxUnindexedHistories = 128
+
+	// historyCacheSize is the number of decoded state histories cached for the
+	// historical state reads.
+	historyCacheSize = 64
+)
+
+// errHistoryIndexBehind is returned if a historical state read would have to
+// scan too many state histories not indexed yet.
+var errHistoryIndexBehind = errors.New("state history index is not ready yet")
+
+// historyIndexer indexes the state histories in the background by the accounts
+// and storage slots they touch. The value of an account or a storage slot at a
+// historical state is then found in the first state history touching it after
+// that state, or in the disk state if none did.
+//
+// The index is kept in sync with the state histories: the lookups of the
+// histories truncated from the head or the tail are removed along with them.
+type historyIndexer struct {
+	diskdb  ethdb.KeyValueStore
+	freezer *rawdb.ResettableFreezer
+	cache   *lru.Cache[uint64, *history] // Recently read state histories, by id
+	lock    sync.Mutex                   // Lock to serialize the indexing and the truncation
+
+	wakeCh  chan struct{}
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newHistoryIndexer creates the indexer of the state histories in the freezer,
+// start must be called to run it.
+func newHistoryIndexer(diskdb ethdb.KeyValueStore, freezer *rawdb.ResettableFreezer) *historyIndexer {
+	return &historyIndexer{
+		diskdb:  diskdb,
+		freezer: freezer,
+		cache:   lru.NewCache[uint64, *history](historyCacheSize),
+		wakeCh:  make(chan struct{}, 1),
+		closeCh: make(chan struct{}),
+	}
+}
+
+// start runs the background indexing.
+func (i *historyIndexer) start() {
+	if i == nil {
+		return
+	}
+	i.wg.Add(1)
+	go i.loop()
+}
+
+// close stops the background indexing.
+func (i *historyIndexer) close() {
+	if i == nil {
+		return
+	}
+	select {
+	case <-i.closeCh:
+	default:
+		close(i.closeCh)
+	}
+	i.wg.Wait()
+}
+
+// notify wakes the indexer up to index the newly written state histories.
+func (i *historyIndexer) notify() {
+	if i == nil {
+		return
+	}
+	select {
+	case i.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// loop indexes the state histories whenever new ones are written.
+func (i *historyIndexer) loop() {
+	defer i.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var (
+		logged  = time.Now()
+		started = rawdb.ReadHistoryLookupHead(i.diskdb)
+	)
+	for {
+		done, err := i.index()
+		if err != nil {
+			log.Error("Failed to index state histories", "err", err)
+		}
+		if !done && err == nil {
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Indexing state histories", "from", started, "indexed", rawdb.ReadHistoryLookupHead(i.diskdb))
+				logged = time.Now()
+			}
+			select {
+			case <-i.closeCh:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-i.wakeCh:
+		case <-ticker.C:
+		case <-i.closeCh:
+			return
+		}
+	}
+}
+
+// index indexes a batch of the state histories not indexed yet, and reports
+// whether all of them are indexed.
+func (i *historyIndexer) index() (bool, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	tail, err := i.freezer.Tail()
+	if err != nil {
+		return false, err
+	}
+	head, err := i.freezer.Ancients()
+	if err != nil {
+		return false, err
+	}
+	// The lookups of the pruned histories are not needed, skip them.
+	indexed := max(rawdb.ReadHistoryLookupHead(i.diskdb), oldestHistory(i.diskdb, tail)-1)
+	if indexed >= head {
+		return true, nil
+	}
+	var (
+		last  = min(head, indexed+historyIndexBatch)
+		batch = i.diskdb.NewBatch()
+	)
+	for id := indexed + 1; id <= last; id++ {
+		h, err := readStoredHistory(i.diskdb, i.freezer, id)
+		if err != nil {
+			return false, err
+		}
+		writeHistoryLookups(batch, h, id)
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			rawdb.WriteHistoryLookupHead(batch, id)
+			if err := batch.Write(); err != nil {
+				return false, err
+			}
+			batch.Reset()
+		}
+	}
+	rawdb.WriteHistoryLookupHead(batch, last)
+	if err := batch.Write(); err != nil {
+		return false, err
+	}
+	return last == head, nil
+}
+
+// truncateHead removes the lookups of the state histories above the new head
+// before truncating them by the given function.
+func (i *historyIndexer) truncateHead(nhead uint64, truncate func() (int, error)) (int, error) {
+	if i == nil {
+		return truncate()
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if indexed := rawdb.ReadHistoryLookupHead(i.diskdb); indexed > nhead {
+		tail, err := i.freezer.Tail()
+		if err != nil {
+			return 0, err
+		}
+		head, err := i.freezer.Ancients()
+		if err != nil {
+			return 0, err
+		}
+		batch := i.diskdb.NewBatch()
+		for id := max(nhead, tail) + 1; id <= indexed && id <= head; id++ {
+			h, err := readHistory(i.freezer, id, false)
+			if err != nil {
+				return 0, err
+			}
+			deleteHistoryLookups(batch, h, id)
+		}
+		rawdb.WriteHistoryLookupHead(batch, nhead)
+		if err := batch.Write(); err != nil {
+			return 0, err
+		}
+	}
+	// The ids of the truncated histories are reused by the following ones.
+	i.cache.Purge()
+	return truncate()
+}
+
+// truncateTail truncates the state histories below the new tail by the given
+// function, and removes their lookups afterwards unless they are archived. The
+// leftover lookups of a crash in between are harmless, as no read looks up
+// pruned histories.
+func (i *historyIndexer) truncateTail(ntail uint64, truncate func() (int, error)) (int, error) {
+	if i == nil {
+		return truncate()
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	tail, err := i.freezer.Tail()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		indexed   = rawdb.ReadHistoryLookupHead(i.diskdb)
+		histories = make(map[uint64]*history)
+	)
+	for id := tail + 1; id <= ntail && id <= indexed; id++ {
+		h, err := readHistory(i.freezer, id, false)
+		if err != nil {
+			return 0, err
+		}
+		histories[id] = h
+	}
+	pruned, err := truncate()
+	if err != nil || len(histories) == 0 {
+		return pruned, err
+	}
+	// The lookups are kept if the truncated histories are moved into the archive.
+	if first, last := rawdb.ReadHistoryArchiveRange(i.diskdb); first != 0 && last >= ntail {
+		return pruned, nil
+	}
+	batch := i.diskdb.NewBatch()
+	for id, h := range histories {
+		deleteHistoryLookups(batch, h, id)
+	}
+	return pruned, batch.Write()
+}
+
+// history returns the state history of the given id.
+func (i *historyIndexer) history(id uint64) (*history, error) {
+	if h, ok := i.cache.Get(id); ok {
+		return h, nil
+	}
+	h, err := readStoredHistory(i.diskdb, i.freezer, id)
+	if err != nil {
+		return nil, err
+	}
+	i.cache.Add(id, h)
+	return h, nil
+}
+
+// find returns the first state history within [from, to] matching the given
+// function, looked up by the given lookup function among the indexed ones and
+// scanned among the others. Nil is returned if there is none.
+func (i *historyIndexer) find(from, to uint64, lookup func(from uint64) (uint64, bool), match func(*history) bool) (uint64, *history, error) {
+	indexed := rawdb.ReadHistoryLookupHead(i.diskdb)
+	for from <= min(indexed, to) {
+		id, ok := lookup(from)
+		if !ok || id > indexed || id > to {
+			break
+		}
+		h, err := i.history(id)
+		if err != nil {
+			return 0, nil, err
+		}
+		// Lookups left over by a crash may be stale, skip them.
+		if match(h) {
+			return id, h, nil
+		}
+		from = id + 1
+	}
+	from = max(from, indexed+1)
+	if from <= to && to-from >= maxUnindexedHistories {
+		return 0, nil, errHistoryIndexBehind
+	}
+	for id := from; id <= to; id++ {
+		h, err := i.history(id)
+		if err != nil {
+			return 0, nil, err
+		}
+		if match(h) {
+			return id, h, nil
+		}
+	}
+	return 0, nil, nil
+}
+
+// writeHistoryLookups indexes the state history by the accounts and storage
+// slots it touches.
+func writeHistoryLookups(db ethdb.KeyValueWriter, h *history, id uint64) {
+	for _, addr := range h.accountList {
+		rawdb.WriteAccountHistoryLookup(db, addr, id)
+	}
+	for addr, slots := range h.storageList {
+		for _, slot := range slots {
+			rawdb.WriteStorageHistoryLookup(db, addr, slot, id)
+		}
+	}
+	for _, addr := range h.meta.incomplete {
+		rawdb.WriteIncompleteHistoryLookup(db, addr, id)
+	}
+}
+
+// deleteHistoryLookups removes the lookups of the state history.
+func deleteHistoryLookups(db ethdb.KeyValueWriter, h *history, id uint64) {
+	for _, addr := range h.accountList {
+		rawdb.DeleteAccountHistoryLookup(db, addr, id)
+	}
+	for addr, slots := range h.storageList {
+		for _, slot := range slots {
+			rawdb.DeleteStorageHistoryLookup(db, addr, slot, id)
+		}
+	}
+	for _, addr := range h.meta.incomplete {
+		rawdb.DeleteIncompleteHistoryLookup(db, addr, id)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie/triestate"
+)
+
+var (
+	// errHistoryIndexDisabled is returned if a historical state is requested
+	// while the state histories are not indexed.
+	errHistoryIndexDisabled = errors.New("state history index is disabled")
+
+	// errIncompleteStorage is returned if a historical storage slot is requested
+	// while the state histories since then don't record all the changed slots
+	// of the account, due to the deletion of a large contract.
+	errIncompleteStorage = errors.New("storage history is incomplete")
+)
+
+// HistoricReader serves the accounts and storage slots of a historical state,
+// whose trie nodes are gone, from the state histories since then and the disk
+// state. The value of an account or a storage slot is the original one in the
+// first state history touching it after the historical state, or the one in
+// the disk state if none did.
+type HistoricReader struct {
+	db     *Database
+	loader triestate.TrieLoader
+	root   common.Hash
+	id     uint64
+}
+
+// HistoricReader returns a reader of the historical state with the given root,
+// the tries of the disk state being loaded by the given loader. The state must
+// be at or below the disk layer, with the state histories since then kept.
+func (db *Database) HistoricReader(root common.Hash, loader triestate.TrieLoader) (*HistoricReader, error) {
+	if db.indexer == nil {
+		return nil, errHistoryIndexDisabled
+	}
+	root = types.TrieRootHash(root)
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	if dl := db.tree.bottom(); *id > dl.stateID() {
+		return nil, fmt.Errorf("state %#x is not historical", root)
+	}
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	if *id+1 < oldestHistory(db.diskdb, tail) {
+		return nil, fmt.Errorf("state history of %#x is pruned", root)
+	}
+	return &HistoricReader{db: db, loader: loader, root: root, id: *id}, nil
+}
+
+// Account returns the account at the historical state, nil if it's absent.
+func (r *HistoricReader) Account(address common.Address) (*types.StateAccount, error) {
+	for {
+		dl := r.db.tree.bottom()
+		_, h, err := r.db.indexer.find(r.id+1, dl.stateID(), func(from uint64) (uint64, bool) {
+			return rawdb.ReadAccountHistoryLookup(r.db.diskdb, address, from)
+		}, func(h *history) bool {
+			_, ok := h.accounts[address]
+			return ok
+		})
+		if err != nil {
+			return nil, err
+		}
+		if h != nil {
+			blob := h.accounts[address]
+			if len(blob) == 0 {
+				return nil, nil
+			}
+			return types.FullAccount(blob)
+		}
+		account, err := r.diskAccount(dl, address)
+		if err != nil && r.db.tree.bottom() != dl {
+			continue // the disk layer was replaced meanwhile, retry
+		}
+		return account, err
+	}
+}
+
+// Storage returns the value of the storage slot with the given hash at the
+// historical state, nil if it's absent.
+func (r *HistoricReader) Storage(address common.Address, slot common.Hash) ([]byte, error) {
+	for {
+		dl := r.db.tree.bottom()
+		id, h, err := r.db.indexer.find(r.id+1, dl.stateID(), func(from uint64) (uint64, bool) {
+			return rawdb.ReadStorageHistoryLookup(r.db.diskdb, address, slot, from)
+		}, func(h *history) bool {
+			_, ok := h.storages[address][slot]
+			return ok
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The slot could be deleted along with a large contract in between,
+		// without being recorded.
+		last := dl.stateID()
+		if h != nil {
+			last = id - 1
+		}
+		_, incomplete, err := r.db.indexer.find(r.id+1, last, func(from uint64) (uint64, bool) {
+			return rawdb.ReadIncompleteHistoryLookup(r.db.diskdb, address, from)
+		}, func(h *history) bool {
+			return slices.Contains(h.meta.incomplete, address)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if incomplete != nil {
+			return nil, errIncompleteStorage
+		}
+		var blob []byte
+		if h != nil {
+			blob = h.storages[address][slot]
+		} else {
+			blob, err = r.diskStorage(dl, address, slot)
+			if err != nil {
+				if r.db.tree.bottom() != dl {
+					continue // the disk layer was replaced meanwhile, retry
+				}
+				return nil, err
+			}
+		}
+		if len(blob) == 0 {
+			return nil, nil
+		}
+		_, content, _, err := rlp.Split(blob)
+		return content, err
+	}
+}
+
+// diskAccount returns the account in the state of the disk layer.
+func (r *HistoricReader) diskAccount(dl *diskLayer, address common.Address) (*types.StateAccount, error) {
+	tr, err := r.loader.OpenTrie(dl.rootHash())
+	if err != nil {
+		return nil, err
+	}
+	blob, err := tr.Get(crypto.Keccak256(address.Bytes()))
+	if err != nil || len(blob) == 0 {
+		return nil, err
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// diskStorage returns the rlp encoded storage slot in the state of the disk layer.
+func (r *HistoricReader) diskStorage(dl *diskLayer, address common.Address, slot common.Hash) ([]byte, error) {
+	account, err := r.diskAccount(dl, address)
+	if err != nil || account == nil || account.Root == types.EmptyRootHash {
+		return nil, err
+	}
+	tr, err := r.loader.OpenStorageTrie(dl.rootHash(), crypto.Keccak256Hash(address.Bytes()), account.Root)
+	if err != nil {
+		return nil, err
+	}
+	return tr.Get(slot.Bytes())
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie/triestate"
+)
+
+// indexHistories indexes all the state histories of the tester.
+func (t *tester) indexHistories() error {
+	for {
+		done, err := t.db.indexer.index()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// cachedLoader caches the opened tries of the hash loader, which are expensive
+// to open as the states are rehashed.
+type cachedLoader struct {
+	loader   *hashLoader
+	account  triestate.Trie
+	storages map[common.Hash]triestate.Trie
+}
+
+func (l *cachedLoader) OpenTrie(root common.Hash) (triestate.Trie, error) {
+	if l.account == nil {
+		tr, err := l.loader.OpenTrie(root)
+		if err != nil {
+			return nil, err
+		}
+		l.account = tr
+	}
+	return l.account, nil
+}
+
+func (l *cachedLoader) OpenStorageTrie(stateRoot common.Hash, addrHash, root common.Hash) (triestate.Trie, error) {
+	if tr, ok := l.storages[addrHash]; ok {
+		return tr, nil
+	}
+	tr, err := l.loader.OpenStorageTrie(stateRoot, addrHash, root)
+	if err != nil {
+		return nil, err
+	}
+	l.storages[addrHash] = tr
+	return tr, nil
+}
+
+// verifyHistoricState checks the historical state of the given index against
+// the state snapshot.
+func (t *tester) verifyHistoricState(index int) error {
+	var (
+		root   = t.roots[index]
+		bottom = t.db.tree.bottom().rootHash()
+		loader = &cachedLoader{
+			loader:   newHashLoader(t.snapAccounts[bottom], t.snapStorages[bottom]),
+			storages: make(map[common.Hash]triestate.Trie),
+		}
+	)
+	reader, err := t.db.HistoricReader(root, loader)
+	if err != nil {
+		return err
+	}
+	for addrHash, addr := range t.preimages {
+		account, err := reader.Account(addr)
+		if err != nil {
+			return err
+		}
+		blob := t.snapAccounts[root][addrHash]
+		if len(blob) == 0 {
+			if account != nil {
+				return fmt.Errorf("account %x is unexpected", addr)
+			}
+			continue
+		}
+		want, _ := types.FullAccount(blob)
+		if account == nil || account.Nonce != want.Nonce || account.Balance.Cmp(want.Balance) != 0 || account.Root != want.Root {
+			return fmt.Errorf("account %x is mismatched", addr)
+		}
+		for hash, slot := range t.snapStorages[root][addrHash] {
+			value, err := reader.Storage(addr, hash)
+			if err != nil {
+				return err
+			}
+			_, content, _, _ := rlp.Split(slot)
+			if !bytes.Equal(value, content) {
+				return fmt.Errorf("slot %x of account %x is mismatched", hash, addr)
+			}
+		}
+	}
+	return nil
+}
+
+func TestHistoricReader(t *testing.T) {
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	if _, err := tester.db.HistoricReader(tester.roots[0], nil); err != errHistoryIndexDisabled {
+		t.Fatalf("Unexpected error, want: %v, got: %v", errHistoryIndexDisabled, err)
+	}
+	tester.db.indexer = newHistoryIndexer(tester.db.diskdb, tester.db.freezer)
+	if err := tester.indexHistories(); err != nil {
+		t.Fatalf("Failed to index state histories: %v", err)
+	}
+	bottom := tester.bottomIndex()
+	if head := rawdb.ReadHistoryLookupHead(tester.db.diskdb); head != uint64(bottom+1) {
+		t.Fatalf("Unexpected indexed head, want: %d, got: %d", bottom+1, head)
+	}
+	for _, i := range []int{0, bottom} {
+		if err := tester.verifyHistoricState(i); err != nil {
+			t.Fatalf("Invalid historical state %d: %v", i, err)
+		}
+	}
+	if _, err := tester.db.HistoricReader(tester.lastHash(), nil); err == nil {
+		t.Fatal("Unexpected historic reader of the state above the disk layer")
+	}
+	// Revert the database, the lookups of the truncated histories are removed
+	// along with them.
+	target := bottom / 2
+	for i := bottom; i > target; i-- {
+		root := tester.roots[i]
+		loader := newHashLoader(tester.snapAccounts[root], tester.snapStorages[root])
+		if err := tester.db.Recover(tester.roots[i-1], loader); err != nil {
+			t.Fatalf("Failed to revert db: %v", err)
+		}
+	}
+	if head := rawdb.ReadHistoryLookupHead(tester.db.diskdb); head != uint64(target+1) {
+		t.Fatalf("Unexpected indexed head, want: %d, got: %d", target+1, head)
+	}
+	for _, i := range []int{target} {
+		if err := tester.verifyHistoricState(i); err != nil {
+			t.Fatalf("Invalid historical state %d: %v", i, err)
+		}
+	}
+}
@@ -98,6 +98,7 @@ type JournalFileReader struct {
 type JournalKVWriter struct {
 	journalBuf bytes.Buffer
 	diskdb     ethdb.Database
+	put        func(ethdb.KeyValueWriter, []byte) // Stores the journal, rawdb.WriteTrieJournal if nil
 }
 
 type JournalKVReader struct {
@@ -129,7 +130,11 @@ func (kw *JournalKVWriter) Write(b []byte) (int, error) {
 }
 
 func (kw *JournalKVWriter) Close() {
-	rawdb.WriteTrieJournal(kw.diskdb, kw.journalBuf.Bytes())
+	if kw.put != nil {
+		kw.put(kw.diskdb, kw.journalBuf.Bytes())
+	} else {
+		rawdb.WriteTrieJournal(kw.diskdb, kw.journalBuf.Bytes())
+	}
 	kw.journalBuf.Reset()
 }
 
@@ -277,7 +282,7 @@ func (db *Database) loadLayers() layer {
 		log.Info("Recover node buffer list from ancient db")
 
 		nb, err = NewTrieNodeBuffer(db.diskdb, db.config.TrieNodeBufferType, db.bufferSize, nil, 0,
-			db.config.ProposeBlockInterval, db.config.NotifyKeep, db.freezer, db.fastRecovery, db.useBase)
+			db.config.ProposeBlockInterval, db.config.NotifyKeep, db.freezer, db.fastRecovery, db.useBase, db.newCheckpointConfig())
 		if err != nil {
 			log.Error("Failed to new trie node buffer for recovery", "error", err)
 		} else {
@@ -289,7 +294,7 @@ func (db *Database) loadLayers() layer {
 	if nb == nil || err != nil {
 		// Return single layer with persistent state.
 		nb, err = NewTrieNodeBuffer(db.diskdb, db.config.TrieNodeBufferType, db.bufferSize, nil, 0,
-			db.config.ProposeBlockInterval, db.config.NotifyKeep, nil, false, db.useBase)
+			db.config.ProposeBlockInterval, db.config.NotifyKeep, nil, false, db.useBase, db.newCheckpointConfig())
 		if err != nil {
 			log.Crit("Failed to new trie node buffer", "error", err)
 			return nil
@@ -365,7 +370,7 @@ func (db *Database) loadDiskLayer(r *rlp.Stream, journalTypeForReader JournalTyp
 
 	// Calculate the internal state transitions by id difference.
 	nb, err := NewTrieNodeBuffer(db.diskdb, db.config.TrieNodeBufferType, db.bufferSize, nodes, id-stored, db.config.ProposeBlockInterval,
-		db.config.NotifyKeep, nil, false, db.useBase)
+		db.config.NotifyKeep, nil, false, db.useBase, db.newCheckpointConfig())
 	if err != nil {
 		log.Error("Failed to new trie node buffer", "error", err)
 		return nil, err
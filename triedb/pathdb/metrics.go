@@ -61,6 +61,10 @@ var (
 	baseNodeBufferDifflayerAvgSize = metrics.NewRegisteredGauge("pathdb/basenodebuffer/difflayeravgsize", nil)
 	proposedBlockReaderSuccess     = metrics.NewRegisteredMeter("pathdb/nodebufferlist/proposedblockreader/success", nil)
 	proposedBlockReaderMismatch    = metrics.NewRegisteredMeter("pathdb/nodebufferlist/proposedblockreader/mismatch", nil)
+	nodeBufferListCheckpointTimer  = metrics.NewRegisteredTimer("pathdb/nodebufferlist/checkpoint/time", nil)
+	nodeBufferListCheckpointSize   = metrics.NewRegisteredGauge("pathdb/nodebufferlist/checkpoint/size", nil)
+	nodeBufferListRecoveryTimer    = metrics.NewRegisteredTimer("pathdb/nodebufferlist/recovery/time", nil)
+	nodeBufferListRecoveryReplayed = metrics.NewRegisteredGauge("pathdb/nodebufferlist/recovery/replayed", nil)
 
 	// pbss difflayer cache
 	diffHashCacheHitMeter      = metrics.NewRegisteredMeter("pathdb/difflayer/hashcache/hit", nil)
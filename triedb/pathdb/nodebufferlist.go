@@ -77,6 +77,9 @@ type nodebufferlist struct {
 	forceKeepCh     chan struct{}  // Trigger force keep event loop.
 	waitForceKeepCh chan struct{}  // Wait force keep event loop.
 	keepFunc        NotifyKeepFunc // Used to keep op-proposer output proof.
+
+	checkpointCfg *checkpointConfig // Checkpoint config, nil if not checkpointed.
+	checkpointID  uint64            // The last state id in the latest checkpoint.
 }
 
 // newNodeBufferList initializes the node buffer list with the provided nodes
@@ -90,6 +93,7 @@ func newNodeBufferList(
 	freezer *rawdb.ResettableFreezer,
 	fastRecovery bool,
 	useBase bool,
+	checkpoint *checkpointConfig,
 ) (*nodebufferlist, error) {
 	var (
 		rsevMdNum uint64
@@ -124,7 +128,7 @@ func newNodeBufferList(
 		err error
 	)
 	if !useBase && fastRecovery {
-		nf, err = recoverNodeBufferList(db, freezer, base, limit, wpBlocks, rsevMdNum, dlInMd)
+		nf, err = recoverNodeBufferList(db, freezer, base, limit, wpBlocks, rsevMdNum, dlInMd, checkpoint)
 		if err != nil {
 			log.Error("Failed to recover node buffer list", "error", err)
 			return nil, err
@@ -147,6 +151,7 @@ func newNodeBufferList(
 			forceKeepCh:     make(chan struct{}),
 			waitForceKeepCh: make(chan struct{}),
 			keepFunc:        keepFunc,
+			checkpointCfg:   checkpoint,
 		}
 		nf.useBase.Store(useBase)
 	}
@@ -159,9 +164,11 @@ func newNodeBufferList(
 	return nf, nil
 }
 
-// recoverNodeBufferList recovers node buffer list
+// recoverNodeBufferList recovers node buffer list, from the latest checkpoint
+// and the state histories after it if possible.
 func recoverNodeBufferList(db ethdb.Database, freezer *rawdb.ResettableFreezer, base *multiDifflayer,
-	limit, wpBlocks, rsevMdNum, dlInMd uint64) (*nodebufferlist, error) {
+	limit, wpBlocks, rsevMdNum, dlInMd uint64, checkpoint *checkpointConfig) (*nodebufferlist, error) {
+	start := time.Now()
 	nbl := &nodebufferlist{
 		db:              db,
 		wpBlocks:        wpBlocks,
@@ -174,6 +181,7 @@ func recoverNodeBufferList(db ethdb.Database, freezer *rawdb.ResettableFreezer,
 		waitStopCh:      make(chan struct{}),
 		forceKeepCh:     make(chan struct{}),
 		waitForceKeepCh: make(chan struct{}),
+		checkpointCfg:   checkpoint,
 	}
 	head, err := freezer.Ancients()
 	if err != nil {
@@ -188,6 +196,18 @@ func recoverNodeBufferList(db ethdb.Database, freezer *rawdb.ResettableFreezer,
 	log.Info("Ancient db meta info", "persistent_state_id", nbl.persistID, "head_state_id", head,
 		"tail_state_id", tail, "waiting_recover_num", head-nbl.persistID)
 
+	if checkpoint != nil {
+		last, err := nbl.loadCheckpoint(freezer, head)
+		if err == nil {
+			return nbl.recoverFromCheckpoint(freezer, last, head, start)
+		}
+		if !errors.Is(err, errMissJournal) {
+			log.Warn("Failed to load node buffer list checkpoint, discard it", "error", err)
+		}
+		nbl.base.reset()
+		nbl.head, nbl.tail = nil, nil
+		nbl.size, nbl.layers, nbl.count, nbl.stateId, nbl.block = 0, 0, 0, 0, 0
+	}
 	startStateID := nbl.persistID + 1
 	startBlock, err := readBlockNumber(freezer, startStateID)
 	if err != nil {
@@ -237,11 +257,33 @@ func recoverNodeBufferList(db ethdb.Database, freezer *rawdb.ResettableFreezer,
 	}
 	nbl.diffToBase()
 
+	nodeBufferListRecoveryTimer.UpdateSince(start)
+	nodeBufferListRecoveryReplayed.Update(int64(head - nbl.persistID))
 	log.Info("Succeed to add diff layer", "base_size", nbl.base.size, "tail_state_id", nbl.tail.id,
 		"head_state_id", nbl.head.id, "nbl_layers", nbl.layers, "base_layers", nbl.base.layers)
 	return nbl, nil
 }
 
+// recoverFromCheckpoint replays the state histories after the loaded checkpoint,
+// the last state id of which is given, to finish recovering the node buffer list.
+func (nf *nodebufferlist) recoverFromCheckpoint(freezer *rawdb.ResettableFreezer, last, head uint64, start time.Time) (*nodebufferlist, error) {
+	for id := last + 1; id <= head; id++ {
+		h, err := nf.readStateHistory(freezer, id)
+		if err != nil {
+			return nil, err
+		}
+		nf.commit(h.meta.root, id, h.meta.block, flattenTrieNodes(h.nodes))
+	}
+	nf.diffToBase()
+
+	nodeBufferListRecoveryTimer.UpdateSince(start)
+	nodeBufferListRecoveryReplayed.Update(int64(head - last))
+	log.Info("Recovered node buffer list from checkpoint", "persist_id", nf.persistID, "checkpoint_state_id", last,
+		"head_state_id", head, "replayed", head-last, "nbl_layers", nf.layers, "base_layers", nf.base.layers,
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return nf, nil
+}
+
 // linkMultiDiffLayers links specified amount of multiDiffLayers for recovering
 func (nf *nodebufferlist) linkMultiDiffLayers(blockIntervalLength int) {
 	for i := 0; i < blockIntervalLength; i++ {
@@ -253,10 +295,13 @@ func (nf *nodebufferlist) linkMultiDiffLayers(blockIntervalLength int) {
 
 func (nf *nodebufferlist) readStateHistory(freezer *rawdb.ResettableFreezer, stateID uint64) (*history, error) {
 	h, err := readHistory(freezer, stateID, true)
-	if err != nil || h.nodes == nil {
+	if err != nil {
 		log.Error("Failed to read history from freezer db", "error", err)
 		return nil, err
 	}
+	if h.nodes == nil {
+		return nil, fmt.Errorf("trie nodes of state history [%d] not found", stateID)
+	}
 	return h, nil
 }
 
@@ -767,6 +812,13 @@ func (nf *nodebufferlist) backgroundFlush() {
 func (nf *nodebufferlist) loop() {
 	mergeTicker := time.NewTicker(time.Second * mergeMultiDifflayerInterval)
 	defer mergeTicker.Stop()
+
+	var checkpointCh <-chan time.Time
+	if nf.checkpointCfg != nil {
+		checkpointTicker := time.NewTicker(nf.checkpointCfg.interval)
+		defer checkpointTicker.Stop()
+		checkpointCh = checkpointTicker.C
+	}
 	for {
 		select {
 		case <-nf.stopCh:
@@ -820,6 +872,14 @@ func (nf *nodebufferlist) loop() {
 				nf.backgroundFlush()
 			}
 			nf.isFlushing.Swap(false)
+
+		case <-checkpointCh:
+			if nf.stopFlushing.Load() {
+				continue
+			}
+			if err := nf.checkpoint(); err != nil {
+				log.Error("Failed to checkpoint node buffer list", "error", err)
+			}
 		}
 	}
 }
@@ -965,6 +1025,9 @@ type multiDifflayer struct {
 	limit  uint64                                    // The maximum memory allowance in bytes
 	nodes  map[common.Hash]map[string]*trienode.Node // The dirty node set, mapped by owner and path
 
+	shared bool                     // Whether the nodes are shared with a checkpoint, copied on write
+	owned  map[common.Hash]struct{} // The subsets copied since the nodes were last shared
+
 	pre  *multiDifflayer
 	next *multiDifflayer
 }
@@ -1019,7 +1082,7 @@ func (mf *multiDifflayer) commit(root common.Hash, id uint64, block uint64, laye
 		overwriteSize int64
 	)
 	for owner, subset := range nodes {
-		current, exist := mf.nodes[owner]
+		current, exist := mf.subset(owner)
 		if !exist {
 			// Allocate a new map for the subset instead of claiming it directly
 			// from the passed map to avoid potential concurrent map read/write.
@@ -1032,6 +1095,9 @@ func (mf *multiDifflayer) commit(root common.Hash, id uint64, block uint64, laye
 				delta += int64(len(n.Blob) + len(path))
 			}
 			mf.nodes[owner] = current
+			if mf.owned != nil {
+				mf.owned[owner] = struct{}{}
+			}
 			continue
 		}
 		for path, n := range subset {
@@ -1075,6 +1141,42 @@ func (mf *multiDifflayer) reset() {
 	mf.pre = nil
 	mf.next = nil
 	mf.nodes = make(map[common.Hash]map[string]*trienode.Node)
+	mf.shared = false
+	mf.owned = nil
+}
+
+// share returns the dirty nodes to be read without holding the locks, which
+// are copied on the next write instead of modified in place.
+func (mf *multiDifflayer) share() map[common.Hash]map[string]*trienode.Node {
+	mf.shared = true
+	mf.owned = nil
+	return mf.nodes
+}
+
+// subset returns the writable subset of the owner, copying it first if it's
+// still shared.
+func (mf *multiDifflayer) subset(owner common.Hash) (map[string]*trienode.Node, bool) {
+	if mf.shared {
+		nodes := make(map[common.Hash]map[string]*trienode.Node, len(mf.nodes))
+		for o, subset := range mf.nodes {
+			nodes[o] = subset
+		}
+		mf.nodes, mf.shared, mf.owned = nodes, false, make(map[common.Hash]struct{})
+	}
+	current, ok := mf.nodes[owner]
+	if !ok || mf.owned == nil {
+		return current, ok
+	}
+	if _, copied := mf.owned[owner]; !copied {
+		subset := make(map[string]*trienode.Node, len(current))
+		for path, n := range current {
+			subset[path] = n
+		}
+		current = subset
+		mf.nodes[owner] = current
+		mf.owned[owner] = struct{}{}
+	}
+	return current, true
 }
 
 // empty returns an indicator if multiDifflayer contains any state transition inside.
@@ -1126,7 +1228,7 @@ func (mf *multiDifflayer) revert(db ethdb.KeyValueReader, nodes map[common.Hash]
 	}
 	var delta int64
 	for owner, subset := range nodes {
-		current, ok := mf.nodes[owner]
+		current, ok := mf.subset(owner)
 		if !ok {
 			panic(fmt.Sprintf("non-existent subset (%x)", owner))
 		}
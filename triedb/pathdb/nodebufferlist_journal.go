@@ -0,0 +1,445 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+const (
+	// nodeBufferListJournalVersion is the version of the node buffer list
+	// checkpoint.
+	nodeBufferListJournalVersion uint64 = 0
+
+	// nodeBufferListJournalSuffix is appended to the journal file path to
+	// name the file type checkpoint of the node buffer list.
+	nodeBufferListJournalSuffix = ".nodebufferlist"
+
+	// DefaultCheckpointInterval defines the interval to checkpoint the node
+	// buffer list.
+	DefaultCheckpointInterval = 5 * time.Minute
+)
+
+// maxCheckpointKVSize is the maximum size of the buffered nodes written into a
+// KV type checkpoint, which is stored as a single value. The larger ones are
+// written into the file type checkpoint if available.
+var maxCheckpointKVSize uint64 = 256 * 1024 * 1024
+
+// checkpointConfig tells where and how often the node buffer list is
+// checkpointed, so that it can be recovered from the latest checkpoint and the
+// state histories after it, rather than all the unpersisted ones.
+type checkpointConfig struct {
+	db          ethdb.Database // Key-value store holding the KV type checkpoint
+	file        string         // Path of the file type checkpoint, empty if unavailable
+	journalType JournalType    // Type of the checkpoints to write
+	interval    time.Duration  // Interval between two checkpoints
+}
+
+// newCheckpointConfig returns the checkpoint config of the node buffer list,
+// nil if it's not recoverable from the state histories at all.
+func (db *Database) newCheckpointConfig() *checkpointConfig {
+	if db.readOnly || !db.fastRecovery || db.useBase || db.config.TrieNodeBufferType != NodeBufferList {
+		return nil
+	}
+	config := &checkpointConfig{
+		db:          db.diskdb,
+		journalType: db.DetermineJournalTypeForWriter(),
+		interval:    DefaultCheckpointInterval,
+	}
+	if db.config.CheckpointInterval > 0 {
+		config.interval = db.config.CheckpointInterval
+	}
+	if db.config.JournalFilePath != "" {
+		config.file = db.config.JournalFilePath + nodeBufferListJournalSuffix
+	} else {
+		config.journalType = JournalKVType
+	}
+	return config
+}
+
+// deleteCheckpoint removes the node buffer list checkpoints of both types.
+func (db *Database) deleteCheckpoint() {
+	rawdb.DeleteNodeBufferListJournal(db.diskdb)
+	if db.config.JournalFilePath == "" {
+		return
+	}
+	file := db.config.JournalFilePath + nodeBufferListJournalSuffix
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Error("Failed to remove node buffer list journal", "path", file, "err", err)
+	}
+}
+
+// newWriter creates the writer of a new checkpoint of the given type. The file
+// type checkpoint is written into a temporary file, which replaces the previous
+// one once done.
+func (c *checkpointConfig) newWriter(journalType JournalType) JournalWriter {
+	if journalType == JournalKVType {
+		return &JournalKVWriter{
+			diskdb: c.db,
+			put:    rawdb.WriteNodeBufferListJournal,
+		}
+	}
+	if err := os.Remove(c.file + ".tmp"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return newJournalWriter(c.file+".tmp", c.db, JournalFileType)
+}
+
+// newReader opens the latest checkpoint, preferring the KV type one as in
+// DetermineJournalTypeForReader.
+func (c *checkpointConfig) newReader() (JournalReader, JournalType, error) {
+	if journal := rawdb.ReadNodeBufferListJournal(c.db); len(journal) != 0 {
+		return &JournalKVReader{journalBuf: bytes.NewBuffer(journal)}, JournalKVType, nil
+	}
+	if c.file == "" {
+		return nil, JournalKVType, errMissJournal
+	}
+	fd, err := os.Open(c.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, JournalFileType, errMissJournal
+	}
+	if err != nil {
+		return nil, JournalFileType, err
+	}
+	return &JournalFileReader{file: fd}, JournalFileType, nil
+}
+
+// journalMultiDifflayer represents a multiDifflayer persisted in the checkpoint.
+type journalMultiDifflayer struct {
+	Root   common.Hash
+	ID     uint64
+	Block  uint64
+	Layers uint64
+	Nodes  []journalNodes
+}
+
+// nodeBufferListCheckpoint is the content of a node buffer list checkpoint.
+type nodeBufferListCheckpoint struct {
+	persistID uint64                  // The persisted state id when checkpointed
+	base      *journalMultiDifflayer  // The base multiDifflayer, nil if persisted since then
+	layers    []journalMultiDifflayer // The multiDifflayers, from the tail to the head
+}
+
+// writeCheckpointEntry writes an entry into the checkpoint, wrapped with the
+// checksum in the file type one as the layer journal does.
+func writeCheckpointEntry(w io.Writer, journalType JournalType, val interface{}) error {
+	blob, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	if journalType == JournalFileType {
+		if err := rlp.Encode(w, blob); err != nil {
+			return err
+		}
+		return rlp.Encode(w, sha256.Sum256(blob))
+	}
+	_, err = w.Write(blob)
+	return err
+}
+
+// readCheckpointEntry reads an entry from the checkpoint, io.EOF is returned
+// if the checkpoint is exhausted.
+func readCheckpointEntry(r *rlp.Stream, journalType JournalType, val interface{}) error {
+	if journalType != JournalFileType {
+		return r.Decode(val)
+	}
+	var (
+		blob   []byte
+		shaSum [32]byte
+	)
+	if err := r.Decode(&blob); err != nil {
+		return err
+	}
+	if err := r.Decode(&shaSum); err != nil {
+		return fmt.Errorf("failed to load shasum: %v", err)
+	}
+	if expected := sha256.Sum256(blob); shaSum != expected {
+		return fmt.Errorf("expected shaSum: %v, real:%v", expected, shaSum)
+	}
+	return rlp.DecodeBytes(blob, val)
+}
+
+// checkpoint writes the base and all the multiDifflayers into a new checkpoint,
+// replacing the previous one. It's only called by the background loop, which
+// moves the multiDifflayers to the base and flushes it, so no flushing happens
+// meanwhile.
+func (nf *nodebufferlist) checkpoint() error {
+	if nf.checkpointCfg == nil {
+		return nil
+	}
+	start := time.Now()
+
+	// The buffers are only shared under the lock, being copied on write since
+	// then, so that the nodes are compressed and written without the lock.
+	nf.mux.Lock()
+	nf.baseMux.Lock()
+	if nf.stateId == nf.checkpointID {
+		nf.baseMux.Unlock()
+		nf.mux.Unlock()
+		return nil
+	}
+	var (
+		stateID   = nf.stateId
+		persistID = nf.persistID
+		buffered  = nf.base.size
+		base      = journalMultiDifflayer{
+			Root:   nf.base.root,
+			ID:     nf.base.id,
+			Block:  nf.base.block,
+			Layers: nf.base.layers,
+		}
+		baseNodes  = nf.base.share()
+		layers     []journalMultiDifflayer
+		layerNodes []map[common.Hash]map[string]*trienode.Node
+	)
+	nf.traverseReverse(func(buffer *multiDifflayer) bool {
+		layers = append(layers, journalMultiDifflayer{
+			Root:   buffer.root,
+			ID:     buffer.id,
+			Block:  buffer.block,
+			Layers: buffer.layers,
+		})
+		layerNodes = append(layerNodes, buffer.share())
+		buffered += buffer.size
+		return true
+	})
+	nf.baseMux.Unlock()
+	nf.mux.Unlock()
+
+	base.Nodes = compressTrieNodes(baseNodes)
+	for i := range layers {
+		layers[i].Nodes = compressTrieNodes(layerNodes[i])
+	}
+	journalType := nf.checkpointCfg.journalType
+	if journalType == JournalKVType && buffered > maxCheckpointKVSize {
+		if nf.checkpointCfg.file == "" {
+			return fmt.Errorf("node buffer list too large for the KV type checkpoint, size %v", common.StorageSize(buffered))
+		}
+		journalType = JournalFileType
+	}
+	w := nf.checkpointCfg.newWriter(journalType)
+	if w == nil {
+		return errors.New("failed to create node buffer list journal")
+	}
+	write := func() error {
+		if err := rlp.Encode(w, nodeBufferListJournalVersion); err != nil {
+			return err
+		}
+		if err := writeCheckpointEntry(w, journalType, persistID); err != nil {
+			return err
+		}
+		if err := writeCheckpointEntry(w, journalType, &base); err != nil {
+			return err
+		}
+		for i := range layers {
+			if err := writeCheckpointEntry(w, journalType, &layers[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := write(); err != nil {
+		w.Close()
+		return err
+	}
+	size := w.Size()
+	w.Close()
+
+	// Drop the checkpoint of the other type, it's stale anyway.
+	if journalType == JournalFileType {
+		if err := os.Rename(nf.checkpointCfg.file+".tmp", nf.checkpointCfg.file); err != nil {
+			return err
+		}
+		rawdb.DeleteNodeBufferListJournal(nf.checkpointCfg.db)
+	} else if nf.checkpointCfg.file != "" {
+		if err := os.Remove(nf.checkpointCfg.file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	nf.checkpointID = stateID
+
+	nodeBufferListCheckpointTimer.UpdateSince(start)
+	nodeBufferListCheckpointSize.Update(int64(size))
+	log.Info("Checkpointed node buffer list", "persist_id", persistID, "state_id", stateID, "layers", len(layers),
+		"size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// readNodeBufferListCheckpoint reads the latest checkpoint of the node buffer
+// list.
+func readNodeBufferListCheckpoint(config *checkpointConfig) (*nodeBufferListCheckpoint, error) {
+	reader, journalType, err := config.newReader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	r := rlp.NewStream(reader, 0)
+	version, err := r.Uint64()
+	if err != nil {
+		return nil, errMissVersion
+	}
+	if version != nodeBufferListJournalVersion {
+		return nil, fmt.Errorf("%w want %d got %d", errUnexpectedVersion, nodeBufferListJournalVersion, version)
+	}
+	var (
+		cp   = new(nodeBufferListCheckpoint)
+		base journalMultiDifflayer
+	)
+	if err := readCheckpointEntry(r, journalType, &cp.persistID); err != nil {
+		return nil, fmt.Errorf("failed to load persisted state id: %v", err)
+	}
+	if err := readCheckpointEntry(r, journalType, &base); err != nil {
+		return nil, fmt.Errorf("failed to load base multi difflayer: %v", err)
+	}
+	cp.base = &base
+	for {
+		var layer journalMultiDifflayer
+		if err := readCheckpointEntry(r, journalType, &layer); err != nil {
+			if err == io.EOF {
+				return cp, nil
+			}
+			return nil, fmt.Errorf("failed to load multi difflayer: %v", err)
+		}
+		cp.layers = append(cp.layers, layer)
+	}
+}
+
+// trim drops the multiDifflayers of the checkpoint which are already persisted,
+// as the base and the tail ones are possibly flushed after the checkpoint. An
+// error is returned if the checkpoint is not aligned with the persisted state.
+func (cp *nodeBufferListCheckpoint) trim(persistID uint64) error {
+	if cp.persistID == persistID {
+		return nil
+	}
+	// The base might be loaded from the layer journal without the state id
+	// resolved, which is derived from the persisted one as in flushing.
+	current := cp.persistID + cp.base.Layers
+	cp.base = nil
+	if current == persistID {
+		return nil
+	}
+	for i, layer := range cp.layers {
+		if layer.Layers != 0 {
+			current = layer.ID
+		}
+		if current == persistID {
+			cp.layers = cp.layers[i+1:]
+			return nil
+		}
+	}
+	return fmt.Errorf("checkpoint persisted state id %d is not aligned with %d", cp.persistID, persistID)
+}
+
+// verify checks the multiDifflayers of the checkpoint against the state
+// histories, in case the state is reverted after the checkpoint. The state id
+// of the last multiDifflayer is returned.
+func (cp *nodeBufferListCheckpoint) verify(freezer *rawdb.ResettableFreezer, persistID, head uint64) (uint64, error) {
+	check := func(layer *journalMultiDifflayer) error {
+		if layer.Layers == 0 || layer.ID == 0 {
+			return nil
+		}
+		if layer.ID > head {
+			return fmt.Errorf("state history [%d] is not available, head %d", layer.ID, head)
+		}
+		blob := rawdb.ReadStateHistoryMeta(freezer, layer.ID)
+		if len(blob) == 0 {
+			return fmt.Errorf("state history [%d] not found", layer.ID)
+		}
+		var m meta
+		if err := m.decode(blob); err != nil {
+			return err
+		}
+		if m.root != layer.Root || m.block != layer.Block {
+			return fmt.Errorf("state history [%d] is mismatched, want %#x(%d), got %#x(%d)", layer.ID, layer.Root, layer.Block, m.root, m.block)
+		}
+		return nil
+	}
+	last := persistID
+	if cp.base != nil {
+		if err := check(cp.base); err != nil {
+			return 0, err
+		}
+		last += cp.base.Layers
+	}
+	for i := range cp.layers {
+		if err := check(&cp.layers[i]); err != nil {
+			return 0, err
+		}
+		if cp.layers[i].Layers != 0 {
+			last = cp.layers[i].ID
+		}
+	}
+	return last, nil
+}
+
+// loadCheckpoint rebuilds the base and the multiDifflayers from the latest
+// checkpoint, returning the state id of the last recovered state transition.
+func (nf *nodebufferlist) loadCheckpoint(freezer *rawdb.ResettableFreezer, head uint64) (uint64, error) {
+	cp, err := readNodeBufferListCheckpoint(nf.checkpointCfg)
+	if err != nil {
+		return 0, err
+	}
+	if err := cp.trim(nf.persistID); err != nil {
+		return 0, err
+	}
+	last, err := cp.verify(freezer, nf.persistID, head)
+	if err != nil {
+		return 0, err
+	}
+	if cp.base != nil && cp.base.Layers != 0 {
+		if err := nf.base.commit(cp.base.Root, cp.base.ID, cp.base.Block, cp.base.Layers, flattenTrieNodes(cp.base.Nodes)); err != nil {
+			return 0, err
+		}
+	}
+	for _, layer := range cp.layers {
+		mdl := newMultiDifflayer(nf.limit, 0, common.Hash{}, make(map[common.Hash]map[string]*trienode.Node), 0)
+		if layer.Layers != 0 {
+			if err := mdl.commit(layer.Root, layer.ID, layer.Block, layer.Layers, flattenTrieNodes(layer.Nodes)); err != nil {
+				return 0, err
+			}
+			nf.stateId, nf.block = layer.ID, layer.Block
+		}
+		nf.pushFront(mdl)
+	}
+	if nf.head == nil {
+		nf.pushFront(newMultiDifflayer(nf.limit, 0, common.Hash{}, make(map[common.Hash]map[string]*trienode.Node), 0))
+	}
+	nf.size, nf.layers, nf.count = 0, 0, 0
+	nf.traverse(func(buffer *multiDifflayer) bool {
+		nf.size += buffer.size
+		nf.layers += buffer.layers
+		nf.count++
+		return true
+	})
+	nf.checkpointID = last
+	return last, nil
+}
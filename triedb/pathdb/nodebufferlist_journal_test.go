@@ -0,0 +1,200 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package pathdb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie/testutil"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+)
+
+// makeBlockHistories returns the state histories of the consecutive blocks
+// since the first one, with the trie nodes inside.
+func makeBlockHistories(n int) []*history {
+	var (
+		parent = types.EmptyRootHash
+		result []*history
+	)
+	for i := 0; i < n; i++ {
+		root := testutil.RandomHash()
+		result = append(result, newHistory(root, parent, uint64(i+1), randomStateSet(3), randomTrieNodes(3)))
+		parent = root
+	}
+	return result
+}
+
+// recoverTestBufferList recovers a node buffer list from the freezer, with the
+// settings of the given one.
+func recoverTestBufferList(t *testing.T, freezer *rawdb.ResettableFreezer, nf *nodebufferlist, checkpoint *checkpointConfig) *nodebufferlist {
+	base := newMultiDifflayer(nf.limit, 0, common.Hash{}, make(map[common.Hash]map[string]*trienode.Node), 0)
+	recovered, err := recoverNodeBufferList(nf.db, freezer, base, nf.limit, nf.wpBlocks, nf.rsevMdNum, nf.dlInMd, checkpoint)
+	if err != nil {
+		t.Fatalf("Failed to recover node buffer list: %v", err)
+	}
+	return recovered
+}
+
+func TestNodeBufferListCheckpoint(t *testing.T) {
+	for _, journalType := range []JournalType{JournalKVType, JournalFileType} {
+		var (
+			hs         = makeBlockHistories(10)
+			freezer, _ = rawdb.NewStateFreezer(t.TempDir(), false, true)
+			checkpoint = &checkpointConfig{
+				db:          rawdb.NewMemoryDatabase(),
+				file:        filepath.Join(t.TempDir(), "journal"+nodeBufferListJournalSuffix),
+				journalType: journalType,
+				interval:    time.Hour,
+			}
+		)
+		for i, h := range hs {
+			accountData, storageData, accountIndex, storageIndex, trieNodes := h.encode()
+			rawdb.WriteStateHistoryWithTrieNodes(freezer, uint64(i+1), h.meta.encode(), accountIndex, storageIndex, accountData, storageData, trieNodes)
+		}
+		nf, err := newNodeBufferList(checkpoint.db, DefaultBufferSize, nil, 0, 4, nil, freezer, false, false, checkpoint)
+		if err != nil {
+			t.Fatalf("Failed to create node buffer list: %v", err)
+		}
+		nf.waitAndStopFlushing()
+
+		// Checkpoint the node buffer list in the middle of the histories.
+		for i, h := range hs[:6] {
+			nf.commit(h.meta.root, uint64(i+1), h.meta.block, flattenTrieNodes(h.nodes))
+		}
+		if err := nf.checkpoint(); err != nil {
+			t.Fatalf("Failed to checkpoint node buffer list: %v", err)
+		}
+		if journalType == JournalKVType && len(rawdb.ReadNodeBufferListJournal(checkpoint.db)) == 0 {
+			t.Fatal("Node buffer list journal is not stored")
+		}
+		// Recover from the checkpoint, the histories after it are replayed.
+		recovered := recoverTestBufferList(t, freezer, nf, checkpoint)
+		if id := recovered.checkpointID; id != 6 {
+			t.Fatalf("Unexpected checkpointed state id, want: %d, got: %d", 6, id)
+		}
+		full := recoverTestBufferList(t, freezer, nf, nil)
+		if recovered.stateId != 10 || recovered.getLayers() != full.getLayers() {
+			t.Fatalf("Unexpected recovered node buffer list, state id: %d, layers: %d, want: %d", recovered.stateId, recovered.getLayers(), full.getLayers())
+		}
+		want, got := full.getAllNodes(), recovered.getAllNodes()
+		if len(want) != len(got) {
+			t.Fatalf("Unexpected number of owners, want: %d, got: %d", len(want), len(got))
+		}
+		for owner, subset := range want {
+			for path, n := range subset {
+				if m, ok := got[owner][path]; !ok || !bytes.Equal(m.Blob, n.Blob) {
+					t.Fatalf("Trie node %x:%x is mismatched", owner, path)
+				}
+			}
+		}
+		// The checkpoint of the reverted states is discarded.
+		freezer.Close()
+		other, _ := rawdb.NewStateFreezer(t.TempDir(), false, true)
+		for i, h := range makeBlockHistories(10) {
+			accountData, storageData, accountIndex, storageIndex, trieNodes := h.encode()
+			rawdb.WriteStateHistoryWithTrieNodes(other, uint64(i+1), h.meta.encode(), accountIndex, storageIndex, accountData, storageData, trieNodes)
+		}
+		if recovered := recoverTestBufferList(t, other, nf, checkpoint); recovered.checkpointID != 0 || recovered.stateId != 0 {
+			t.Fatalf("Unexpected recovery from the mismatched checkpoint, state id %d", recovered.checkpointID)
+		}
+		other.Close()
+	}
+}
+
+// Tests that the nodes shared with a checkpoint are copied on write instead of
+// modified in place.
+func TestMultiDifflayerShare(t *testing.T) {
+	var (
+		mf     = newMultiDifflayer(DefaultBufferSize, 0, common.Hash{}, make(map[common.Hash]map[string]*trienode.Node), 0)
+		nodes  = randomTrieNodes(3)
+		owner  common.Hash
+		update = make(map[common.Hash]map[string]*trienode.Node)
+	)
+	mf.commit(testutil.RandomHash(), 1, 1, 1, nodes)
+	for o, subset := range nodes {
+		owner, update[o] = o, make(map[string]*trienode.Node)
+		for path := range subset {
+			update[o][path] = trienode.New(testutil.RandomHash(), testutil.RandomHash().Bytes())
+		}
+	}
+	for o, subset := range randomTrieNodes(3) {
+		update[o] = subset
+	}
+	shared := mf.share()
+	mf.commit(testutil.RandomHash(), 2, 2, 1, update)
+
+	if len(shared) != 1 {
+		t.Fatalf("Shared owners modified, want: %d, got: %d", 1, len(shared))
+	}
+	for path, n := range nodes[owner] {
+		if shared[owner][path] != n {
+			t.Fatalf("Shared trie node %x is modified", path)
+		}
+		if m := mf.nodes[owner][path]; m != update[owner][path] {
+			t.Fatalf("Trie node %x is not updated", path)
+		}
+	}
+	if len(mf.nodes) != 2 {
+		t.Fatalf("Unexpected owners, want: %d, got: %d", 2, len(mf.nodes))
+	}
+}
+
+// Tests that the node buffer list too large for the KV type checkpoint is
+// checkpointed into the file instead, or not at all without the file.
+func TestNodeBufferListCheckpointKVSize(t *testing.T) {
+	defer func(size uint64) { maxCheckpointKVSize = size }(maxCheckpointKVSize)
+	maxCheckpointKVSize = 1
+
+	for _, file := range []string{filepath.Join(t.TempDir(), "journal"+nodeBufferListJournalSuffix), ""} {
+		var (
+			freezer, _ = rawdb.NewStateFreezer(t.TempDir(), false, true)
+			checkpoint = &checkpointConfig{
+				db:          rawdb.NewMemoryDatabase(),
+				file:        file,
+				journalType: JournalKVType,
+				interval:    time.Hour,
+			}
+		)
+		nf, err := newNodeBufferList(checkpoint.db, DefaultBufferSize, nil, 0, 4, nil, freezer, false, false, checkpoint)
+		if err != nil {
+			t.Fatalf("Failed to create node buffer list: %v", err)
+		}
+		nf.waitAndStopFlushing()
+
+		h := makeBlockHistories(1)[0]
+		nf.commit(h.meta.root, 1, h.meta.block, flattenTrieNodes(h.nodes))
+		err = nf.checkpoint()
+		if len(rawdb.ReadNodeBufferListJournal(checkpoint.db)) != 0 {
+			t.Fatal("Oversized node buffer list journal is stored")
+		}
+		if file == "" {
+			if err == nil {
+				t.Fatal("Oversized node buffer list checkpointed without the file")
+			}
+		} else if _, ferr := os.Stat(file); err != nil || ferr != nil {
+			t.Fatalf("Node buffer list is not checkpointed into the file: %v, %v", err, ferr)
+		}
+		freezer.Close()
+	}
+}